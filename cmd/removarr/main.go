@@ -3,20 +3,28 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"removarr/internal/config"
 	"removarr/internal/server"
+	"removarr/internal/tracing"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	_ "removarr/docs" // swag init will generate this
 )
 
+// shutdownTimeout is how long shutdown waits for in-flight requests and background work
+const shutdownTimeout = 30 * time.Second
+
 // @title           Removarr API
 // @version         1.0
 // @description     API for managing seedbox media deletion
@@ -32,6 +40,10 @@ import (
 // @BasePath  /api
 
 // @securityDefinitions.basic  BasicAuth
+
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-Api-Key
 func main() {
 	var configPath string
 	flag.StringVar(&configPath, "config", "config.yaml", "Path to configuration file")
@@ -49,6 +61,16 @@ func main() {
 	logger := setupLogger(cfg)
 	slog.SetDefault(logger)
 
+	// Export traces, if enabled
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	if cfg.Tracing.Enabled {
+		slog.Info("Tracing enabled", "endpoint", cfg.Tracing.Endpoint, "sample_ratio", cfg.Tracing.Ratio())
+	}
+
 	// Connect to database
 	db, err := connectDatabase(cfg)
 	if err != nil {
@@ -63,7 +85,8 @@ func main() {
 	// Start server
 	go func() {
 		slog.Info("Starting server", "host", cfg.Server.Host, "port", cfg.Server.Port)
-		if err := srv.Start(); err != nil {
+		// ErrServerClosed just means Shutdown was called
+		if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("Server error", "error", err)
 			os.Exit(1)
 		}
@@ -75,9 +98,16 @@ func main() {
 	<-quit
 
 	slog.Info("Shutting down server...")
-	if err := srv.Shutdown(context.Background()); err != nil {
+	// In-flight syncs and deletions are cancelled; give them a moment to record where they stopped
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
 		slog.Error("Error shutting down server", "error", err)
 	}
+	// Export the spans of the work that just stopped
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
 }
 
 func setupLogger(cfg *config.Config) *slog.Logger {
@@ -102,7 +132,8 @@ func setupLogger(cfg *config.Config) *slog.Logger {
 		handler = slog.NewTextHandler(os.Stdout, opts)
 	}
 
-	return slog.New(handler)
+	// Log lines written with a context carry its trace and span IDs
+	return slog.New(tracing.LogHandler{Handler: handler})
 }
 
 func connectDatabase(cfg *config.Config) (*sql.DB, error) {
@@ -116,10 +147,13 @@ func connectDatabase(cfg *config.Config) (*sql.DB, error) {
 		cfg.Database.SSLMode,
 	)
 
-	db, err := sql.Open("pgx", dsn)
+	connConfig, err := pgx.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	// Queries show up as spans of the request or job that made them
+	connConfig.Tracer = tracing.QueryTracer{}
+	db := stdlib.OpenDB(*connConfig)
 
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
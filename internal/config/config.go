@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -21,6 +22,9 @@ type Config struct {
 	QBittorrent QBittorrentConfig `yaml:"-"` // Ignored in YAML, loaded from DB
 	Tautulli TautulliConfig `yaml:"-"` // Ignored in YAML, loaded from DB
 	Logging  LoggingConfig  `yaml:"logging"`
+	Health   HealthConfig   `yaml:"health"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
 }
 
 type ServerConfig struct {
@@ -86,6 +90,35 @@ type TautulliConfig struct {
 	APIKey  string `yaml:"api_key"` // Or from env
 }
 
+type HealthConfig struct {
+	// CriticalIntegrations must pass their last health check for /health/ready, e.g. ["sonarr", "radarr", "qbittorrent"]
+	CriticalIntegrations []string `yaml:"critical_integrations"` // Or comma-separated from env
+}
+
+type TracingConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	Endpoint    string   `yaml:"endpoint"`               // OTLP/HTTP collector, e.g. "localhost:4318"; or from env
+	Insecure    bool     `yaml:"insecure"`               // Plain HTTP, e.g. for a collector on the same host
+	ServiceName string   `yaml:"service_name"`           // Defaults to "removarr"
+	SampleRatio *float64 `yaml:"sample_ratio,omitempty"` // Share of traces kept, 0 to 1; nil (unset) keeps all
+}
+
+// Ratio is the share of traces kept: SampleRatio, or 1 if it isn't set
+// An explicit 0 keeps no traces started here, only those an upstream caller sampled.
+func (c TracingConfig) Ratio() float64 {
+	if c.SampleRatio == nil {
+		return 1
+	}
+	return *c.SampleRatio
+}
+
+// MetricsConfig controls /metrics, which is off unless enabled
+// Scrapers send Token as a bearer token; without one, only admins can read the metrics.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Token   string `yaml:"token"` // Or from env, which also turns metrics on
+}
+
 type LoggingConfig struct {
 	Level  string `yaml:"level"` // debug, info, warn, error
 	Format string `yaml:"format"` // json, text
@@ -166,6 +199,27 @@ func (c *Config) loadFromEnv() {
 	if c.QBittorrent.Password == "" {
 		c.QBittorrent.Password = os.Getenv("REMOVARR_QBITTORRENT_PASSWORD")
 	}
+
+	// Tracing collector; setting it turns tracing on
+	if endpoint := os.Getenv("REMOVARR_TRACING_ENDPOINT"); endpoint != "" {
+		c.Tracing.Enabled = true
+		c.Tracing.Endpoint = endpoint
+	}
+
+	// Metrics scrape token; setting it turns metrics on
+	if token := os.Getenv("REMOVARR_METRICS_TOKEN"); token != "" {
+		c.Metrics.Enabled = true
+		c.Metrics.Token = token
+	}
+
+	// Integrations that readiness depends on
+	if critical := os.Getenv("REMOVARR_CRITICAL_INTEGRATIONS"); critical != "" && len(c.Health.CriticalIntegrations) == 0 {
+		for _, name := range strings.Split(critical, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				c.Health.CriticalIntegrations = append(c.Health.CriticalIntegrations, name)
+			}
+		}
+	}
 }
 
 func (c *Config) setDefaults() {
@@ -198,6 +252,13 @@ func (c *Config) setDefaults() {
 		c.Database.SSLMode = "disable"
 	}
 
+	if c.Tracing.Endpoint == "" {
+		c.Tracing.Endpoint = "localhost:4318"
+	}
+	if c.Tracing.ServiceName == "" {
+		c.Tracing.ServiceName = "removarr"
+	}
+
 	if c.Logging.Level == "" {
 		c.Logging.Level = "info"
 	}
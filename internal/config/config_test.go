@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTracingSampleRatio(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want float64
+	}{
+		{"unset keeps all", "tracing:\n  enabled: true\n", 1},
+		{"explicit 0 keeps none", "tracing:\n  enabled: true\n  sample_ratio: 0\n", 0},
+		{"explicit share", "tracing:\n  enabled: true\n  sample_ratio: 0.25\n", 0.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.Tracing.Ratio(); got != tt.want {
+				t.Errorf("Ratio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsTokenFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  port: 8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Metrics.Enabled {
+		t.Error("metrics enabled by default")
+	}
+
+	t.Setenv("REMOVARR_METRICS_TOKEN", "metrics-token")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Metrics.Enabled || cfg.Metrics.Token != "metrics-token" {
+		t.Errorf("Metrics = %+v, want enabled with the token from env", cfg.Metrics)
+	}
+}
@@ -19,22 +19,87 @@ type AuditLog struct {
 	CreatedAt   pgtype.Timestamp `json:"created_at"`
 }
 
+type DeletionRequest struct {
+	ID                int32            `json:"id"`
+	MediaItemID       pgtype.Int4      `json:"media_item_id"`
+	MediaTitle        string           `json:"media_title"`
+	MediaType         string           `json:"media_type"`
+	RequestedByUserID pgtype.Int4      `json:"requested_by_user_id"`
+	Status            string           `json:"status"`
+	Note              pgtype.Text      `json:"note"`
+	ReviewedByUserID  pgtype.Int4      `json:"reviewed_by_user_id"`
+	ReviewedAt        pgtype.Timestamp `json:"reviewed_at"`
+	ReviewNote        pgtype.Text      `json:"review_note"`
+	ExecutedAt        pgtype.Timestamp `json:"executed_at"`
+	LastError         pgtype.Text      `json:"last_error"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+}
+
+type IntegrationCheck struct {
+	ID          int64            `json:"id"`
+	Integration string           `json:"integration"`
+	CheckedAt   pgtype.Timestamp `json:"checked_at"`
+	Status      string           `json:"status"`
+	Version     pgtype.Text      `json:"version"`
+	LatencyMs   int64            `json:"latency_ms"`
+	Error       pgtype.Text      `json:"error"`
+}
+
 type MediaItem struct {
-	ID                 int32            `json:"id"`
-	Title              string           `json:"title"`
-	Type               string           `json:"type"`
-	TmdbID             pgtype.Int4      `json:"tmdb_id"`
-	TvdbID             pgtype.Int4      `json:"tvdb_id"`
-	SonarrID           pgtype.Int4      `json:"sonarr_id"`
-	RadarrID           pgtype.Int4      `json:"radarr_id"`
-	OverseerrRequestID pgtype.Int4      `json:"overseerr_request_id"`
-	RequestedByUserID  pgtype.Int4      `json:"requested_by_user_id"`
-	FilePath           pgtype.Text      `json:"file_path"`
-	FileSize           pgtype.Int8      `json:"file_size"`
-	AddedDate          pgtype.Timestamp `json:"added_date"`
-	LastSyncedAt       pgtype.Timestamp `json:"last_synced_at"`
-	CreatedAt          pgtype.Timestamp `json:"created_at"`
-	UpdatedAt          pgtype.Timestamp `json:"updated_at"`
+	ID                   int32            `json:"id"`
+	Title                string           `json:"title"`
+	Type                 string           `json:"type"`
+	TmdbID               pgtype.Int4      `json:"tmdb_id"`
+	TvdbID               pgtype.Int4      `json:"tvdb_id"`
+	SonarrID             pgtype.Int4      `json:"sonarr_id"`
+	RadarrID             pgtype.Int4      `json:"radarr_id"`
+	OverseerrRequestID   pgtype.Int4      `json:"overseerr_request_id"`
+	RequestedByUserID    pgtype.Int4      `json:"requested_by_user_id"`
+	FilePath             pgtype.Text      `json:"file_path"`
+	FileSize             pgtype.Int8      `json:"file_size"`
+	AddedDate            pgtype.Timestamp `json:"added_date"`
+	LastSyncedAt         pgtype.Timestamp `json:"last_synced_at"`
+	CreatedAt            pgtype.Timestamp `json:"created_at"`
+	UpdatedAt            pgtype.Timestamp `json:"updated_at"`
+	OverseerrRequestOpen bool             `json:"overseerr_request_open"`
+	IsProtected          bool             `json:"is_protected"`
+	ProtectedUntil       pgtype.Timestamp `json:"protected_until"`
+	ProtectionNote       pgtype.Text      `json:"protection_note"`
+	ProtectedByUserID    pgtype.Int4      `json:"protected_by_user_id"`
+	ProtectedByAdmin     bool             `json:"protected_by_admin"`
+	ProtectedByTag       bool             `json:"protected_by_tag"`
+	Tags                 []byte           `json:"tags"`
+	Genres               []byte           `json:"genres"`
+	Year                 pgtype.Int4      `json:"year"`
+	Rating               pgtype.Float8    `json:"rating"`
+	QualityProfile       pgtype.Text      `json:"quality_profile"`
+	Quality              pgtype.Text      `json:"quality"`
+	EligibleSince        pgtype.Timestamp `json:"eligible_since"`
+	RequesterEmail       pgtype.Text      `json:"requester_email"`
+	SyncHash             pgtype.Text      `json:"sync_hash"`
+	SyncGeneration       pgtype.Int8      `json:"sync_generation"`
+	MissingSince         pgtype.Timestamp `json:"missing_since"`
+	EstimatedEligibleAt  pgtype.Timestamp `json:"estimated_eligible_at"`
+	Score                pgtype.Float8    `json:"score"`
+}
+
+type ScheduledDeletion struct {
+	ID                int32            `json:"id"`
+	MediaItemID       pgtype.Int4      `json:"media_item_id"`
+	MediaTitle        string           `json:"media_title"`
+	MediaType         string           `json:"media_type"`
+	ScheduledByUserID pgtype.Int4      `json:"scheduled_by_user_id"`
+	Status            string           `json:"status"`
+	ExecuteAfter      pgtype.Timestamp `json:"execute_after"`
+	Token             string           `json:"token"`
+	NotifiedEmail     pgtype.Text      `json:"notified_email"`
+	PostponedCount    int32            `json:"postponed_count"`
+	CancelReason      pgtype.Text      `json:"cancel_reason"`
+	LastError         pgtype.Text      `json:"last_error"`
+	ExecutedAt        pgtype.Timestamp `json:"executed_at"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
 }
 
 type SeedingOverride struct {
@@ -54,6 +119,53 @@ type Setting struct {
 	UpdatedAt pgtype.Timestamp `json:"updated_at"`
 }
 
+type StorageSnapshot struct {
+	ID                 int32            `json:"id"`
+	TakenAt            pgtype.Timestamp `json:"taken_at"`
+	LibraryBytes       int64            `json:"library_bytes"`
+	LibraryBytesByType []byte           `json:"library_bytes_by_type"`
+	TorrentCount       int32            `json:"torrent_count"`
+	TorrentBytes       int64            `json:"torrent_bytes"`
+	Trackers           []byte           `json:"trackers"`
+	EligibleBytes      int64            `json:"eligible_bytes"`
+	Disks              []byte           `json:"disks"`
+}
+
+type SyncGeneration struct {
+	Source     string           `json:"source"`
+	Generation int64            `json:"generation"`
+	UpdatedAt  pgtype.Timestamp `json:"updated_at"`
+}
+
+type SyncRun struct {
+	ID            int64            `json:"id"`
+	Source        string           `json:"source"`
+	Trigger       string           `json:"trigger"`
+	StartedAt     pgtype.Timestamp `json:"started_at"`
+	FinishedAt    pgtype.Timestamp `json:"finished_at"`
+	DurationMs    pgtype.Int8      `json:"duration_ms"`
+	Error         pgtype.Text      `json:"error"`
+	RowsSeen      int32            `json:"rows_seen"`
+	RowsAdded     int32            `json:"rows_added"`
+	RowsUpdated   int32            `json:"rows_updated"`
+	RowsUnchanged int32            `json:"rows_unchanged"`
+	RowsFailed    int32            `json:"rows_failed"`
+	RowsMissing   int32            `json:"rows_missing"`
+}
+
+type TorrentSample struct {
+	ID                 int64            `json:"id"`
+	TorrentHash        string           `json:"torrent_hash"`
+	SampledAt          pgtype.Timestamp `json:"sampled_at"`
+	Resolution         string           `json:"resolution"`
+	UploadBytes        int64            `json:"upload_bytes"`
+	DownloadBytes      int64            `json:"download_bytes"`
+	Ratio              pgtype.Numeric   `json:"ratio"`
+	SeedingTimeSeconds pgtype.Int8      `json:"seeding_time_seconds"`
+	SwarmSeeders       pgtype.Int4      `json:"swarm_seeders"`
+	SwarmLeechers      pgtype.Int4      `json:"swarm_leechers"`
+}
+
 type TautulliHistory struct {
 	ID            int32            `json:"id"`
 	MediaItemID   pgtype.Int4      `json:"media_item_id"`
@@ -83,17 +195,56 @@ type Torrent struct {
 	LastSyncedAt           pgtype.Timestamp `json:"last_synced_at"`
 	CreatedAt              pgtype.Timestamp `json:"created_at"`
 	UpdatedAt              pgtype.Timestamp `json:"updated_at"`
+	Name                   pgtype.Text      `json:"name"`
+	ContentPath            pgtype.Text      `json:"content_path"`
+	SizeBytes              pgtype.Int8      `json:"size_bytes"`
+	TrackerUrl             pgtype.Text      `json:"tracker_url"`
+	LinkSource             string           `json:"link_source"`
+	IsIgnored              bool             `json:"is_ignored"`
+	CrossSeedGroup         pgtype.Text      `json:"cross_seed_group"`
+	UploadRate             pgtype.Float8    `json:"upload_rate"`
+	SyncHash               pgtype.Text      `json:"sync_hash"`
+	SyncGeneration         pgtype.Int8      `json:"sync_generation"`
+	MissingSince           pgtype.Timestamp `json:"missing_since"`
+	PieceHash              pgtype.Text      `json:"piece_hash"`
 }
 
 type User struct {
-	ID           int32            `json:"id"`
-	Username     string           `json:"username"`
-	Email        pgtype.Text      `json:"email"`
-	PasswordHash pgtype.Text      `json:"password_hash"`
-	PlexID       pgtype.Int4      `json:"plex_id"`
-	PlexUsername pgtype.Text      `json:"plex_username"`
-	IsAdmin      pgtype.Bool      `json:"is_admin"`
-	IsActive     pgtype.Bool      `json:"is_active"`
-	CreatedAt    pgtype.Timestamp `json:"created_at"`
-	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+	ID                 int32            `json:"id"`
+	Username           string           `json:"username"`
+	Email              pgtype.Text      `json:"email"`
+	PasswordHash       pgtype.Text      `json:"password_hash"`
+	PlexID             pgtype.Int4      `json:"plex_id"`
+	PlexUsername       pgtype.Text      `json:"plex_username"`
+	IsAdmin            pgtype.Bool      `json:"is_admin"`
+	IsActive           pgtype.Bool      `json:"is_active"`
+	CreatedAt          pgtype.Timestamp `json:"created_at"`
+	UpdatedAt          pgtype.Timestamp `json:"updated_at"`
+	NotificationEvents []byte           `json:"notification_events"`
+}
+
+type Webhook struct {
+	ID        int32            `json:"id"`
+	Name      string           `json:"name"`
+	Url       string           `json:"url"`
+	Secret    string           `json:"secret"`
+	Events    []byte           `json:"events"`
+	Enabled   bool             `json:"enabled"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64            `json:"id"`
+	WebhookID      int32            `json:"webhook_id"`
+	Event          string           `json:"event"`
+	Payload        []byte           `json:"payload"`
+	Status         string           `json:"status"`
+	Attempts       int32            `json:"attempts"`
+	ResponseStatus pgtype.Int4      `json:"response_status"`
+	LastError      pgtype.Text      `json:"last_error"`
+	NextAttemptAt  pgtype.Timestamp `json:"next_attempt_at"`
+	DeliveredAt    pgtype.Timestamp `json:"delivered_at"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
 }
@@ -9,7 +9,8 @@ CREATE TABLE users (
     is_admin BOOLEAN DEFAULT FALSE,
     is_active BOOLEAN DEFAULT TRUE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    notification_events JSONB NOT NULL DEFAULT '[]' -- event types the user wants emailed
 );
 
 -- Media items cache
@@ -28,7 +29,27 @@ CREATE TABLE media_items (
     added_date TIMESTAMP,
     last_synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    overseerr_request_open BOOLEAN NOT NULL DEFAULT FALSE, -- linked Overseerr request not yet fulfilled
+    is_protected BOOLEAN NOT NULL DEFAULT FALSE, -- never delete (manual)
+    protected_until TIMESTAMP, -- manual protection expiry, NULL means no expiry
+    protection_note TEXT,
+    protected_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    protected_by_admin BOOLEAN NOT NULL DEFAULT FALSE, -- manual protection set by an admin, only admins may clear it
+    protected_by_tag BOOLEAN NOT NULL DEFAULT FALSE, -- has the protection tag in Radarr/Sonarr
+    tags JSONB NOT NULL DEFAULT '[]', -- Radarr/Sonarr tag labels
+    genres JSONB NOT NULL DEFAULT '[]',
+    year INTEGER,
+    rating DOUBLE PRECISION, -- 0-10, IMDb first for movies
+    quality_profile VARCHAR(255),
+    quality VARCHAR(100), -- quality of the file on disk (Radarr only), e.g. 'Remux-2160p'
+    eligible_since TIMESTAMP, -- when the item last became eligible for deletion, NULL while it isn't
+    requester_email VARCHAR(255), -- email of the Overseerr user who requested it
+    sync_hash VARCHAR(64), -- fingerprint of the synced Radarr/Sonarr fields, unchanged rows are skipped
+    sync_generation BIGINT, -- generation of the last full sync that saw the item
+    missing_since TIMESTAMP, -- when a full sync last stopped seeing it in Radarr/Sonarr, NULL while it is there
+    estimated_eligible_at TIMESTAMP, -- when it is estimated to become eligible, NULL while eligible or without an estimate
+    score DOUBLE PRECISION -- dead weight score as of the last sync, NULL if not scored, e.g. protected
 );
 
 -- Indexes for media_items
@@ -37,6 +58,12 @@ CREATE INDEX idx_media_items_sonarr_id ON media_items(sonarr_id);
 CREATE INDEX idx_media_items_radarr_id ON media_items(radarr_id);
 CREATE INDEX idx_media_items_requested_by ON media_items(requested_by_user_id);
 CREATE INDEX idx_media_items_last_synced ON media_items(last_synced_at);
+CREATE INDEX idx_media_items_protected ON media_items(id) WHERE is_protected OR protected_by_tag;
+CREATE INDEX idx_media_items_tags ON media_items USING GIN (tags);
+CREATE INDEX idx_media_items_genres ON media_items USING GIN (genres);
+CREATE INDEX idx_media_items_missing ON media_items(missing_since) WHERE missing_since IS NOT NULL;
+CREATE INDEX idx_media_items_estimated_eligible_at ON media_items(estimated_eligible_at);
+CREATE INDEX idx_media_items_score ON media_items(score);
 
 -- Torrents tracking
 CREATE TABLE torrents (
@@ -56,13 +83,28 @@ CREATE TABLE torrents (
     is_seeding BOOLEAN DEFAULT TRUE,
     last_synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    name TEXT, -- qBittorrent torrent name
+    content_path TEXT, -- qBittorrent content path
+    size_bytes BIGINT DEFAULT 0,
+    tracker_url TEXT, -- raw tracker URL reported by qBittorrent
+    link_source VARCHAR(20) NOT NULL DEFAULT 'auto', -- 'auto' or 'manual'
+    is_ignored BOOLEAN NOT NULL DEFAULT FALSE, -- hidden from the unlinked torrents list
+    cross_seed_group VARCHAR(64), -- shared by torrents with the same data, NULL if not cross-seeded
+    upload_rate DOUBLE PRECISION, -- bytes per second, averaged between syncs
+    sync_hash VARCHAR(64), -- fingerprint of the synced qBittorrent fields, unchanged rows are skipped
+    sync_generation BIGINT, -- generation of the last full sync that saw the torrent
+    missing_since TIMESTAMP, -- when a full sync last stopped seeing it in qBittorrent, NULL while it is there
+    piece_hash VARCHAR(64) -- SHA-256 of the torrent's piece hashes, fetched once for cross-seed detection
 );
 
 -- Indexes for torrents
 CREATE INDEX idx_torrents_media_item ON torrents(media_item_id);
 CREATE INDEX idx_torrents_hash ON torrents(hash);
 CREATE INDEX idx_torrents_tracker ON torrents(tracker_id);
+CREATE INDEX idx_torrents_unlinked ON torrents(media_item_id) WHERE media_item_id IS NULL;
+CREATE INDEX idx_torrents_cross_seed_group ON torrents(cross_seed_group) WHERE cross_seed_group IS NOT NULL;
+CREATE INDEX idx_torrents_missing ON torrents(missing_since) WHERE missing_since IS NOT NULL;
 
 -- Seeding overrides (per-tracker custom requirements)
 CREATE TABLE seeding_overrides (
@@ -105,12 +147,12 @@ CREATE INDEX idx_tautulli_history_last_watched ON tautulli_history(last_watched_
 -- Audit log (deletions only)
 CREATE TABLE audit_logs (
     id SERIAL PRIMARY KEY,
-    user_id INTEGER REFERENCES users(id),
+    user_id INTEGER REFERENCES users(id), -- NULL for actions removarr takes on its own
     action VARCHAR(50) NOT NULL, -- 'delete'
     media_item_id INTEGER REFERENCES media_items(id) ON DELETE SET NULL,
     media_title VARCHAR(500),
     media_type VARCHAR(50),
-    details JSONB, -- Additional context about the deletion
+    details JSONB, -- JSON object with additional context; 'delete' rows carry size_bytes
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -120,3 +162,165 @@ CREATE INDEX idx_audit_logs_media_item ON audit_logs(media_item_id);
 CREATE INDEX idx_audit_logs_created_at ON audit_logs(created_at);
 CREATE INDEX idx_audit_logs_action ON audit_logs(action);
 
+-- Storage usage snapshots (statistics page)
+CREATE TABLE storage_snapshots (
+    id SERIAL PRIMARY KEY,
+    taken_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    library_bytes BIGINT NOT NULL DEFAULT 0,
+    library_bytes_by_type JSONB, -- {"movie": bytes, "series": bytes}
+    torrent_count INTEGER NOT NULL DEFAULT 0,
+    torrent_bytes BIGINT NOT NULL DEFAULT 0,
+    trackers JSONB, -- {"tracker name": {"count": n, "bytes": bytes}}
+    eligible_bytes BIGINT NOT NULL DEFAULT 0, -- library bytes eligible for deletion
+    disks JSONB -- {"/path": {"total_bytes": bytes, "free_bytes": bytes}}
+);
+
+-- Index for storage_snapshots
+CREATE INDEX idx_storage_snapshots_taken_at ON storage_snapshots(taken_at);
+
+-- Torrent counter history (downsampled as it ages)
+CREATE TABLE torrent_samples (
+    id BIGSERIAL PRIMARY KEY,
+    torrent_hash VARCHAR(64) NOT NULL REFERENCES torrents(hash) ON DELETE CASCADE,
+    sampled_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolution VARCHAR(10) NOT NULL DEFAULT 'raw', -- 'raw', 'hour' or 'day'
+    upload_bytes BIGINT NOT NULL DEFAULT 0,
+    download_bytes BIGINT NOT NULL DEFAULT 0,
+    ratio DECIMAL(10, 2) DEFAULT 0,
+    seeding_time_seconds BIGINT DEFAULT 0,
+    swarm_seeders INTEGER, -- seeders in the swarm as reported by the tracker
+    swarm_leechers INTEGER -- leechers in the swarm as reported by the tracker
+);
+
+-- Indexes for torrent_samples
+CREATE INDEX idx_torrent_samples_hash_time ON torrent_samples(torrent_hash, sampled_at);
+CREATE INDEX idx_torrent_samples_resolution_time ON torrent_samples(resolution, sampled_at);
+
+
+-- User deletion requests, approved or rejected by admins
+CREATE TABLE deletion_requests (
+    id SERIAL PRIMARY KEY,
+    media_item_id INTEGER REFERENCES media_items(id) ON DELETE SET NULL,
+    media_title VARCHAR(500) NOT NULL,
+    media_type VARCHAR(50) NOT NULL,
+    requested_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'requested', -- 'requested', 'approved', 'rejected', 'scheduled' or 'executed'
+    note TEXT,
+    reviewed_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    review_note TEXT,
+    executed_at TIMESTAMP,
+    last_error TEXT, -- why the last execution attempt failed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT deletion_requests_status_check CHECK (status IN ('requested', 'approved', 'rejected', 'scheduled', 'executed'))
+);
+
+-- Indexes for deletion_requests
+CREATE INDEX idx_deletion_requests_status ON deletion_requests(status);
+CREATE INDEX idx_deletion_requests_user ON deletion_requests(requested_by_user_id);
+CREATE UNIQUE INDEX idx_deletion_requests_open ON deletion_requests(media_item_id)
+    WHERE status IN ('requested', 'approved');
+
+-- Deletions waiting for their grace period, during which the requester can postpone them or keep the media
+CREATE TABLE scheduled_deletions (
+    id SERIAL PRIMARY KEY,
+    media_item_id INTEGER REFERENCES media_items(id) ON DELETE SET NULL,
+    media_title VARCHAR(500) NOT NULL,
+    media_type VARCHAR(50) NOT NULL,
+    scheduled_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled', -- 'scheduled', 'cancelled' or 'executed'
+    execute_after TIMESTAMP NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE, -- authorizes the postpone/keep link sent to the requester
+    notified_email VARCHAR(255), -- where the heads-up was sent
+    postponed_count INTEGER NOT NULL DEFAULT 0,
+    cancel_reason TEXT,
+    last_error TEXT, -- why the last execution attempt failed
+    executed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT scheduled_deletions_status_check CHECK (status IN ('scheduled', 'cancelled', 'executed'))
+);
+
+-- Indexes for scheduled_deletions
+CREATE INDEX idx_scheduled_deletions_due ON scheduled_deletions(execute_after) WHERE status = 'scheduled';
+CREATE UNIQUE INDEX idx_scheduled_deletions_open ON scheduled_deletions(media_item_id)
+    WHERE status = 'scheduled';
+
+-- Outgoing webhooks: signed JSON POSTs for the events each webhook subscribes to
+CREATE TABLE webhooks (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL, -- HMAC-SHA256 key for the X-Removarr-Signature-256 header
+    events JSONB NOT NULL DEFAULT '[]', -- subscribed event names, e.g. ["media.deleted"]
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Webhook delivery log; failed deliveries are retried with backoff
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'succeeded' or 'failed'
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER, -- HTTP status of the last attempt
+    last_error TEXT,
+    next_attempt_at TIMESTAMP, -- NULL once succeeded or failed for good
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT webhook_deliveries_status_check CHECK (status IN ('pending', 'succeeded', 'failed'))
+);
+
+-- Indexes for webhook_deliveries
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_created ON webhook_deliveries(created_at);
+
+-- Full sync generation per source (sonarr, radarr, qbittorrent)
+-- Each successful full sync bumps it and stamps the rows it saw; rows left behind are marked missing
+CREATE TABLE sync_generations (
+    source VARCHAR(50) PRIMARY KEY,
+    generation BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per source per full sync run, for the sync status
+CREATE TABLE sync_runs (
+    id BIGSERIAL PRIMARY KEY,
+    source VARCHAR(50) NOT NULL, -- 'sonarr', 'radarr', 'overseerr', 'tautulli' or 'qbittorrent'
+    trigger VARCHAR(50) NOT NULL, -- what started the run, e.g. 'periodic', 'manual', 'dashboard'
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP, -- NULL while running
+    duration_ms BIGINT,
+    error TEXT,
+    rows_seen INTEGER NOT NULL DEFAULT 0,
+    rows_added INTEGER NOT NULL DEFAULT 0,
+    rows_updated INTEGER NOT NULL DEFAULT 0,
+    rows_unchanged INTEGER NOT NULL DEFAULT 0,
+    rows_failed INTEGER NOT NULL DEFAULT 0,
+    rows_missing INTEGER NOT NULL DEFAULT 0
+);
+
+-- Indexes for sync_runs
+CREATE INDEX idx_sync_runs_source ON sync_runs(source, started_at DESC);
+CREATE INDEX idx_sync_runs_started ON sync_runs(started_at);
+
+-- Results of the periodic integration health checks
+CREATE TABLE integration_checks (
+    id BIGSERIAL PRIMARY KEY,
+    integration VARCHAR(50) NOT NULL, -- 'overseerr', 'sonarr', 'radarr', 'prowlarr', 'qbittorrent', 'tautulli' or 'plex'
+    checked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status VARCHAR(20) NOT NULL, -- 'ok', 'unauthorized', 'unreachable' or 'error'
+    version VARCHAR(100), -- reported by the integration, NULL unless the check succeeded
+    latency_ms BIGINT NOT NULL,
+    error TEXT
+);
+
+-- Indexes for integration_checks
+CREATE INDEX idx_integration_checks_integration ON integration_checks(integration, checked_at DESC);
+CREATE INDEX idx_integration_checks_checked ON integration_checks(checked_at);
@@ -0,0 +1,5 @@
+package database
+
+// SchemaVersion is the migration version this build expects, the number of the latest file in migrations/
+// Bump it with every new migration; TestSchemaVersionMatchesMigrations fails until it is
+const SchemaVersion = 23
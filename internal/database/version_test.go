@@ -0,0 +1,35 @@
+package database
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+// TestSchemaVersionMatchesMigrations keeps SchemaVersion in step with migrations/, so /health/ready doesn't
+// report a freshly migrated database as out of date
+func TestSchemaVersionMatchesMigrations(t *testing.T) {
+	entries, err := os.ReadDir("../../migrations")
+	if err != nil {
+		t.Fatalf("failed to read migrations: %v", err)
+	}
+
+	migrationFile := regexp.MustCompile(`^(\d+)_.+\.up\.sql$`)
+	latest := 0
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			t.Fatalf("bad migration number in %s: %v", entry.Name(), err)
+		}
+		latest = max(latest, version)
+	}
+
+	if latest != SchemaVersion {
+		t.Errorf("SchemaVersion is %d, the latest migration is %d", SchemaVersion, latest)
+	}
+}
@@ -0,0 +1,104 @@
+// Package events carries things that happen in removarr (media deleted, media eligible, sync failed...)
+// from the services that detect them to the subscribers that act on them, e.g. notifications and webhooks
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Type identifies what happened
+type Type string
+
+const (
+	MediaDeleted      Type = "media_deleted"      // a media item was deleted
+	MediaEligible     Type = "media_eligible"     // a media item became eligible for deletion
+	DeletionScheduled Type = "deletion_scheduled" // a media item will be deleted once its grace period is over
+	SyncCompleted     Type = "sync_completed"     // a sync finished
+	SyncFailed        Type = "sync_failed"        // a sync failed
+	TorrentUnlinked   Type = "torrent_unlinked"   // torrents aren't linked to any media item
+)
+
+// AllTypes lists every event type, in display order
+var AllTypes = []Type{MediaDeleted, MediaEligible, DeletionScheduled, SyncCompleted, SyncFailed, TorrentUnlinked}
+
+// Label is the event type's name for display
+func (t Type) Label() string {
+	switch t {
+	case MediaDeleted:
+		return "Media deleted"
+	case MediaEligible:
+		return "Media eligible for deletion"
+	case DeletionScheduled:
+		return "Deletion scheduled"
+	case SyncCompleted:
+		return "Sync completed"
+	case SyncFailed:
+		return "Sync failed"
+	case TorrentUnlinked:
+		return "Torrent unlinked"
+	}
+	return string(t)
+}
+
+// Event is something that happened
+// Media fields are only set for media events
+type Event struct {
+	Type        Type                   `json:"type"`
+	Time        time.Time              `json:"time"`
+	MediaItemID *int                   `json:"media_item_id,omitempty"`
+	MediaTitle  string                 `json:"media_title,omitempty"`
+	MediaType   string                 `json:"media_type,omitempty"`
+	RequesterID *int                   `json:"requester_id,omitempty"` // user who requested the media
+	ActorID     *int                   `json:"actor_id,omitempty"`     // user whose action caused the event
+	Message     string                 `json:"message,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	// Path is the removarr page about the event, e.g. /scheduled/{token}; notifications link to it when a public URL is set
+	Path string `json:"path,omitempty"`
+	// RecipientEmail is emailed the notification even without opting in, e.g. the Overseerr requester of a scheduled deletion
+	RecipientEmail string `json:"-"`
+}
+
+// Handler reacts to an event
+type Handler func(ctx context.Context, event Event)
+
+// Bus delivers published events to every subscriber
+// A nil *Bus drops events, so services work without one
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler for every event published from now on
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish delivers the event to every subscriber in the background, so slow subscribers don't hold up the caller
+// Handlers get a context that isn't cancelled with ctx, since events often outlive the request that caused them
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.handlers...)
+	b.mu.RUnlock()
+
+	slog.Debug("Publishing event", "type", event.Type, "media_title", event.MediaTitle, "subscribers", len(handlers))
+	detached := context.WithoutCancel(ctx)
+	for _, handler := range handlers {
+		go handler(detached, event)
+	}
+}
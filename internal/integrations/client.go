@@ -48,3 +48,35 @@ func NewClient(cfg *config.Config) *Client {
 
 	return client
 }
+
+// Health returns the circuit breaker state of each enabled integration, in settings page order
+func (c *Client) Health() []IntegrationHealth {
+	health := []IntegrationHealth{}
+	add := func(name, title, baseURL string) {
+		h := breakerFor(name, baseURL).health()
+		h.Title = title
+		health = append(health, h)
+	}
+	if c.Overseerr != nil {
+		add("overseerr", "Overseerr", c.Overseerr.baseURL)
+	}
+	if c.Sonarr != nil {
+		add("sonarr", "Sonarr", c.Sonarr.baseURL)
+	}
+	if c.Radarr != nil {
+		add("radarr", "Radarr", c.Radarr.baseURL)
+	}
+	if c.Prowlarr != nil {
+		add("prowlarr", "Prowlarr", c.Prowlarr.baseURL)
+	}
+	if c.QBittorrent != nil {
+		add("qbittorrent", "qBittorrent", c.QBittorrent.baseURL)
+	}
+	if c.Tautulli != nil {
+		add("tautulli", "Tautulli", c.Tautulli.baseURL)
+	}
+	if c.Plex != nil {
+		add("plex", "Plex", c.Plex.baseURL)
+	}
+	return health
+}
@@ -1,14 +1,55 @@
 package integrations
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
 	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
+
+	"removarr/internal/metrics"
+	"removarr/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Retry and circuit breaker tuning, shared by every integration
+const (
+	maxAttempts             = 3 // per idempotent request, including the first
+	retryBaseDelay          = 250 * time.Millisecond
+	retryMaxDelay           = 4 * time.Second
+	breakerFailureThreshold = 5 // consecutive failed requests that open the circuit
+	breakerOpenDuration     = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without calling an integration that has kept failing, until it is tried again
+var ErrCircuitOpen = errors.New("circuit open, too many recent failures")
+
+// ErrUnauthorized is returned when an integration rejects the configured API key or credentials
+var ErrUnauthorized = errors.New("credentials rejected")
+
+// responseError is the error for an unexpected response from an integration
+// 401 and 403 wrap ErrUnauthorized, so callers can tell bad credentials from an integration that is down
+func responseError(name string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%s API error: %w: %s", name, ErrUnauthorized, resp.Status)
+	}
+	return fmt.Errorf("%s API error: %s - %s", name, resp.Status, string(body))
+}
+
 // newHTTPClient creates an HTTP client that prefers IPv4 connections
 // This is needed because containers often don't have proper IPv6 connectivity
-func newHTTPClient(timeout time.Duration) *http.Client {
+// Requests go through the integration's circuit breaker, and idempotent ones are retried with backoff.
+// timeout applies to each attempt, from dialing to reading the response body.
+func newHTTPClient(name, baseURL string, timeout time.Duration) *http.Client {
 	transport := &http.Transport{
 		DialContext: (&net.Dialer{
 			Timeout:   timeout,
@@ -22,8 +63,336 @@ func newHTTPClient(timeout time.Duration) *http.Client {
 	}
 
 	return &http.Client{
-		Transport: transport,
-		Timeout:   timeout,
+		Transport: &resilientTransport{
+			name:    name,
+			base:    transport,
+			timeout: timeout,
+			breaker: breakerFor(name, baseURL),
+		},
+	}
+}
+
+// resilientTransport retries idempotent requests and fails fast while the integration's circuit is open
+type resilientTransport struct {
+	name    string
+	base    http.RoundTripper
+	timeout time.Duration
+	breaker *circuitBreaker
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	// The query is left out of the span, it can hold the API key
+	ctx, span := tracing.Start(req.Context(), t.name+" "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("integration", t.name),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer func() {
+		spanErr := err
+		if err == nil {
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			if resp.StatusCode >= 400 {
+				spanErr = errors.New(resp.Status)
+			}
+		}
+		tracing.End(span, spanErr)
+	}()
+	req = req.WithContext(ctx)
+
+	if err := t.breaker.allow(); err != nil {
+		metrics.IntegrationRequests.WithLabelValues(t.name, req.Method, "circuit_open").Inc()
+		return nil, fmt.Errorf("%s: %w", t.name, err)
+	}
+
+	started := time.Now()
+	resp, err = t.roundTrip(req)
+	metrics.IntegrationRequestDuration.WithLabelValues(t.name, req.Method).Observe(time.Since(started).Seconds())
+	result := "error"
+	if err == nil {
+		result = fmt.Sprintf("%dxx", resp.StatusCode/100)
+	}
+	metrics.IntegrationRequests.WithLabelValues(t.name, req.Method, result).Inc()
+
+	switch {
+	case errors.Is(req.Context().Err(), context.Canceled):
+		t.breaker.release() // the caller gave up, says nothing about the integration
+	case err != nil:
+		t.breaker.failure(err.Error())
+	case resp.StatusCode >= 500:
+		t.breaker.failure(resp.Status)
+	default:
+		t.breaker.success()
+	}
+	return resp, err
+}
+
+// roundTrip sends req, retrying idempotent requests on connection errors, timeouts and retryable statuses
+func (t *resilientTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	retryable := isRetryableMethod(req.Method) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.send(attemptReq)
+		if !retryable || attempt >= maxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		wait := retryDelay(attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if after, ok := retryAfter(resp); ok {
+				wait = after
+			}
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		metrics.IntegrationRetries.WithLabelValues(t.name).Inc()
+		trace.SpanFromContext(req.Context()).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("reason", reason),
+			attribute.String("wait", wait.String()),
+		))
+		slog.Debug("Retrying integration request", "integration", t.name, "method", req.Method, "path", req.URL.Path,
+			"attempt", attempt, "wait", wait, "reason", reason)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt, bounded by the transport's timeout until the response body is closed
+func (t *resilientTransport) send(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases an attempt's timeout once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// isRetryableMethod reports whether a request with method can safely be sent again after a failed attempt
+// DELETE is idempotent but not retried: when an attempt timed out after the delete went through, the retry gets
+// a 404 and the caller would treat a successful delete as failed. APIs that delete over another verb (qBittorrent)
+// must send it as a POST for the same reason
+func isRetryableMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut:
+		return true
+	}
+	return false
+}
+
+// isRetryableStatus reports whether a response status is worth another attempt
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay is the exponential backoff before the retry after attempt, with jitter so clients don't retry in step
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << (attempt - 1)
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter returns the delay a 429 or 503 response asks for, capped at retryMaxDelay
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	delay := time.Duration(seconds) * time.Second
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay, true
+}
+
+// breakerState is the state of a circuit breaker
+type breakerState string
+
+const (
+	breakerClosed   breakerState = "closed"    // calls go through
+	breakerOpen     breakerState = "open"      // calls fail fast with ErrCircuitOpen
+	breakerHalfOpen breakerState = "half_open" // one trial call goes through; it closes or reopens the circuit
+)
+
+// circuitBreaker stops calling an integration that keeps failing, so syncs and deletions fail fast
+// instead of waiting out timeouts and retries against a service that is down
+type circuitBreaker struct {
+	name string
+
+	mu            sync.Mutex
+	state         breakerState
+	failures      int // consecutive
+	openedAt      time.Time
+	trial         bool // a half-open trial call is in flight
+	lastError     string
+	lastErrorAt   time.Time
+	lastSuccessAt time.Time
+}
+
+// breakers holds a circuit breaker per integration and URL, kept when the clients are rebuilt after a settings change
+// A connection test against another URL gets its own breaker
+var breakers = struct {
+	sync.Mutex
+	byKey map[string]*circuitBreaker
+}{byKey: make(map[string]*circuitBreaker)}
+
+func breakerFor(name, baseURL string) *circuitBreaker {
+	breakers.Lock()
+	defer breakers.Unlock()
+	key := name + " " + baseURL
+	b, ok := breakers.byKey[key]
+	if !ok {
+		b = &circuitBreaker{name: name, state: breakerClosed}
+		breakers.byKey[key] = b
+	}
+	return b
+}
+
+// allow returns ErrCircuitOpen if the call must not be made
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < breakerOpenDuration {
+			return ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		b.trial = true
+		return nil
+	case breakerHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+		return nil
+	}
+	return nil
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != breakerClosed {
+		slog.Info("Integration recovered, circuit closed", "integration", b.name)
+		metrics.IntegrationCircuitOpen.WithLabelValues(b.name).Set(0)
 	}
+	b.state = breakerClosed
+	b.failures = 0
+	b.trial = false
+	b.lastSuccessAt = time.Now()
+}
+
+func (b *circuitBreaker) failure(reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	b.lastError = reason
+	b.lastErrorAt = time.Now()
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= breakerFailureThreshold) {
+		slog.Warn("Integration keeps failing, circuit opened", "integration", b.name, "failures", b.failures,
+			"retry_in", breakerOpenDuration, "error", reason)
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		metrics.IntegrationCircuitOpen.WithLabelValues(b.name).Set(1)
+	}
+}
+
+// release ends a call that was cancelled by the caller, without counting it either way
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
 }
 
+// IntegrationHealth is the circuit breaker state of an integration
+type IntegrationHealth struct {
+	Name                string     `json:"name"`
+	Title               string     `json:"title"`
+	State               string     `json:"state"` // "closed" (healthy), "open" (failing fast) or "half_open" (trying again)
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"` // when an open circuit lets a call through again
+}
+
+// Healthy reports whether calls go through normally
+func (h IntegrationHealth) Healthy() bool {
+	return h.State == string(breakerClosed)
+}
+
+func (b *circuitBreaker) health() IntegrationHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	health := IntegrationHealth{
+		Name:                b.name,
+		State:               string(b.state),
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if !b.lastErrorAt.IsZero() {
+		at := b.lastErrorAt
+		health.LastErrorAt = &at
+	}
+	if !b.lastSuccessAt.IsZero() {
+		at := b.lastSuccessAt
+		health.LastSuccessAt = &at
+	}
+	if b.state == breakerOpen {
+		retryAt := b.openedAt.Add(breakerOpenDuration)
+		health.RetryAt = &retryAt
+		if !time.Now().Before(retryAt) {
+			health.State = string(breakerHalfOpen) // the next call is the trial
+		}
+	}
+	return health
+}
@@ -0,0 +1,168 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := &circuitBreaker{name: "test", state: breakerClosed}
+
+	// Failures below the threshold, or broken up by a success, keep the circuit closed
+	for i := 0; i < breakerFailureThreshold-1; i++ {
+		b.failure("boom")
+	}
+	b.success()
+	for i := 0; i < breakerFailureThreshold-1; i++ {
+		b.failure("boom")
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() after %d failures = %v", breakerFailureThreshold-1, err)
+	}
+
+	b.failure("boom")
+	if b.state != breakerOpen {
+		t.Fatalf("state = %s after %d consecutive failures, want open", b.state, breakerFailureThreshold)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() while open = %v, want ErrCircuitOpen", err)
+	}
+	if health := b.health(); health.Healthy() || health.RetryAt == nil || health.LastError != "boom" {
+		t.Errorf("health while open = %+v", health)
+	}
+
+	// Once the open period is over a single trial call goes through
+	b.openedAt = time.Now().Add(-breakerOpenDuration)
+	if health := b.health(); health.State != string(breakerHalfOpen) {
+		t.Errorf("health state after the open period = %s, want half_open", health.State)
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("trial allow() = %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() during the trial = %v, want ErrCircuitOpen", err)
+	}
+
+	// A failed trial reopens the circuit straight away
+	b.failure("still down")
+	if b.state != breakerOpen {
+		t.Fatalf("state after a failed trial = %s, want open", b.state)
+	}
+
+	// A successful trial closes it
+	b.openedAt = time.Now().Add(-breakerOpenDuration)
+	if err := b.allow(); err != nil {
+		t.Fatalf("trial allow() = %v", err)
+	}
+	b.success()
+	if health := b.health(); !health.Healthy() || health.ConsecutiveFailures != 0 {
+		t.Errorf("health after a successful trial = %+v", health)
+	}
+}
+
+func TestCircuitBreakerRelease(t *testing.T) {
+	b := &circuitBreaker{name: "test", state: breakerOpen, openedAt: time.Now().Add(-breakerOpenDuration)}
+	if err := b.allow(); err != nil {
+		t.Fatalf("trial allow() = %v", err)
+	}
+	// The caller gave up on the trial, the next call gets to try instead
+	b.release()
+	if err := b.allow(); err != nil {
+		t.Errorf("allow() after a released trial = %v", err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		full := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+		for i := 0; i < 20; i++ {
+			if delay := retryDelay(attempt); delay < full/2 || delay > full {
+				t.Fatalf("retryDelay(%d) = %v, want between %v and %v", attempt, delay, full/2, full)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, tt := range []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"2", 2 * time.Second, true},
+		{"3600", retryMaxDelay, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, false},
+	} {
+		resp := &http.Response{Header: http.Header{"Retry-After": {tt.header}}}
+		if got, ok := retryAfter(resp); got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsRetryableMethod(t *testing.T) {
+	for method, want := range map[string]bool{
+		http.MethodGet:    true,
+		http.MethodHead:   true,
+		http.MethodPut:    true,
+		http.MethodDelete: false,
+		http.MethodPost:   false,
+	} {
+		if got := isRetryableMethod(method); got != want {
+			t.Errorf("isRetryableMethod(%s) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+// flakyServer answers 503 (asking for an immediate retry) until it has failed failures times
+func flakyServer(failures int32) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	return server, &calls
+}
+
+func TestTransportRetries(t *testing.T) {
+	server, calls := flakyServer(maxAttempts - 1)
+	defer server.Close()
+
+	client := newHTTPClient("test", server.URL, 5*time.Second)
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != maxAttempts {
+		t.Errorf("GET = %d after %d calls, want 200 after %d", resp.StatusCode, calls.Load(), maxAttempts)
+	}
+}
+
+func TestTransportDoesNotRetryDelete(t *testing.T) {
+	server, calls := flakyServer(1)
+	defer server.Close()
+
+	client := newHTTPClient("test", server.URL, 5*time.Second)
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodDelete, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("DELETE = %d after %d calls, want 503 after 1", resp.StatusCode, calls.Load())
+	}
+}
@@ -2,6 +2,7 @@ package integrations
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,7 +21,7 @@ type OverseerrRequest struct {
 	ID          int    `json:"id"`
 	MediaID     int    `json:"mediaId"`
 	MediaType   string `json:"mediaType"`
-	Status      int    `json:"status"`
+	Status      int    `json:"status"` // 1 pending approval, 2 approved, 3 declined
 	RequestedBy struct {
 		ID       int    `json:"id"`
 		Email    string `json:"email"`
@@ -33,6 +34,7 @@ type OverseerrRequest struct {
 		TVDBID   *int   `json:"tvdbId"`
 		Title    string `json:"title"`
 		MediaType string `json:"mediaType"`
+		Status   int    `json:"status"` // 1 unknown, 2 pending, 3 processing, 4 partially available, 5 available
 	} `json:"media"`
 }
 
@@ -48,11 +50,11 @@ func NewOverseerrClient(baseURL, apiKey string) *OverseerrClient {
 	return &OverseerrClient{
 		baseURL: baseURL,
 		apiKey:  apiKey,
-		client:  newHTTPClient(30 * time.Second),
+		client:  newHTTPClient("overseerr", baseURL, 30*time.Second),
 	}
 }
 
-func (c *OverseerrClient) makeRequest(method, endpoint string, body interface{}) (*http.Response, error) {
+func (c *OverseerrClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Response, error) {
 	url := fmt.Sprintf("%s/api/v1%s", c.baseURL, endpoint)
 	
 	var reqBody io.Reader
@@ -64,7 +66,7 @@ func (c *OverseerrClient) makeRequest(method, endpoint string, body interface{})
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -76,8 +78,8 @@ func (c *OverseerrClient) makeRequest(method, endpoint string, body interface{})
 }
 
 // GetRequests fetches all requests from Overseerr
-func (c *OverseerrClient) GetRequests() ([]OverseerrRequest, error) {
-	resp, err := c.makeRequest("GET", "/request", nil)
+func (c *OverseerrClient) GetRequests(ctx context.Context) ([]OverseerrRequest, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/request", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -100,8 +102,8 @@ func (c *OverseerrClient) GetRequests() ([]OverseerrRequest, error) {
 }
 
 // GetRequestByID fetches a specific request
-func (c *OverseerrClient) GetRequestByID(id int) (*OverseerrRequest, error) {
-	resp, err := c.makeRequest("GET", fmt.Sprintf("/request/%d", id), nil)
+func (c *OverseerrClient) GetRequestByID(ctx context.Context, id int) (*OverseerrRequest, error) {
+	resp, err := c.makeRequest(ctx, "GET", fmt.Sprintf("/request/%d", id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -121,8 +123,8 @@ func (c *OverseerrClient) GetRequestByID(id int) (*OverseerrRequest, error) {
 }
 
 // DeleteRequest deletes a request from Overseerr
-func (c *OverseerrClient) DeleteRequest(id int) error {
-	resp, err := c.makeRequest("DELETE", fmt.Sprintf("/request/%d", id), nil)
+func (c *OverseerrClient) DeleteRequest(ctx context.Context, id int) error {
+	resp, err := c.makeRequest(ctx, "DELETE", fmt.Sprintf("/request/%d", id), nil)
 	if err != nil {
 		return err
 	}
@@ -137,9 +139,9 @@ func (c *OverseerrClient) DeleteRequest(id int) error {
 }
 
 // FindRequestByMediaID finds an Overseerr request by TMDB ID (for movies) or TVDB ID (for series)
-func (c *OverseerrClient) FindRequestByMediaID(tmdbID *int, tvdbID *int, mediaType string) (*OverseerrRequest, error) {
+func (c *OverseerrClient) FindRequestByMediaID(ctx context.Context, tmdbID *int, tvdbID *int, mediaType string) (*OverseerrRequest, error) {
 	// Get all requests
-	requests, err := c.GetRequests()
+	requests, err := c.GetRequests(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get requests: %w", err)
 	}
@@ -207,3 +209,35 @@ func (c *OverseerrClient) FindRequestByMediaID(tmdbID *int, tvdbID *int, mediaTy
 	return nil, nil // No matching request found
 }
 
+
+// GetVersion fetches the Overseerr version, after checking that the API key is accepted
+// The status endpoint is public, so the key is checked against the current user first
+func (c *OverseerrClient) GetVersion(ctx context.Context) (string, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/auth/me", nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("overseerr", resp)
+	}
+
+	resp, err = c.makeRequest(ctx, "GET", "/status", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("overseerr", resp)
+	}
+
+	var status struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return "", err
+	}
+
+	return status.Version, nil
+}
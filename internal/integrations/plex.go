@@ -1,6 +1,7 @@
 package integrations
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -31,13 +32,13 @@ func NewPlexClient(baseURL, token string) *PlexClient {
 	return &PlexClient{
 		baseURL: baseURL,
 		token:   token,
-		client:  newHTTPClient(30 * time.Second),
+		client:  newHTTPClient("plex", baseURL, 30*time.Second),
 	}
 }
 
-func (c *PlexClient) makeRequest(method, endpoint string) (*http.Response, error) {
+func (c *PlexClient) makeRequest(ctx context.Context, method, endpoint string) (*http.Response, error) {
 	url := fmt.Sprintf("%s%s", c.baseURL, endpoint)
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -49,8 +50,8 @@ func (c *PlexClient) makeRequest(method, endpoint string) (*http.Response, error
 }
 
 // GetUsers fetches all users from Plex
-func (c *PlexClient) GetUsers() ([]PlexUser, error) {
-	resp, err := c.makeRequest("GET", "/api/users")
+func (c *PlexClient) GetUsers(ctx context.Context) ([]PlexUser, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/api/users")
 	if err != nil {
 		return nil, err
 	}
@@ -70,8 +71,8 @@ func (c *PlexClient) GetUsers() ([]PlexUser, error) {
 }
 
 // VerifyToken verifies if the Plex token is valid
-func (c *PlexClient) VerifyToken() (bool, error) {
-	resp, err := c.makeRequest("GET", "/api/v2/user")
+func (c *PlexClient) VerifyToken(ctx context.Context) (bool, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/api/v2/user")
 	if err != nil {
 		return false, err
 	}
@@ -80,3 +81,27 @@ func (c *PlexClient) VerifyToken() (bool, error) {
 	return resp.StatusCode == http.StatusOK, nil
 }
 
+
+// GetVersion fetches the Plex Media Server version, which also checks that the token is accepted
+func (c *PlexClient) GetVersion(ctx context.Context) (string, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("plex", resp)
+	}
+
+	var result struct {
+		MediaContainer struct {
+			Version string `json:"version"`
+		} `json:"MediaContainer"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	return result.MediaContainer.Version, nil
+}
@@ -1,6 +1,7 @@
 package integrations
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -27,13 +28,13 @@ func NewProwlarrClient(baseURL, apiKey string) *ProwlarrClient {
 	return &ProwlarrClient{
 		baseURL: baseURL,
 		apiKey:  apiKey,
-		client:  newHTTPClient(30 * time.Second),
+		client:  newHTTPClient("prowlarr", baseURL, 30*time.Second),
 	}
 }
 
-func (c *ProwlarrClient) makeRequest(method, endpoint string) (*http.Response, error) {
+func (c *ProwlarrClient) makeRequest(ctx context.Context, method, endpoint string) (*http.Response, error) {
 	url := fmt.Sprintf("%s/api/v1%s?apikey=%s", c.baseURL, endpoint, c.apiKey)
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -43,8 +44,8 @@ func (c *ProwlarrClient) makeRequest(method, endpoint string) (*http.Response, e
 }
 
 // GetIndexers fetches all indexers from Prowlarr
-func (c *ProwlarrClient) GetIndexers() ([]ProwlarrIndexer, error) {
-	resp, err := c.makeRequest("GET", "/indexer")
+func (c *ProwlarrClient) GetIndexers(ctx context.Context) ([]ProwlarrIndexer, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/indexer")
 	if err != nil {
 		return nil, err
 	}
@@ -64,8 +65,8 @@ func (c *ProwlarrClient) GetIndexers() ([]ProwlarrIndexer, error) {
 }
 
 // GetIndexerByID fetches a specific indexer
-func (c *ProwlarrClient) GetIndexerByID(id int) (*ProwlarrIndexer, error) {
-	resp, err := c.makeRequest("GET", fmt.Sprintf("/indexer/%d", id))
+func (c *ProwlarrClient) GetIndexerByID(ctx context.Context, id int) (*ProwlarrIndexer, error) {
+	resp, err := c.makeRequest(ctx, "GET", fmt.Sprintf("/indexer/%d", id))
 	if err != nil {
 		return nil, err
 	}
@@ -84,3 +85,25 @@ func (c *ProwlarrClient) GetIndexerByID(id int) (*ProwlarrIndexer, error) {
 	return &indexer, nil
 }
 
+
+// GetVersion fetches the Prowlarr version, which also checks that the API key is accepted
+func (c *ProwlarrClient) GetVersion(ctx context.Context) (string, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/system/status")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("prowlarr", resp)
+	}
+
+	var status struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return "", err
+	}
+
+	return status.Version, nil
+}
@@ -2,11 +2,15 @@ package integrations
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,6 +20,35 @@ type QBittorrentClient struct {
 	password string
 	client   *http.Client
 	sid      string // session ID
+	mainData *mainDataState
+}
+
+// mainDataState is the sync/maindata state between polls: the last response ID, the session it was issued to
+// (qBittorrent keeps it per session), and every torrent with the changes so far applied
+type mainDataState struct {
+	mu       sync.Mutex
+	sid      string
+	rid      int64
+	torrents map[string]QBittorrentTorrent
+}
+
+// mainDataStates holds the sync/maindata state per URL and user, kept when the clients are rebuilt after a settings
+// change, so polling carries on with deltas instead of starting over with a full update
+var mainDataStates = struct {
+	sync.Mutex
+	byKey map[string]*mainDataState
+}{byKey: make(map[string]*mainDataState)}
+
+func mainDataStateFor(baseURL, username string) *mainDataState {
+	mainDataStates.Lock()
+	defer mainDataStates.Unlock()
+	key := baseURL + " " + username
+	state, ok := mainDataStates.byKey[key]
+	if !ok {
+		state = &mainDataState{}
+		mainDataStates.byKey[key] = state
+	}
+	return state
 }
 
 type QBittorrentTorrent struct {
@@ -32,6 +65,16 @@ type QBittorrentTorrent struct {
 	Category       string  `json:"category"`
 	Tags           string  `json:"tags"`
 	ContentPath    string  `json:"content_path"`
+	NumComplete    int     `json:"num_complete"`   // seeders in the swarm
+	NumIncomplete  int     `json:"num_incomplete"` // leechers in the swarm
+}
+
+// QBittorrentMainData is what changed since the previous sync/maindata poll
+type QBittorrentMainData struct {
+	FullUpdate bool                 // qBittorrent sent everything, on the first poll or when it lost track of the previous one
+	Changed    []QBittorrentTorrent // torrents added or changed since the previous poll, with the changes applied; every torrent on a full update
+	Removed    []string             // hashes of torrents removed since the previous poll
+	Torrents   []QBittorrentTorrent // every torrent, with the changes applied
 }
 
 type QBittorrentTorrentInfo struct {
@@ -55,16 +98,17 @@ func NewQBittorrentClient(baseURL, username, password string) *QBittorrentClient
 		baseURL:  baseURL,
 		username: username,
 		password: password,
-		client:  newHTTPClient(30 * time.Second),
+		client:   newHTTPClient("qbittorrent", baseURL, 30*time.Second),
+		mainData: mainDataStateFor(baseURL, username),
 	}
 }
 
-func (c *QBittorrentClient) login() error {
+func (c *QBittorrentClient) login(ctx context.Context) error {
 	data := url.Values{}
 	data.Set("username", c.username)
 	data.Set("password", c.password)
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v2/auth/login", c.baseURL), bytes.NewBufferString(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v2/auth/login", c.baseURL), bytes.NewBufferString(data.Encode()))
 	if err != nil {
 		return err
 	}
@@ -92,20 +136,20 @@ func (c *QBittorrentClient) login() error {
 	return nil
 }
 
-func (c *QBittorrentClient) ensureLoggedIn() error {
+func (c *QBittorrentClient) ensureLoggedIn(ctx context.Context) error {
 	if c.sid == "" {
-		return c.login()
+		return c.login(ctx)
 	}
 	return nil
 }
 
-func (c *QBittorrentClient) makeRequest(method, endpoint string) (*http.Response, error) {
-	if err := c.ensureLoggedIn(); err != nil {
+func (c *QBittorrentClient) makeRequest(ctx context.Context, method, endpoint string) (*http.Response, error) {
+	if err := c.ensureLoggedIn(ctx); err != nil {
 		return nil, err
 	}
 
 	url := fmt.Sprintf("%s/api/v2%s", c.baseURL, endpoint)
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -119,9 +163,51 @@ func (c *QBittorrentClient) makeRequest(method, endpoint string) (*http.Response
 	return c.client.Do(req)
 }
 
+// postForm sends a form as a POST, which the transport doesn't retry; used for requests that change state,
+// so a request that timed out after qBittorrent acted on it isn't sent again
+func (c *QBittorrentClient) postForm(ctx context.Context, endpoint string, form url.Values) (*http.Response, error) {
+	if err := c.ensureLoggedIn(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/api/v2%s", c.baseURL, endpoint),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{
+		Name:  "SID",
+		Value: c.sid,
+	})
+
+	return c.client.Do(req)
+}
+
 // GetTorrents fetches all torrents from qBittorrent
-func (c *QBittorrentClient) GetTorrents() ([]QBittorrentTorrent, error) {
-	resp, err := c.makeRequest("GET", "/torrents/info")
+func (c *QBittorrentClient) GetTorrents(ctx context.Context) ([]QBittorrentTorrent, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/torrents/info")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("qbittorrent API error: %s - %s", resp.Status, string(body))
+	}
+
+	var torrents []QBittorrentTorrent
+	if err := json.NewDecoder(resp.Body).Decode(&torrents); err != nil {
+		return nil, err
+	}
+
+	return torrents, nil
+}
+
+// GetTorrentsByHash fetches the given torrents from qBittorrent; unknown hashes are left out
+func (c *QBittorrentClient) GetTorrentsByHash(ctx context.Context, hashes []string) ([]QBittorrentTorrent, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/torrents/info?hashes="+url.QueryEscape(strings.Join(hashes, "|")))
 	if err != nil {
 		return nil, err
 	}
@@ -140,9 +226,99 @@ func (c *QBittorrentClient) GetTorrents() ([]QBittorrentTorrent, error) {
 	return torrents, nil
 }
 
+// SyncMainData polls /sync/maindata for what changed since the previous poll
+// qBittorrent only sends the torrents and fields that changed; they are merged into the torrents kept from earlier
+// polls, so the changed torrents come back whole
+func (c *QBittorrentClient) SyncMainData(ctx context.Context) (*QBittorrentMainData, error) {
+	state := c.mainData
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	// The response ID only means something to the session it was issued to
+	if c.sid == "" {
+		c.sid = state.sid
+	}
+	if err := c.ensureLoggedIn(ctx); err != nil {
+		return nil, err
+	}
+	if c.sid != state.sid {
+		state.sid, state.rid, state.torrents = c.sid, 0, nil
+	}
+
+	resp, err := c.makeRequest(ctx, "GET", fmt.Sprintf("/sync/maindata?rid=%d", state.rid))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden {
+		// The session expired, log in again on the next poll
+		c.sid, state.sid, state.rid, state.torrents = "", "", 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("qbittorrent API error: %s - %s", resp.Status, string(body))
+	}
+
+	var mainData struct {
+		RID             int64                      `json:"rid"`
+		FullUpdate      bool                       `json:"full_update"`
+		Torrents        map[string]json.RawMessage `json:"torrents"`
+		TorrentsRemoved []string                   `json:"torrents_removed"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&mainData); err != nil {
+		return nil, err
+	}
+
+	// Without the earlier torrents a delta can't be applied, ask for everything again
+	if !mainData.FullUpdate && state.torrents == nil {
+		state.rid = 0
+		return nil, fmt.Errorf("qbittorrent sent a delta without a previous full update")
+	}
+	if mainData.FullUpdate {
+		state.torrents = make(map[string]QBittorrentTorrent)
+	}
+
+	data := &QBittorrentMainData{
+		FullUpdate: mainData.FullUpdate,
+		Changed:    make([]QBittorrentTorrent, 0, len(mainData.Torrents)),
+		Removed:    mainData.TorrentsRemoved,
+	}
+	for hash, raw := range mainData.Torrents {
+		// Decoding into the stored torrent only overwrites the fields that were sent
+		torrent := state.torrents[hash]
+		if err := json.Unmarshal(raw, &torrent); err != nil {
+			// Start over with a full update next time rather than keep a half-applied delta
+			state.rid = 0
+			state.torrents = nil
+			return nil, fmt.Errorf("failed to decode torrent %s: %w", hash, err)
+		}
+		torrent.Hash = hash
+		state.torrents[hash] = torrent
+		data.Changed = append(data.Changed, torrent)
+	}
+	for _, hash := range mainData.TorrentsRemoved {
+		delete(state.torrents, hash)
+	}
+	state.rid = mainData.RID
+
+	data.Torrents = make([]QBittorrentTorrent, 0, len(state.torrents))
+	for _, torrent := range state.torrents {
+		data.Torrents = append(data.Torrents, torrent)
+	}
+	return data, nil
+}
+
+// ResetMainData makes the next SyncMainData poll ask for a full update, e.g. when the last delta couldn't be stored
+func (c *QBittorrentClient) ResetMainData() {
+	c.mainData.mu.Lock()
+	defer c.mainData.mu.Unlock()
+	c.mainData.rid = 0
+}
+
 // GetTorrentProperties fetches detailed properties of a torrent
-func (c *QBittorrentClient) GetTorrentProperties(hash string) (*QBittorrentTorrentInfo, error) {
-	resp, err := c.makeRequest("GET", fmt.Sprintf("/torrents/properties?hash=%s", hash))
+func (c *QBittorrentClient) GetTorrentProperties(ctx context.Context, hash string) (*QBittorrentTorrentInfo, error) {
+	resp, err := c.makeRequest(ctx, "GET", fmt.Sprintf("/torrents/properties?hash=%s", hash))
 	if err != nil {
 		return nil, err
 	}
@@ -161,10 +337,34 @@ func (c *QBittorrentClient) GetTorrentProperties(hash string) (*QBittorrentTorre
 	return &props, nil
 }
 
+// GetTorrentPieceHashes fetches the SHA-1 hash of every piece of a torrent
+// Two torrents with the same piece hashes contain the same data
+func (c *QBittorrentClient) GetTorrentPieceHashes(ctx context.Context, hash string) ([]string, error) {
+	resp, err := c.makeRequest(ctx, "GET", fmt.Sprintf("/torrents/pieceHashes?hash=%s", hash))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("qbittorrent API error: %s - %s", resp.Status, string(body))
+	}
+
+	var pieceHashes []string
+	if err := json.NewDecoder(resp.Body).Decode(&pieceHashes); err != nil {
+		return nil, err
+	}
+
+	return pieceHashes, nil
+}
+
 // DeleteTorrent deletes a torrent and optionally its files
-func (c *QBittorrentClient) DeleteTorrent(hash string, deleteFiles bool) error {
-	endpoint := fmt.Sprintf("/torrents/delete?hashes=%s&deleteFiles=%t", hash, deleteFiles)
-	resp, err := c.makeRequest("GET", endpoint)
+func (c *QBittorrentClient) DeleteTorrent(ctx context.Context, hash string, deleteFiles bool) error {
+	resp, err := c.postForm(ctx, "/torrents/delete", url.Values{
+		"hashes":      {hash},
+		"deleteFiles": {strconv.FormatBool(deleteFiles)},
+	})
 	if err != nil {
 		return err
 	}
@@ -178,3 +378,23 @@ func (c *QBittorrentClient) DeleteTorrent(hash string, deleteFiles bool) error {
 	return nil
 }
 
+
+// GetVersion fetches the qBittorrent version, e.g. "v4.6.2", which also checks that the login is accepted
+func (c *QBittorrentClient) GetVersion(ctx context.Context) (string, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/app/version")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("qbittorrent", resp)
+	}
+
+	version, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(version)), nil
+}
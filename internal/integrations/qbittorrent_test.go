@@ -0,0 +1,190 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakeMainData serves sync/maindata responses in order, recording the rid and session of each poll
+type fakeMainData struct {
+	mu        sync.Mutex
+	logins    int
+	responses []string
+	rids      []int64
+	sids      []string
+}
+
+func (f *fakeMainData) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/api/v2/auth/login":
+		f.logins++
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: fmt.Sprintf("session-%d", f.logins)})
+		fmt.Fprint(w, "Ok.")
+	case "/api/v2/sync/maindata":
+		rid, _ := strconv.ParseInt(r.URL.Query().Get("rid"), 10, 64)
+		f.rids = append(f.rids, rid)
+		cookie, _ := r.Cookie("SID")
+		sid := ""
+		if cookie != nil {
+			sid = cookie.Value
+		}
+		f.sids = append(f.sids, sid)
+		response := f.responses[0]
+		f.responses = f.responses[1:]
+		fmt.Fprint(w, response)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func torrentHashes(torrents []QBittorrentTorrent) []string {
+	hashes := make([]string, 0, len(torrents))
+	for _, torrent := range torrents {
+		hashes = append(hashes, torrent.Hash)
+	}
+	sort.Strings(hashes)
+	return hashes
+}
+
+func TestSyncMainDataDeltas(t *testing.T) {
+	fake := &fakeMainData{responses: []string{
+		`{"rid":1,"full_update":true,"torrents":{
+			"aaa":{"name":"Movie","uploaded":100,"state":"uploading"},
+			"bbb":{"name":"Show","uploaded":200,"state":"stalledUP"},
+			"ccc":{"name":"Other","uploaded":300}}}`,
+		`{"rid":2,"torrents":{"aaa":{"uploaded":150}},"torrents_removed":["ccc"]}`,
+		`{"rid":3}`,
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	client := NewQBittorrentClient(server.URL, "admin", "secret")
+	data, err := client.SyncMainData(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.FullUpdate || len(data.Changed) != 3 || len(data.Torrents) != 3 {
+		t.Fatalf("first poll: full %v, %d changed, %d torrents; want a full update of 3", data.FullUpdate,
+			len(data.Changed), len(data.Torrents))
+	}
+
+	data, err = client.SyncMainData(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.FullUpdate {
+		t.Error("second poll reported a full update")
+	}
+	if len(data.Changed) != 1 {
+		t.Fatalf("second poll changed %v, want only aaa", torrentHashes(data.Changed))
+	}
+	// Fields the delta didn't send are kept from earlier polls
+	if changed := data.Changed[0]; changed.Hash != "aaa" || changed.Uploaded != 150 || changed.Name != "Movie" ||
+		changed.State != "uploading" {
+		t.Errorf("merged torrent = %+v", changed)
+	}
+	if len(data.Removed) != 1 || data.Removed[0] != "ccc" {
+		t.Errorf("removed = %v, want [ccc]", data.Removed)
+	}
+	if got := torrentHashes(data.Torrents); fmt.Sprint(got) != "[aaa bbb]" {
+		t.Errorf("torrents = %v, want [aaa bbb]", got)
+	}
+
+	// A client rebuilt after a settings change carries on from the same rid and session
+	client = NewQBittorrentClient(server.URL, "admin", "secret")
+	data, err = client.SyncMainData(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.FullUpdate || len(data.Changed) != 0 || len(data.Torrents) != 2 {
+		t.Errorf("third poll: full %v, %d changed, %d torrents; want an empty delta over 2", data.FullUpdate,
+			len(data.Changed), len(data.Torrents))
+	}
+
+	if fmt.Sprint(fake.rids) != "[0 1 2]" {
+		t.Errorf("polled with rids %v, want [0 1 2]", fake.rids)
+	}
+	if fake.logins != 1 || fmt.Sprint(fake.sids) != "[session-1 session-1 session-1]" {
+		t.Errorf("%d logins, sessions %v; want one session throughout", fake.logins, fake.sids)
+	}
+}
+
+func TestSyncMainDataReset(t *testing.T) {
+	fake := &fakeMainData{responses: []string{
+		`{"rid":1,"full_update":true,"torrents":{"aaa":{"name":"Movie"}}}`,
+		`{"rid":2,"full_update":true,"torrents":{"aaa":{"name":"Movie"}}}`,
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	client := NewQBittorrentClient(server.URL, "admin", "secret")
+	if _, err := client.SyncMainData(ctx); err != nil {
+		t.Fatal(err)
+	}
+	client.ResetMainData()
+	if _, err := client.SyncMainData(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(fake.rids) != "[0 0]" {
+		t.Errorf("polled with rids %v, want a full update again after the reset", fake.rids)
+	}
+}
+
+func TestSyncMainDataDeltaWithoutFullUpdate(t *testing.T) {
+	fake := &fakeMainData{responses: []string{
+		`{"rid":5,"torrents":{"aaa":{"uploaded":1}}}`,
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewQBittorrentClient(server.URL, "admin", "secret")
+	if _, err := client.SyncMainData(context.Background()); err == nil {
+		t.Error("a delta with nothing to apply it to was accepted")
+	}
+}
+
+func TestDeleteTorrentIsNotRetried(t *testing.T) {
+	var deletes []*http.Request
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session"})
+			fmt.Fprint(w, "Ok.")
+		case "/api/v2/torrents/delete":
+			r.ParseForm()
+			mu.Lock()
+			deletes = append(deletes, r)
+			mu.Unlock()
+			// The first attempt fails in a way a GET would be retried for
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	err := NewQBittorrentClient(server.URL, "admin", "secret").DeleteTorrent(context.Background(), "aaa", true)
+	if err == nil {
+		t.Fatal("DeleteTorrent() succeeded, want the 503")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(deletes) != 1 {
+		t.Fatalf("delete sent %d times, want once", len(deletes))
+	}
+	if r := deletes[0]; r.Method != http.MethodPost || r.PostForm.Get("hashes") != "aaa" || r.PostForm.Get("deleteFiles") != "true" {
+		t.Errorf("delete sent as %s with form %v, want a POST of hashes=aaa&deleteFiles=true", r.Method, r.PostForm)
+	}
+}
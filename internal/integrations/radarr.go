@@ -2,6 +2,7 @@ package integrations
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -25,6 +26,11 @@ type RadarrMovie struct {
 	Added            string            `json:"added"`
 	QualityProfileID int               `json:"qualityProfileId"`
 	RootFolderPath  string            `json:"rootFolderPath"`
+	Tags             []int             `json:"tags"`
+	Genres           []string          `json:"genres"`
+	Year             int               `json:"year"`
+	Ratings          RadarrRatings     `json:"ratings"`
+	MovieFile        *RadarrMovieFile  `json:"movieFile"`
 	Statistics       *RadarrStatistics `json:"statistics"`
 }
 
@@ -32,11 +38,59 @@ type RadarrStatistics struct {
 	SizeOnDisk int64 `json:"sizeOnDisk"`
 }
 
+// RadarrRatings holds the ratings from each source Radarr knows, nil if the source has none
+type RadarrRatings struct {
+	IMDB *RadarrRating `json:"imdb"`
+	TMDB *RadarrRating `json:"tmdb"`
+}
+
+type RadarrRating struct {
+	Value float64 `json:"value"` // 0-10
+	Votes int     `json:"votes"`
+}
+
+type RadarrMovieFile struct {
+	Quality struct {
+		Quality struct {
+			Name       string `json:"name"` // e.g. "Remux-2160p"
+			Resolution int    `json:"resolution"`
+		} `json:"quality"`
+	} `json:"quality"`
+}
+
+type RadarrQualityProfile struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type RadarrTag struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
+
+type RadarrRootFolder struct {
+	ID        int    `json:"id"`
+	Path      string `json:"path"`
+	FreeSpace int64  `json:"freeSpace"`
+}
+
+// RadarrWebhook is the body Radarr POSTs to a "Webhook" connection
+// Only the fields removarr acts on are decoded
+type RadarrWebhook struct {
+	EventType string              `json:"eventType"` // Test, Grab, Download (import), Rename, MovieAdded, MovieDelete, MovieFileDelete...
+	Movie     *RadarrWebhookMovie `json:"movie"`
+}
+
+type RadarrWebhookMovie struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
 func NewRadarrClient(baseURL, apiKey string) *RadarrClient {
 	return &RadarrClient{
 		baseURL: baseURL,
 		apiKey:  apiKey,
-		client:  newHTTPClient(30 * time.Second),
+		client:  newHTTPClient("radarr", baseURL, 30*time.Second),
 	}
 }
 
@@ -50,9 +104,9 @@ func (c *RadarrClient) GetBaseURL() string {
 	return c.baseURL
 }
 
-func (c *RadarrClient) makeRequest(method, endpoint string) (*http.Response, error) {
+func (c *RadarrClient) makeRequest(ctx context.Context, method, endpoint string) (*http.Response, error) {
 	url := fmt.Sprintf("%s/api/v3%s?apikey=%s", c.baseURL, endpoint, c.apiKey)
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -62,8 +116,8 @@ func (c *RadarrClient) makeRequest(method, endpoint string) (*http.Response, err
 }
 
 // GetMovies fetches all movies from Radarr
-func (c *RadarrClient) GetMovies() ([]RadarrMovie, error) {
-	resp, err := c.makeRequest("GET", "/movie")
+func (c *RadarrClient) GetMovies(ctx context.Context) ([]RadarrMovie, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/movie")
 	if err != nil {
 		return nil, err
 	}
@@ -83,8 +137,8 @@ func (c *RadarrClient) GetMovies() ([]RadarrMovie, error) {
 }
 
 // GetMovieByID fetches a specific movie
-func (c *RadarrClient) GetMovieByID(id int) (*RadarrMovie, error) {
-	resp, err := c.makeRequest("GET", fmt.Sprintf("/movie/%d", id))
+func (c *RadarrClient) GetMovieByID(ctx context.Context, id int) (*RadarrMovie, error) {
+	resp, err := c.makeRequest(ctx, "GET", fmt.Sprintf("/movie/%d", id))
 	if err != nil {
 		return nil, err
 	}
@@ -105,10 +159,10 @@ func (c *RadarrClient) GetMovieByID(id int) (*RadarrMovie, error) {
 
 // DeleteMovie deletes a movie and its files
 // addImportExclusion=false prevents the movie from being added to the exclusion list
-func (c *RadarrClient) DeleteMovie(id int, deleteFiles bool, addImportExclusion bool) error {
+func (c *RadarrClient) DeleteMovie(ctx context.Context, id int, deleteFiles bool, addImportExclusion bool) error {
 	// Use makeRequest which handles API key authentication
 	endpoint := fmt.Sprintf("/movie/%d?deleteFiles=%t&addImportExclusion=%t", id, deleteFiles, addImportExclusion)
-	resp, err := c.makeRequest("DELETE", endpoint)
+	resp, err := c.makeRequest(ctx, "DELETE", endpoint)
 	if err != nil {
 		return err
 	}
@@ -124,9 +178,9 @@ func (c *RadarrClient) DeleteMovie(id int, deleteFiles bool, addImportExclusion
 }
 
 // UnmonitorMovie unmonitors a movie
-func (c *RadarrClient) UnmonitorMovie(id int) error {
+func (c *RadarrClient) UnmonitorMovie(ctx context.Context, id int) error {
 	// Get the full movie object to preserve all required fields
-	movie, err := c.GetMovieByID(id)
+	movie, err := c.GetMovieByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get movie: %w", err)
 	}
@@ -138,7 +192,7 @@ func (c *RadarrClient) UnmonitorMovie(id int) error {
 	// If they're missing from GetMovieByID response, fetch defaults
 	if movie.QualityProfileID == 0 {
 		// Try to get quality profile from all movies
-		movies, err := c.GetMovies()
+		movies, err := c.GetMovies(ctx)
 		if err == nil && len(movies) > 0 {
 			// Use the quality profile from the first movie as a fallback
 			movie.QualityProfileID = movies[0].QualityProfileID
@@ -152,7 +206,7 @@ func (c *RadarrClient) UnmonitorMovie(id int) error {
 	
 	if movie.RootFolderPath == "" {
 		// Try to get root folder from all movies
-		movies, err := c.GetMovies()
+		movies, err := c.GetMovies(ctx)
 		if err == nil && len(movies) > 0 {
 			movie.RootFolderPath = movies[0].RootFolderPath
 			if movie.RootFolderPath == "" {
@@ -170,7 +224,7 @@ func (c *RadarrClient) UnmonitorMovie(id int) error {
 		return fmt.Errorf("failed to marshal movie: %w", err)
 	}
 
-	req, err := http.NewRequest("PUT", url, io.NopCloser(bytes.NewReader(jsonData)))
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -190,3 +244,88 @@ func (c *RadarrClient) UnmonitorMovie(id int) error {
 
 	return nil
 }
+
+// GetRootFolders fetches the root folders configured in Radarr
+func (c *RadarrClient) GetRootFolders(ctx context.Context) ([]RadarrRootFolder, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/rootfolder")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("radarr API error: %s - %s", resp.Status, string(body))
+	}
+
+	var folders []RadarrRootFolder
+	if err := json.NewDecoder(resp.Body).Decode(&folders); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// GetTags fetches the tags defined in Radarr
+func (c *RadarrClient) GetTags(ctx context.Context) ([]RadarrTag, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/tag")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("radarr API error: %s - %s", resp.Status, string(body))
+	}
+
+	var tags []RadarrTag
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// GetQualityProfiles fetches the quality profiles defined in Radarr
+func (c *RadarrClient) GetQualityProfiles(ctx context.Context) ([]RadarrQualityProfile, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/qualityprofile")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("radarr API error: %s - %s", resp.Status, string(body))
+	}
+
+	var profiles []RadarrQualityProfile
+	if err := json.NewDecoder(resp.Body).Decode(&profiles); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// GetVersion fetches the Radarr version, which also checks that the API key is accepted
+func (c *RadarrClient) GetVersion(ctx context.Context) (string, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/system/status")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("radarr", resp)
+	}
+
+	var status struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return "", err
+	}
+
+	return status.Version, nil
+}
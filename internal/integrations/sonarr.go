@@ -2,6 +2,7 @@ package integrations
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -23,6 +24,11 @@ type SonarrSeries struct {
 	Monitored       bool   `json:"monitored"`
 	Status          string `json:"status"`
 	Added           string `json:"added"`
+	Tags            []int  `json:"tags"`
+	Genres          []string `json:"genres"`
+	Year            int    `json:"year"`
+	Ratings         SonarrRatings `json:"ratings"`
+	QualityProfileID int   `json:"qualityProfileId"`
 	Statistics      *SonarrStatistics `json:"statistics"`
 }
 
@@ -30,11 +36,44 @@ type SonarrStatistics struct {
 	SizeOnDisk int64 `json:"sizeOnDisk"`
 }
 
+type SonarrRatings struct {
+	Value float64 `json:"value"` // 0-10
+	Votes int     `json:"votes"`
+}
+
+type SonarrQualityProfile struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type SonarrTag struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
+
+type SonarrRootFolder struct {
+	ID        int    `json:"id"`
+	Path      string `json:"path"`
+	FreeSpace int64  `json:"freeSpace"`
+}
+
+// SonarrWebhook is the body Sonarr POSTs to a "Webhook" connection
+// Only the fields removarr acts on are decoded
+type SonarrWebhook struct {
+	EventType string               `json:"eventType"` // Test, Grab, Download (import), Rename, SeriesAdd, SeriesDelete, EpisodeFileDelete...
+	Series    *SonarrWebhookSeries `json:"series"`
+}
+
+type SonarrWebhookSeries struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
 func NewSonarrClient(baseURL, apiKey string) *SonarrClient {
 	return &SonarrClient{
 		baseURL: baseURL,
 		apiKey:  apiKey,
-		client:  newHTTPClient(30 * time.Second),
+		client:  newHTTPClient("sonarr", baseURL, 30*time.Second),
 	}
 }
 
@@ -48,9 +87,9 @@ func (c *SonarrClient) GetBaseURL() string {
 	return c.baseURL
 }
 
-func (c *SonarrClient) makeRequest(method, endpoint string) (*http.Response, error) {
+func (c *SonarrClient) makeRequest(ctx context.Context, method, endpoint string) (*http.Response, error) {
 	url := fmt.Sprintf("%s/api/v3%s?apikey=%s", c.baseURL, endpoint, c.apiKey)
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -60,8 +99,8 @@ func (c *SonarrClient) makeRequest(method, endpoint string) (*http.Response, err
 }
 
 // GetSeries fetches all series from Sonarr
-func (c *SonarrClient) GetSeries() ([]SonarrSeries, error) {
-	resp, err := c.makeRequest("GET", "/series")
+func (c *SonarrClient) GetSeries(ctx context.Context) ([]SonarrSeries, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/series")
 	if err != nil {
 		return nil, err
 	}
@@ -81,8 +120,8 @@ func (c *SonarrClient) GetSeries() ([]SonarrSeries, error) {
 }
 
 // GetSeriesByID fetches a specific series
-func (c *SonarrClient) GetSeriesByID(id int) (*SonarrSeries, error) {
-	resp, err := c.makeRequest("GET", fmt.Sprintf("/series/%d", id))
+func (c *SonarrClient) GetSeriesByID(ctx context.Context, id int) (*SonarrSeries, error) {
+	resp, err := c.makeRequest(ctx, "GET", fmt.Sprintf("/series/%d", id))
 	if err != nil {
 		return nil, err
 	}
@@ -103,9 +142,9 @@ func (c *SonarrClient) GetSeriesByID(id int) (*SonarrSeries, error) {
 
 // DeleteSeries deletes a series and its files
 // addImportExclusion=false prevents the series from being added to the exclusion list
-func (c *SonarrClient) DeleteSeries(id int, deleteFiles bool, addImportExclusion bool) error {
+func (c *SonarrClient) DeleteSeries(ctx context.Context, id int, deleteFiles bool, addImportExclusion bool) error {
 	endpoint := fmt.Sprintf("/series/%d?deleteFiles=%t&addImportExclusion=%t", id, deleteFiles, addImportExclusion)
-	resp, err := c.makeRequest("DELETE", endpoint)
+	resp, err := c.makeRequest(ctx, "DELETE", endpoint)
 	if err != nil {
 		return err
 	}
@@ -120,8 +159,8 @@ func (c *SonarrClient) DeleteSeries(id int, deleteFiles bool, addImportExclusion
 }
 
 // UnmonitorSeries unmonitors a series
-func (c *SonarrClient) UnmonitorSeries(id int) error {
-	series, err := c.GetSeriesByID(id)
+func (c *SonarrClient) UnmonitorSeries(ctx context.Context, id int) error {
+	series, err := c.GetSeriesByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -133,7 +172,7 @@ func (c *SonarrClient) UnmonitorSeries(id int) error {
 		return err
 	}
 
-	req, err := http.NewRequest("PUT", url, io.NopCloser(bytes.NewReader(jsonData)))
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(jsonData))
 	if err != nil {
 		return err
 	}
@@ -153,3 +192,87 @@ func (c *SonarrClient) UnmonitorSeries(id int) error {
 	return nil
 }
 
+// GetRootFolders fetches the root folders configured in Sonarr
+func (c *SonarrClient) GetRootFolders(ctx context.Context) ([]SonarrRootFolder, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/rootfolder")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("sonarr API error: %s - %s", resp.Status, string(body))
+	}
+
+	var folders []SonarrRootFolder
+	if err := json.NewDecoder(resp.Body).Decode(&folders); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// GetTags fetches the tags defined in Sonarr
+func (c *SonarrClient) GetTags(ctx context.Context) ([]SonarrTag, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/tag")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("sonarr API error: %s - %s", resp.Status, string(body))
+	}
+
+	var tags []SonarrTag
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// GetQualityProfiles fetches the quality profiles defined in Sonarr
+func (c *SonarrClient) GetQualityProfiles(ctx context.Context) ([]SonarrQualityProfile, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/qualityprofile")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("sonarr API error: %s - %s", resp.Status, string(body))
+	}
+
+	var profiles []SonarrQualityProfile
+	if err := json.NewDecoder(resp.Body).Decode(&profiles); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// GetVersion fetches the Sonarr version, which also checks that the API key is accepted
+func (c *SonarrClient) GetVersion(ctx context.Context) (string, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/system/status")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("sonarr", resp)
+	}
+
+	var status struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return "", err
+	}
+
+	return status.Version, nil
+}
@@ -1,11 +1,15 @@
 package integrations
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,31 +19,102 @@ type TautulliClient struct {
 	client  *http.Client
 }
 
+// TautulliHistory is a row of Tautulli's get_history
+// Episodes carry the rating key of their show in GrandparentRatingKey
 type TautulliHistory struct {
-	MediaType    string `json:"media_type"`
-	Title        string `json:"title"`
-	User         string `json:"user"`
-	LastPlayed   int64  `json:"last_played"` // Unix timestamp
-	PlayCount    int    `json:"play_count"`
-	TMDBID       *int   `json:"tmdb_id"`
-	TVDBID       *int   `json:"tvdb_id"`
+	MediaType            string      `json:"media_type"` // "movie", "episode" or "track"
+	Title                string      `json:"full_title"`
+	User                 string      `json:"user"`
+	UserID               int         `json:"user_id"`     // Plex user ID
+	Date                 int64       `json:"date"`        // Unix timestamp the play started
+	Stopped              int64       `json:"stopped"`     // Unix timestamp, 0 while playing
+	GroupCount           int         `json:"group_count"` // plays grouped into this row
+	RatingKey            TautulliKey `json:"rating_key"`
+	GrandparentRatingKey TautulliKey `json:"grandparent_rating_key"`
+	GUID                 string      `json:"guid"`
 }
 
 type TautulliHistoryResponse struct {
 	Response struct {
-		Data []TautulliHistory `json:"data"`
+		Result  string `json:"result"`
+		Message string `json:"message"`
+		Data    struct {
+			RecordsTotal int               `json:"recordsTotal"`
+			Data         []TautulliHistory `json:"data"`
+		} `json:"data"`
 	} `json:"response"`
 }
 
+// TautulliKey is a Plex rating key, which Tautulli sends as a number, or as "" when there is none
+type TautulliKey string
+
+func (k *TautulliKey) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*k = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*k = TautulliKey(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid rating key %s", data)
+	}
+	*k = TautulliKey(n.String())
+	return nil
+}
+
+// ExternalIDs are the TMDB and TVDB IDs of a Plex item, 0 when unknown
+type ExternalIDs struct {
+	TMDBID int
+	TVDBID int
+}
+
+// ParseGUIDs reads the external IDs from Plex GUIDs: the tmdb:// and tvdb:// GUIDs of Plex's current agents,
+// and the GUID of an item matched by a legacy agent, e.g. com.plexapp.agents.thetvdb://81189/1/1?lang=en
+func ParseGUIDs(guids ...string) ExternalIDs {
+	var ids ExternalIDs
+	for _, guid := range guids {
+		scheme, rest, ok := strings.Cut(guid, "://")
+		if !ok {
+			continue
+		}
+		// The ID is the first path segment; legacy episode GUIDs go on with the season and episode
+		if i := strings.IndexAny(rest, "/?"); i >= 0 {
+			rest = rest[:i]
+		}
+		id, err := strconv.Atoi(rest)
+		if err != nil || id <= 0 {
+			continue
+		}
+		switch scheme {
+		case "tmdb", "com.plexapp.agents.themoviedb":
+			ids.TMDBID = id
+		case "tvdb", "com.plexapp.agents.thetvdb":
+			ids.TVDBID = id
+		}
+	}
+	return ids
+}
+
+// externalIDs caches the external IDs per Tautulli URL and rating key, kept when the clients are rebuilt
+// after a settings change. Rating keys don't change while an item stays in Plex, so an item is looked up once.
+var externalIDs = struct {
+	sync.Mutex
+	byKey map[string]ExternalIDs
+}{byKey: make(map[string]ExternalIDs)}
+
 func NewTautulliClient(baseURL, apiKey string) *TautulliClient {
 	return &TautulliClient{
 		baseURL: baseURL,
 		apiKey:  apiKey,
-		client:  newHTTPClient(30 * time.Second),
+		client:  newHTTPClient("tautulli", baseURL, 30*time.Second),
 	}
 }
 
-func (c *TautulliClient) makeRequest(method string, params map[string]string) (*http.Response, error) {
+func (c *TautulliClient) makeRequest(ctx context.Context, method string, params map[string]string) (*http.Response, error) {
 	u, err := url.Parse(c.baseURL)
 	if err != nil {
 		return nil, err
@@ -53,7 +128,7 @@ func (c *TautulliClient) makeRequest(method string, params map[string]string) (*
 	}
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequest(method, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -61,12 +136,21 @@ func (c *TautulliClient) makeRequest(method string, params map[string]string) (*
 	return c.client.Do(req)
 }
 
-// GetHistory fetches watch history from Tautulli
-func (c *TautulliClient) GetHistory() ([]TautulliHistory, error) {
-	resp, err := c.makeRequest("GET", map[string]string{
-		"cmd": "get_history",
-		"length": "10000", // Get a lot of history
-	})
+// GetHistory fetches watch history from Tautulli, with repeated plays of an item grouped into one row
+func (c *TautulliClient) GetHistory(ctx context.Context) ([]TautulliHistory, error) {
+	return c.getHistory(ctx, map[string]string{})
+}
+
+// GetHistoryByUser fetches watch history for a specific user
+func (c *TautulliClient) GetHistoryByUser(ctx context.Context, username string) ([]TautulliHistory, error) {
+	return c.getHistory(ctx, map[string]string{"user": username})
+}
+
+func (c *TautulliClient) getHistory(ctx context.Context, params map[string]string) ([]TautulliHistory, error) {
+	params["cmd"] = "get_history"
+	params["grouping"] = "1"
+	params["length"] = "10000" // Get a lot of history
+	resp, err := c.makeRequest(ctx, "GET", params)
 	if err != nil {
 		return nil, err
 	}
@@ -81,32 +165,94 @@ func (c *TautulliClient) GetHistory() ([]TautulliHistory, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
 	}
+	if result.Response.Result != "" && result.Response.Result != "success" {
+		return nil, fmt.Errorf("tautulli API error: %s", result.Response.Message)
+	}
 
-	return result.Response.Data, nil
+	return result.Response.Data.Data, nil
 }
 
-// GetHistoryByUser fetches watch history for a specific user
-func (c *TautulliClient) GetHistoryByUser(username string) ([]TautulliHistory, error) {
-	resp, err := c.makeRequest("GET", map[string]string{
-		"cmd": "get_history",
-		"user": username,
-		"length": "10000",
+// GetExternalIDs returns the TMDB and TVDB IDs of the Plex item with a rating key, from its metadata
+func (c *TautulliClient) GetExternalIDs(ctx context.Context, ratingKey string) (ExternalIDs, error) {
+	cacheKey := c.baseURL + " " + ratingKey
+	externalIDs.Lock()
+	ids, ok := externalIDs.byKey[cacheKey]
+	externalIDs.Unlock()
+	if ok {
+		return ids, nil
+	}
+
+	resp, err := c.makeRequest(ctx, "GET", map[string]string{
+		"cmd":        "get_metadata",
+		"rating_key": ratingKey,
 	})
 	if err != nil {
-		return nil, err
+		return ExternalIDs{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("tautulli API error: %s - %s", resp.Status, string(body))
+		return ExternalIDs{}, responseError("tautulli", resp)
 	}
 
-	var result TautulliHistoryResponse
+	var result struct {
+		Response struct {
+			Result  string `json:"result"`
+			Message string `json:"message"`
+			Data    struct {
+				GUID  string   `json:"guid"`
+				GUIDs []string `json:"guids"`
+			} `json:"data"`
+		} `json:"response"`
+	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		return ExternalIDs{}, err
+	}
+	if result.Response.Result != "success" {
+		return ExternalIDs{}, fmt.Errorf("tautulli API error: %s", result.Response.Message)
 	}
 
-	return result.Response.Data, nil
+	ids = ParseGUIDs(append(result.Response.Data.GUIDs, result.Response.Data.GUID)...)
+	externalIDs.Lock()
+	externalIDs.byKey[cacheKey] = ids
+	externalIDs.Unlock()
+	return ids, nil
 }
 
+// GetVersion fetches the Tautulli version, which also checks that the API key is accepted
+func (c *TautulliClient) GetVersion(ctx context.Context) (string, error) {
+	resp, err := c.makeRequest(ctx, "GET", map[string]string{
+		"cmd": "get_tautulli_info",
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("tautulli", resp)
+	}
+
+	var result struct {
+		Response struct {
+			Result  string `json:"result"`
+			Message string `json:"message"`
+			Data    struct {
+				Version string `json:"tautulli_version"`
+			} `json:"data"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	// Older versions reject an invalid API key with a 200 and an error result
+	if result.Response.Result != "success" {
+		if strings.Contains(strings.ToLower(result.Response.Message), "apikey") {
+			return "", fmt.Errorf("tautulli API error: %w: %s", ErrUnauthorized, result.Response.Message)
+		}
+		return "", fmt.Errorf("tautulli API error: %s", result.Response.Message)
+	}
+
+	return result.Response.Data.Version, nil
+}
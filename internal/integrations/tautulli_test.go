@@ -0,0 +1,89 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTautulliGetHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cmd := r.URL.Query().Get("cmd"); cmd != "get_history" {
+			t.Errorf("cmd = %s, want get_history", cmd)
+		}
+		fmt.Fprint(w, `{"response":{"result":"success","message":null,"data":{
+			"recordsFiltered":2,"recordsTotal":2,"data":[
+			{"media_type":"movie","full_title":"The Matrix","user":"neo","user_id":1234,
+				"date":1700000000,"stopped":1700007200,"group_count":2,
+				"rating_key":101,"grandparent_rating_key":"","guid":"plex://movie/5d7768"},
+			{"media_type":"episode","full_title":"Show - Pilot","user":"trinity","user_id":5678,
+				"date":1700100000,"stopped":0,"group_count":1,
+				"rating_key":202,"grandparent_rating_key":200,
+				"guid":"com.plexapp.agents.thetvdb://81189/1/1?lang=en"}
+		]}}}`)
+	}))
+	defer server.Close()
+
+	history, err := NewTautulliClient(server.URL, "key").GetHistory(context.Background())
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("got %d history rows, want 2", len(history))
+	}
+	movie, episode := history[0], history[1]
+	if movie.RatingKey != "101" || movie.GrandparentRatingKey != "" || movie.UserID != 1234 || movie.GroupCount != 2 {
+		t.Errorf("movie = %+v", movie)
+	}
+	if episode.RatingKey != "202" || episode.GrandparentRatingKey != "200" || episode.Stopped != 0 {
+		t.Errorf("episode = %+v", episode)
+	}
+}
+
+func TestTautulliGetExternalIDsCached(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if key := r.URL.Query().Get("rating_key"); key != "101" {
+			t.Errorf("rating_key = %s, want 101", key)
+		}
+		fmt.Fprint(w, `{"response":{"result":"success","data":{
+			"guid":"plex://movie/5d7768","guids":["imdb://tt0133093","tmdb://603","tvdb://169"]}}}`)
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		// A rebuilt client keeps the cache
+		ids, err := NewTautulliClient(server.URL, "key").GetExternalIDs(context.Background(), "101")
+		if err != nil {
+			t.Fatalf("GetExternalIDs() error = %v", err)
+		}
+		if ids != (ExternalIDs{TMDBID: 603, TVDBID: 169}) {
+			t.Errorf("GetExternalIDs() = %+v", ids)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("metadata fetched %d times, want 1", n)
+	}
+}
+
+func TestParseGUIDs(t *testing.T) {
+	tests := []struct {
+		guids []string
+		want  ExternalIDs
+	}{
+		{[]string{"tmdb://603", "tvdb://169", "imdb://tt0133093"}, ExternalIDs{TMDBID: 603, TVDBID: 169}},
+		{[]string{"com.plexapp.agents.themoviedb://603?lang=en"}, ExternalIDs{TMDBID: 603}},
+		{[]string{"com.plexapp.agents.thetvdb://81189/1/1?lang=en"}, ExternalIDs{TVDBID: 81189}},
+		{[]string{"plex://movie/5d7768", "com.plexapp.agents.imdb://tt0133093?lang=en"}, ExternalIDs{}},
+		{[]string{"tmdb://", "not a guid", ""}, ExternalIDs{}},
+	}
+	for _, tt := range tests {
+		if got := ParseGUIDs(tt.guids...); got != tt.want {
+			t.Errorf("ParseGUIDs(%q) = %+v, want %+v", tt.guids, got, tt.want)
+		}
+	}
+}
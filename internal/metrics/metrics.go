@@ -0,0 +1,158 @@
+// Package metrics defines the Prometheus metrics removarr exposes on /metrics
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "removarr"
+
+// Syncs, per source: sonarr, radarr, overseerr, tautulli or qbittorrent
+var (
+	SyncRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_runs_total",
+		Help:      "Syncs of a source, by result (success or error).",
+	}, []string{"source", "result"})
+
+	SyncDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_duration_seconds",
+		Help:      "How long the sync of a source took.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"source"})
+
+	SyncRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_rows_total",
+		Help:      "Rows synced from a source, by outcome (added, updated, unchanged, failed or missing).",
+	}, []string{"source", "outcome"})
+
+	SyncLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_last_success_timestamp_seconds",
+		Help:      "When the last successful sync of a source finished, as a Unix timestamp.",
+	}, []string{"source"})
+)
+
+// Integration requests, per integration: overseerr, sonarr, radarr, prowlarr, qbittorrent, tautulli or plex
+var (
+	IntegrationRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "integration_requests_total",
+		Help:      "Requests to an integration, by result: the status class (2xx, 4xx, 5xx), error, or circuit_open.",
+	}, []string{"integration", "method", "result"})
+
+	IntegrationRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "integration_request_duration_seconds",
+		Help:      "How long a request to an integration took, retries included.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"integration", "method"})
+
+	IntegrationRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "integration_retries_total",
+		Help:      "Retries of requests to an integration, after a connection error, timeout or retryable status.",
+	}, []string{"integration"})
+
+	IntegrationCircuitOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "integration_circuit_open",
+		Help:      "1 while the integration's circuit breaker is open or half open, 0 once it closes.",
+	}, []string{"integration"})
+)
+
+// Deletions
+var (
+	Deletions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deletions_total",
+		Help:      "Media deletions, by result (success, partial when some steps failed, or failed).",
+	}, []string{"result"})
+
+	BytesFreed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deletion_freed_bytes_total",
+		Help:      "Size of the media deleted, as reported on the statistics page.",
+	})
+)
+
+// HTTP requests, per route template, e.g. /api/media/{id}/delete
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "How long serving an HTTP request took.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+// libraryQueryTimeout bounds the queries behind the library gauges, so a slow database doesn't hold up a scrape
+const libraryQueryTimeout = 5 * time.Second
+
+var (
+	mediaItemsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "media_items"),
+		"Media items, by eligibility for deletion as of the last eligibility check.",
+		[]string{"eligibility"}, nil,
+	)
+	unlinkedTorrentsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unlinked_torrents"),
+		"Torrents not linked to a media item, ignored ones excluded.",
+		nil, nil,
+	)
+)
+
+// libraryCollector reads the library gauges from the database on each scrape
+type libraryCollector struct {
+	db *sql.DB
+}
+
+// RegisterLibrary registers the gauges read from the database: media items by eligibility and unlinked torrents
+func RegisterLibrary(db *sql.DB) {
+	prometheus.MustRegister(&libraryCollector{db: db})
+}
+
+func (c *libraryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mediaItemsDesc
+	ch <- unlinkedTorrentsDesc
+}
+
+func (c *libraryCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), libraryQueryTimeout)
+	defer cancel()
+
+	// eligible_since is kept up to date by the periodic eligibility check
+	var eligible, ineligible int64
+	if err := c.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FILTER (WHERE eligible_since IS NOT NULL), COUNT(*) FILTER (WHERE eligible_since IS NULL)
+		FROM media_items WHERE missing_since IS NULL`,
+	).Scan(&eligible, &ineligible); err != nil {
+		slog.Warn("Failed to count media items for metrics", "error", err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(mediaItemsDesc, prometheus.GaugeValue, float64(eligible), "eligible")
+		ch <- prometheus.MustNewConstMetric(mediaItemsDesc, prometheus.GaugeValue, float64(ineligible), "ineligible")
+	}
+
+	var unlinked int64
+	if err := c.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM torrents WHERE media_item_id IS NULL AND missing_since IS NULL AND NOT is_ignored",
+	).Scan(&unlinked); err != nil {
+		slog.Warn("Failed to count unlinked torrents for metrics", "error", err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(unlinkedTorrentsDesc, prometheus.GaugeValue, float64(unlinked))
+	}
+}
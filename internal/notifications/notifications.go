@@ -0,0 +1,342 @@
+// Package notifications tells admins and users about events over the channels configured in Settings:
+// a generic webhook, a Discord webhook, an Apprise API server and SMTP email
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"removarr/internal/events"
+)
+
+// SettingKey is the settings key the notification config is stored under, as JSON
+const SettingKey = "notifications"
+
+// Config is the notification configuration
+type Config struct {
+	Webhook WebhookConfig               `json:"webhook"`
+	Discord DiscordConfig               `json:"discord"`
+	Apprise AppriseConfig               `json:"apprise"`
+	SMTP    SMTPConfig                  `json:"smtp"`
+	Events  map[events.Type]EventConfig `json:"events"`
+	// PublicURL is where removarr is reachable by the people notified, e.g. https://removarr.example.com
+	// Links in notifications (.Link) are only set when it is
+	PublicURL string `json:"public_url"`
+}
+
+// WebhookConfig posts every notification as JSON to a URL
+type WebhookConfig struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url"`
+}
+
+// DiscordConfig posts notifications to a Discord channel webhook
+type DiscordConfig struct {
+	Enabled    bool   `json:"enabled"`
+	WebhookURL string `json:"webhook_url"`
+}
+
+// AppriseConfig posts notifications to an Apprise API server, e.g. http://apprise:8000/notify/removarr
+type AppriseConfig struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url"`
+	Tag     string `json:"tag"` // optional, limits delivery to the Apprise URLs with this tag
+}
+
+// SMTPConfig sends notifications by email, to the admin recipients in To and to users who opted in
+// The connection is upgraded with STARTTLS when the server supports it
+type SMTPConfig struct {
+	Enabled  bool     `json:"enabled"`
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// EventConfig is whether an event is notified, and the text/template used for its title and body
+// Templates can use the event's fields (.MediaTitle, .MediaType, .Message, .Time, .Data...), .Actor and .Requester, the usernames,
+// and .Link, the removarr page about the event
+type EventConfig struct {
+	Enabled bool   `json:"enabled"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+}
+
+// DefaultEventConfigs are the templates used until they are changed in Settings
+func DefaultEventConfigs() map[events.Type]EventConfig {
+	return map[events.Type]EventConfig{
+		events.MediaDeleted: {
+			Enabled: true,
+			Title:   "Deleted: {{ .MediaTitle }}",
+			Body:    "{{ .MediaTitle }} ({{ .MediaType }}) was deleted{{ with .Actor }} by {{ . }}{{ end }}.{{ with .Message }}\n{{ . }}{{ end }}",
+		},
+		events.MediaEligible: {
+			Enabled: true,
+			Title:   "Eligible for deletion: {{ .MediaTitle }}",
+			Body:    "{{ .MediaTitle }} ({{ .MediaType }}) has met its seeding requirements and can now be deleted.",
+		},
+		events.DeletionScheduled: {
+			Enabled: true,
+			Title:   "Scheduled for deletion: {{ .MediaTitle }}",
+			Body:    "{{ .MediaTitle }} ({{ .MediaType }}) will be deleted after {{ .Data.execute_after }}.{{ with .Link }}\nTo postpone the deletion or keep it, visit {{ . }}{{ end }}",
+		},
+		events.SyncCompleted: {
+			Enabled: false,
+			Title:   "Sync completed",
+			Body:    "{{ .Message }}",
+		},
+		events.SyncFailed: {
+			Enabled: true,
+			Title:   "Sync failed",
+			Body:    "The sync failed: {{ .Message }}",
+		},
+		events.TorrentUnlinked: {
+			Enabled: false,
+			Title:   "Torrent unlinked",
+			Body:    "{{ .Message }}",
+		},
+	}
+}
+
+// DefaultConfig has every channel disabled and every event enabled with the default templates
+func DefaultConfig() Config {
+	return Config{
+		SMTP:   SMTPConfig{Port: 587},
+		Events: DefaultEventConfigs(),
+	}
+}
+
+// ParseConfig decodes a stored config; events missing from it keep their defaults
+func ParseConfig(raw string) (Config, error) {
+	config := DefaultConfig()
+	if raw == "" {
+		return config, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return DefaultConfig(), err
+	}
+	for eventType, defaults := range DefaultEventConfigs() {
+		if _, ok := config.Events[eventType]; !ok {
+			config.Events[eventType] = defaults
+		}
+	}
+	return config, nil
+}
+
+// Validate checks the event templates parse and enabled channels have what they need
+func (c Config) Validate() error {
+	for eventType, eventConfig := range c.Events {
+		if _, err := template.New("").Parse(eventConfig.Title); err != nil {
+			return fmt.Errorf("invalid %s title template: %w", eventType, err)
+		}
+		if _, err := template.New("").Parse(eventConfig.Body); err != nil {
+			return fmt.Errorf("invalid %s body template: %w", eventType, err)
+		}
+	}
+	if c.Webhook.Enabled && c.Webhook.URL == "" {
+		return fmt.Errorf("webhook URL is required")
+	}
+	if c.Discord.Enabled && c.Discord.WebhookURL == "" {
+		return fmt.Errorf("discord webhook URL is required")
+	}
+	if c.Apprise.Enabled && c.Apprise.URL == "" {
+		return fmt.Errorf("apprise URL is required")
+	}
+	if c.SMTP.Enabled && (c.SMTP.Host == "" || c.SMTP.From == "") {
+		return fmt.Errorf("SMTP host and from address are required")
+	}
+	if c.PublicURL != "" {
+		if u, err := url.Parse(c.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("public URL must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// Message is a rendered notification
+type Message struct {
+	Title string       `json:"title"`
+	Body  string       `json:"body"`
+	Event events.Event `json:"event"`
+}
+
+// templateData is what event templates are executed with
+type templateData struct {
+	events.Event
+	Actor     string
+	Requester string
+	Link      string
+}
+
+// render executes an event's templates
+func render(eventConfig EventConfig, data templateData) (Message, error) {
+	var title, body bytes.Buffer
+	titleTemplate, err := template.New("title").Parse(eventConfig.Title)
+	if err != nil {
+		return Message{}, err
+	}
+	if err := titleTemplate.Execute(&title, data); err != nil {
+		return Message{}, err
+	}
+	bodyTemplate, err := template.New("body").Parse(eventConfig.Body)
+	if err != nil {
+		return Message{}, err
+	}
+	if err := bodyTemplate.Execute(&body, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Title: strings.TrimSpace(title.String()),
+		Body:  strings.TrimSpace(body.String()),
+		Event: data.Event,
+	}, nil
+}
+
+// Service sends notifications for the events it is subscribed to
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// Config returns the stored notification config, the defaults if none is stored
+func (s *Service) Config(ctx context.Context) Config {
+	var raw string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = $1", SettingKey).Scan(&raw)
+	if err != nil && err != sql.ErrNoRows {
+		slog.WarnContext(ctx, "Failed to get notification settings", "error", err)
+	}
+	config, err := ParseConfig(raw)
+	if err != nil {
+		slog.WarnContext(ctx, "Invalid notification settings, using defaults", "error", err)
+	}
+	return config
+}
+
+// Handle notifies an event on every enabled channel, and emails the users who opted in and the event's recipient
+// It is subscribed to the event bus
+func (s *Service) Handle(ctx context.Context, event events.Event) {
+	config := s.Config(ctx)
+	eventConfig, ok := config.Events[event.Type]
+	if !ok || !eventConfig.Enabled {
+		return
+	}
+
+	data := templateData{
+		Event:     event,
+		Actor:     s.username(ctx, event.ActorID),
+		Requester: s.username(ctx, event.RequesterID),
+	}
+	if config.PublicURL != "" && event.Path != "" {
+		data.Link = strings.TrimRight(config.PublicURL, "/") + event.Path
+	}
+	message, err := render(eventConfig, data)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to render notification", "event", event.Type, "error", err)
+		return
+	}
+
+	for _, provider := range providers(config) {
+		if err := provider.Send(ctx, message); err != nil {
+			slog.ErrorContext(ctx, "Failed to send notification", "channel", provider.Name(), "event", event.Type, "error", err)
+		}
+	}
+
+	if config.SMTP.Enabled {
+		recipients, err := s.optedInEmails(ctx, event, config.SMTP.To)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get notification recipients", "event", event.Type, "error", err)
+			return
+		}
+		if event.RecipientEmail != "" && !containsAddress(recipients, event.RecipientEmail) &&
+			!containsAddress(config.SMTP.To, event.RecipientEmail) {
+			recipients = append(recipients, event.RecipientEmail)
+		}
+		if len(recipients) > 0 {
+			if err := newSMTPProvider(config.SMTP).sendTo(recipients, message); err != nil {
+				slog.ErrorContext(ctx, "Failed to email notification", "event", event.Type, "recipients", len(recipients), "error", err)
+			}
+		}
+	}
+}
+
+// Test sends a test notification on one channel with the given config, so it can be checked before saving
+func (s *Service) Test(ctx context.Context, config Config, channel string) error {
+	message := Message{
+		Title: "removarr test notification",
+		Body:  "Notifications are working.",
+		Event: events.Event{Type: "test"},
+	}
+	for _, provider := range allProviders(config) {
+		if provider.Name() == channel {
+			return provider.Send(ctx, message)
+		}
+	}
+	return fmt.Errorf("unknown notification channel: %s", channel)
+}
+
+// optedInEmails returns the email addresses of active users who opted in to the event:
+// admins get every event, other users only events about media they requested
+// Addresses already in exclude (the admin recipients) are skipped
+func (s *Service) optedInEmails(ctx context.Context, event events.Event, exclude []string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT email FROM users
+		WHERE is_active AND COALESCE(email, '') <> ''
+			AND notification_events @> jsonb_build_array($1::text)
+			AND (is_admin OR id = $2)`,
+		string(event.Type), event.RequesterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skip := make(map[string]bool)
+	for _, address := range exclude {
+		skip[strings.ToLower(address)] = true
+	}
+	var emails []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		if !skip[strings.ToLower(email)] {
+			emails = append(emails, email)
+		}
+	}
+	return emails, rows.Err()
+}
+
+// containsAddress reports whether addresses contains address, ignoring case
+func containsAddress(addresses []string, address string) bool {
+	for _, a := range addresses {
+		if strings.EqualFold(a, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// username returns a user's username, empty if unknown
+func (s *Service) username(ctx context.Context, userID *int) string {
+	if userID == nil {
+		return ""
+	}
+	var username string
+	if err := s.db.QueryRowContext(ctx, "SELECT username FROM users WHERE id = $1", *userID).Scan(&username); err != nil {
+		return ""
+	}
+	return username
+}
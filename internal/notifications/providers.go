@@ -0,0 +1,211 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"removarr/internal/events"
+)
+
+// Provider delivers notifications over one channel
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, message Message) error
+}
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// providers returns the enabled channels
+func providers(config Config) []Provider {
+	var enabled []Provider
+	if config.Webhook.Enabled {
+		enabled = append(enabled, &webhookProvider{url: config.Webhook.URL})
+	}
+	if config.Discord.Enabled {
+		enabled = append(enabled, &discordProvider{url: config.Discord.WebhookURL})
+	}
+	if config.Apprise.Enabled {
+		enabled = append(enabled, &appriseProvider{url: config.Apprise.URL, tag: config.Apprise.Tag})
+	}
+	if config.SMTP.Enabled && len(config.SMTP.To) > 0 {
+		enabled = append(enabled, newSMTPProvider(config.SMTP))
+	}
+	return enabled
+}
+
+// allProviders returns every channel, enabled or not, for testing
+func allProviders(config Config) []Provider {
+	return []Provider{
+		&webhookProvider{url: config.Webhook.URL},
+		&discordProvider{url: config.Discord.WebhookURL},
+		&appriseProvider{url: config.Apprise.URL, tag: config.Apprise.Tag},
+		newSMTPProvider(config.SMTP),
+	}
+}
+
+// postJSON posts payload to url and fails on a non-2xx response
+func postJSON(ctx context.Context, url string, payload interface{}) error {
+	if url == "" {
+		return fmt.Errorf("no URL configured")
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s - %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// webhookProvider posts the message and its event as JSON
+type webhookProvider struct {
+	url string
+}
+
+func (p *webhookProvider) Name() string { return "webhook" }
+
+func (p *webhookProvider) Send(ctx context.Context, message Message) error {
+	return postJSON(ctx, p.url, map[string]interface{}{
+		"event": message.Event.Type,
+		"title": message.Title,
+		"body":  message.Body,
+		"data":  message.Event,
+	})
+}
+
+// discordProvider posts the message as an embed to a Discord channel webhook
+type discordProvider struct {
+	url string
+}
+
+func (p *discordProvider) Name() string { return "discord" }
+
+// Embed colors per event type
+var discordColors = map[events.Type]int{
+	events.MediaDeleted:      0xdc2626, // red
+	events.MediaEligible:     0xd97706, // amber
+	events.DeletionScheduled: 0xea580c, // orange
+	events.SyncCompleted:     0x16a34a, // green
+	events.SyncFailed:        0x7f1d1d, // dark red
+	events.TorrentUnlinked:   0x6b7280, // gray
+}
+
+func (p *discordProvider) Send(ctx context.Context, message Message) error {
+	color, ok := discordColors[message.Event.Type]
+	if !ok {
+		color = 0x4f46e5 // indigo
+	}
+	embed := map[string]interface{}{
+		"title":       message.Title,
+		"description": message.Body,
+		"color":       color,
+	}
+	if !message.Event.Time.IsZero() {
+		embed["timestamp"] = message.Event.Time.Format(time.RFC3339)
+	}
+	return postJSON(ctx, p.url, map[string]interface{}{
+		"username": "removarr",
+		"embeds":   []interface{}{embed},
+	})
+}
+
+// appriseProvider posts the message to an Apprise API server's notify endpoint
+type appriseProvider struct {
+	url string
+	tag string
+}
+
+func (p *appriseProvider) Name() string { return "apprise" }
+
+func (p *appriseProvider) Send(ctx context.Context, message Message) error {
+	notifyType := "info"
+	switch message.Event.Type {
+	case events.SyncFailed:
+		notifyType = "failure"
+	case events.MediaEligible, events.DeletionScheduled:
+		notifyType = "warning"
+	case events.MediaDeleted, events.SyncCompleted:
+		notifyType = "success"
+	}
+	payload := map[string]interface{}{
+		"title": message.Title,
+		"body":  message.Body,
+		"type":  notifyType,
+	}
+	if p.tag != "" {
+		payload["tag"] = p.tag
+	}
+	return postJSON(ctx, p.url, payload)
+}
+
+// smtpProvider emails the message to the configured admin recipients
+type smtpProvider struct {
+	config SMTPConfig
+}
+
+func newSMTPProvider(config SMTPConfig) *smtpProvider {
+	return &smtpProvider{config: config}
+}
+
+func (p *smtpProvider) Name() string { return "smtp" }
+
+func (p *smtpProvider) Send(ctx context.Context, message Message) error {
+	if len(p.config.To) == 0 {
+		return fmt.Errorf("no recipients configured")
+	}
+	return p.sendTo(p.config.To, message)
+}
+
+// sendTo emails the message to the given addresses
+// net/smtp has no context support; the server's own timeouts apply
+func (p *smtpProvider) sendTo(to []string, message Message) error {
+	if p.config.Host == "" || p.config.From == "" {
+		return fmt.Errorf("SMTP host and from address are required")
+	}
+	port := p.config.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(p.config.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if p.config.Username != "" {
+		auth = smtp.PlainAuth("", p.config.Username, p.config.Password, p.config.Host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", p.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	return smtp.SendMail(addr, auth, p.config.From, to, msg.Bytes())
+}
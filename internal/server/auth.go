@@ -2,10 +2,12 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -65,7 +67,7 @@ func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 		// Fall back to session-based auth
 		session, err := s.store.Get(r, sessionKey)
 		if err != nil {
-			slog.Warn("Session error in requireAuth", "error", err, "path", r.URL.Path)
+			slog.WarnContext(r.Context(), "Session error in requireAuth", "error", err, "path", r.URL.Path)
 			// Redirect to login for web requests, return 401 for API
 			if r.Header.Get("HX-Request") != "" || r.Header.Get("Accept") == "application/json" {
 				w.Header().Set("WWW-Authenticate", `Basic realm="Removarr"`)
@@ -78,7 +80,7 @@ func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 
 		userID, ok := session.Values[userIDKey].(int)
 		if !ok || userID == 0 {
-			slog.Info("No valid session found", "path", r.URL.Path, "has_session", session != nil, "userID", userID)
+			slog.InfoContext(r.Context(), "No valid session found", "path", r.URL.Path, "has_session", session != nil, "userID", userID)
 			// Redirect to login for web requests, return 401 for API
 			if r.Header.Get("HX-Request") != "" || r.Header.Get("Accept") == "application/json" {
 				w.Header().Set("WWW-Authenticate", `Basic realm="Removarr"`)
@@ -89,7 +91,7 @@ func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 		
-		slog.Info("Auth check passed", "user_id", userID, "path", r.URL.Path)
+		slog.InfoContext(r.Context(), "Auth check passed", "user_id", userID, "path", r.URL.Path)
 
 		// Add auth context to request
 		ctx := context.WithValue(r.Context(), "auth", AuthContext{
@@ -136,6 +138,39 @@ func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// requireIncomingWebhookToken authorizes Sonarr, Radarr and qBittorrent callbacks with the incoming webhook token
+// The token is accepted as the X-Api-Key header, the apikey query parameter or the Basic Auth password,
+// whichever the sender can be configured with
+func (s *Server) requireIncomingWebhookToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := s.getSetting(incomingWebhookTokenKey, "")
+		if expected == "" {
+			http.Error(w, "Incoming webhooks are not set up, open Admin > Webhooks to get a token", http.StatusForbidden)
+			return
+		}
+
+		if !hasWebhookToken(r, expected) {
+			slog.WarnContext(r.Context(), "Rejected incoming webhook with an invalid token", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// hasWebhookToken reports whether a request carries the expected token, in any of the places senders put it
+func hasWebhookToken(r *http.Request, expected string) bool {
+	token := r.Header.Get("X-Api-Key")
+	if token == "" {
+		token = r.URL.Query().Get("apikey")
+	}
+	if token == "" {
+		_, token, _ = r.BasicAuth()
+	}
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
 // @Summary      Login
 // @Description  Authenticate user and create session
 // @Tags         auth
@@ -204,12 +239,12 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	session.Values[isAdminKey] = user.IsAdmin
 
 	if err := session.Save(r, w); err != nil {
-		slog.Error("Failed to save session", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to save session", "error", err)
 		http.Error(w, "Session error", http.StatusInternalServerError)
 		return
 	}
 	
-	slog.Info("Session saved successfully", "user_id", user.ID, "username", user.Username, "cookie_set", true)
+	slog.InfoContext(r.Context(), "Session saved successfully", "user_id", user.ID, "username", user.Username, "cookie_set", true)
 
 	// Check if this is an HTMX request (from web form)
 	if r.Header.Get("HX-Request") != "" {
@@ -218,7 +253,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("HX-Redirect", "/dashboard")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Redirecting..."))
-		slog.Info("HTMX login redirect", "redirect_to", "/dashboard")
+		slog.InfoContext(r.Context(), "HTMX login redirect", "redirect_to", "/dashboard")
 		return
 	}
 	
@@ -227,7 +262,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	if contentType != "" && contentType != "application/json" {
 		// Regular form submission - redirect
 		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
-		slog.Info("Form login redirect", "redirect_to", "/dashboard")
+		slog.InfoContext(r.Context(), "Form login redirect", "redirect_to", "/dashboard")
 		return
 	}
 
@@ -292,3 +327,26 @@ func (s *Server) handlePlexAuth(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Plex authentication not yet implemented", http.StatusNotImplemented)
 }
 
+// requireMetricsAccess authorizes /metrics scrapes with the configured metrics token, sent as a bearer token
+// Without a token, only admins can read the metrics, e.g. a scraper with an admin's Basic Auth.
+func (s *Server) requireMetricsAccess(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := s.config.Metrics.Token
+		if token == "" {
+			s.requireAuth(s.requireAdmin(next.ServeHTTP))(w, r)
+			return
+		}
+		if !hasBearerToken(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Removarr metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// hasBearerToken reports whether a request carries the expected token in its Authorization header
+func hasBearerToken(r *http.Request, expected string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"removarr/internal/config"
+)
+
+func TestHasWebhookToken(t *testing.T) {
+	const token = "0123456789abcdef"
+	tests := []struct {
+		name     string
+		request  func() *http.Request
+		expected string
+		want     bool
+	}{
+		{"header", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/api/incoming/sonarr", nil)
+			r.Header.Set("X-Api-Key", token)
+			return r
+		}, token, true},
+		{"query parameter", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/api/incoming/radarr?apikey="+token, nil)
+		}, token, true},
+		{"basic auth password", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/api/incoming/qbittorrent", nil)
+			r.SetBasicAuth("qbittorrent", token)
+			return r
+		}, token, true},
+		{"wrong token", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/api/incoming/sonarr", nil)
+			r.Header.Set("X-Api-Key", "fedcba9876543210")
+			return r
+		}, token, false},
+		{"header takes precedence", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/api/incoming/sonarr?apikey="+token, nil)
+			r.Header.Set("X-Api-Key", "wrong")
+			return r
+		}, token, false},
+		{"no token", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/api/incoming/sonarr", nil)
+		}, token, false},
+		{"not set up", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/api/incoming/sonarr", nil)
+		}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasWebhookToken(tt.request(), tt.expected); got != tt.want {
+				t.Errorf("hasWebhookToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireMetricsAccess(t *testing.T) {
+	s := &Server{config: &config.Config{Metrics: config.MetricsConfig{Enabled: true, Token: "metrics-token"}}}
+	handler := s.requireMetricsAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"bearer token", "Bearer metrics-token", http.StatusOK},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"token without scheme", "metrics-token", http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
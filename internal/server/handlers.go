@@ -4,15 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"removarr/internal/events"
 	"removarr/internal/integrations"
+	"removarr/internal/notifications"
 	"removarr/internal/services"
 
 	"golang.org/x/crypto/bcrypt"
@@ -26,7 +31,7 @@ func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
 	var userCount int
 	err := s.db.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM users").Scan(&userCount)
 	if err != nil {
-		slog.Error("Failed to check setup status", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to check setup status", "error", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
@@ -73,12 +78,12 @@ func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
 			req.Username, email, string(hashedPassword), true, true,
 		)
 		if err != nil {
-			slog.Error("Failed to create admin user", "error", err)
+			slog.ErrorContext(r.Context(), "Failed to create admin user", "error", err)
 			http.Error(w, "Failed to create user", http.StatusInternalServerError)
 			return
 		}
 
-		slog.Info("First admin user created", "username", req.Username)
+		slog.InfoContext(r.Context(), "First admin user created", "username", req.Username)
 
 		// Return success - frontend will redirect to login
 		w.Header().Set("Content-Type", "application/json")
@@ -97,7 +102,7 @@ func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := s.renderTemplate(w, "setup.html", data); err != nil {
 		http.Error(w, "Template error", http.StatusInternalServerError)
-		slog.Error("Template render error", "error", err)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
 	}
 }
 
@@ -131,7 +136,7 @@ func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
 	session, err := s.store.Get(r, sessionKey)
 	if err == nil {
 		if userID, ok := session.Values[userIDKey].(int); ok && userID > 0 {
-			slog.Info("Already logged in, redirecting to dashboard", "user_id", userID)
+			slog.InfoContext(r.Context(), "Already logged in, redirecting to dashboard", "user_id", userID)
 			http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 			return
 		}
@@ -142,30 +147,29 @@ func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
 	data := map[string]interface{}{
 		"User": nil, // No user for login page
 	}
-	slog.Info("Rendering login page")
+	slog.InfoContext(r.Context(), "Rendering login page")
 	if err := s.renderTemplate(w, "login.html", data); err != nil {
 		http.Error(w, "Template error", http.StatusInternalServerError)
-		slog.Error("Template render error", "error", err)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
 	}
 }
 
+// dashboardSyncFreshness is how recent a sync has to be for a dashboard load not to start another one
+const dashboardSyncFreshness = time.Minute
+
+// recentUploadWindow is the period the dashboard reports upload over
+const recentUploadWindow = 7 * 24 * time.Hour
+
+// eligibleWithinCondition keeps media that is eligible or estimated to become eligible within $n days
+const eligibleWithinCondition = " AND (eligible_since IS NOT NULL OR estimated_eligible_at <= CURRENT_TIMESTAMP + $%d::int * INTERVAL '1 day')"
+
 func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
-	// Always sync on dashboard load (background, non-blocking)
-	// Only on full page loads, not HTMX requests
+	// Sync on dashboard load (background, non-blocking)
+	// Only on full page loads, not HTMX requests, and not right after another sync finished
 	if r.Header.Get("HX-Request") == "" {
-		go func() {
-			ctx := context.Background()
-			slog.Info("Triggering background sync on dashboard load")
-			if err := s.mediaSync.SyncAll(ctx); err != nil {
-				slog.Error("Background auto-sync failed", "error", err)
-			} else {
-				slog.Info("Background auto-sync completed successfully")
-			}
-			// Also sync torrents
-			if err := s.torrentSync.SyncFromQBittorrent(ctx); err != nil {
-				slog.Error("Background torrent sync failed", "error", err)
-			}
-		}()
+		if _, started := s.syncs.StartUnlessFresh("dashboard", dashboardSyncFreshness); started {
+			slog.InfoContext(r.Context(), "Triggered background sync on dashboard load")
+		}
 	}
 	
 	// Get last sync time for display
@@ -174,10 +178,45 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		"SELECT MAX(last_synced_at) FROM media_items",
 	).Scan(&lastSyncTime)
 	
+	authCtx, _ := r.Context().Value("auth").(AuthContext)
+
 	// Get filters from query params
 	mediaType := r.URL.Query().Get("type")
 	eligible := r.URL.Query().Get("eligible")
 	downloaded := r.URL.Query().Get("downloaded")
+	sortBy := r.URL.Query().Get("sort")
+	tag := r.URL.Query().Get("tag")
+	genre := r.URL.Query().Get("genre")
+	qualityProfile := r.URL.Query().Get("quality_profile")
+	eligibleWithin := 0 // days, 0 means no filter
+	if days, err := strconv.Atoi(r.URL.Query().Get("eligible_within")); err == nil && days > 0 {
+		eligibleWithin = days
+	}
+
+	// Sorting by recent upload needs the torrent history, so it is paginated after the eligibility checks.
+	// Estimates and scores are stored on the media items at every sync, so those sort and filter in SQL.
+	paginateInMemory := sortBy == "least_upload"
+
+	// Upload over the last week, from the torrent history
+	recentUploads, err := s.torrentHistory.RecentUploadByMedia(r.Context(), time.Now().Add(-recentUploadWindow))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get recent upload", "error", err)
+		recentUploads = map[int]int64{}
+	}
+
+	// Pending and approved deletion requests
+	openRequests, err := s.deletionRequests.OpenRequestsByMedia(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get deletion requests", "error", err)
+		openRequests = map[int]services.DeletionRequest{}
+	}
+
+	// Deletions waiting for their grace period
+	scheduledDeletions, err := s.scheduledDeletions.PendingByMedia(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get scheduled deletions", "error", err)
+		scheduledDeletions = map[int]services.ScheduledDeletion{}
+	}
 	
 	// Pagination
 	page := 1
@@ -190,7 +229,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * pageSize
 
 	// Build query - first get total count
-	countQuery := "SELECT COUNT(*) FROM media_items WHERE 1=1"
+	countQuery := "SELECT COUNT(*) FROM media_items WHERE missing_since IS NULL"
 	countArgs := []interface{}{}
 	countArgPos := 1
 
@@ -199,16 +238,36 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		countArgs = append(countArgs, mediaType)
 		countArgPos++
 	}
+	if tag != "" {
+		countQuery += fmt.Sprintf(" AND tags @> jsonb_build_array($%d::text)", countArgPos)
+		countArgs = append(countArgs, tag)
+		countArgPos++
+	}
+	if genre != "" {
+		countQuery += fmt.Sprintf(" AND genres @> jsonb_build_array($%d::text)", countArgPos)
+		countArgs = append(countArgs, genre)
+		countArgPos++
+	}
+	if qualityProfile != "" {
+		countQuery += fmt.Sprintf(" AND quality_profile = $%d", countArgPos)
+		countArgs = append(countArgs, qualityProfile)
+		countArgPos++
+	}
+	if eligibleWithin > 0 {
+		countQuery += fmt.Sprintf(eligibleWithinCondition, countArgPos)
+		countArgs = append(countArgs, eligibleWithin)
+		countArgPos++
+	}
 
 	var totalCount int
-	err := s.db.QueryRowContext(r.Context(), countQuery, countArgs...).Scan(&totalCount)
+	err = s.db.QueryRowContext(r.Context(), countQuery, countArgs...).Scan(&totalCount)
 	if err != nil {
-		slog.Error("Failed to get media count", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to get media count", "error", err)
 		totalCount = 0
 	}
 
 	// Build main query
-	query := "SELECT id, title, type, tmdb_id, tvdb_id, sonarr_id, radarr_id, overseerr_request_id, requested_by_user_id, file_path, file_size, added_date, last_synced_at FROM media_items WHERE 1=1"
+	query := "SELECT id, title, type, tmdb_id, tvdb_id, sonarr_id, radarr_id, overseerr_request_id, requested_by_user_id, file_path, file_size, added_date, last_synced_at, year, rating, quality_profile, quality, tags FROM media_items WHERE missing_since IS NULL"
 	args := []interface{}{}
 	argPos := 1
 
@@ -217,9 +276,41 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		args = append(args, mediaType)
 		argPos++
 	}
+	if tag != "" {
+		query += fmt.Sprintf(" AND tags @> jsonb_build_array($%d::text)", argPos)
+		args = append(args, tag)
+		argPos++
+	}
+	if genre != "" {
+		query += fmt.Sprintf(" AND genres @> jsonb_build_array($%d::text)", argPos)
+		args = append(args, genre)
+		argPos++
+	}
+	if qualityProfile != "" {
+		query += fmt.Sprintf(" AND quality_profile = $%d", argPos)
+		args = append(args, qualityProfile)
+		argPos++
+	}
+	if eligibleWithin > 0 {
+		query += fmt.Sprintf(eligibleWithinCondition, argPos)
+		args = append(args, eligibleWithin)
+		argPos++
+	}
 
-	query += fmt.Sprintf(" ORDER BY added_date DESC LIMIT $%d OFFSET $%d", argPos, argPos+1)
-	args = append(args, pageSize, offset)
+	switch sortBy {
+	case "eligible_date":
+		// Eligible first, then soonest estimate, unknown estimates last
+		query += " ORDER BY eligible_since IS NULL, estimated_eligible_at NULLS LAST, added_date DESC"
+	case "score":
+		// Highest score first, unscored items last
+		query += " ORDER BY score DESC NULLS LAST, added_date DESC"
+	default:
+		query += " ORDER BY added_date DESC"
+	}
+	if !paginateInMemory {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argPos, argPos+1)
+		args = append(args, pageSize, offset)
+	}
 
 	rows, err := s.db.QueryContext(r.Context(), query, args...)
 	if err != nil {
@@ -248,12 +339,28 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		SonarrURL       string
 		OverseerrURL    string
 		PosterURL       string
+		TorrentGroups   []services.TorrentGroup
+		TorrentCount    int
+		EligibleTorrents int
+		EstimatedEligibleAt *time.Time
+		RecentUpload    int64
+		Score           *services.MediaScore
+		Protection      *services.Protection // nil unless protected
+		CanProtect      bool                 // admins and the requester can manage protection and request deletion
+		DeletionRequest *services.DeletionRequest // open deletion request, nil if none
+		ScheduledDeletion *services.ScheduledDeletion // pending scheduled deletion, nil if none
+		Year            int
+		Rating          *float64
+		QualityProfile  string
+		Quality         string
+		Tags            []string
 	}
 
 	mediaItems := []MediaItem{} // Initialize as empty slice, not nil
+	statuses := make(map[int]*services.EligibilityStatus) // for scoring, keyed by media item ID
 	
 	// Debug: Log what we're querying
-	slog.Info("Dashboard query", "sql", query, "args", args, "mediaType", mediaType, "eligible", eligible, "downloaded", downloaded)
+	slog.InfoContext(r.Context(), "Dashboard query", "sql", query, "args", args, "mediaType", mediaType, "eligible", eligible, "downloaded", downloaded)
 	
 	for rows.Next() {
 		var item struct {
@@ -270,24 +377,30 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 			FileSize           sql.NullInt64
 			AddedDate          sql.NullTime
 			LastSyncedAt       time.Time
+			Year               sql.NullInt64
+			Rating             sql.NullFloat64
+			QualityProfile     sql.NullString
+			Quality            sql.NullString
+			Tags               []byte
 		}
 
 		err := rows.Scan(&item.ID, &item.Title, &item.Type, &item.TMDBID, &item.TVDBID,
 			&item.SonarrID, &item.RadarrID, &item.OverseerrRequestID, &item.RequestedByUserID,
-			&item.FilePath, &item.FileSize, &item.AddedDate, &item.LastSyncedAt)
+			&item.FilePath, &item.FileSize, &item.AddedDate, &item.LastSyncedAt,
+			&item.Year, &item.Rating, &item.QualityProfile, &item.Quality, &item.Tags)
 		if err != nil {
-			slog.Error("Error scanning media row", "error", err)
+			slog.ErrorContext(r.Context(), "Error scanning media row", "error", err)
 			continue
 		}
 		
-		slog.Info("Processing media item from DB", "id", item.ID, "title", item.Title, "type", item.Type, "file_size", item.FileSize.Int64)
+		slog.InfoContext(r.Context(), "Processing media item from DB", "id", item.ID, "title", item.Title, "type", item.Type, "file_size", item.FileSize.Int64)
 
 		// Check eligibility - this should never error for "no torrents" case
 		// (it returns status with reason, not an error)
 		eligibility, err := s.eligibility.CheckEligibility(r.Context(), item.ID)
 		if err != nil {
 			// Only real errors (like DB issues) should reach here
-			slog.Debug("Eligibility check error", "media_id", item.ID, "error", err)
+			slog.DebugContext(r.Context(), "Eligibility check error", "media_id", item.ID, "error", err)
 			eligibility = &services.EligibilityStatus{
 				IsEligible: false,
 				Reason: fmt.Sprintf("Error: %v", err),
@@ -300,25 +413,26 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		// Apply filters
 		filteredOut := false
 		if eligible == "true" && !eligibility.IsEligible {
-			slog.Info("Filtering out - not eligible", "title", item.Title)
+			slog.InfoContext(r.Context(), "Filtering out - not eligible", "title", item.Title)
 			filteredOut = true
 		}
 		if eligible == "false" && eligibility.IsEligible {
-			slog.Info("Filtering out - eligible when filtered for not eligible", "title", item.Title)
+			slog.InfoContext(r.Context(), "Filtering out - eligible when filtered for not eligible", "title", item.Title)
 			filteredOut = true
 		}
 		if downloaded == "true" && item.FileSize.Int64 == 0 {
-			slog.Info("Filtering out - not downloaded when filtered for downloaded", "title", item.Title)
+			slog.InfoContext(r.Context(), "Filtering out - not downloaded when filtered for downloaded", "title", item.Title)
 			filteredOut = true
 		}
 		if downloaded == "false" && item.FileSize.Int64 > 0 {
-			slog.Info("Filtering out - downloaded when filtered for not downloaded", "title", item.Title)
+			slog.InfoContext(r.Context(), "Filtering out - downloaded when filtered for not downloaded", "title", item.Title)
 			filteredOut = true
 		}
 		
 		if filteredOut {
 			continue
 		}
+		statuses[item.ID] = eligibility
 
 		isDownloaded := item.FileSize.Int64 > 0 && item.FilePath.Valid && item.FilePath.String != ""
 
@@ -383,7 +497,21 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 			tmdbID = &id
 		}
 
-		slog.Info("Adding media item to results", "title", item.Title, "type", item.Type, "downloaded", isDownloaded)
+		var rating *float64
+		if item.Rating.Valid {
+			rating = &item.Rating.Float64
+		}
+		var tags []string
+		json.Unmarshal(item.Tags, &tags)
+		var deletionRequest *services.DeletionRequest
+		if request, ok := openRequests[item.ID]; ok {
+			deletionRequest = &request
+		}
+		var scheduledDeletion *services.ScheduledDeletion
+		if deletion, ok := scheduledDeletions[item.ID]; ok {
+			scheduledDeletion = &deletion
+		}
+
 		mediaItems = append(mediaItems, MediaItem{
 			ID:               item.ID,
 			Title:            item.Title,
@@ -404,10 +532,53 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 			SonarrURL:        sonarrURL,
 			OverseerrURL:     overseerrURL,
 			PosterURL:        posterURL,
+			TorrentGroups:    eligibility.TorrentGroups(),
+			TorrentCount:     len(eligibility.Torrents),
+			EligibleTorrents: eligibility.EligibleTorrentCount(),
+			EstimatedEligibleAt: eligibility.EstimatedEligibleAt,
+			RecentUpload:     recentUploads[item.ID],
+			Protection:       eligibility.Protection,
+			CanProtect:       authCtx.IsAdmin || (item.RequestedByUserID.Valid && int(item.RequestedByUserID.Int64) == authCtx.UserID),
+			Year:             int(item.Year.Int64),
+			Rating:           rating,
+			QualityProfile:   item.QualityProfile.String,
+			Quality:          item.Quality.String,
+			Tags:             tags,
+			DeletionRequest:  deletionRequest,
+			ScheduledDeletion: scheduledDeletion,
 		})
 	}
+
+	// Dead weight scores
+	scores, err := s.scoring.Score(r.Context(), statuses, s.scoreWeights())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to score media items", "error", err)
+	}
+	for i := range mediaItems {
+		if score, ok := scores[mediaItems[i].ID]; ok {
+			mediaItems[i].Score = &score
+		}
+	}
+
+	if paginateInMemory {
+		// Downloaded media that no longer uploads first, it is the cheapest to delete
+		sort.SliceStable(mediaItems, func(i, j int) bool {
+			a, b := mediaItems[i], mediaItems[j]
+			if a.Downloaded != b.Downloaded {
+				return a.Downloaded
+			}
+			return a.RecentUpload < b.RecentUpload
+		})
+
+		totalCount = len(mediaItems)
+		if offset >= len(mediaItems) {
+			mediaItems = mediaItems[:0]
+		} else {
+			mediaItems = mediaItems[offset:min(offset+pageSize, len(mediaItems))]
+		}
+	}
 	
-	slog.Info("Processed media items", "count", len(mediaItems), "query_params", map[string]string{
+	slog.InfoContext(r.Context(), "Processed media items", "count", len(mediaItems), "query_params", map[string]string{
 		"type": mediaType,
 		"eligible": eligible,
 		"downloaded": downloaded,
@@ -428,10 +599,14 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 			"TotalPages": totalPages,
 			"TotalCount": totalCount,
 			"PageSize":   pageSize,
+			"User": map[string]interface{}{
+				"Username": authCtx.Username,
+				"IsAdmin":  authCtx.IsAdmin,
+			},
 		}
 		if err := templates.ExecuteTemplate(w, "media_list", data); err != nil {
 			http.Error(w, "Template error", http.StatusInternalServerError)
-			slog.Error("Template render error", "error", err)
+			slog.ErrorContext(r.Context(), "Template render error", "error", err)
 		}
 		return
 	}
@@ -439,8 +614,6 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	// Full page render - pass media items to dashboard template
 	// Always pass Media as a slice, even if empty, so template can check length
 	// Also pass User info for the nav bar
-	authCtx, _ := r.Context().Value("auth").(AuthContext)
-	
 	firstItem := "none"
 	if len(mediaItems) > 0 {
 		firstItem = mediaItems[0].Title
@@ -459,6 +632,12 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		"Type":         mediaType, // Pass current filter values to template
 		"Eligible":     eligible,
 		"Downloaded":   downloaded,
+		"Sort":         sortBy,
+		"EligibleWithin": eligibleWithin,
+		"Tag":          tag,
+		"Genre":        genre,
+		"QualityProfile": qualityProfile,
+		"FilterOptions": s.mediaFilterOptions(r.Context()),
 		"Page":         page,
 		"TotalPages":   totalPages,
 		"TotalCount":   totalCount,
@@ -470,7 +649,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		"LastSyncTime": lastSyncDisplay,
 	}
 	
-	slog.Info("Rendering dashboard", "media_count", len(mediaItems), "first_item", firstItem, "filters", map[string]string{
+	slog.InfoContext(r.Context(), "Rendering dashboard", "media_count", len(mediaItems), "first_item", firstItem, "filters", map[string]string{
 		"type": mediaType,
 		"eligible": eligible,
 		"downloaded": downloaded,
@@ -493,10 +672,36 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	// But we need to re-order the template parsing
 	if err := s.renderTemplate(w, "dashboard.html", data); err != nil {
 		http.Error(w, "Template error", http.StatusInternalServerError)
-		slog.Error("Template render error", "error", err)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
 	}
 }
 
+// mediaFilterOptions lists the values offered by the dashboard's tag, genre and quality profile filters
+func (s *Server) mediaFilterOptions(ctx context.Context) map[string][]string {
+	queries := map[string]string{
+		"Tags":            "SELECT DISTINCT jsonb_array_elements_text(tags) AS value FROM media_items ORDER BY value",
+		"Genres":          "SELECT DISTINCT jsonb_array_elements_text(genres) AS value FROM media_items ORDER BY value",
+		"QualityProfiles": "SELECT DISTINCT quality_profile FROM media_items WHERE quality_profile IS NOT NULL ORDER BY quality_profile",
+	}
+
+	options := make(map[string][]string)
+	for name, query := range queries {
+		rows, err := s.db.QueryContext(ctx, query)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to list filter options", "filter", name, "error", err)
+			continue
+		}
+		for rows.Next() {
+			var value string
+			if err := rows.Scan(&value); err == nil {
+				options[name] = append(options[name], value)
+			}
+		}
+		rows.Close()
+	}
+	return options
+}
+
 func (s *Server) handleAdminPage(w http.ResponseWriter, r *http.Request) {
 	// Get auth context
 	authCtx, ok := r.Context().Value("auth").(AuthContext)
@@ -511,7 +716,7 @@ func (s *Server) handleAdminPage(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.renderTemplate(w, "admin.html", data); err != nil {
 		http.Error(w, "Template error", http.StatusInternalServerError)
-		slog.Error("Template render error", "error", err)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
 	}
 }
 
@@ -529,7 +734,7 @@ func (s *Server) handleSettingsPage(w http.ResponseWriter, r *http.Request) {
 		"SELECT value FROM settings WHERE key = 'sync_frequency'",
 	).Scan(&syncFrequency)
 	if err != nil && err != sql.ErrNoRows {
-		slog.Error("Failed to get sync frequency", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to get sync frequency", "error", err)
 	}
 	if syncFrequency == "" {
 		syncFrequency = "5m" // Default
@@ -555,12 +760,21 @@ func (s *Server) handleSettingsPage(w http.ResponseWriter, r *http.Request) {
 		"Settings": map[string]interface{}{
 			"SyncFrequency": syncFrequency,
 			"QBittorrentStats": qbitStats,
+			"LibraryRootFolders": strings.Join(s.libraryRootFolders(), "\n"),
+			"StorageQuotaGB": s.getSetting("storage_quota_gb", "0"),
+			"ScoreWeights": s.scoreWeights(),
+			"ProtectionTag": s.getSetting("protection.tag", services.DefaultProtectionTag),
+			"GracePeriod": s.getSetting("deletion.grace_period", "72h"),
+			"StaleRetention": s.getSetting("sync.stale_retention", services.DefaultStaleRetention.String()),
+			"IntegrationHealth": s.integrations.Health(),
+			"Notifications": s.notificationSettings(r.Context()),
+			"EventTypes": events.AllTypes,
 		},
 	}
 
 	if err := s.renderTemplate(w, "settings.html", data); err != nil {
 		http.Error(w, "Template error", http.StatusInternalServerError)
-		slog.Error("Template render error", "error", err)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
 	}
 }
 
@@ -572,6 +786,10 @@ func (s *Server) handleSettingsPage(w http.ResponseWriter, r *http.Request) {
 // @Param        user_id   query     int     false  "Filter by user ID"
 // @Param        type      query     string  false  "Filter by type (movie/series)"
 // @Param        sync      query     bool    false  "Sync from Sonarr/Radarr before listing"
+// @Param        tag       query     string  false  "Filter by Radarr/Sonarr tag label"
+// @Param        genre     query     string  false  "Filter by genre"
+// @Param        quality_profile  query  string  false  "Filter by Radarr/Sonarr quality profile name"
+// @Param        sort      query     string  false  "Sort order: score (highest dead weight score first), default is recently added"
 // @Security     BasicAuth
 // @Success      200       {array}   map[string]interface{}
 // @Failure      401       {object}  map[string]string  "Unauthorized"
@@ -579,24 +797,26 @@ func (s *Server) handleSettingsPage(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleListMedia(w http.ResponseWriter, r *http.Request) {
 	// Check if sync is requested
 	if r.URL.Query().Get("sync") == "true" {
-		ctx := r.Context()
-		if err := s.mediaSync.SyncAll(ctx); err != nil {
-			slog.Error("Media sync failed", "error", err)
-			http.Error(w, "Media sync failed", http.StatusInternalServerError)
-			return
-		}
-		if err := s.torrentSync.SyncFromQBittorrent(ctx); err != nil {
-			slog.Error("Torrent sync failed", "error", err)
-			// Don't fail the request, just log the error
+		// Joins the sync that's already running, if any; failed sources are logged, the listing still goes out
+		if _, _, err := s.syncs.Sync(r.Context(), "api"); err != nil {
+			return // request went away, the sync carries on
 		}
 	}
 
 	// Get filters
 	userID := r.URL.Query().Get("user_id")
 	mediaType := r.URL.Query().Get("type")
+	tag := r.URL.Query().Get("tag")
+	genre := r.URL.Query().Get("genre")
+	qualityProfile := r.URL.Query().Get("quality_profile")
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "score" {
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
 
 	// Build query
-	query := "SELECT id, title, type, tmdb_id, tvdb_id, sonarr_id, radarr_id, overseerr_request_id, requested_by_user_id, file_path, file_size, added_date, last_synced_at FROM media_items WHERE 1=1"
+	query := "SELECT id, title, type, tmdb_id, tvdb_id, sonarr_id, radarr_id, overseerr_request_id, requested_by_user_id, file_path, file_size, added_date, last_synced_at, year, rating, quality_profile, quality, tags, genres FROM media_items WHERE missing_since IS NULL"
 	args := []interface{}{}
 	argPos := 1
 
@@ -612,7 +832,30 @@ func (s *Server) handleListMedia(w http.ResponseWriter, r *http.Request) {
 		argPos++
 	}
 
-	query += " ORDER BY added_date DESC LIMIT 100"
+	if tag != "" {
+		query += fmt.Sprintf(" AND tags @> jsonb_build_array($%d::text)", argPos)
+		args = append(args, tag)
+		argPos++
+	}
+
+	if genre != "" {
+		query += fmt.Sprintf(" AND genres @> jsonb_build_array($%d::text)", argPos)
+		args = append(args, genre)
+		argPos++
+	}
+
+	if qualityProfile != "" {
+		query += fmt.Sprintf(" AND quality_profile = $%d", argPos)
+		args = append(args, qualityProfile)
+		argPos++
+	}
+
+	// Scores are computed in Go, so sorting by score looks at every item before limiting; their eligibility is
+	// checked for all of them at once below, in a few queries rather than several per item
+	query += " ORDER BY added_date DESC"
+	if sortBy != "score" {
+		query += " LIMIT 100"
+	}
 
 	rows, err := s.db.QueryContext(r.Context(), query, args...)
 	if err != nil {
@@ -622,6 +865,7 @@ func (s *Server) handleListMedia(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 
 	var results []map[string]interface{}
+	var ids []int
 	for rows.Next() {
 		var item struct {
 			ID                 int
@@ -637,11 +881,18 @@ func (s *Server) handleListMedia(w http.ResponseWriter, r *http.Request) {
 			FileSize           sql.NullInt64
 			AddedDate          sql.NullTime
 			LastSyncedAt       time.Time
+			Year               sql.NullInt64
+			Rating             sql.NullFloat64
+			QualityProfile     sql.NullString
+			Quality            sql.NullString
+			Tags               []byte
+			Genres             []byte
 		}
 
 		err := rows.Scan(&item.ID, &item.Title, &item.Type, &item.TMDBID, &item.TVDBID,
 			&item.SonarrID, &item.RadarrID, &item.OverseerrRequestID, &item.RequestedByUserID,
-			&item.FilePath, &item.FileSize, &item.AddedDate, &item.LastSyncedAt)
+			&item.FilePath, &item.FileSize, &item.AddedDate, &item.LastSyncedAt,
+			&item.Year, &item.Rating, &item.QualityProfile, &item.Quality, &item.Tags, &item.Genres)
 		if err != nil {
 			continue
 		}
@@ -674,18 +925,70 @@ func (s *Server) handleListMedia(w http.ResponseWriter, r *http.Request) {
 		if item.FilePath.Valid {
 			result["file_path"] = item.FilePath.String
 		}
+		result["tags"] = json.RawMessage(item.Tags)
+		result["genres"] = json.RawMessage(item.Genres)
+		if item.Year.Valid {
+			result["year"] = item.Year.Int64
+		}
+		if item.Rating.Valid {
+			result["rating"] = item.Rating.Float64
+		}
+		if item.QualityProfile.Valid {
+			result["quality_profile"] = item.QualityProfile.String
+		}
+		if item.Quality.Valid {
+			result["quality"] = item.Quality.String
+		}
 
-		// Check eligibility
-		eligibility, err := s.eligibility.CheckEligibility(r.Context(), item.ID)
-		if err == nil {
+		results = append(results, result)
+		ids = append(ids, item.ID)
+	}
+
+	// Eligibility of every listed item in a few queries, however many items sorting by score looks at
+	statuses, err := s.eligibility.CheckEligibilities(r.Context(), ids)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to check eligibility", "error", err)
+	}
+	for _, result := range results {
+		if eligibility, ok := statuses[result["id"].(int)]; ok {
 			result["eligible"] = eligibility.IsEligible
 			result["eligibility_reason"] = eligibility.Reason
 			result["seeding_time"] = eligibility.SeedingTime
 			result["seeding_ratio"] = eligibility.SeedingRatio
 			result["tracker_type"] = eligibility.TrackerType
+			result["torrents"] = eligibility.Torrents
+			result["protected"] = eligibility.Protection != nil
+			if eligibility.Protection != nil {
+				result["protection"] = eligibility.Protection
+			}
+			if eligibility.EstimatedEligibleAt != nil {
+				result["estimated_eligible_at"] = eligibility.EstimatedEligibleAt
+			}
 		}
+	}
 
-		results = append(results, result)
+	scores, err := s.scoring.Score(r.Context(), statuses, s.scoreWeights())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to score media items", "error", err)
+	}
+	for _, result := range results {
+		if score, ok := scores[result["id"].(int)]; ok {
+			result["score"] = score.Score
+			result["score_factors"] = score.Factors
+		}
+	}
+
+	if sortBy == "score" {
+		// Highest score first, unscored items last
+		sort.SliceStable(results, func(i, j int) bool {
+			a, aOK := results[i]["score"].(float64)
+			b, bOK := results[j]["score"].(float64)
+			if !aOK || !bOK {
+				return aOK
+			}
+			return a > b
+		})
+		results = results[:min(len(results), 100)]
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -741,7 +1044,7 @@ func (s *Server) handleBulkDeleteMedia(w http.ResponseWriter, r *http.Request) {
 	// Delete each media item
 	for _, id := range req.IDs {
 		if err := s.deletion.DeleteMediaItem(ctx, id, authCtx.UserID); err != nil {
-			slog.Error("Failed to delete media item in bulk", "id", id, "error", err)
+			slog.ErrorContext(ctx, "Failed to delete media item in bulk", "id", id, "error", err)
 			errors = append(errors, fmt.Sprintf("Media ID %d: %v", id, err))
 		} else {
 			successCount++
@@ -761,6 +1064,79 @@ func (s *Server) handleBulkDeleteMedia(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// @Summary      Remove finished torrents
+// @Description  Remove the torrents of a media item whose seeding requirements are met, keeping the media and its other torrents. Admins and the requester only.
+// @Tags         media
+// @Produce      json
+// @Param        id   path      int  true  "Media item ID"
+// @Security     BasicAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string  "No eligible torrents"
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Forbidden"
+// @Failure      404  {object}  map[string]string  "Media item not found"
+// @Failure      409  {object}  map[string]string  "Media item is protected"
+// @Router       /media/{id}/torrents/remove-eligible [post]
+func (s *Server) handleRemoveEligibleTorrents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	allowed, err := s.isAdminOrRequester(r, authCtx, id)
+	if err != nil {
+		http.Error(w, "Media item not found", http.StatusNotFound)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Only admins and the requester can remove this item's torrents", http.StatusForbidden)
+		return
+	}
+
+	eligibility, err := s.eligibility.CheckEligibility(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to check eligibility", "media_id", id, "error", err)
+		http.Error(w, "Failed to check eligibility", http.StatusInternalServerError)
+		return
+	}
+
+	var hashes []string
+	for _, t := range eligibility.Torrents {
+		if t.IsEligible {
+			hashes = append(hashes, t.Hash)
+		}
+	}
+	if len(hashes) == 0 {
+		http.Error(w, "No torrents have met their seeding requirements", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.deletion.RemoveTorrents(r.Context(), id, hashes, authCtx.UserID); err != nil {
+		if errors.Is(err, services.ErrProtected) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to remove torrents", "media_id", id, "error", err)
+		http.Error(w, fmt.Sprintf("Failed to remove torrents: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"removed": len(hashes),
+		"kept":    len(eligibility.Torrents) - len(hashes),
+	})
+}
+
 // @Summary      List users
 // @Description  Get a list of all users
 // @Tags         admin
@@ -847,7 +1223,7 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		req.Username, email, string(hashedPassword), req.IsAdmin, true,
 	)
 	if err != nil {
-		slog.Error("Failed to create user", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to create user", "error", err)
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
@@ -942,7 +1318,7 @@ func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	_, err = s.db.ExecContext(r.Context(), query, args...)
 	if err != nil {
-		slog.Error("Failed to update user", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to update user", "error", err)
 		http.Error(w, "Failed to update user", http.StatusInternalServerError)
 		return
 	}
@@ -971,7 +1347,7 @@ func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	_, err = s.db.ExecContext(r.Context(), "DELETE FROM users WHERE id = $1", id)
 	if err != nil {
-		slog.Error("Failed to delete user", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to delete user", "error", err)
 		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
 		return
 	}
@@ -1024,6 +1400,13 @@ func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 			"api_key": s.getSetting("tautulli.api_key", s.config.Tautulli.APIKey),
 		},
 		"sync_frequency": s.getSetting("sync_frequency", "5m"),
+		"library_root_folders": s.libraryRootFolders(),
+		"storage_quota_gb": s.storageQuotaBytes() / (1 << 30),
+		"scoring_weights": s.scoreWeights(),
+		"protection_tag": s.getSetting("protection.tag", services.DefaultProtectionTag),
+		"deletion_grace_period": s.getSetting("deletion.grace_period", "72h"),
+		"stale_retention": s.getSetting("sync.stale_retention", services.DefaultStaleRetention.String()),
+		"notifications": s.notificationSettings(r.Context()),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1048,12 +1431,133 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 		}
 		
 		if err := s.setSetting("sync_frequency", syncFreq, "string"); err != nil {
-			slog.Error("Failed to save sync frequency", "error", err)
+			slog.ErrorContext(r.Context(), "Failed to save sync frequency", "error", err)
 			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
 			return
 		}
 		settingsUpdated = true
-		slog.Info("Sync frequency updated", "frequency", syncFreq)
+		slog.InfoContext(r.Context(), "Sync frequency updated", "frequency", syncFreq)
+	}
+
+	// Handle library_root_folders setting (used by the orphan report)
+	if rawFolders, ok := req["library_root_folders"].([]interface{}); ok {
+		var folders []string
+		for _, raw := range rawFolders {
+			folder, _ := raw.(string)
+			folder = strings.TrimSpace(folder)
+			if folder == "" {
+				continue
+			}
+			if !filepath.IsAbs(folder) {
+				http.Error(w, fmt.Sprintf("Root folder must be an absolute path: %s", folder), http.StatusBadRequest)
+				return
+			}
+			folders = append(folders, filepath.Clean(folder))
+		}
+
+		if err := s.setSetting("library_root_folders", strings.Join(folders, "\n"), "string"); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save library root folders", "error", err)
+			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+			return
+		}
+		slog.InfoContext(r.Context(), "Library root folders updated", "folders", folders)
+	}
+
+	// Handle storage_quota_gb setting (used by the statistics page projection)
+	if rawQuota, ok := req["storage_quota_gb"].(float64); ok {
+		if rawQuota < 0 || rawQuota != float64(int64(rawQuota)) {
+			http.Error(w, "Storage quota must be a whole number of GB (0 for no quota)", http.StatusBadRequest)
+			return
+		}
+		if err := s.setSetting("storage_quota_gb", strconv.FormatInt(int64(rawQuota), 10), "integer"); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save storage quota", "error", err)
+			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+			return
+		}
+		slog.InfoContext(r.Context(), "Storage quota updated", "quota_gb", int64(rawQuota))
+	}
+
+	// Handle scoring_weights setting (used by the dashboard's dead weight score)
+	if rawWeights, ok := req["scoring_weights"].(map[string]interface{}); ok {
+		weights := s.scoreWeights()
+		encoded, _ := json.Marshal(rawWeights)
+		if err := json.Unmarshal(encoded, &weights); err != nil {
+			http.Error(w, "Invalid scoring weights", http.StatusBadRequest)
+			return
+		}
+		for _, weight := range []float64{weights.Size, weights.Eligibility, weights.RecentUpload,
+			weights.LastWatched, weights.PlayCount, weights.Age, weights.OpenRequest} {
+			if weight < 0 {
+				http.Error(w, "Scoring weights can't be negative", http.StatusBadRequest)
+				return
+			}
+		}
+		stored, _ := json.Marshal(weights)
+		if err := s.setSetting("scoring.weights", string(stored), "json"); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save scoring weights", "error", err)
+			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+			return
+		}
+		slog.InfoContext(r.Context(), "Scoring weights updated", "weights", string(stored))
+		// The stored scores the dashboard sorts by are out of date until the next sync otherwise
+		s.goBackground(func() { s.recordEligibility(s.ctx, s.currentServices()) })
+	}
+
+	// Handle protection_tag setting (Radarr/Sonarr tag that protects media, empty to disable)
+	if tag, ok := req["protection_tag"].(string); ok {
+		tag = strings.TrimSpace(tag)
+		if err := s.setSetting("protection.tag", tag, "string"); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save protection tag", "error", err)
+			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+			return
+		}
+		slog.InfoContext(r.Context(), "Protection tag updated", "tag", tag)
+	}
+
+	// Handle deletion_grace_period setting (how long scheduled deletions wait, 0 to delete right away)
+	if grace, ok := req["deletion_grace_period"].(string); ok {
+		grace = strings.TrimSpace(grace)
+		if parsed, err := time.ParseDuration(grace); err != nil || parsed < 0 {
+			http.Error(w, "Invalid grace period format (use format like '72h', '24h', or 0 to delete right away)", http.StatusBadRequest)
+			return
+		}
+		if err := s.setSetting("deletion.grace_period", grace, "string"); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save deletion grace period", "error", err)
+			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+			return
+		}
+		slog.InfoContext(r.Context(), "Deletion grace period updated", "grace_period", grace)
+	}
+
+	// Handle stale_retention setting (how long media and torrents missing from their source are kept, 0 to purge on the next sync)
+	if retention, ok := req["stale_retention"].(string); ok {
+		retention = strings.TrimSpace(retention)
+		if parsed, err := time.ParseDuration(retention); err != nil || parsed < 0 {
+			http.Error(w, "Invalid retention format (use format like '168h', '24h', or 0 to purge on the next sync)", http.StatusBadRequest)
+			return
+		}
+		if err := s.setSetting("sync.stale_retention", retention, "string"); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save stale retention", "error", err)
+			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+			return
+		}
+		slog.InfoContext(r.Context(), "Stale retention updated", "retention", retention)
+	}
+
+	// Handle notifications setting (channels and per-event templates)
+	if rawNotifications, ok := req["notifications"].(map[string]interface{}); ok {
+		config, err := s.decodeNotificationSettings(r.Context(), rawNotifications)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stored, _ := json.Marshal(config)
+		if err := s.setSetting(notifications.SettingKey, string(stored), "json"); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save notification settings", "error", err)
+			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+			return
+		}
+		slog.InfoContext(r.Context(), "Notification settings updated")
 	}
 
 	// Handle integration settings - save to database
@@ -1068,7 +1572,7 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 			
 			// Save enabled state
 			if err := s.setSetting(fmt.Sprintf("%s.enabled", serviceName), fmt.Sprintf("%t", enabled), "boolean"); err != nil {
-				slog.Error("Failed to save setting", "key", fmt.Sprintf("%s.enabled", serviceName), "error", err)
+				slog.ErrorContext(r.Context(), "Failed to save setting", "key", fmt.Sprintf("%s.enabled", serviceName), "error", err)
 				http.Error(w, "Failed to save settings", http.StatusInternalServerError)
 				return
 			}
@@ -1077,7 +1581,7 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 			if url != "" {
 				url = strings.TrimSuffix(url, "/")
 				if err := s.setSetting(fmt.Sprintf("%s.url", serviceName), url, "string"); err != nil {
-					slog.Error("Failed to save setting", "key", fmt.Sprintf("%s.url", serviceName), "error", err)
+					slog.ErrorContext(r.Context(), "Failed to save setting", "key", fmt.Sprintf("%s.url", serviceName), "error", err)
 					http.Error(w, "Failed to save settings", http.StatusInternalServerError)
 					return
 				}
@@ -1086,7 +1590,7 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 			// Save API key if provided (only for services that use API keys)
 			if apiKey != "" && serviceName != "qbittorrent" {
 				if err := s.setSetting(fmt.Sprintf("%s.api_key", serviceName), apiKey, "string"); err != nil {
-					slog.Error("Failed to save setting", "key", fmt.Sprintf("%s.api_key", serviceName), "error", err)
+					slog.ErrorContext(r.Context(), "Failed to save setting", "key", fmt.Sprintf("%s.api_key", serviceName), "error", err)
 					http.Error(w, "Failed to save settings", http.StatusInternalServerError)
 					return
 				}
@@ -1096,14 +1600,14 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 			if serviceName == "qbittorrent" {
 				if username != "" {
 					if err := s.setSetting("qbittorrent.username", username, "string"); err != nil {
-						slog.Error("Failed to save setting", "key", "qbittorrent.username", "error", err)
+						slog.ErrorContext(r.Context(), "Failed to save setting", "key", "qbittorrent.username", "error", err)
 						http.Error(w, "Failed to save settings", http.StatusInternalServerError)
 						return
 					}
 				}
 				if password != "" {
 					if err := s.setSetting("qbittorrent.password", password, "string"); err != nil {
-						slog.Error("Failed to save setting", "key", "qbittorrent.password", "error", err)
+						slog.ErrorContext(r.Context(), "Failed to save setting", "key", "qbittorrent.password", "error", err)
 						http.Error(w, "Failed to save settings", http.StatusInternalServerError)
 						return
 					}
@@ -1117,19 +1621,16 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 	// Reload settings from database and update integrations
 	if settingsUpdated {
 		s.loadIntegrationSettings()
-		s.integrations = integrations.NewClient(s.config)
 		// Update services that depend on integrations
-		s.mediaSync = services.NewMediaSyncService(s.db, s.integrations)
-		s.torrentSync = services.NewTorrentSyncService(s.db, s.integrations)
-		s.eligibility = services.NewEligibilityService(s.db, s.integrations)
-		s.deletion = services.NewDeletionService(
-			s.db,
-			s.integrations.Sonarr,
-			s.integrations.Radarr,
-			s.integrations.Overseerr,
-			s.integrations.QBittorrent,
-		)
-		slog.Info("Settings updated and integrations reloaded")
+		s.setIntegrations(integrations.NewClient(s.config))
+		slog.InfoContext(r.Context(), "Settings updated and integrations reloaded")
+		// Check the changed integrations now rather than at the next interval
+		integrationHealth := s.currentServices().integrationHealth
+		s.goBackground(func() {
+			if err := integrationHealth.CheckAll(s.ctx); err != nil {
+				slog.ErrorContext(r.Context(), "Failed to record integration health checks", "error", err)
+			}
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1158,7 +1659,7 @@ func (s *Server) handleTestIntegration(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	success, message := s.testIntegrationConnection(req.Service, req.URL, req.APIKey, req.Username, req.Password)
+	success, message := s.testIntegrationConnection(r.Context(), req.Service, req.URL, req.APIKey, req.Username, req.Password)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1167,7 +1668,7 @@ func (s *Server) handleTestIntegration(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *Server) testIntegrationConnection(service string, url string, apiKey string, username string, password string) (bool, string) {
+func (s *Server) testIntegrationConnection(ctx context.Context, service string, url string, apiKey string, username string, password string) (bool, string) {
 	// Create a temporary client for testing
 	switch service {
 	case "overseerr":
@@ -1175,7 +1676,7 @@ func (s *Server) testIntegrationConnection(service string, url string, apiKey st
 			return false, "API key is required"
 		}
 		client := integrations.NewOverseerrClient(url, apiKey)
-		_, err := client.GetRequests()
+		_, err := client.GetRequests(ctx)
 		if err != nil {
 			return false, err.Error()
 		}
@@ -1186,7 +1687,7 @@ func (s *Server) testIntegrationConnection(service string, url string, apiKey st
 			return false, "API key is required"
 		}
 		client := integrations.NewSonarrClient(url, apiKey)
-		_, err := client.GetSeries()
+		_, err := client.GetSeries(ctx)
 		if err != nil {
 			return false, err.Error()
 		}
@@ -1197,7 +1698,7 @@ func (s *Server) testIntegrationConnection(service string, url string, apiKey st
 			return false, "API key is required"
 		}
 		client := integrations.NewRadarrClient(url, apiKey)
-		_, err := client.GetMovies()
+		_, err := client.GetMovies(ctx)
 		if err != nil {
 			return false, err.Error()
 		}
@@ -1208,7 +1709,7 @@ func (s *Server) testIntegrationConnection(service string, url string, apiKey st
 			return false, "API key is required"
 		}
 		client := integrations.NewProwlarrClient(url, apiKey)
-		_, err := client.GetIndexers()
+		_, err := client.GetIndexers(ctx)
 		if err != nil {
 			return false, err.Error()
 		}
@@ -1220,7 +1721,7 @@ func (s *Server) testIntegrationConnection(service string, url string, apiKey st
 		}
 		client := integrations.NewQBittorrentClient(url, username, password)
 		// GetTorrents will automatically login if needed
-		_, err := client.GetTorrents()
+		_, err := client.GetTorrents(ctx)
 		if err != nil {
 			return false, err.Error()
 		}
@@ -1231,7 +1732,7 @@ func (s *Server) testIntegrationConnection(service string, url string, apiKey st
 			return false, "API key is required"
 		}
 		client := integrations.NewTautulliClient(url, apiKey)
-		_, err := client.GetHistory()
+		_, err := client.GetHistory(ctx)
 		if err != nil {
 			return false, err.Error()
 		}
@@ -1254,16 +1755,16 @@ func (s *Server) handlePosterProxyRadarr(w http.ResponseWriter, r *http.Request)
 	
 	// Fetch poster from Radarr
 	posterURL := fmt.Sprintf("%s/MediaCover/%s/poster.jpg", s.integrations.Radarr.GetBaseURL(), movieID)
-	req, err := http.NewRequest("GET", posterURL, nil)
+	req, err := http.NewRequestWithContext(r.Context(), "GET", posterURL, nil)
 	if err != nil {
-		slog.Error("Failed to create poster request", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to create poster request", "error", err)
 		http.Error(w, "Failed to fetch poster", http.StatusInternalServerError)
 		return
 	}
 	
 	resp, err := s.integrations.Radarr.GetClient().Do(req)
 	if err != nil {
-		slog.Error("Failed to fetch Radarr poster", "error", err, "movie_id", movieID)
+		slog.ErrorContext(r.Context(), "Failed to fetch Radarr poster", "error", err, "movie_id", movieID)
 		http.Error(w, "Failed to fetch poster", http.StatusInternalServerError)
 		return
 	}
@@ -1297,18 +1798,18 @@ func (s *Server) getQBittorrentStats(ctx context.Context) map[string]interface{}
 
 	// Get total torrent count
 	var totalCount int
-	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM torrents").Scan(&totalCount)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM torrents WHERE missing_since IS NULL").Scan(&totalCount)
 	if err != nil {
-		slog.Error("Failed to get torrent count", "error", err)
+		slog.ErrorContext(ctx, "Failed to get torrent count", "error", err)
 		return stats
 	}
 	stats["TotalTorrents"] = totalCount
 
 	// Get seeding torrent count
 	var seedingCount int
-	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM torrents WHERE is_seeding = true").Scan(&seedingCount)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM torrents WHERE is_seeding = true AND missing_since IS NULL").Scan(&seedingCount)
 	if err != nil {
-		slog.Error("Failed to get seeding count", "error", err)
+		slog.ErrorContext(ctx, "Failed to get seeding count", "error", err)
 	} else {
 		stats["SeedingTorrents"] = seedingCount
 	}
@@ -1316,10 +1817,10 @@ func (s *Server) getQBittorrentStats(ctx context.Context) map[string]interface{}
 	// Get total upload/download bytes
 	var totalUpload, totalDownload sql.NullInt64
 	err = s.db.QueryRowContext(ctx,
-		"SELECT COALESCE(SUM(upload_bytes), 0), COALESCE(SUM(download_bytes), 0) FROM torrents",
+		"SELECT COALESCE(SUM(upload_bytes), 0), COALESCE(SUM(download_bytes), 0) FROM torrents WHERE missing_since IS NULL",
 	).Scan(&totalUpload, &totalDownload)
 	if err != nil {
-		slog.Error("Failed to get torrent stats", "error", err)
+		slog.ErrorContext(ctx, "Failed to get torrent stats", "error", err)
 	} else {
 		if totalUpload.Valid {
 			stats["TotalUpload"] = totalUpload.Int64
@@ -1335,7 +1836,7 @@ func (s *Server) getQBittorrentStats(ctx context.Context) map[string]interface{}
 		"SELECT MAX(last_synced_at) FROM torrents",
 	).Scan(&lastSync)
 	if err != nil && err != sql.ErrNoRows {
-		slog.Error("Failed to get last sync time", "error", err)
+		slog.ErrorContext(ctx, "Failed to get last sync time", "error", err)
 	} else if lastSync.Valid {
 		stats["LastSync"] = lastSync.Time.Format("2006-01-02 15:04:05 MST")
 	}
@@ -1355,16 +1856,16 @@ func (s *Server) handlePosterProxySonarr(w http.ResponseWriter, r *http.Request)
 	
 	// Fetch poster from Sonarr
 	posterURL := fmt.Sprintf("%s/MediaCover/%s/poster.jpg", s.integrations.Sonarr.GetBaseURL(), seriesID)
-	req, err := http.NewRequest("GET", posterURL, nil)
+	req, err := http.NewRequestWithContext(r.Context(), "GET", posterURL, nil)
 	if err != nil {
-		slog.Error("Failed to create poster request", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to create poster request", "error", err)
 		http.Error(w, "Failed to fetch poster", http.StatusInternalServerError)
 		return
 	}
 	
 	resp, err := s.integrations.Sonarr.GetClient().Do(req)
 	if err != nil {
-		slog.Error("Failed to fetch Sonarr poster", "error", err, "series_id", seriesID)
+		slog.ErrorContext(r.Context(), "Failed to fetch Sonarr poster", "error", err, "series_id", seriesID)
 		http.Error(w, "Failed to fetch poster", http.StatusInternalServerError)
 		return
 	}
@@ -23,12 +23,12 @@ func (s *Server) handleLogoutPage(w http.ResponseWriter, r *http.Request) {
 		session.Options.MaxAge = -1
 		// Save the session (this should delete the cookie)
 		if err := session.Save(r, w); err != nil {
-			slog.Error("Failed to save cleared session", "error", err, "user_id", userID)
+			slog.ErrorContext(r.Context(), "Failed to save cleared session", "error", err, "user_id", userID)
 		} else {
-			slog.Info("Session cleared", "path", r.URL.Path, "user_id", userID)
+			slog.InfoContext(r.Context(), "Session cleared", "path", r.URL.Path, "user_id", userID)
 		}
 	} else {
-		slog.Warn("No session found to clear", "error", err)
+		slog.WarnContext(r.Context(), "No session found to clear", "error", err)
 	}
 
 	// Always do a regular HTTP redirect - this ensures URL updates properly
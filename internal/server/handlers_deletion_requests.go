@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"removarr/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// handleDeletionRequestsPage renders the deletion requests page
+// Users see their own requests; admins see everyone's and review them
+func (s *Server) handleDeletionRequestsPage(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := map[string]interface{}{
+		"User": authCtx,
+	}
+
+	if err := s.renderTemplate(w, "deletion_requests.html", data); err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
+	}
+}
+
+// @Summary      List deletion requests
+// @Description  Admins get every request, other users their own
+// @Tags         deletion-requests
+// @Produce      json
+// @Param        status  query     string  false  "Filter by status: requested, approved, rejected, scheduled or executed"
+// @Security     BasicAuth
+// @Success      200     {array}   services.DeletionRequest
+// @Failure      400     {object}  map[string]string  "Invalid status"
+// @Failure      401     {object}  map[string]string  "Unauthorized"
+// @Router       /deletion-requests [get]
+func (s *Server) handleListDeletionRequests(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter := services.DeletionRequestFilter{Status: r.URL.Query().Get("status")}
+	switch filter.Status {
+	case "", services.DeletionRequestRequested, services.DeletionRequestApproved,
+		services.DeletionRequestRejected, services.DeletionRequestScheduled, services.DeletionRequestExecuted:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	if !authCtx.IsAdmin {
+		filter.UserID = authCtx.UserID
+	}
+
+	requests, err := s.deletionRequests.List(r.Context(), filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list deletion requests", "error", err)
+		http.Error(w, "Failed to list deletion requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+// @Summary      Request deletion
+// @Description  Flag a media item as done with, for an admin to approve. Admins and the requester only.
+// @Tags         deletion-requests
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int     true   "Media item ID"
+// @Param        body  body      object  false  "Optional note"  example({"note":"Watched it"})
+// @Security     BasicAuth
+// @Success      200   {object}  services.DeletionRequest
+// @Failure      401   {object}  map[string]string  "Unauthorized"
+// @Failure      403   {object}  map[string]string  "Forbidden"
+// @Failure      409   {object}  map[string]string  "Media item is protected or already has an open request"
+// @Router       /media/{id}/deletion-request [post]
+func (s *Server) handleCreateDeletionRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The note is optional, so an empty body is fine
+	var req struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	allowed, err := s.isAdminOrRequester(r, authCtx, id)
+	if err != nil {
+		http.Error(w, "Media item not found", http.StatusNotFound)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Only admins and the requester can request deletion of this item", http.StatusForbidden)
+		return
+	}
+
+	request, err := s.deletionRequests.Create(r.Context(), id, authCtx.UserID, req.Note)
+	if err != nil {
+		if errors.Is(err, services.ErrProtected) || errors.Is(err, services.ErrOpenRequestExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to create deletion request", "media_id", id, "error", err)
+		http.Error(w, "Failed to create deletion request", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// @Summary      Withdraw deletion request
+// @Description  Withdraw a deletion request that hasn't been reviewed yet. Admins and the user who made it only.
+// @Tags         deletion-requests
+// @Produce      json
+// @Param        id   path      int  true  "Deletion request ID"
+// @Security     BasicAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Forbidden"
+// @Failure      409  {object}  map[string]string  "Request already reviewed"
+// @Router       /deletion-requests/{id} [delete]
+func (s *Server) handleWithdrawDeletionRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	request, err := s.deletionRequests.Get(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Deletion request not found", http.StatusNotFound)
+		return
+	}
+	if !authCtx.IsAdmin && (request.RequestedByUserID == nil || *request.RequestedByUserID != authCtx.UserID) {
+		http.Error(w, "Only admins and the user who made the request can withdraw it", http.StatusForbidden)
+		return
+	}
+
+	if err := s.deletionRequests.Withdraw(r.Context(), id, authCtx.UserID); err != nil {
+		if errors.Is(err, services.ErrInvalidTransition) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to withdraw deletion request", "request_id", id, "error", err)
+		http.Error(w, "Failed to withdraw deletion request", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// @Summary      Approve deletion requests
+// @Description  Approve requested deletions in a batch. Approved media is deleted as soon as it is eligible, right away if it already is.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      object  true  "Request IDs and optional note"  example({"ids":[1,2],"note":""})
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid request"
+// @Router       /admin/deletion-requests/approve [post]
+func (s *Server) handleApproveDeletionRequests(w http.ResponseWriter, r *http.Request) {
+	s.reviewDeletionRequests(w, r, s.deletionRequests.Approve)
+}
+
+// @Summary      Reject deletion requests
+// @Description  Reject requested deletions in a batch
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      object  true  "Request IDs and optional note"  example({"ids":[1,2],"note":"Still being watched"})
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid request"
+// @Router       /admin/deletion-requests/reject [post]
+func (s *Server) handleRejectDeletionRequests(w http.ResponseWriter, r *http.Request) {
+	s.reviewDeletionRequests(w, r, s.deletionRequests.Reject)
+}
+
+// reviewDeletionRequests applies review to each request in the body, reporting per-request errors like bulk delete
+func (s *Server) reviewDeletionRequests(w http.ResponseWriter, r *http.Request,
+	review func(ctx context.Context, id int, adminID int, note string) error) {
+	var req struct {
+		IDs  []int  `json:"ids"`
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 {
+		http.Error(w, "No request IDs provided", http.StatusBadRequest)
+		return
+	}
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	errs := []string{}
+	reviewed := 0
+	for _, id := range req.IDs {
+		if err := review(r.Context(), id, authCtx.UserID, req.Note); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to review deletion request", "request_id", id, "error", err)
+			errs = append(errs, fmt.Sprintf("Request %d: %v", id, err))
+		} else {
+			reviewed++
+		}
+	}
+
+	response := map[string]interface{}{
+		"success":  len(errs) == 0,
+		"reviewed": reviewed,
+		"total":    len(req.IDs),
+	}
+	if len(errs) > 0 {
+		response["errors"] = errs
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
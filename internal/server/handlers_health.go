@@ -0,0 +1,224 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"removarr/internal/database"
+	"removarr/internal/services"
+)
+
+// readinessTimeout bounds each readiness check, so a hung database fails the probe instead of timing it out
+const readinessTimeout = 5 * time.Second
+
+// Health check statuses
+const (
+	healthCheckOK      = "ok"
+	healthCheckFailing = "failing"
+)
+
+// healthCheck is the outcome of one readiness check
+type healthCheck struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"` // "ok" or "failing"
+	Message   string `json:"message,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// readinessReport is whether removarr can serve traffic, and the checks that decided it
+type readinessReport struct {
+	Status string        `json:"status"` // "ready" or "not_ready"
+	Checks []healthCheck `json:"checks"`
+}
+
+// @Summary      Liveness
+// @Description  Responds 200 while the process is serving requests. Doesn't check dependencies, so a database outage doesn't get removarr restarted; see /health/ready.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Router       /health/live [get]
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "alive"})
+}
+
+// @Summary      Readiness
+// @Description  Responds 200 once removarr can serve traffic: the database answers, its migration version matches this build, the templates loaded and the configured critical integrations passed their last health check. Responds 503 with the failing checks otherwise.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  readinessReport
+// @Failure      503  {object}  readinessReport  "A check failed"
+// @Router       /health/ready [get]
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	report := readinessReport{Status: "ready"}
+	run := func(name string, check func(ctx context.Context) (string, error)) {
+		started := time.Now()
+		message, err := check(ctx)
+		result := healthCheck{Name: name, Status: healthCheckOK, Message: message, LatencyMs: time.Since(started).Milliseconds()}
+		if err != nil {
+			result.Status = healthCheckFailing
+			result.Message = err.Error()
+			report.Status = "not_ready"
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	run("database", func(ctx context.Context) (string, error) {
+		return "", s.db.PingContext(ctx)
+	})
+	run("migrations", s.checkSchemaVersion)
+	run("templates", func(ctx context.Context) (string, error) {
+		return "", checkTemplates()
+	})
+	if len(s.config.Health.CriticalIntegrations) > 0 {
+		run("integrations", s.checkCriticalIntegrations)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != "ready" {
+		slog.WarnContext(ctx, "Readiness check failed", "checks", report.Checks)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// checkSchemaVersion fails unless the database is migrated to exactly the version this build expects
+func (s *Server) checkSchemaVersion(ctx context.Context) (string, error) {
+	var version int
+	var dirty bool
+	err := s.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("no migrations applied, expected version %d", database.SchemaVersion)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get migration version: %w", err)
+	}
+	if dirty {
+		return "", fmt.Errorf("migration %d failed partway (dirty)", version)
+	}
+	if version != database.SchemaVersion {
+		return "", fmt.Errorf("database is at migration %d, this build expects %d", version, database.SchemaVersion)
+	}
+	return fmt.Sprintf("version %d", version), nil
+}
+
+// checkCriticalIntegrations fails if a critical integration isn't configured or failed its last health check
+// One that hasn't been checked yet, right after startup, doesn't fail it
+func (s *Server) checkCriticalIntegrations(ctx context.Context) (string, error) {
+	statuses, err := s.integrationHealth.Status(ctx)
+	if err != nil {
+		return "", err
+	}
+	byName := make(map[string]services.IntegrationStatus, len(statuses))
+	for _, status := range statuses {
+		byName[status.Name] = status
+	}
+
+	var errs []error
+	for _, name := range s.config.Health.CriticalIntegrations {
+		status, ok := byName[name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: not configured", name))
+		case status.LastCheck != nil && !status.Healthy:
+			errs = append(errs, fmt.Errorf("%s: %s", name, status.LastCheck.Status))
+		}
+	}
+	return "", errors.Join(errs...)
+}
+
+// integrationHealthReport is the health of every configured integration
+type integrationHealthReport struct {
+	Healthy      bool                         `json:"healthy"` // no integration failed its last check
+	Integrations []services.IntegrationStatus `json:"integrations"`
+}
+
+func newIntegrationHealthReport(statuses []services.IntegrationStatus) integrationHealthReport {
+	report := integrationHealthReport{Healthy: true, Integrations: statuses}
+	for _, status := range statuses {
+		// An integration that hasn't been checked yet, right after startup, doesn't count against it
+		if status.LastCheck != nil && !status.Healthy {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// @Summary      Integration health
+// @Description  The last health check of each configured integration, for monitoring. Responds 503 if any integration failed its last check. Error messages are left out, they can contain URLs with API keys.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  integrationHealthReport
+// @Failure      503  {object}  integrationHealthReport  "An integration failed its last check"
+// @Router       /health/integrations [get]
+func (s *Server) handleIntegrationHealth(w http.ResponseWriter, r *http.Request) {
+	statuses, err := s.integrationHealth.Status(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get integration health", "error", err)
+		http.Error(w, "Failed to get integration health", http.StatusInternalServerError)
+		return
+	}
+
+	// Unauthenticated, so only the outcome of each check
+	for i := range statuses {
+		if statuses[i].LastCheck != nil {
+			last := *statuses[i].LastCheck
+			last.Error = ""
+			statuses[i].LastCheck = &last
+		}
+		statuses[i].Recent = nil
+		statuses[i].Circuit.LastError = ""
+	}
+	report := newIntegrationHealthReport(statuses)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// @Summary      Integration health details
+// @Description  The recent health checks of each configured integration, with their errors, and its circuit breaker state
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {object}  integrationHealthReport
+// @Router       /admin/integrations/health [get]
+func (s *Server) handleAdminIntegrationHealth(w http.ResponseWriter, r *http.Request) {
+	statuses, err := s.integrationHealth.Status(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get integration health", "error", err)
+		http.Error(w, "Failed to get integration health", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newIntegrationHealthReport(statuses))
+}
+
+// @Summary      Check integrations
+// @Description  Check every configured integration now, instead of waiting for the next periodic check
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {object}  integrationHealthReport
+// @Router       /admin/integrations/health/check [post]
+func (s *Server) handleCheckIntegrations(w http.ResponseWriter, r *http.Request) {
+	if err := s.integrationHealth.CheckAll(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to record integration health checks", "error", err)
+		http.Error(w, "Failed to check integrations", http.StatusInternalServerError)
+		return
+	}
+
+	s.handleAdminIntegrationHealth(w, r)
+}
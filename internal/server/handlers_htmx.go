@@ -1,23 +1,30 @@
 package server
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
 
+	"removarr/internal/services"
+
 	"github.com/gorilla/mux"
 )
 
 func (s *Server) handleSyncMedia(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	if err := s.mediaSync.SyncAll(ctx); err != nil {
-		slog.Error("Media sync failed", "error", err)
-		http.Error(w, "Sync failed", http.StatusInternalServerError)
-		return
+	var actorID *int
+	if authCtx, ok := ctx.Value("auth").(AuthContext); ok {
+		actorID = &authCtx.UserID
+	}
+
+	// Joins the sync that's already running, if any, rather than starting a second one
+	run, started, err := s.syncs.Sync(ctx, "manual")
+	if err != nil {
+		return // request went away, the sync carries on
 	}
-	if err := s.torrentSync.SyncFromQBittorrent(ctx); err != nil {
-		slog.Error("Torrent sync failed", "error", err)
-		// Don't fail the request, just log the error
+	if started {
+		s.publishSyncResult(ctx, "manual", run, actorID)
 	}
 
 	// Redirect to refresh the dashboard
@@ -43,7 +50,12 @@ func (s *Server) handleDeleteMediaHTMX(w http.ResponseWriter, r *http.Request) {
 	// Perform deletion
 	ctx := r.Context()
 	if err := s.deletion.DeleteMediaItem(ctx, id, authCtx.UserID); err != nil {
-		slog.Error("Failed to delete media item", "id", id, "error", err)
+		if errors.Is(err, services.ErrProtected) {
+			// Keep the item in the UI, nothing was deleted
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to delete media item", "id", id, "error", err)
 		// Still remove from UI, but log the error
 		// In the future, we could show an error message
 	}
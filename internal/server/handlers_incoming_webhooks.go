@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"removarr/internal/integrations"
+	"removarr/internal/services"
+)
+
+// @Summary      Sonarr webhook
+// @Description  Receives Sonarr "Webhook" connection notifications and updates the series right away. Imports, renames, new series and deleted episode files resync the series; deleted series are marked missing and purged after the stale retention period.
+// @Tags         incoming-webhooks
+// @Accept       json
+// @Produce      json
+// @Param        body  body      integrations.SonarrWebhook  true  "Sonarr notification"
+// @Security     ApiKeyAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid notification"
+// @Router       /incoming/sonarr [post]
+func (s *Server) handleSonarrWebhook(w http.ResponseWriter, r *http.Request) {
+	var payload integrations.SonarrWebhook
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid notification", http.StatusBadRequest)
+		return
+	}
+
+	action := "ignored"
+	if payload.Series != nil {
+		var err error
+		switch payload.EventType {
+		case "Download", "Rename", "SeriesAdd", "EpisodeFileDelete":
+			action = "synced"
+			err = s.syncs.SyncOne(r.Context(), services.SyncSourceSonarr, func(ctx context.Context) error {
+				return s.currentServices().mediaSync.SyncSeries(ctx, payload.Series.ID)
+			})
+		case "SeriesDelete":
+			action = "marked_missing"
+			err = s.syncs.SyncOne(r.Context(), services.SyncSourceSonarr, func(ctx context.Context) error {
+				return s.currentServices().mediaSync.RemoveSeries(ctx, payload.Series.ID)
+			})
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to apply Sonarr webhook", "event", payload.EventType, "series", payload.Series.Title, "error", err)
+			http.Error(w, "Failed to apply notification", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	slog.InfoContext(r.Context(), "Received Sonarr webhook", "event", payload.EventType, "action", action)
+	writeIncomingWebhookResult(w, action)
+}
+
+// @Summary      Radarr webhook
+// @Description  Receives Radarr "Webhook" connection notifications and updates the movie right away. Imports, renames, new movies and deleted movie files resync the movie; deleted movies are marked missing and purged after the stale retention period.
+// @Tags         incoming-webhooks
+// @Accept       json
+// @Produce      json
+// @Param        body  body      integrations.RadarrWebhook  true  "Radarr notification"
+// @Security     ApiKeyAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid notification"
+// @Router       /incoming/radarr [post]
+func (s *Server) handleRadarrWebhook(w http.ResponseWriter, r *http.Request) {
+	var payload integrations.RadarrWebhook
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid notification", http.StatusBadRequest)
+		return
+	}
+
+	action := "ignored"
+	if payload.Movie != nil {
+		var err error
+		switch payload.EventType {
+		case "Download", "Rename", "MovieAdded", "MovieFileDelete":
+			action = "synced"
+			err = s.syncs.SyncOne(r.Context(), services.SyncSourceRadarr, func(ctx context.Context) error {
+				return s.currentServices().mediaSync.SyncMovie(ctx, payload.Movie.ID)
+			})
+		case "MovieDelete":
+			action = "marked_missing"
+			err = s.syncs.SyncOne(r.Context(), services.SyncSourceRadarr, func(ctx context.Context) error {
+				return s.currentServices().mediaSync.RemoveMovie(ctx, payload.Movie.ID)
+			})
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to apply Radarr webhook", "event", payload.EventType, "movie", payload.Movie.Title, "error", err)
+			http.Error(w, "Failed to apply notification", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	slog.InfoContext(r.Context(), "Received Radarr webhook", "event", payload.EventType, "action", action)
+	writeIncomingWebhookResult(w, action)
+}
+
+// @Summary      qBittorrent callback
+// @Description  Called by qBittorrent's "Run external program on torrent finished" (e.g. curl with hash=%I) to update the torrent right away
+// @Tags         incoming-webhooks
+// @Produce      json
+// @Param        hash  query     string  true  "Torrent info hash; several can be separated with |"
+// @Security     ApiKeyAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid hash"
+// @Router       /incoming/qbittorrent [post]
+func (s *Server) handleQBittorrentWebhook(w http.ResponseWriter, r *http.Request) {
+	var hashes []string
+	for _, hash := range strings.Split(r.FormValue("hash"), "|") {
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if hash == "" {
+			continue
+		}
+		if _, err := hex.DecodeString(hash); err != nil || (len(hash) != 40 && len(hash) != 64) {
+			http.Error(w, "Invalid hash", http.StatusBadRequest)
+			return
+		}
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) == 0 {
+		http.Error(w, "Invalid hash", http.StatusBadRequest)
+		return
+	}
+
+	err := s.syncs.SyncOne(r.Context(), services.SyncSourceQBittorrent, func(ctx context.Context) error {
+		return s.currentServices().torrentSync.SyncTorrents(ctx, hashes)
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to apply qBittorrent callback", "hashes", hashes, "error", err)
+		http.Error(w, "Failed to sync torrents", http.StatusInternalServerError)
+		return
+	}
+
+	writeIncomingWebhookResult(w, "synced")
+}
+
+func writeIncomingWebhookResult(w http.ResponseWriter, action string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"action":  action,
+	})
+}
+
+// @Summary      Regenerate incoming webhook token
+// @Description  Replace the token Sonarr, Radarr and qBittorrent callbacks authenticate with. Senders using the old token are rejected from now on.
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {object}  map[string]interface{}
+// @Router       /admin/webhooks/incoming/token [post]
+func (s *Server) handleRegenerateIncomingWebhookToken(w http.ResponseWriter, r *http.Request) {
+	token, err := s.regenerateIncomingWebhookToken()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to regenerate incoming webhook token", "error", err)
+		http.Error(w, "Failed to regenerate token", http.StatusInternalServerError)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Regenerated incoming webhook token")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"token":   token,
+	})
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"removarr/internal/events"
+	"removarr/internal/notifications"
+)
+
+// notificationSettings returns the notification config for display, without the SMTP password
+func (s *Server) notificationSettings(ctx context.Context) notifications.Config {
+	config := s.notifications.Config(ctx)
+	config.SMTP.Password = ""
+	return config
+}
+
+// decodeNotificationSettings applies submitted notification settings on top of the stored ones
+// An empty SMTP password keeps the stored password, since it is never sent to the browser
+func (s *Server) decodeNotificationSettings(ctx context.Context, raw map[string]interface{}) (notifications.Config, error) {
+	config := s.notifications.Config(ctx)
+	storedPassword := config.SMTP.Password
+	config.SMTP.Password = ""
+
+	encoded, _ := json.Marshal(raw)
+	if err := json.Unmarshal(encoded, &config); err != nil {
+		return config, fmt.Errorf("invalid notification settings")
+	}
+	if config.SMTP.Password == "" {
+		config.SMTP.Password = storedPassword
+	}
+
+	var to []string
+	for _, address := range config.SMTP.To {
+		if address = strings.TrimSpace(address); address != "" {
+			to = append(to, address)
+		}
+	}
+	config.SMTP.To = to
+
+	for eventType := range config.Events {
+		if !isEventType(eventType) {
+			return config, fmt.Errorf("unknown event type: %s", eventType)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// isEventType reports whether t is a known event type
+func isEventType(t events.Type) bool {
+	for _, known := range events.AllTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// @Summary      Test notification channel
+// @Description  Send a test notification on one channel with the submitted settings, before saving them
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      object  true  "Channel (webhook, discord, apprise or smtp) and notification settings"
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid request"
+// @Router       /admin/notifications/test [post]
+func (s *Server) handleTestNotification(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Channel       string                 `json:"channel"`
+		Notifications map[string]interface{} `json:"notifications"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Channel == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	config, err := s.decodeNotificationSettings(r.Context(), req.Notifications)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	success, message := true, "Test notification sent"
+	if err := s.notifications.Test(r.Context(), config, req.Channel); err != nil {
+		success, message = false, err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": success,
+		"message": message,
+	})
+}
+
+// accountNotifications is a user's email notification opt-in
+type accountNotifications struct {
+	Email  string        `json:"email"`
+	Events []events.Type `json:"events"`
+}
+
+// handleAccountPage renders the current user's notification preferences
+func (s *Server) handleAccountPage(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	prefs, err := s.loadAccountNotifications(r.Context(), authCtx.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load notification preferences", "user_id", authCtx.UserID, "error", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	optedIn := make(map[events.Type]bool)
+	for _, eventType := range prefs.Events {
+		optedIn[eventType] = true
+	}
+
+	data := map[string]interface{}{
+		"User":         authCtx,
+		"Email":        prefs.Email,
+		"EventTypes":   events.AllTypes,
+		"OptedIn":      optedIn,
+		"EmailEnabled": s.notifications.Config(r.Context()).SMTP.Enabled,
+	}
+
+	if err := s.renderTemplate(w, "account.html", data); err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
+	}
+}
+
+// loadAccountNotifications reads a user's email and opted-in event types
+func (s *Server) loadAccountNotifications(ctx context.Context, userID int) (accountNotifications, error) {
+	var (
+		prefs     accountNotifications
+		rawEvents []byte
+	)
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COALESCE(email, ''), notification_events FROM users WHERE id = $1",
+		userID,
+	).Scan(&prefs.Email, &rawEvents)
+	if err != nil {
+		return prefs, err
+	}
+	prefs.Events = []events.Type{}
+	json.Unmarshal(rawEvents, &prefs.Events)
+	return prefs, nil
+}
+
+// @Summary      Get notification preferences
+// @Description  The current user's email address and the events they want emailed
+// @Tags         account
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {object}  accountNotifications
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Router       /account/notifications [get]
+func (s *Server) handleGetAccountNotifications(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	prefs, err := s.loadAccountNotifications(r.Context(), authCtx.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load notification preferences", "user_id", authCtx.UserID, "error", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// @Summary      Update notification preferences
+// @Description  Set the current user's email address and the events they want emailed. Admins get every event, other users only events about media they requested.
+// @Tags         account
+// @Accept       json
+// @Produce      json
+// @Param        body  body      accountNotifications  true  "Email and event types"
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid request"
+// @Failure      401   {object}  map[string]string  "Unauthorized"
+// @Router       /account/notifications [put]
+func (s *Server) handleUpdateAccountNotifications(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req accountNotifications
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			http.Error(w, "Invalid email address", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Events == nil {
+		req.Events = []events.Type{}
+	}
+	for _, eventType := range req.Events {
+		if !isEventType(eventType) {
+			http.Error(w, fmt.Sprintf("Unknown event type: %s", eventType), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Events) > 0 && req.Email == "" {
+		http.Error(w, "An email address is required to receive notifications", http.StatusBadRequest)
+		return
+	}
+
+	eventsJSON, _ := json.Marshal(req.Events)
+	if _, err := s.db.ExecContext(r.Context(),
+		`UPDATE users SET email = NULLIF($2, ''), notification_events = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		authCtx.UserID, req.Email, string(eventsJSON),
+	); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to save notification preferences", "user_id", authCtx.UserID, "error", err)
+		http.Error(w, "Failed to save notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// isAdminOrRequester reports whether the user may manage a media item's protection and deletion requests:
+// admins, and the user who requested it
+func (s *Server) isAdminOrRequester(r *http.Request, authCtx AuthContext, mediaID int) (bool, error) {
+	if authCtx.IsAdmin {
+		return true, nil
+	}
+	var requestedBy sql.NullInt64
+	if err := s.db.QueryRowContext(r.Context(),
+		"SELECT requested_by_user_id FROM media_items WHERE id = $1",
+		mediaID,
+	).Scan(&requestedBy); err != nil {
+		return false, err
+	}
+	return requestedBy.Valid && int(requestedBy.Int64) == authCtx.UserID, nil
+}
+
+// checkProtectionChangeable writes an error and returns false if the user may not replace or clear
+// the media item's manual protection, because an admin set it
+func (s *Server) checkProtectionChangeable(w http.ResponseWriter, r *http.Request, authCtx AuthContext, mediaID int) bool {
+	protection, err := s.protection.Get(r.Context(), mediaID)
+	if err != nil {
+		http.Error(w, "Media item not found", http.StatusNotFound)
+		return false
+	}
+	if !protection.ChangeableBy(authCtx.IsAdmin) {
+		http.Error(w, "Only admins can change a protection set by an admin", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// @Summary      Get media protection
+// @Description  Whether a media item is on the keep list, manually or by Radarr/Sonarr tag
+// @Tags         media
+// @Produce      json
+// @Param        id   path      int  true  "Media item ID"
+// @Security     BasicAuth
+// @Success      200  {object}  services.Protection
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      404  {object}  map[string]string  "Media item not found"
+// @Router       /media/{id}/protection [get]
+func (s *Server) handleGetProtection(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	protection, err := s.protection.Get(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Media item not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(protection)
+}
+
+// @Summary      Protect media
+// @Description  Add a media item to the keep list so it is never deleted. Admins and the requester only; a protection set by an admin can only be replaced by an admin.
+// @Tags         media
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int     true  "Media item ID"
+// @Param        body  body      object  true  "Optional expiry (RFC 3339 until) and note"
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid request"
+// @Failure      401   {object}  map[string]string  "Unauthorized"
+// @Failure      403   {object}  map[string]string  "Forbidden"
+// @Router       /media/{id}/protection [put]
+func (s *Server) handleProtectMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Until *time.Time `json:"until"`
+		Note  string     `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		http.Error(w, "Protection expiry must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	allowed, err := s.isAdminOrRequester(r, authCtx, id)
+	if err != nil {
+		http.Error(w, "Media item not found", http.StatusNotFound)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Only admins and the requester can protect this item", http.StatusForbidden)
+		return
+	}
+	if !s.checkProtectionChangeable(w, r, authCtx, id) {
+		return
+	}
+
+	if err := s.protection.Protect(r.Context(), id, authCtx.UserID, authCtx.IsAdmin, req.Until, req.Note); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to protect media item", "media_id", id, "error", err)
+		http.Error(w, "Failed to protect media item", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// @Summary      Unprotect media
+// @Description  Remove a media item's manual protection. Tag protection is removed in Radarr/Sonarr. Admins and the requester only; a protection set by an admin can only be removed by an admin.
+// @Tags         media
+// @Produce      json
+// @Param        id   path      int  true  "Media item ID"
+// @Security     BasicAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Forbidden"
+// @Router       /media/{id}/protection [delete]
+func (s *Server) handleUnprotectMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	allowed, err := s.isAdminOrRequester(r, authCtx, id)
+	if err != nil {
+		http.Error(w, "Media item not found", http.StatusNotFound)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Only admins and the requester can unprotect this item", http.StatusForbidden)
+		return
+	}
+	if !s.checkProtectionChangeable(w, r, authCtx, id) {
+		return
+	}
+
+	if err := s.protection.Unprotect(r.Context(), id, authCtx.UserID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to unprotect media item", "media_id", id, "error", err)
+		http.Error(w, "Failed to unprotect media item", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
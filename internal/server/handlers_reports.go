@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handleReportsPage renders the orphaned torrent and file reports page (admin only)
+func (s *Server) handleReportsPage(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if !authCtx.IsAdmin {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	data := map[string]interface{}{
+		"User": authCtx,
+	}
+
+	if err := s.renderTemplate(w, "reports.html", data); err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
+	}
+}
+
+// @Summary      Orphan report
+// @Description  List orphaned torrents, downloaded media not seeding anywhere and untracked files in the library root folders
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {object}  services.OrphanReport
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Forbidden"
+// @Router       /admin/reports/orphans [get]
+func (s *Server) handleOrphanReport(w http.ResponseWriter, r *http.Request) {
+	report, err := s.reports.GenerateOrphanReport(r.Context(), s.libraryRootFolders())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to generate orphan report", "error", err)
+		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// @Summary      Delete orphaned torrent
+// @Description  Delete a torrent that is not linked to any media item from qBittorrent, with its files unless a cross-seed, another torrent or a library item still uses them
+// @Tags         admin
+// @Produce      json
+// @Param        hash  path      string  true  "Torrent hash"
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid request"
+// @Router       /admin/reports/orphans/torrents/{hash}/delete [post]
+func (s *Server) handleDeleteOrphanedTorrent(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.reports.DeleteOrphanedTorrent(r.Context(), hash, authCtx.UserID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete orphaned torrent", "hash", hash, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Torrent deleted",
+	})
+}
+
+// @Summary      Delete untracked file
+// @Description  Delete a file or folder in a library root folder that no media item or torrent references
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      object  true  "Path to delete"  example({"path":"/movies/Old Movie (2001)"})
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid request"
+// @Router       /admin/reports/orphans/files/delete [post]
+func (s *Server) handleDeleteUntrackedFile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.reports.DeleteUntrackedFile(r.Context(), req.Path, s.libraryRootFolders(), authCtx.UserID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete untracked file", "path", req.Path, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "File deleted",
+	})
+}
+
+// @Summary      Dead torrents
+// @Description  List seeding torrents whose tracker is not working or that have not uploaded anything recently
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {array}   services.DeadTorrent
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Forbidden"
+// @Router       /admin/reports/dead-torrents [get]
+func (s *Server) handleDeadTorrents(w http.ResponseWriter, r *http.Request) {
+	torrents, err := s.torrentHistory.DeadTorrents(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to find dead torrents", "error", err)
+		http.Error(w, "Failed to find dead torrents", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(torrents)
+}
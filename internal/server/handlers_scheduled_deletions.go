@@ -0,0 +1,239 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"removarr/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// handleScheduledDeletionPage renders the page a requester's heads-up links to, where they can
+// postpone the deletion or keep the media. The token in the link is the only authorization.
+func (s *Server) handleScheduledDeletionPage(w http.ResponseWriter, r *http.Request) {
+	deletion, err := s.scheduledDeletions.GetByToken(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		http.Error(w, "Scheduled deletion not found", http.StatusNotFound)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Deletion":     deletion,
+		"CanPostpone":  deletion.CanPostpone(),
+		"PostponesMax": services.MaxPostpones,
+		"GracePeriod":  formatGracePeriod(s.scheduledDeletions.GracePeriod(r.Context())),
+	}
+
+	if err := s.renderTemplate(w, "scheduled_deletion.html", data); err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
+	}
+}
+
+// formatGracePeriod formats a grace period for display, e.g. "3 days" or "12h0m0s"
+func formatGracePeriod(grace time.Duration) string {
+	if grace > 0 && grace%(24*time.Hour) == 0 {
+		days := int(grace / (24 * time.Hour))
+		if days == 1 {
+			return "1 day"
+		}
+		return strconv.Itoa(days) + " days"
+	}
+	return grace.String()
+}
+
+// @Summary      Postpone scheduled deletion
+// @Description  Push a scheduled deletion back by the grace period, from the link in the requester's heads-up. No login needed; the token authorizes it.
+// @Tags         scheduled-deletions
+// @Produce      json
+// @Param        token  path      string  true  "Token from the heads-up link"
+// @Success      200    {object}  services.ScheduledDeletion
+// @Failure      404    {object}  map[string]string  "Scheduled deletion not found"
+// @Failure      409    {object}  map[string]string  "Deletion is no longer scheduled or can't be postponed again"
+// @Router       /scheduled/{token}/postpone [post]
+func (s *Server) handlePostponeScheduledDeletion(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	if _, err := s.scheduledDeletions.GetByToken(r.Context(), token); err != nil {
+		http.Error(w, "Scheduled deletion not found", http.StatusNotFound)
+		return
+	}
+
+	grace := s.scheduledDeletions.GracePeriod(r.Context())
+	if grace <= 0 {
+		grace = services.DefaultGracePeriod
+	}
+	deletion, err := s.scheduledDeletions.Postpone(r.Context(), token, grace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deletion)
+}
+
+// @Summary      Keep scheduled media
+// @Description  Protect the media of a scheduled deletion and cancel the deletion, from the link in the requester's heads-up. No login needed; the token authorizes it.
+// @Tags         scheduled-deletions
+// @Produce      json
+// @Param        token  path      string  true  "Token from the heads-up link"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]string  "Scheduled deletion not found"
+// @Failure      409    {object}  map[string]string  "Deletion is no longer scheduled"
+// @Router       /scheduled/{token}/keep [post]
+func (s *Server) handleKeepScheduledMedia(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	if _, err := s.scheduledDeletions.GetByToken(r.Context(), token); err != nil {
+		http.Error(w, "Scheduled deletion not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.scheduledDeletions.Keep(r.Context(), token); err != nil {
+		if errors.Is(err, services.ErrNotScheduled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to keep scheduled media", "error", err)
+		http.Error(w, "Failed to keep media", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// @Summary      List scheduled deletions
+// @Description  Deletions waiting for their grace period, and past ones
+// @Tags         admin
+// @Produce      json
+// @Param        status  query     string  false  "Filter by status: scheduled, cancelled or executed"
+// @Security     BasicAuth
+// @Success      200     {array}   services.ScheduledDeletion
+// @Failure      400     {object}  map[string]string  "Invalid status"
+// @Router       /admin/scheduled-deletions [get]
+func (s *Server) handleListScheduledDeletions(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", services.ScheduledDeletionScheduled, services.ScheduledDeletionCancelled, services.ScheduledDeletionExecuted:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	deletions, err := s.scheduledDeletions.List(r.Context(), status)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list scheduled deletions", "error", err)
+		http.Error(w, "Failed to list scheduled deletions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deletions)
+}
+
+// @Summary      Schedule deletion
+// @Description  Delete a media item once the grace period is over, after giving its requester a heads-up
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Media item ID"
+// @Security     BasicAuth
+// @Success      200  {object}  services.ScheduledDeletion
+// @Failure      400  {object}  map[string]string  "No grace period configured"
+// @Failure      409  {object}  map[string]string  "Media item is protected or already scheduled"
+// @Router       /admin/media/{id}/schedule-deletion [post]
+func (s *Server) handleScheduleDeletion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	grace := s.scheduledDeletions.GracePeriod(r.Context())
+	if grace <= 0 {
+		http.Error(w, "No deletion grace period is configured, delete the media directly", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := s.scheduledDeletions.Schedule(r.Context(), id, authCtx.UserID, grace)
+	if err != nil {
+		if errors.Is(err, services.ErrProtected) || errors.Is(err, services.ErrAlreadyScheduled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to schedule deletion", "media_id", id, "error", err)
+		http.Error(w, "Failed to schedule deletion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deletion)
+}
+
+// @Summary      Cancel scheduled deletion
+// @Description  Cancel a deletion that is waiting for its grace period
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int     true   "Scheduled deletion ID"
+// @Param        body  body      object  false  "Optional reason"  example({"reason":"Still watching"})
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      409   {object}  map[string]string  "Deletion is no longer scheduled"
+// @Router       /admin/scheduled-deletions/{id} [delete]
+func (s *Server) handleCancelScheduledDeletion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid scheduled deletion ID", http.StatusBadRequest)
+		return
+	}
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The reason is optional, so an empty body is fine
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "Cancelled by " + authCtx.Username
+	}
+
+	if err := s.scheduledDeletions.Cancel(r.Context(), id, authCtx.UserID, reason); err != nil {
+		if errors.Is(err, services.ErrNotScheduled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to cancel scheduled deletion", "scheduled_deletion_id", id, "error", err)
+		http.Error(w, "Failed to cancel scheduled deletion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"removarr/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// handleStalePage renders the media items and torrents missing from their source (admin only)
+func (s *Server) handleStalePage(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if !authCtx.IsAdmin {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	data := map[string]interface{}{
+		"User":      authCtx,
+		"Retention": formatGracePeriod(s.stale.Retention(r.Context())),
+	}
+
+	if err := s.renderTemplate(w, "stale.html", data); err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
+	}
+}
+
+// @Summary      Stale media and torrents
+// @Description  Media items gone from Radarr/Sonarr and torrents gone from qBittorrent, as of the last full sync, with when they will be purged
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {object}  map[string]interface{}
+// @Router       /admin/stale [get]
+func (s *Server) handleListStale(w http.ResponseWriter, r *http.Request) {
+	media, err := s.stale.ListMedia(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list stale media items", "error", err)
+		http.Error(w, "Failed to list stale media", http.StatusInternalServerError)
+		return
+	}
+	torrents, err := s.stale.ListTorrents(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list stale torrents", "error", err)
+		http.Error(w, "Failed to list stale torrents", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"media":             media,
+		"torrents":          torrents,
+		"retention_seconds": int64(s.stale.Retention(r.Context()).Seconds()),
+	})
+}
+
+// @Summary      Remove stale media item
+// @Description  Purge a media item missing from Radarr/Sonarr now instead of after the retention period. Its torrents are unlinked, not deleted.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Media item ID"
+// @Security     BasicAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]string  "Media item not found or not missing"
+// @Router       /admin/stale/media/{id} [delete]
+func (s *Server) handleRemoveStaleMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.stale.RemoveMedia(r.Context(), id); err != nil {
+		if errors.Is(err, services.ErrNotStale) {
+			http.Error(w, "Media item not found or not missing", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to remove stale media item", "media_id", id, "error", err)
+		http.Error(w, "Failed to remove media item", http.StatusInternalServerError)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Removed stale media item", "media_id", id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// @Summary      Remove stale torrent
+// @Description  Purge a torrent missing from qBittorrent now instead of after the retention period, along with its history
+// @Tags         admin
+// @Produce      json
+// @Param        hash  path      string  true  "Torrent hash"
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      404   {object}  map[string]string  "Torrent not found or not missing"
+// @Router       /admin/stale/torrents/{hash} [delete]
+func (s *Server) handleRemoveStaleTorrent(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+
+	if err := s.stale.RemoveTorrent(r.Context(), hash); err != nil {
+		if errors.Is(err, services.ErrNotStale) {
+			http.Error(w, "Torrent not found or not missing", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to remove stale torrent", "hash", hash, "error", err)
+		http.Error(w, "Failed to remove torrent", http.StatusInternalServerError)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Removed stale torrent", "hash", hash)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
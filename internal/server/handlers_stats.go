@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleStatisticsPage renders the storage statistics page
+func (s *Server) handleStatisticsPage(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := map[string]interface{}{
+		"User": authCtx,
+	}
+
+	if err := s.renderTemplate(w, "statistics.html", data); err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
+	}
+}
+
+// @Summary      Storage statistics
+// @Description  Storage usage snapshots, space freed per month and the projected date the library reaches the storage quota
+// @Tags         stats
+// @Produce      json
+// @Param        days  query     int  false  "Days of history to return (default 90)"
+// @Security     BasicAuth
+// @Success      200   {object}  services.StorageHistory
+// @Failure      401   {object}  map[string]string  "Unauthorized"
+// @Router       /stats/storage [get]
+func (s *Server) handleStorageStats(w http.ResponseWriter, r *http.Request) {
+	days := 90
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	history, err := s.storageStats.History(r.Context(), since, s.storageQuotaBytes())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get storage history", "error", err)
+		http.Error(w, "Failed to get storage statistics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// @Summary      Sync status
+// @Description  Whether a sync is running (what started it, which source it is on, how many triggers joined it) and how the last sync of each source went
+// @Tags         media
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {object}  services.SyncStatus
+// @Router       /sync/status [get]
+func (s *Server) handleSyncStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.syncs.Status(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get sync status", "error", err)
+		http.Error(w, "Failed to get sync status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// handleTorrentsPage renders the manual torrent linking page (admin only)
+func (s *Server) handleTorrentsPage(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if !authCtx.IsAdmin {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	data := map[string]interface{}{
+		"User": authCtx,
+	}
+
+	if err := s.renderTemplate(w, "torrents.html", data); err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
+	}
+}
+
+// @Summary      List unlinked torrents
+// @Description  Get torrents that are not linked to a media item, with suggested matches
+// @Tags         admin
+// @Produce      json
+// @Param        include_ignored  query     bool  false  "Include ignored torrents"
+// @Security     BasicAuth
+// @Success      200  {array}   services.TorrentLinkInfo
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Forbidden"
+// @Router       /admin/torrents/unlinked [get]
+func (s *Server) handleListUnlinkedTorrents(w http.ResponseWriter, r *http.Request) {
+	includeIgnored := r.URL.Query().Get("include_ignored") == "true"
+
+	torrents, err := s.torrentLinks.ListUnlinkedTorrents(r.Context(), includeIgnored)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list unlinked torrents", "error", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(torrents)
+}
+
+func (s *Server) handleListManualTorrentLinks(w http.ResponseWriter, r *http.Request) {
+	torrents, err := s.torrentLinks.ListManualLinks(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list manual torrent links", "error", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(torrents)
+}
+
+func (s *Server) handleSearchMediaForLink(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.torrentLinks.SearchMedia(r.Context(), query, 20)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to search media", "error", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// @Summary      Link torrent to media
+// @Description  Manually link a torrent to a media item. Manual links are never overwritten by sync.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        hash  path      string  true  "Torrent hash"
+// @Param        body  body      object  true  "Media item"  example({"media_item_id":1})
+// @Security     BasicAuth
+// @Success      200   {object}  map[string]interface{}
+// @Failure      400   {object}  map[string]string  "Invalid request"
+// @Router       /admin/torrents/{hash}/link [post]
+func (s *Server) handleLinkTorrent(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+
+	var req struct {
+		MediaItemID int `json:"media_item_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MediaItemID <= 0 {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.torrentLinks.LinkTorrent(r.Context(), hash, req.MediaItemID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to link torrent", "hash", hash, "media_item_id", req.MediaItemID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Torrent linked manually", "hash", hash, "media_item_id", req.MediaItemID)
+	writeTorrentLinkSuccess(w, "Torrent linked")
+}
+
+func (s *Server) handleUnlinkTorrent(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.torrentLinks.UnlinkTorrent(r.Context(), hash, authCtx.UserID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to unlink torrent", "hash", hash, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Torrent unlinked manually", "hash", hash)
+	writeTorrentLinkSuccess(w, "Torrent unlinked")
+}
+
+func (s *Server) handleResetTorrentLink(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+
+	if err := s.torrentLinks.ResetTorrentLink(r.Context(), hash); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to reset torrent link", "hash", hash, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeTorrentLinkSuccess(w, "Torrent will be matched automatically on the next sync")
+}
+
+func (s *Server) handleIgnoreTorrent(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+
+	// Default to ignoring; {"ignored": false} un-ignores
+	req := struct {
+		Ignored *bool `json:"ignored"`
+	}{}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+	ignored := req.Ignored == nil || *req.Ignored
+
+	if err := s.torrentLinks.SetTorrentIgnored(r.Context(), hash, ignored); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update torrent ignore state", "hash", hash, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if ignored {
+		writeTorrentLinkSuccess(w, "Torrent ignored")
+	} else {
+		writeTorrentLinkSuccess(w, "Torrent no longer ignored")
+	}
+}
+
+// @Summary      Torrent history
+// @Description  Upload, ratio and swarm samples of a torrent with the upload rate between samples
+// @Tags         torrents
+// @Produce      json
+// @Param        hash  path      string  true   "Torrent hash"
+// @Param        days  query     int     false  "Days of history to return (default 30)"
+// @Security     BasicAuth
+// @Success      200   {array}   services.TorrentSample
+// @Failure      401   {object}  map[string]string  "Unauthorized"
+// @Router       /torrents/{hash}/history [get]
+func (s *Server) handleTorrentHistory(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+
+	days := 30
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	samples, err := s.torrentHistory.History(r.Context(), hash, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get torrent history", "hash", hash, "error", err)
+		http.Error(w, "Failed to get torrent history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(samples)
+}
+
+func writeTorrentLinkSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": message,
+	})
+}
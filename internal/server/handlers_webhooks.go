@@ -0,0 +1,270 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"removarr/internal/webhooks"
+
+	"github.com/gorilla/mux"
+)
+
+// handleWebhooksPage renders the outgoing webhooks page with the delivery log, and the incoming webhook setup (admin only)
+func (s *Server) handleWebhooksPage(w http.ResponseWriter, r *http.Request) {
+	authCtx, ok := r.Context().Value("auth").(AuthContext)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if !authCtx.IsAdmin {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	incomingToken, err := s.incomingWebhookToken()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get incoming webhook token", "error", err)
+	}
+
+	data := map[string]interface{}{
+		"User":          authCtx,
+		"EventNames":    webhooks.EventNames(),
+		"IncomingToken": incomingToken,
+	}
+
+	if err := s.renderTemplate(w, "webhooks.html", data); err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Template render error", "error", err)
+	}
+}
+
+// @Summary      List webhooks
+// @Description  Outgoing webhooks and the events they subscribe to
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {array}   webhooks.Webhook
+// @Router       /admin/webhooks [get]
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	list, err := s.webhooks.List(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list webhooks", "error", err)
+		http.Error(w, "Failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// @Summary      Create webhook
+// @Description  Add an outgoing webhook. A signing secret is generated when none is given.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      webhooks.Webhook  true  "Name, URL, events and enabled"
+// @Security     BasicAuth
+// @Success      200   {object}  webhooks.Webhook
+// @Failure      400   {object}  map[string]string  "Invalid webhook"
+// @Router       /admin/webhooks [post]
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhooks.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := s.webhooks.Create(r.Context(), req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create webhook", "error", err)
+		http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// @Summary      Update webhook
+// @Description  Change an outgoing webhook. An empty secret keeps the current one.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int               true  "Webhook ID"
+// @Param        body  body      webhooks.Webhook  true  "Name, URL, secret, events and enabled"
+// @Security     BasicAuth
+// @Success      200   {object}  webhooks.Webhook
+// @Failure      400   {object}  map[string]string  "Invalid webhook"
+// @Failure      404   {object}  map[string]string  "Webhook not found"
+// @Router       /admin/webhooks/{id} [put]
+func (s *Server) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	var req webhooks.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := s.webhooks.Update(r.Context(), id, req)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to update webhook", "webhook_id", id, "error", err)
+		http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// @Summary      Delete webhook
+// @Description  Remove an outgoing webhook and its delivery log
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Webhook ID"
+// @Security     BasicAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]string  "Webhook not found"
+// @Router       /admin/webhooks/{id} [delete]
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.webhooks.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, webhooks.ErrNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to delete webhook", "webhook_id", id, "error", err)
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// @Summary      Test webhook
+// @Description  Send a ping delivery to a webhook right away and return it with the outcome
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Webhook ID"
+// @Security     BasicAuth
+// @Success      200  {object}  webhooks.Delivery
+// @Failure      404  {object}  map[string]string  "Webhook not found"
+// @Router       /admin/webhooks/{id}/test [post]
+func (s *Server) handleTestWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := s.webhooks.Ping(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to test webhook", "webhook_id", id, "error", err)
+		http.Error(w, "Failed to test webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// @Summary      Webhook delivery log
+// @Description  Recent webhook deliveries, newest first
+// @Tags         admin
+// @Produce      json
+// @Param        webhook_id  query     int     false  "Only deliveries to this webhook"
+// @Param        status      query     string  false  "Filter by status: pending, succeeded or failed"
+// @Security     BasicAuth
+// @Success      200         {array}   webhooks.Delivery
+// @Failure      400         {object}  map[string]string  "Invalid filter"
+// @Router       /admin/webhooks/deliveries [get]
+func (s *Server) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	var filter webhooks.DeliveryFilter
+	if raw := r.URL.Query().Get("webhook_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+			return
+		}
+		filter.WebhookID = id
+	}
+	filter.Status = r.URL.Query().Get("status")
+	switch filter.Status {
+	case "", webhooks.DeliveryPending, webhooks.DeliverySucceeded, webhooks.DeliveryFailed:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := s.webhooks.Deliveries(r.Context(), filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list webhook deliveries", "error", err)
+		http.Error(w, "Failed to list webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// @Summary      Redeliver webhook
+// @Description  Send a logged delivery again right away, with a fresh set of retries
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Delivery ID"
+// @Security     BasicAuth
+// @Success      200  {object}  webhooks.Delivery
+// @Failure      404  {object}  map[string]string  "Delivery not found"
+// @Router       /admin/webhooks/deliveries/{id}/redeliver [post]
+func (s *Server) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := s.webhooks.Redeliver(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrNotFound) {
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to redeliver webhook", "delivery_id", id, "error", err)
+		http.Error(w, "Failed to redeliver webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
@@ -5,31 +5,61 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"removarr/internal/config"
+	"removarr/internal/events"
 	"removarr/internal/integrations"
+	"removarr/internal/metrics"
+	"removarr/internal/notifications"
 	"removarr/internal/services"
+	"removarr/internal/webhooks"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 type Server struct {
-	config         *config.Config
-	configPath     string // Path to config file for persistence
-	db             *sql.DB
-	router         *mux.Router
-	httpServer     *http.Server
-	integrations   *integrations.Client
-	store          *sessions.CookieStore
-	mediaSync      *services.MediaSyncService
-	torrentSync    *services.TorrentSyncService
-	eligibility    *services.EligibilityService
-	deletion       *services.DeletionService
+	config             *config.Config
+	configPath         string // Path to config file for persistence
+	db                 *sql.DB
+	router             *mux.Router
+	httpServer         *http.Server
+	integrations       *integrations.Client
+	store              *sessions.CookieStore
+	mediaSync          *services.MediaSyncService
+	torrentSync        *services.TorrentSyncService
+	eligibility        *services.EligibilityService
+	deletion           *services.DeletionService
+	torrentLinks       *services.TorrentLinkService
+	reports            *services.ReportService
+	storageStats       *services.StorageStatsService
+	torrentHistory     *services.TorrentHistoryService
+	scoring            *services.ScoringService
+	protection         *services.ProtectionService
+	deletionRequests   *services.DeletionRequestService
+	scheduledDeletions *services.ScheduledDeletionService
+	stale              *services.StaleService
+	integrationHealth  *services.IntegrationHealthService
+	syncs              *services.SyncCoordinator
+	events             *events.Bus
+	notifications      *notifications.Service
+	webhooks           *webhooks.Service
+
+	// servicesMu guards integrations and the services built on it, which setIntegrations replaces when the
+	// settings change. Work outside the request that replaces them reads them through currentServices.
+	servicesMu sync.RWMutex
+
+	// Background work and requests run on ctx; Shutdown cancels it and waits for them
+	ctx        context.Context
+	cancel     context.CancelFunc
+	background sync.WaitGroup
 }
 
 func New(cfg *config.Config, db *sql.DB, configPath string) *Server {
@@ -49,34 +79,35 @@ func New(cfg *config.Config, db *sql.DB, configPath string) *Server {
 	// Create integrations client
 	integrationsClient := integrations.NewClient(cfg)
 
-	// Create services
-	mediaSyncService := services.NewMediaSyncService(db, integrationsClient)
-	torrentSyncService := services.NewTorrentSyncService(db, integrationsClient)
-	eligibilityService := services.NewEligibilityService(db, integrationsClient)
-	deletionService := services.NewDeletionService(
-		db,
-		integrationsClient.Sonarr,
-		integrationsClient.Radarr,
-		integrationsClient.Overseerr,
-		integrationsClient.QBittorrent,
-	)
+	ctx, cancel := context.WithCancel(context.Background())
 
 	srv := &Server{
-		config:       cfg,
-		configPath:   configPath,
-		db:           db,
-		router:       router,
-		integrations: integrationsClient,
-		store:        store,
-		mediaSync:    mediaSyncService,
-		torrentSync:  torrentSyncService,
-		eligibility:  eligibilityService,
-		deletion:     deletionService,
+		ctx:           ctx,
+		cancel:        cancel,
+		config:        cfg,
+		configPath:    configPath,
+		db:            db,
+		router:        router,
+		integrations:  integrationsClient,
+		store:         store,
+		events:        events.NewBus(),
+		notifications: notifications.NewService(db),
+		webhooks:      webhooks.NewService(db),
 	}
+	// The bus outlives buildServices, so subscribers are registered once
+	srv.events.Subscribe(srv.notifications.Handle)
+	srv.events.Subscribe(srv.webhooks.Handle)
+	// Like the bus, the coordinator outlives buildServices; it picks up the current sync services on every run
+	srv.syncs = services.NewSyncCoordinator(ctx, db, func() []services.SyncStep {
+		current := srv.currentServices()
+		return services.FullSyncSteps(current.mediaSync, current.torrentSync)
+	})
+	srv.setIntegrations(integrationsClient)
+	metrics.RegisterLibrary(db)
 
 	// Initialize templates
 	if err := initTemplates(); err != nil {
-		slog.Error("Failed to initialize templates", "error", err)
+		slog.ErrorContext(ctx, "Failed to initialize templates", "error", err)
 		// Continue anyway - templates will fail gracefully
 	}
 
@@ -86,18 +117,7 @@ func New(cfg *config.Config, db *sql.DB, configPath string) *Server {
 	srv.loadIntegrationSettings()
 	
 	// Reload integrations with merged config
-	integrationsClient = integrations.NewClient(srv.config)
-	srv.integrations = integrationsClient
-	srv.mediaSync = services.NewMediaSyncService(db, integrationsClient)
-	srv.torrentSync = services.NewTorrentSyncService(db, integrationsClient)
-	srv.eligibility = services.NewEligibilityService(db, integrationsClient)
-	srv.deletion = services.NewDeletionService(
-		db,
-		integrationsClient.Sonarr,
-		integrationsClient.Radarr,
-		integrationsClient.Overseerr,
-		integrationsClient.QBittorrent,
-	)
+	srv.setIntegrations(integrations.NewClient(srv.config))
 
 	srv.httpServer = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
@@ -105,16 +125,112 @@ func New(cfg *config.Config, db *sql.DB, configPath string) *Server {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		// Requests are cancelled on shutdown, so a slow integration call doesn't hold it up
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	// Start periodic sync goroutine
-	go srv.startPeriodicSync()
+	srv.goBackground(func() { srv.startPeriodicSync(ctx) })
+	// Retry failed webhook deliveries
+	srv.goBackground(func() { srv.webhooks.Run(ctx) })
+	// Check the integrations are reachable and accept their credentials
+	srv.goBackground(func() { srv.startIntegrationChecks(ctx) })
 
 	return srv
 }
 
+// setIntegrations replaces the integrations client and rebuilds the services that depend on it
+func (s *Server) setIntegrations(client *integrations.Client) {
+	s.servicesMu.Lock()
+	defer s.servicesMu.Unlock()
+	s.integrations = client
+	s.buildServices()
+}
+
+// serviceSet is the services background work uses, as of one moment
+type serviceSet struct {
+	mediaSync          *services.MediaSyncService
+	torrentSync        *services.TorrentSyncService
+	eligibility        *services.EligibilityService
+	deletionRequests   *services.DeletionRequestService
+	scheduledDeletions *services.ScheduledDeletionService
+	stale              *services.StaleService
+	storageStats       *services.StorageStatsService
+	integrationHealth  *services.IntegrationHealthService
+	scoring            *services.ScoringService
+}
+
+// currentServices returns the current services, for work that may run while the settings are saved
+func (s *Server) currentServices() serviceSet {
+	s.servicesMu.RLock()
+	defer s.servicesMu.RUnlock()
+	return serviceSet{
+		mediaSync:          s.mediaSync,
+		torrentSync:        s.torrentSync,
+		eligibility:        s.eligibility,
+		deletionRequests:   s.deletionRequests,
+		scheduledDeletions: s.scheduledDeletions,
+		stale:              s.stale,
+		storageStats:       s.storageStats,
+		scoring:            s.scoring,
+		integrationHealth:  s.integrationHealth,
+	}
+}
+
+// buildServices (re)creates the services that depend on the integrations client
+// Called by setIntegrations, with servicesMu held
+func (s *Server) buildServices() {
+	s.mediaSync = services.NewMediaSyncService(s.db, s.integrations)
+	s.torrentSync = services.NewTorrentSyncService(s.db, s.integrations, s.events)
+	s.eligibility = services.NewEligibilityService(s.db, s.integrations, s.events)
+	s.deletion = services.NewDeletionService(
+		s.db,
+		s.integrations.Sonarr,
+		s.integrations.Radarr,
+		s.integrations.Overseerr,
+		s.integrations.QBittorrent,
+		s.events,
+	)
+	s.torrentLinks = services.NewTorrentLinkService(s.db, s.events)
+	s.reports = services.NewReportService(s.db, s.integrations)
+	s.storageStats = services.NewStorageStatsService(s.db, s.eligibility, s.reports)
+	s.torrentHistory = services.NewTorrentHistoryService(s.db)
+	s.scoring = services.NewScoringService(s.db, s.torrentHistory)
+	s.protection = services.NewProtectionService(s.db)
+	s.scheduledDeletions = services.NewScheduledDeletionService(s.db, s.deletion, s.protection, s.events)
+	s.deletionRequests = services.NewDeletionRequestService(s.db, s.eligibility, s.deletion, s.scheduledDeletions)
+	s.stale = services.NewStaleService(s.db)
+	s.integrationHealth = services.NewIntegrationHealthService(s.db, s.integrations)
+}
+
+// storageSnapshotInterval is the minimum time between storage snapshots
+const storageSnapshotInterval = time.Hour
+
+// goBackground runs fn in a goroutine that Shutdown waits for
+func (s *Server) goBackground(fn func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
+}
+
+// recordEligibility records eligibility transitions, and stores the eligibility estimates and scores
+// the dashboard sorts and filters by
+func (s *Server) recordEligibility(ctx context.Context, current serviceSet) {
+	statuses, err := current.eligibility.RecordTransitions(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record eligibility transitions", "error", err)
+		return
+	}
+	if err := current.scoring.Record(ctx, statuses, s.scoreWeights()); err != nil {
+		slog.ErrorContext(ctx, "Failed to record scores", "error", err)
+	}
+}
+
 // startPeriodicSync runs a background goroutine that syncs at a configurable interval
-func (s *Server) startPeriodicSync() {
+// Returns once ctx is cancelled
+func (s *Server) startPeriodicSync(ctx context.Context) {
 	var ticker *time.Ticker
 	var currentFrequency time.Duration = 5 * time.Minute // Default
 	
@@ -128,22 +244,45 @@ func (s *Server) startPeriodicSync() {
 
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-ticker.C:
-			slog.Info("Starting periodic sync", "frequency", currentFrequency)
-			ctx := context.Background()
-			if err := s.mediaSync.SyncAll(ctx); err != nil {
-				slog.Error("Periodic sync failed", "error", err)
-			} else {
-				slog.Info("Periodic sync completed successfully")
+			slog.InfoContext(ctx, "Starting periodic sync", "frequency", currentFrequency)
+			// Joins the sync a dashboard load or the sync button started, if one is running
+			run, started, err := s.syncs.Sync(ctx, "periodic")
+			if err != nil {
+				return // shutting down
+			}
+			if started {
+				s.publishSyncResult(ctx, "periodic", run, nil)
+			}
+			current := s.currentServices()
+			// Purge media items and torrents that have been missing from their source for the retention period
+			if _, _, err := current.stale.Purge(ctx); err != nil {
+				slog.ErrorContext(ctx, "Failed to purge stale rows", "error", err)
+			}
+			// Notify about media that became eligible for deletion
+			s.recordEligibility(ctx, current)
+			// Execute approved deletion requests whose media became eligible
+			if n, err := current.deletionRequests.ExecuteApproved(ctx); err != nil {
+				slog.ErrorContext(ctx, "Failed to execute approved deletion requests", "error", err)
+			} else if n > 0 {
+				slog.InfoContext(ctx, "Executed approved deletion requests", "count", n)
+			}
+			// Execute scheduled deletions whose grace period is over
+			if n, err := current.scheduledDeletions.ProcessDue(ctx); err != nil {
+				slog.ErrorContext(ctx, "Failed to execute scheduled deletions", "error", err)
+			} else if n > 0 {
+				slog.InfoContext(ctx, "Executed scheduled deletions", "count", n)
 			}
-			// Also sync torrents
-			if err := s.torrentSync.SyncFromQBittorrent(ctx); err != nil {
-				slog.Error("Periodic torrent sync failed", "error", err)
+			// Record storage usage for the statistics page, at most once per snapshot interval
+			if err := current.storageStats.SnapshotIfDue(ctx, s.libraryRootFolders(), storageSnapshotInterval); err != nil {
+				slog.ErrorContext(ctx, "Failed to record storage snapshot", "error", err)
 			}
 		case <-frequencyCheck.C:
 			// Check if frequency changed
 			var syncFrequencyStr string
-			err := s.db.QueryRowContext(context.Background(),
+			err := s.db.QueryRowContext(ctx,
 				"SELECT value FROM settings WHERE key = 'sync_frequency'",
 			).Scan(&syncFrequencyStr)
 
@@ -156,7 +295,7 @@ func (s *Server) startPeriodicSync() {
 			
 			// Update ticker if frequency changed
 			if newFrequency != currentFrequency {
-				slog.Info("Sync frequency changed, updating ticker", "old", currentFrequency, "new", newFrequency)
+				slog.InfoContext(ctx, "Sync frequency changed, updating ticker", "old", currentFrequency, "new", newFrequency)
 				ticker.Stop()
 				currentFrequency = newFrequency
 				ticker = time.NewTicker(currentFrequency)
@@ -165,7 +304,55 @@ func (s *Server) startPeriodicSync() {
 	}
 }
 
+// integrationCheckInterval is the time between integration health checks
+const integrationCheckInterval = 5 * time.Minute
+
+// startIntegrationChecks checks the integrations now and then every integrationCheckInterval
+// Returns once ctx is cancelled
+func (s *Server) startIntegrationChecks(ctx context.Context) {
+	ticker := time.NewTicker(integrationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.currentServices().integrationHealth.CheckAll(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to record integration health checks", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishSyncResult tells subscribers how a sync went; trigger is "periodic" or "manual"
+// A sync with failed sources publishes SyncFailed instead of SyncCompleted
+func (s *Server) publishSyncResult(ctx context.Context, trigger string, run *services.SyncRun, actorID *int) {
+	if err := run.Err(); err != nil {
+		s.events.Publish(ctx, events.Event{Type: events.SyncFailed, ActorID: actorID, Message: err.Error(), Data: map[string]interface{}{"trigger": trigger}})
+		return
+	}
+	s.publishSyncCompleted(ctx, trigger, run.StartedAt, actorID)
+}
+
+// publishSyncCompleted tells subscribers a sync finished; trigger is "periodic" or "manual"
+func (s *Server) publishSyncCompleted(ctx context.Context, trigger string, started time.Time, actorID *int) {
+	duration := time.Since(started).Round(time.Second)
+	s.events.Publish(ctx, events.Event{
+		Type:    events.SyncCompleted,
+		ActorID: actorID,
+		Message: fmt.Sprintf("The %s sync completed in %s", trigger, duration),
+		Data: map[string]interface{}{
+			"trigger":          trigger,
+			"duration_seconds": int(duration.Seconds()),
+		},
+	})
+}
+
 func (s *Server) setupRoutes() {
+	// Run after routing, so requests are labelled by route
+	s.router.Use(traceRequests, instrument)
+
 	// Static files
 	s.router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("web/static/"))))
 
@@ -176,6 +363,14 @@ func (s *Server) setupRoutes() {
 
 	// Health check
 	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
+	s.router.HandleFunc("/health/live", s.handleLiveness).Methods("GET")
+	s.router.HandleFunc("/health/ready", s.handleReadiness).Methods("GET")
+	s.router.HandleFunc("/health/integrations", s.handleIntegrationHealth).Methods("GET")
+
+	// Prometheus metrics, opt-in since they name the integrations and how they are doing
+	if s.config.Metrics.Enabled {
+		s.router.Handle("/metrics", s.requireMetricsAccess(promhttp.Handler())).Methods("GET")
+	}
 
 	// Setup wizard (check if setup is needed)
 	s.router.HandleFunc("/setup", s.handleSetup).Methods("GET", "POST")
@@ -188,6 +383,15 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/auth/logout", s.handleLogout).Methods("POST")
 	api.HandleFunc("/auth/plex", s.handlePlexAuth).Methods("GET", "POST")
 
+	// Scheduled deletion links sent to requesters, authorized by their token
+	api.HandleFunc("/scheduled/{token}/postpone", s.handlePostponeScheduledDeletion).Methods("POST")
+	api.HandleFunc("/scheduled/{token}/keep", s.handleKeepScheduledMedia).Methods("POST")
+
+	// Sonarr, Radarr and qBittorrent callbacks, authorized by the incoming webhook token
+	api.HandleFunc("/incoming/sonarr", s.requireIncomingWebhookToken(s.handleSonarrWebhook)).Methods("POST")
+	api.HandleFunc("/incoming/radarr", s.requireIncomingWebhookToken(s.handleRadarrWebhook)).Methods("POST")
+	api.HandleFunc("/incoming/qbittorrent", s.requireIncomingWebhookToken(s.handleQBittorrentWebhook)).Methods("GET", "POST")
+
 	// Protected routes
 	protected := api.PathPrefix("").Subrouter()
 	protected.Use(func(next http.Handler) http.Handler {
@@ -197,6 +401,18 @@ func (s *Server) setupRoutes() {
 	protected.HandleFunc("/media", s.handleListMedia).Methods("GET")
 	protected.HandleFunc("/media/{id}/delete", s.handleDeleteMedia).Methods("POST")
 	protected.HandleFunc("/media/bulk-delete", s.handleBulkDeleteMedia).Methods("POST")
+	protected.HandleFunc("/media/{id}/torrents/remove-eligible", s.handleRemoveEligibleTorrents).Methods("POST")
+	protected.HandleFunc("/media/{id}/protection", s.handleGetProtection).Methods("GET")
+	protected.HandleFunc("/media/{id}/protection", s.handleProtectMedia).Methods("PUT")
+	protected.HandleFunc("/media/{id}/protection", s.handleUnprotectMedia).Methods("DELETE")
+	protected.HandleFunc("/media/{id}/deletion-request", s.handleCreateDeletionRequest).Methods("POST")
+	protected.HandleFunc("/deletion-requests", s.handleListDeletionRequests).Methods("GET")
+	protected.HandleFunc("/deletion-requests/{id}", s.handleWithdrawDeletionRequest).Methods("DELETE")
+	protected.HandleFunc("/account/notifications", s.handleGetAccountNotifications).Methods("GET")
+	protected.HandleFunc("/account/notifications", s.handleUpdateAccountNotifications).Methods("PUT")
+	protected.HandleFunc("/stats/storage", s.handleStorageStats).Methods("GET")
+	protected.HandleFunc("/sync/status", s.handleSyncStatus).Methods("GET")
+	protected.HandleFunc("/torrents/{hash}/history", s.handleTorrentHistory).Methods("GET")
 
 	// Admin routes
 	admin := protected.PathPrefix("/admin").Subrouter()
@@ -212,11 +428,42 @@ func (s *Server) setupRoutes() {
 	admin.HandleFunc("/settings", s.handleGetSettings).Methods("GET")
 	admin.HandleFunc("/settings", s.handleUpdateSettings).Methods("PUT")
 	admin.HandleFunc("/settings/test", s.handleTestIntegration).Methods("POST")
+	admin.HandleFunc("/integrations/health", s.handleAdminIntegrationHealth).Methods("GET")
+	admin.HandleFunc("/integrations/health/check", s.handleCheckIntegrations).Methods("POST")
+	admin.HandleFunc("/notifications/test", s.handleTestNotification).Methods("POST")
+	admin.HandleFunc("/torrents/unlinked", s.handleListUnlinkedTorrents).Methods("GET")
+	admin.HandleFunc("/torrents/manual", s.handleListManualTorrentLinks).Methods("GET")
+	admin.HandleFunc("/torrents/media-search", s.handleSearchMediaForLink).Methods("GET")
+	admin.HandleFunc("/torrents/{hash}/link", s.handleLinkTorrent).Methods("POST")
+	admin.HandleFunc("/torrents/{hash}/unlink", s.handleUnlinkTorrent).Methods("POST")
+	admin.HandleFunc("/torrents/{hash}/reset", s.handleResetTorrentLink).Methods("POST")
+	admin.HandleFunc("/torrents/{hash}/ignore", s.handleIgnoreTorrent).Methods("POST")
+	admin.HandleFunc("/reports/orphans", s.handleOrphanReport).Methods("GET")
+	admin.HandleFunc("/reports/orphans/torrents/{hash}/delete", s.handleDeleteOrphanedTorrent).Methods("POST")
+	admin.HandleFunc("/reports/orphans/files/delete", s.handleDeleteUntrackedFile).Methods("POST")
+	admin.HandleFunc("/reports/dead-torrents", s.handleDeadTorrents).Methods("GET")
+	admin.HandleFunc("/deletion-requests/approve", s.handleApproveDeletionRequests).Methods("POST")
+	admin.HandleFunc("/deletion-requests/reject", s.handleRejectDeletionRequests).Methods("POST")
+	admin.HandleFunc("/media/{id}/schedule-deletion", s.handleScheduleDeletion).Methods("POST")
+	admin.HandleFunc("/scheduled-deletions", s.handleListScheduledDeletions).Methods("GET")
+	admin.HandleFunc("/scheduled-deletions/{id}", s.handleCancelScheduledDeletion).Methods("DELETE")
+	admin.HandleFunc("/stale", s.handleListStale).Methods("GET")
+	admin.HandleFunc("/stale/media/{id}", s.handleRemoveStaleMedia).Methods("DELETE")
+	admin.HandleFunc("/stale/torrents/{hash}", s.handleRemoveStaleTorrent).Methods("DELETE")
+	admin.HandleFunc("/webhooks", s.handleListWebhooks).Methods("GET")
+	admin.HandleFunc("/webhooks", s.handleCreateWebhook).Methods("POST")
+	admin.HandleFunc("/webhooks/deliveries", s.handleListWebhookDeliveries).Methods("GET")
+	admin.HandleFunc("/webhooks/deliveries/{id}/redeliver", s.handleRedeliverWebhook).Methods("POST")
+	admin.HandleFunc("/webhooks/incoming/token", s.handleRegenerateIncomingWebhookToken).Methods("POST")
+	admin.HandleFunc("/webhooks/{id}", s.handleUpdateWebhook).Methods("PUT")
+	admin.HandleFunc("/webhooks/{id}", s.handleDeleteWebhook).Methods("DELETE")
+	admin.HandleFunc("/webhooks/{id}/test", s.handleTestWebhook).Methods("POST")
 
 	// Public web routes
 	s.router.HandleFunc("/", s.handleIndex).Methods("GET")
 	s.router.HandleFunc("/login", s.handleLoginPage).Methods("GET")
 	s.router.HandleFunc("/logout", s.handleLogoutPage).Methods("GET")
+	s.router.HandleFunc("/scheduled/{token}", s.handleScheduledDeletionPage).Methods("GET")
 	
 	// Protected web routes
 	protectedWeb := s.router.PathPrefix("").Subrouter()
@@ -231,8 +478,15 @@ func (s *Server) setupRoutes() {
 		})
 	})
 	protectedWeb.HandleFunc("/dashboard", s.handleDashboard).Methods("GET")
+	protectedWeb.HandleFunc("/statistics", s.handleStatisticsPage).Methods("GET")
+	protectedWeb.HandleFunc("/requests", s.handleDeletionRequestsPage).Methods("GET")
+	protectedWeb.HandleFunc("/account", s.handleAccountPage).Methods("GET")
 	protectedWeb.HandleFunc("/admin", s.handleAdminPage).Methods("GET")
 	protectedWeb.HandleFunc("/admin/settings", s.handleSettingsPage).Methods("GET")
+	protectedWeb.HandleFunc("/admin/torrents", s.handleTorrentsPage).Methods("GET")
+	protectedWeb.HandleFunc("/admin/reports", s.handleReportsPage).Methods("GET")
+	protectedWeb.HandleFunc("/admin/webhooks", s.handleWebhooksPage).Methods("GET")
+	protectedWeb.HandleFunc("/admin/stale", s.handleStalePage).Methods("GET")
 	
 	// HTMX endpoints (protected)
 	protectedWeb.HandleFunc("/api/media/sync", s.handleSyncMedia).Methods("POST")
@@ -248,10 +502,31 @@ func (s *Server) Start() error {
 	return s.httpServer.ListenAndServe()
 }
 
+// Shutdown stops the server
+// In-flight syncs, deletions and requests are cancelled first, then waited for until ctx is done.
+// Deletions already underway still record what they did.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	s.cancel()
+	err := s.httpServer.Shutdown(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		s.syncs.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.WarnContext(ctx, "Timed out waiting for background work to stop")
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
 }
 
+// handleHealth answers OK whenever the server is up, for existing checks; /health/live and /health/ready tell more
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
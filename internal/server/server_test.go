@@ -0,0 +1,35 @@
+package server
+
+import (
+	"sync"
+	"testing"
+
+	"removarr/internal/config"
+	"removarr/internal/events"
+	"removarr/internal/integrations"
+)
+
+func TestCurrentServicesDuringSettingsSave(t *testing.T) {
+	s := &Server{config: &config.Config{}, events: events.NewBus()}
+	s.setIntegrations(integrations.NewClient(s.config))
+
+	// Run with -race: background work reads the services while a settings save rebuilds them
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			s.setIntegrations(integrations.NewClient(s.config))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if current := s.currentServices(); current.mediaSync == nil || current.torrentSync == nil {
+				t.Error("services missing while they were rebuilt")
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -1,10 +1,17 @@
 package server
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 	"time"
+
+	"removarr/internal/services"
 )
 
 // loadSettingsFromDB loads all settings from database and returns them as a map
@@ -56,6 +63,41 @@ func (s *Server) setSetting(key, value, settingType string) error {
 	return err
 }
 
+// libraryRootFolders returns the extra root folders configured for orphan reports
+// Stored one path per line; Sonarr/Radarr root folders are added by the report service
+func (s *Server) libraryRootFolders() []string {
+	var folders []string
+	for _, line := range strings.Split(s.getSetting("library_root_folders", ""), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			folders = append(folders, line)
+		}
+	}
+	return folders
+}
+
+// storageQuotaBytes returns the library storage quota, 0 if none is set
+// Stored in GB
+func (s *Server) storageQuotaBytes() int64 {
+	quotaGB, err := strconv.ParseInt(s.getSetting("storage_quota_gb", "0"), 10, 64)
+	if err != nil || quotaGB < 0 {
+		return 0
+	}
+	return quotaGB << 30
+}
+
+// scoreWeights returns the dead weight score weights, the defaults if none are configured
+// Stored as JSON; factors missing from the stored weights keep their default weight
+func (s *Server) scoreWeights() services.ScoreWeights {
+	weights := services.DefaultScoreWeights()
+	if raw := s.getSetting("scoring.weights", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &weights); err != nil {
+			slog.Warn("Invalid scoring weights setting, using defaults", "error", err)
+			return services.DefaultScoreWeights()
+		}
+	}
+	return weights
+}
+
 // loadIntegrationSettings loads integration settings from database and applies them to config
 func (s *Server) loadIntegrationSettings() {
 	// Load all settings at once
@@ -153,3 +195,26 @@ func (s *Server) loadIntegrationSettings() {
 	slog.Info("Loaded integration settings from database")
 }
 
+// incomingWebhookTokenKey is the setting holding the token Sonarr, Radarr and qBittorrent callbacks authenticate with
+const incomingWebhookTokenKey = "webhooks.incoming_token"
+
+// incomingWebhookToken returns the incoming webhook token, generating one the first time
+func (s *Server) incomingWebhookToken() (string, error) {
+	if token := s.getSetting(incomingWebhookTokenKey, ""); token != "" {
+		return token, nil
+	}
+	return s.regenerateIncomingWebhookToken()
+}
+
+// regenerateIncomingWebhookToken replaces the incoming webhook token; senders using the old one are rejected from now on
+func (s *Server) regenerateIncomingWebhookToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	if err := s.setSetting(incomingWebhookTokenKey, token, "string"); err != nil {
+		return "", fmt.Errorf("failed to save token: %w", err)
+	}
+	return token, nil
+}
@@ -8,35 +8,53 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 var templates *template.Template
 
-func initTemplates() error {
-	tmpl := template.New("")
-	
-	// Add custom template functions
-	tmpl.Funcs(template.FuncMap{
+// allTemplates lists every template file, in parse order
+// New pages must be added here
+var allTemplates = []string{
+	"web/templates/base.html",
+	"web/templates/media_list.html",
+	"web/templates/login.html",
+	"web/templates/dashboard.html",
+	"web/templates/setup.html",
+	"web/templates/admin.html",
+	"web/templates/settings.html",
+	"web/templates/torrents.html",
+	"web/templates/reports.html",
+	"web/templates/statistics.html",
+	"web/templates/deletion_requests.html",
+	"web/templates/account.html",
+	"web/templates/scheduled_deletion.html",
+	"web/templates/webhooks.html",
+	"web/templates/stale.html",
+}
+
+// templateFuncs returns the custom functions available to all templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
 		"formatBytes":    formatBytes,
 		"formatDuration": formatDuration,
+		"formatRatio":    formatRatio,
+		"timeUntil":      timeUntil,
 		"add":            func(a, b int) int { return a + b },
 		"sub":            func(a, b int) int { return a - b },
-	})
+	}
+}
+
+func initTemplates() error {
+	tmpl := template.New("")
+
+	// Add custom template functions
+	tmpl.Funcs(templateFuncs())
 
 	// Parse all templates - now using unique content template names
 	// Each page defines its own "content" that calls a unique template
 	// This avoids the "last parsed wins" issue
-	templateFiles := []string{
-		"web/templates/base.html",
-		"web/templates/media_list.html",
-		"web/templates/login.html",
-		"web/templates/dashboard.html",
-		"web/templates/setup.html",
-		"web/templates/admin.html",
-		"web/templates/settings.html",
-	}
-	
-	for _, file := range templateFiles {
+	for _, file := range allTemplates {
 		if _, err := os.Stat(file); err == nil {
 			_, err := tmpl.ParseFiles(file)
 			if err != nil {
@@ -52,11 +70,25 @@ func initTemplates() error {
 	return nil
 }
 
+// checkTemplates returns an error if the templates failed to load or a template file has gone missing since
+// Pages are parsed from disk on every render, so either breaks them
+func checkTemplates() error {
+	if templates == nil {
+		return fmt.Errorf("templates not initialized")
+	}
+	for _, file := range allTemplates {
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("template %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
 func (s *Server) renderTemplate(w http.ResponseWriter, tmpl string, data interface{}) error {
 	if templates == nil {
 		return fmt.Errorf("templates not initialized")
 	}
-	
+
 	// The problem: Go templates use the LAST parsed definition when multiple templates
 	// define the same block name. Since dashboard.html is parsed last, its "content" always wins.
 	//
@@ -71,32 +103,16 @@ func (s *Server) renderTemplate(w http.ResponseWriter, tmpl string, data interfa
 	//
 	// Parse templates dynamically based on which page we're rendering.
 	// We'll create a new template set for each request, parsing in the right order.
-	
+
 	// For now, let's try cloning and re-parsing just the needed template
 	// Actually, simpler: Parse login.html AFTER dashboard.html when rendering login
 	// We can do this by re-parsing just that template into a clone
-	
+
 	// Fix: Create a fresh template set for each page with the target template parsed last
 	// This ensures the correct "content" definition is used
 	tmplInstance := template.New("")
-	tmplInstance.Funcs(template.FuncMap{
-		"formatBytes":    formatBytes,
-		"formatDuration": formatDuration,
-		"add":            func(a, b int) int { return a + b },
-		"sub":            func(a, b int) int { return a - b },
-	})
-	
-	// Determine which template should be parsed last
-	allTemplates := []string{
-		"web/templates/base.html",
-		"web/templates/media_list.html",
-		"web/templates/login.html",
-		"web/templates/dashboard.html",
-		"web/templates/setup.html",
-		"web/templates/admin.html",
-		"web/templates/settings.html",
-	}
-	
+	tmplInstance.Funcs(templateFuncs())
+
 	// Reorder templates to put the target template last
 	templateFiles := []string{}
 	for _, file := range allTemplates {
@@ -106,7 +122,7 @@ func (s *Server) renderTemplate(w http.ResponseWriter, tmpl string, data interfa
 	}
 	// Add the target template last
 	templateFiles = append(templateFiles, "web/templates/"+tmpl)
-	
+
 	for _, file := range templateFiles {
 		if _, err := os.Stat(file); err == nil {
 			_, err := tmplInstance.ParseFiles(file)
@@ -115,7 +131,7 @@ func (s *Server) renderTemplate(w http.ResponseWriter, tmpl string, data interfa
 			}
 		}
 	}
-	
+
 	return tmplInstance.ExecuteTemplate(w, "base.html", data)
 }
 
@@ -133,6 +149,19 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// timeUntil formats the time left until t, e.g. "3d"
+func timeUntil(t time.Time) string {
+	seconds := int64(time.Until(t).Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+	return formatDuration(seconds)
+}
+
+func formatRatio(ratio float64) string {
+	return fmt.Sprintf("%.2f", ratio)
+}
+
 func formatDuration(seconds int64) string {
 	if seconds < 60 {
 		return fmt.Sprintf("%ds", seconds)
@@ -145,4 +174,3 @@ func formatDuration(seconds int64) string {
 	}
 	return fmt.Sprintf("%dd", seconds/86400)
 }
-
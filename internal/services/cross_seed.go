@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// crossSeedTorrent is the stored state of a torrent that cross-seed detection works from
+type crossSeedTorrent struct {
+	hash        string
+	contentPath string
+	size        int64
+	pieceHash   string // digest of its piece hashes, "" until fetched
+	group       string
+}
+
+// groupCrossSeeds groups torrents that share the same data so eligibility and
+// deletion can treat cross-seeds to several trackers together.
+// Torrents with the same content path are grouped directly. Torrents of the same
+// size saved under different paths are grouped when their piece hashes match.
+// Stored groups are kept for torrents that didn't change; only changed ones (added or moved by this sync) are
+// matched again. Piece hashes are fetched once per torrent, when it is compared with a same-size torrent in
+// another group, and stored as a digest. Changed groups are written in one statement.
+func (s *TorrentSyncService) groupCrossSeeds(ctx context.Context, db querier, changed map[string]bool) error {
+	torrents, err := loadCrossSeedTorrents(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	fetched := make(map[string]string) // digests fetched by this sync, "" if fetching failed
+	groupIDs := groupTorrents(torrents, changed, func(t *crossSeedTorrent) string {
+		return s.pieceHash(ctx, t, fetched)
+	})
+
+	var pieceHashes, pieceHashDigests []string
+	for hash, digest := range fetched {
+		if digest != "" {
+			pieceHashes = append(pieceHashes, hash)
+			pieceHashDigests = append(pieceHashDigests, digest)
+		}
+	}
+	if len(pieceHashes) > 0 {
+		if _, err := db.ExecContext(ctx,
+			`UPDATE torrents t SET piece_hash = p.piece_hash
+			FROM UNNEST($1::text[], $2::text[]) AS p(hash, piece_hash)
+			WHERE t.hash = p.hash`,
+			pieceHashes, pieceHashDigests,
+		); err != nil {
+			return fmt.Errorf("failed to store piece hashes: %w", err)
+		}
+	}
+
+	var hashes, newGroups []string
+	grouped := 0
+	for _, t := range torrents {
+		groupID := groupIDs[t.hash]
+		if groupID != "" {
+			grouped++
+		}
+		if groupID != t.group {
+			hashes = append(hashes, t.hash)
+			newGroups = append(newGroups, groupID)
+		}
+	}
+	if len(hashes) > 0 {
+		if _, err := db.ExecContext(ctx,
+			`UPDATE torrents t SET cross_seed_group = NULLIF(g.cross_seed_group, '')
+			FROM UNNEST($1::text[], $2::text[]) AS g(hash, cross_seed_group)
+			WHERE t.hash = g.hash`,
+			hashes, newGroups,
+		); err != nil {
+			return fmt.Errorf("failed to update cross-seed groups: %w", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "Cross-seed detection complete", "grouped_torrents", grouped, "regrouped", len(hashes),
+		"piece_hashes_fetched", len(fetched))
+	return nil
+}
+
+// groupTorrents works out the cross-seed group of each torrent, "" for a torrent with no cross-seeds.
+// pieceHash returns the digest of a torrent's piece hashes, or "" if it isn't available.
+func groupTorrents(torrents []crossSeedTorrent, changed map[string]bool, pieceHash func(*crossSeedTorrent) string) map[string]string {
+	groups := newHashUnion()
+	for _, t := range torrents {
+		groups.add(t.hash)
+	}
+
+	// Same content path
+	byPath := make(map[string]string)
+	for _, t := range torrents {
+		if t.contentPath == "" {
+			continue
+		}
+		path := filepath.Clean(t.contentPath)
+		if first, ok := byPath[path]; ok {
+			groups.union(first, t.hash)
+		} else {
+			byPath[path] = t.hash
+		}
+	}
+
+	// Same stored group, for torrents that didn't change since they were grouped
+	byGroup := make(map[string]string)
+	for _, t := range torrents {
+		if t.group == "" || changed[t.hash] {
+			continue
+		}
+		if first, ok := byGroup[t.group]; ok {
+			groups.union(first, t.hash)
+		} else {
+			byGroup[t.group] = t.hash
+		}
+	}
+
+	// Same size in different groups, one of them changed: compare piece hashes
+	bySize := make(map[int64][]*crossSeedTorrent)
+	for i := range torrents {
+		if torrents[i].size > 0 {
+			bySize[torrents[i].size] = append(bySize[torrents[i].size], &torrents[i])
+		}
+	}
+	for _, sameSize := range bySize {
+		for i := 0; i < len(sameSize); i++ {
+			for j := i + 1; j < len(sameSize); j++ {
+				a, b := sameSize[i], sameSize[j]
+				if !changed[a.hash] && !changed[b.hash] {
+					continue
+				}
+				if groups.find(a.hash) == groups.find(b.hash) {
+					continue
+				}
+				if digest := pieceHash(a); digest != "" && digest == pieceHash(b) {
+					groups.union(a.hash, b.hash)
+				}
+			}
+		}
+	}
+
+	groupIDs := make(map[string]string, len(torrents))
+	for _, t := range torrents {
+		if id := groups.find(t.hash); groups.size(id) > 1 {
+			groupIDs[t.hash] = id
+		}
+	}
+	return groupIDs
+}
+
+// loadCrossSeedTorrents loads every torrent still in qBittorrent
+func loadCrossSeedTorrents(ctx context.Context, db querier) ([]crossSeedTorrent, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT hash, COALESCE(content_path, ''), COALESCE(size_bytes, 0), COALESCE(piece_hash, ''),
+			COALESCE(cross_seed_group, '')
+		FROM torrents
+		WHERE missing_since IS NULL`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load torrents: %w", err)
+	}
+	defer rows.Close()
+
+	var torrents []crossSeedTorrent
+	for rows.Next() {
+		var t crossSeedTorrent
+		if err := rows.Scan(&t.hash, &t.contentPath, &t.size, &t.pieceHash, &t.group); err != nil {
+			return nil, fmt.Errorf("failed to scan torrent: %w", err)
+		}
+		torrents = append(torrents, t)
+	}
+	return torrents, rows.Err()
+}
+
+// linkCrossSeeds links a cross-seed that could not be matched on its own to the rest of its group,
+// as long as the group is linked to a single media item. Manual links are left alone.
+// Returns how many torrents it linked.
+func linkCrossSeeds(ctx context.Context, db execer) (int64, error) {
+	result, err := db.ExecContext(ctx,
+		`UPDATE torrents t SET
+			media_item_id = g.media_item_id,
+			updated_at = CURRENT_TIMESTAMP
+		FROM (
+			SELECT cross_seed_group, MIN(media_item_id) AS media_item_id
+			FROM torrents
+			WHERE cross_seed_group IS NOT NULL AND media_item_id IS NOT NULL
+			GROUP BY cross_seed_group
+			HAVING COUNT(DISTINCT media_item_id) = 1
+		) g
+		WHERE t.cross_seed_group = g.cross_seed_group
+			AND t.media_item_id IS NULL
+			AND t.link_source = 'auto'`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to link cross-seeded torrents: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// pieceHash returns the digest of a torrent's piece hashes, fetching it from qBittorrent the first time
+// Returns "" if they could not be fetched; fetched records what this sync fetched, so a failure isn't retried
+func (s *TorrentSyncService) pieceHash(ctx context.Context, t *crossSeedTorrent, fetched map[string]string) string {
+	if t.pieceHash != "" {
+		return t.pieceHash
+	}
+	if digest, ok := fetched[t.hash]; ok {
+		return digest
+	}
+
+	hashes, err := s.integrations.QBittorrent.GetTorrentPieceHashes(ctx, t.hash)
+	if err != nil {
+		slog.DebugContext(ctx, "Failed to get piece hashes", "hash", t.hash, "error", err)
+		fetched[t.hash] = ""
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(hashes, "")))
+	t.pieceHash = hex.EncodeToString(sum[:])
+	fetched[t.hash] = t.pieceHash
+	return t.pieceHash
+}
+
+// hashUnion is a union-find over torrent hashes
+// The representative of each set is its smallest hash, so group IDs are stable between syncs
+type hashUnion struct {
+	parent map[string]string
+	sizes  map[string]int
+}
+
+func newHashUnion() *hashUnion {
+	return &hashUnion{
+		parent: make(map[string]string),
+		sizes:  make(map[string]int),
+	}
+}
+
+func (u *hashUnion) add(hash string) {
+	if _, ok := u.parent[hash]; !ok {
+		u.parent[hash] = hash
+		u.sizes[hash] = 1
+	}
+}
+
+func (u *hashUnion) find(hash string) string {
+	for u.parent[hash] != hash {
+		u.parent[hash] = u.parent[u.parent[hash]]
+		hash = u.parent[hash]
+	}
+	return hash
+}
+
+func (u *hashUnion) union(a, b string) {
+	ra, rb := u.find(a), u.find(b)
+	if ra == rb {
+		return
+	}
+	if rb < ra {
+		ra, rb = rb, ra
+	}
+	u.parent[rb] = ra
+	u.sizes[ra] += u.sizes[rb]
+}
+
+func (u *hashUnion) size(root string) int {
+	return u.sizes[root]
+}
@@ -0,0 +1,167 @@
+package services
+
+import "testing"
+
+func TestHashUnion(t *testing.T) {
+	u := newHashUnion()
+	for _, hash := range []string{"d", "c", "b", "a", "e"} {
+		u.add(hash)
+	}
+	u.union("d", "c")
+	u.union("c", "b")
+	u.union("b", "d") // already together
+
+	// The smallest hash represents the set, whatever order they were joined in
+	for _, hash := range []string{"b", "c", "d"} {
+		if root := u.find(hash); root != "b" {
+			t.Errorf("find(%s) = %s, want b", hash, root)
+		}
+	}
+	if size := u.size("b"); size != 3 {
+		t.Errorf("size(b) = %d, want 3", size)
+	}
+
+	for _, hash := range []string{"a", "e"} {
+		if root := u.find(hash); root != hash {
+			t.Errorf("find(%s) = %s, want itself", hash, root)
+		}
+		if size := u.size(hash); size != 1 {
+			t.Errorf("size(%s) = %d, want 1", hash, size)
+		}
+	}
+
+	u.union("e", "a")
+	u.union("a", "c")
+	if root := u.find("e"); root != "a" {
+		t.Errorf("find(e) = %s, want a", root)
+	}
+	if size := u.size("a"); size != 5 {
+		t.Errorf("size(a) = %d, want 5", size)
+	}
+}
+
+func TestHashUnionAddKeepsSet(t *testing.T) {
+	u := newHashUnion()
+	u.add("a")
+	u.add("b")
+	u.union("a", "b")
+	u.add("b") // torrents are added once per sync, a repeat must not split the set
+	if root := u.find("b"); root != "a" {
+		t.Errorf("find(b) = %s, want a", root)
+	}
+	if size := u.size("a"); size != 2 {
+		t.Errorf("size(a) = %d, want 2", size)
+	}
+}
+
+func TestGroupTorrents(t *testing.T) {
+	tests := []struct {
+		name     string
+		torrents []crossSeedTorrent
+		changed  []string
+		digests  map[string]string // piece hash digests qBittorrent returns, missing if fetching fails
+		want     map[string]string
+	}{
+		{
+			name: "same content path",
+			torrents: []crossSeedTorrent{
+				{hash: "b", contentPath: "/data/Movie", size: 10},
+				{hash: "a", contentPath: "/data/Movie/", size: 10},
+				{hash: "c", contentPath: "/data/Other", size: 20},
+			},
+			changed: []string{"a", "b", "c"},
+			want:    map[string]string{"a": "a", "b": "a"},
+		},
+		{
+			name: "same size and piece hashes under different paths",
+			torrents: []crossSeedTorrent{
+				{hash: "a", contentPath: "/data/torrents/Movie", size: 10},
+				{hash: "b", contentPath: "/data/cross/Movie", size: 10},
+			},
+			changed: []string{"b"},
+			digests: map[string]string{"a": "d1", "b": "d1"},
+			want:    map[string]string{"a": "a", "b": "a"},
+		},
+		{
+			name: "same size, different piece hashes",
+			torrents: []crossSeedTorrent{
+				{hash: "a", contentPath: "/data/torrents/Movie", size: 10},
+				{hash: "b", contentPath: "/data/torrents/Other", size: 10},
+			},
+			changed: []string{"b"},
+			digests: map[string]string{"a": "d1", "b": "d2"},
+			want:    map[string]string{},
+		},
+		{
+			name: "piece hashes not available",
+			torrents: []crossSeedTorrent{
+				{hash: "a", contentPath: "/data/torrents/Movie", size: 10},
+				{hash: "b", contentPath: "/data/cross/Movie", size: 10},
+			},
+			changed: []string{"b"},
+			want:    map[string]string{},
+		},
+		{
+			name: "stored digest is used",
+			torrents: []crossSeedTorrent{
+				{hash: "a", contentPath: "/data/torrents/Movie", size: 10, pieceHash: "d1"},
+				{hash: "b", contentPath: "/data/cross/Movie", size: 10},
+			},
+			changed: []string{"b"},
+			digests: map[string]string{"b": "d1"},
+			want:    map[string]string{"a": "a", "b": "a"},
+		},
+		{
+			name: "unchanged torrents keep their stored group without comparing",
+			torrents: []crossSeedTorrent{
+				{hash: "a", contentPath: "/data/torrents/Movie", size: 10, group: "a"},
+				{hash: "b", contentPath: "/data/cross/Movie", size: 10, group: "a"},
+				{hash: "c", contentPath: "/data/torrents/Other", size: 10},
+			},
+			want: map[string]string{"a": "a", "b": "a"},
+		},
+		{
+			name: "changed torrent leaves its stored group",
+			torrents: []crossSeedTorrent{
+				{hash: "a", contentPath: "/data/torrents/Movie", size: 10, group: "a"},
+				{hash: "b", contentPath: "/data/moved/Movie", size: 12, group: "a"},
+			},
+			changed: []string{"b"},
+			want:    map[string]string{},
+		},
+		{
+			name: "torrents of unknown size are not compared",
+			torrents: []crossSeedTorrent{
+				{hash: "a", contentPath: "/data/torrents/Movie"},
+				{hash: "b", contentPath: "/data/cross/Movie"},
+			},
+			changed: []string{"a", "b"},
+			digests: map[string]string{"a": "d1", "b": "d1"},
+			want:    map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := make(map[string]bool)
+			for _, hash := range tt.changed {
+				changed[hash] = true
+			}
+			pieceHash := func(torrent *crossSeedTorrent) string {
+				if torrent.pieceHash != "" {
+					return torrent.pieceHash
+				}
+				return tt.digests[torrent.hash]
+			}
+
+			got := groupTorrents(tt.torrents, changed, pieceHash)
+			if len(got) != len(tt.want) {
+				t.Fatalf("groupTorrents() = %v, want %v", got, tt.want)
+			}
+			for hash, group := range tt.want {
+				if got[hash] != group {
+					t.Errorf("group of %s = %q, want %q", hash, got[hash], group)
+				}
+			}
+		})
+	}
+}
@@ -3,12 +3,20 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"removarr/internal/events"
 	"removarr/internal/integrations"
+	"removarr/internal/metrics"
+	"removarr/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type DeletionService struct {
@@ -17,6 +25,7 @@ type DeletionService struct {
 	radarr      *integrations.RadarrClient
 	overseerr   *integrations.OverseerrClient
 	qbittorrent *integrations.QBittorrentClient
+	events      *events.Bus
 }
 
 func NewDeletionService(
@@ -25,6 +34,7 @@ func NewDeletionService(
 	radarr *integrations.RadarrClient,
 	overseerr *integrations.OverseerrClient,
 	qbittorrent *integrations.QBittorrentClient,
+	bus *events.Bus,
 ) *DeletionService {
 	return &DeletionService{
 		db:          db,
@@ -32,19 +42,28 @@ func NewDeletionService(
 		radarr:      radarr,
 		overseerr:   overseerr,
 		qbittorrent: qbittorrent,
+		events:      bus,
 	}
 }
 
 // DeleteMediaItem performs the complete deletion workflow:
-// 1. Get media item from DB
+// 1. Get media item from DB (protected items are refused with ErrProtected)
 // 2. Delete files from filesystem (if downloaded)
 // 3. Delete/unmonitor from Sonarr/Radarr
 // 4. Delete from Overseerr (if requested)
 // 5. Delete torrent from qBittorrent
 // 6. Log to audit log
 // 7. Delete from database
-func (s *DeletionService) DeleteMediaItem(ctx context.Context, mediaID int, userID int) error {
+// 8. Publish a MediaDeleted event
+func (s *DeletionService) DeleteMediaItem(ctx context.Context, mediaID int, userID int) (err error) {
+	ctx, span := tracing.Start(ctx, "DeleteMediaItem", trace.WithAttributes(
+		attribute.Int("media.id", mediaID),
+		attribute.Int("user.id", userID),
+	))
+	defer func() { tracing.End(span, err) }()
+
 	// Step 1: Get media item from DB
+	stepCtx, step := tracing.Start(ctx, "load media item")
 	var (
 		id                 int
 		title              string
@@ -57,8 +76,10 @@ func (s *DeletionService) DeleteMediaItem(ctx context.Context, mediaID int, user
 	)
 	var tmdbID sql.NullInt64
 	var tvdbID sql.NullInt64
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, title, type, sonarr_id, radarr_id, overseerr_request_id, file_path, file_size, tmdb_id, tvdb_id
+	var requesterID sql.NullInt64
+	err = s.db.QueryRowContext(stepCtx, `
+		SELECT id, title, type, sonarr_id, radarr_id, overseerr_request_id, file_path, file_size, tmdb_id, tvdb_id,
+			requested_by_user_id
 		FROM media_items
 		WHERE id = $1
 	`, mediaID).Scan(
@@ -66,15 +87,33 @@ func (s *DeletionService) DeleteMediaItem(ctx context.Context, mediaID int, user
 		&sonarrID, &radarrID, &overseerrRequestID,
 		&filePath, &fileSize,
 		&tmdbID, &tvdbID,
+		&requesterID,
 	)
 	if err != nil {
+		tracing.End(step, err)
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("media item not found: %d", mediaID)
 		}
 		return fmt.Errorf("failed to get media item: %w", err)
 	}
 
-	slog.Info("Starting media deletion", "media_id", mediaID, "title", title, "type", mediaType)
+	// Protected items are never deleted
+	err = checkNotProtected(stepCtx, s.db, mediaID)
+	tracing.End(step, err)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.String("media.title", title), attribute.String("media.type", mediaType))
+
+	// Nothing has been touched yet, so a cancelled deletion (e.g. on shutdown) can still stop cleanly
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Once files start going, what was done is recorded even if ctx is cancelled partway
+	// The remaining Sonarr/Radarr/Overseerr/qBittorrent calls still give up on cancellation and end up in the errors
+	record := context.WithoutCancel(ctx)
+
+	slog.InfoContext(ctx, "Starting media deletion", "media_id", mediaID, "title", title, "type", mediaType)
 
 	// Track errors but continue with deletion
 	var errors []string
@@ -83,11 +122,14 @@ func (s *DeletionService) DeleteMediaItem(ctx context.Context, mediaID int, user
 	// We delete files ourselves first to ensure they're removed from disk
 	// This is a critical requirement - files MUST be deleted from disk
 	if filePath.Valid && filePath.String != "" {
-		if err := s.deleteFiles(filePath.String); err != nil {
+		_, step := tracing.Start(ctx, "delete files", trace.WithAttributes(attribute.String("file.path", filePath.String)))
+		err := s.deleteFiles(filePath.String)
+		tracing.End(step, err)
+		if err != nil {
 			errors = append(errors, fmt.Sprintf("failed to delete files: %v", err))
-			slog.Error("Failed to delete files", "path", filePath.String, "error", err)
+			slog.ErrorContext(ctx, "Failed to delete files", "path", filePath.String, "error", err)
 		} else {
-			slog.Info("Deleted files from disk", "path", filePath.String)
+			slog.InfoContext(ctx, "Deleted files from disk", "path", filePath.String)
 		}
 	}
 
@@ -95,47 +137,53 @@ func (s *DeletionService) DeleteMediaItem(ctx context.Context, mediaID int, user
 	// Note: We pass deleteFiles=false since we already deleted files ourselves
 	// If our deletion failed, we could pass true, but Radarr/Sonarr might fail
 	// if files don't exist, so we'll just unmonitor if delete fails
+	stepCtx, step = tracing.Start(ctx, "delete from sonarr or radarr")
+	before := len(errors)
 	if mediaType == "series" && sonarrID.Valid && s.sonarr != nil {
 		// Try to delete from Sonarr (will unmonitor even if files already deleted)
 		// addImportExclusion=false prevents the series from being added to the exclusion list
-		if err := s.sonarr.DeleteSeries(int(sonarrID.Int64), false, false); err != nil {
+		if err := s.sonarr.DeleteSeries(stepCtx, int(sonarrID.Int64), false, false); err != nil {
 			// If delete fails, try unmonitoring
-			slog.Warn("Failed to delete from Sonarr, trying unmonitor", "error", err)
-			if err := s.sonarr.UnmonitorSeries(int(sonarrID.Int64)); err != nil {
+			slog.WarnContext(ctx, "Failed to delete from Sonarr, trying unmonitor", "error", err)
+			if err := s.sonarr.UnmonitorSeries(stepCtx, int(sonarrID.Int64)); err != nil {
 				errors = append(errors, fmt.Sprintf("failed to delete/unmonitor from Sonarr: %v", err))
-				slog.Error("Failed to unmonitor from Sonarr", "error", err)
+				slog.ErrorContext(ctx, "Failed to unmonitor from Sonarr", "error", err)
 			} else {
-				slog.Info("Unmonitored from Sonarr", "sonarr_id", sonarrID.Int64)
+				slog.InfoContext(ctx, "Unmonitored from Sonarr", "sonarr_id", sonarrID.Int64)
 			}
 		} else {
-			slog.Info("Deleted from Sonarr (not added to exclusion list)", "sonarr_id", sonarrID.Int64)
+			slog.InfoContext(ctx, "Deleted from Sonarr (not added to exclusion list)", "sonarr_id", sonarrID.Int64)
 		}
 	} else if mediaType == "movie" && radarrID.Valid && s.radarr != nil {
 		// Try to delete from Radarr first (this removes the movie entry completely)
 		// Note: Radarr's DELETE endpoint removes the movie from its database
 		// If deleteFiles=false, it won't delete files, but it WILL remove the movie entry
 		// addImportExclusion=false prevents the movie from being added to the exclusion list
-		if err := s.radarr.DeleteMovie(int(radarrID.Int64), false, false); err != nil {
+		if err := s.radarr.DeleteMovie(stepCtx, int(radarrID.Int64), false, false); err != nil {
 			// If delete fails (e.g., movie not found, or API error), try unmonitoring as fallback
-			slog.Warn("Failed to delete from Radarr, trying unmonitor as fallback", "error", err, "radarr_id", radarrID.Int64)
-			if err := s.radarr.UnmonitorMovie(int(radarrID.Int64)); err != nil {
+			slog.WarnContext(ctx, "Failed to delete from Radarr, trying unmonitor as fallback", "error", err, "radarr_id", radarrID.Int64)
+			if err := s.radarr.UnmonitorMovie(stepCtx, int(radarrID.Int64)); err != nil {
 				errors = append(errors, fmt.Sprintf("failed to delete/unmonitor from Radarr: %v", err))
-				slog.Error("Failed to unmonitor from Radarr", "error", err, "radarr_id", radarrID.Int64)
+				slog.ErrorContext(ctx, "Failed to unmonitor from Radarr", "error", err, "radarr_id", radarrID.Int64)
 			} else {
-				slog.Info("Successfully unmonitored movie in Radarr", "radarr_id", radarrID.Int64)
+				slog.InfoContext(ctx, "Successfully unmonitored movie in Radarr", "radarr_id", radarrID.Int64)
 			}
 		} else {
-			slog.Info("Successfully deleted movie from Radarr (not added to exclusion list)", "radarr_id", radarrID.Int64)
+			slog.InfoContext(ctx, "Successfully deleted movie from Radarr (not added to exclusion list)", "radarr_id", radarrID.Int64)
 		}
 	}
 
+	endStep(step, errors, before)
+
 	// Step 4: Delete from Overseerr (if requested)
 	// If we don't have a request ID stored, try to find it by TMDB/TVDB ID
 	if s.overseerr != nil {
+		stepCtx, step := tracing.Start(ctx, "delete from overseerr")
+		before := len(errors)
 		var requestID int
 		if overseerrRequestID.Valid {
 			requestID = int(overseerrRequestID.Int64)
-			slog.Info("Using stored Overseerr request ID", "request_id", requestID)
+			slog.InfoContext(ctx, "Using stored Overseerr request ID", "request_id", requestID)
 		} else {
 			// Try to find the request by TMDB/TVDB ID
 			var tmdbIDPtr *int
@@ -150,79 +198,132 @@ func (s *DeletionService) DeleteMediaItem(ctx context.Context, mediaID int, user
 			}
 
 			if tmdbIDPtr != nil || tvdbIDPtr != nil {
-				req, err := s.overseerr.FindRequestByMediaID(tmdbIDPtr, tvdbIDPtr, mediaType)
+				req, err := s.overseerr.FindRequestByMediaID(stepCtx, tmdbIDPtr, tvdbIDPtr, mediaType)
 				if err != nil {
-					slog.Warn("Failed to find Overseerr request", "error", err, "tmdb_id", tmdbIDPtr, "tvdb_id", tvdbIDPtr)
+					slog.WarnContext(ctx, "Failed to find Overseerr request", "error", err, "tmdb_id", tmdbIDPtr, "tvdb_id", tvdbIDPtr)
 				} else if req != nil {
 					requestID = req.ID
-					slog.Info("Found Overseerr request by media ID", "request_id", requestID, "tmdb_id", tmdbIDPtr, "tvdb_id", tvdbIDPtr)
+					slog.InfoContext(ctx, "Found Overseerr request by media ID", "request_id", requestID, "tmdb_id", tmdbIDPtr, "tvdb_id", tvdbIDPtr)
 				} else {
-					slog.Info("No Overseerr request found for media", "tmdb_id", tmdbIDPtr, "tvdb_id", tvdbIDPtr)
+					slog.InfoContext(ctx, "No Overseerr request found for media", "tmdb_id", tmdbIDPtr, "tvdb_id", tvdbIDPtr)
 				}
 			}
 		}
 
 		// Delete the request if we found one
 		if requestID > 0 {
-			if err := s.overseerr.DeleteRequest(requestID); err != nil {
+			if err := s.overseerr.DeleteRequest(stepCtx, requestID); err != nil {
 				errors = append(errors, fmt.Sprintf("failed to delete from Overseerr: %v", err))
-				slog.Error("Failed to delete from Overseerr", "error", err, "request_id", requestID)
+				slog.ErrorContext(ctx, "Failed to delete from Overseerr", "error", err, "request_id", requestID)
 			} else {
-				slog.Info("Deleted from Overseerr", "request_id", requestID)
+				slog.InfoContext(ctx, "Deleted from Overseerr", "request_id", requestID)
 			}
 		} else {
-			slog.Info("No Overseerr request ID available, skipping Overseerr deletion")
+			slog.InfoContext(ctx, "No Overseerr request ID available, skipping Overseerr deletion")
 		}
+		endStep(step, errors, before)
 	}
 
 	// Step 5: Delete torrents from qBittorrent
-	// Get all torrents associated with this media item
-	var torrentHashes []string
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT hash FROM torrents WHERE media_item_id = $1
+	// Cross-seeds of this media's torrents that were never linked go too, since their data is gone
+	stepCtx, step = tracing.Start(ctx, "delete torrents")
+	before = len(errors)
+	torrents, err := loadTorrents(context.WithoutCancel(stepCtx), s.db, `
+		SELECT hash, COALESCE(cross_seed_group, ''), COALESCE(content_path, '')
+		FROM torrents
+		WHERE media_item_id = $1
+		   OR (media_item_id IS NULL AND cross_seed_group IN (
+				SELECT cross_seed_group FROM torrents
+				WHERE media_item_id = $1 AND cross_seed_group IS NOT NULL))
+		ORDER BY cross_seed_group NULLS LAST, hash
 	`, mediaID)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var hash string
-			if err := rows.Scan(&hash); err == nil {
-				torrentHashes = append(torrentHashes, hash)
-			}
-		}
+	if err != nil {
+		errors = append(errors, fmt.Sprintf("failed to load torrents: %v", err))
+		slog.ErrorContext(ctx, "Failed to load torrents", "media_id", mediaID, "error", err)
 	}
 
-	if s.qbittorrent != nil {
-		for _, hash := range torrentHashes {
-			if err := s.qbittorrent.DeleteTorrent(hash, true); err != nil {
-				errors = append(errors, fmt.Sprintf("failed to delete torrent %s: %v", hash, err))
-				slog.Error("Failed to delete torrent", "hash", hash, "error", err)
+	if s.qbittorrent != nil && len(torrents) > 0 {
+		// Data stays on disk while a torrent that isn't removed (e.g. a cross-seed linked to another media item)
+		// or another media item's files still use it
+		remove := make(map[string]bool, len(torrents))
+		for _, t := range torrents {
+			remove[t.hash] = true
+		}
+		kept, _, err := loadKeptData(context.WithoutCancel(stepCtx), s.db, remove, mediaID)
+		if err != nil {
+			// Without knowing what else uses the data, keeping it is the safe choice
+			errors = append(errors, fmt.Sprintf("failed to check shared torrent data: %v", err))
+			slog.ErrorContext(ctx, "Failed to check shared torrent data, keeping it", "media_id", mediaID, "error", err)
+		}
+		for _, t := range torrents {
+			deleteData := kept != nil && !kept.uses(t)
+			if err := s.qbittorrent.DeleteTorrent(stepCtx, t.hash, deleteData); err != nil {
+				errors = append(errors, fmt.Sprintf("failed to delete torrent %s: %v", t.hash, err))
+				slog.ErrorContext(ctx, "Failed to delete torrent", "hash", t.hash, "error", err)
 			} else {
-				slog.Info("Deleted torrent", "hash", hash)
+				slog.InfoContext(ctx, "Deleted torrent", "hash", t.hash, "delete_data", deleteData)
 			}
 		}
 	}
 
+	// Linked rows go with the media item (ON DELETE CASCADE), unlinked cross-seeds must be removed here
+	for _, t := range torrents {
+		if _, err := s.db.ExecContext(context.WithoutCancel(stepCtx), `DELETE FROM torrents WHERE hash = $1 AND media_item_id IS NULL`, t.hash); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete torrent record", "hash", t.hash, "error", err)
+		}
+	}
+	step.SetAttributes(attribute.Int("torrents", len(torrents)))
+	endStep(step, errors, before)
+
 	// Step 6: Log to audit log
-	details := fmt.Sprintf("Deleted media: %s (type: %s)", title, mediaType)
-	if len(errors) > 0 {
-		details += fmt.Sprintf(" - Errors: %v", errors)
+	hashes := make([]string, 0, len(torrents))
+	for _, t := range torrents {
+		hashes = append(hashes, t.hash)
 	}
-	_, err = s.db.ExecContext(ctx, `
+	_, err = s.db.ExecContext(record, `
 		INSERT INTO audit_logs (user_id, action, media_item_id, media_title, media_type, details)
-		VALUES ($1, 'delete', $2, $3, $4, $5)
-	`, userID, mediaID, title, mediaType, details)
+		VALUES (NULLIF($1, 0), 'delete', $2, $3, $4, $5)
+	`, userID, mediaID, title, mediaType, deleteAuditDetails(filePath.String, fileSize.Int64, hashes, errors))
 	if err != nil {
-		slog.Error("Failed to create audit log", "error", err)
+		slog.ErrorContext(ctx, "Failed to create audit log", "error", err)
 	}
 
 	// Step 7: Delete from database
-	_, err = s.db.ExecContext(ctx, `DELETE FROM media_items WHERE id = $1`, mediaID)
+	_, err = s.db.ExecContext(record, `DELETE FROM media_items WHERE id = $1`, mediaID)
 	if err != nil {
+		metrics.Deletions.WithLabelValues("failed").Inc()
 		return fmt.Errorf("failed to delete from database: %w", err)
 	}
+	if len(errors) > 0 {
+		metrics.Deletions.WithLabelValues("partial").Inc()
+	} else {
+		metrics.Deletions.WithLabelValues("success").Inc()
+	}
+	metrics.BytesFreed.Add(float64(fileSize.Int64))
+
+	slog.InfoContext(ctx, "Media deletion completed", "media_id", mediaID, "title", title, "errors", len(errors))
+
+	// Step 8: Publish a MediaDeleted event
+	event := events.Event{
+		Type:        events.MediaDeleted,
+		MediaItemID: &mediaID,
+		MediaTitle:  title,
+		MediaType:   mediaType,
+		ActorID:     &userID,
+		Data: map[string]interface{}{
+			"size_bytes": fileSize.Int64,
+			"torrents":   hashes,
+		},
+	}
+	if requesterID.Valid {
+		id := int(requesterID.Int64)
+		event.RequesterID = &id
+	}
+	if len(errors) > 0 {
+		event.Message = fmt.Sprintf("Completed with errors: %s", strings.Join(errors, "; "))
+	}
+	s.events.Publish(record, event)
 
-	slog.Info("Media deletion completed", "media_id", mediaID, "title", title, "errors", len(errors))
-	
 	if len(errors) > 0 {
 		return fmt.Errorf("deletion completed with errors: %v", errors)
 	}
@@ -230,6 +331,221 @@ func (s *DeletionService) DeleteMediaItem(ctx context.Context, mediaID int, user
 	return nil
 }
 
+// endStep ends the span of a deletion step, failed if the step added to errs since it started with before of them
+func endStep(span trace.Span, errs []string, before int) {
+	var err error
+	if len(errs) > before {
+		err = fmt.Errorf("%s", strings.Join(errs[before:], "; "))
+	}
+	tracing.End(span, err)
+}
+
+// RemoveTorrents removes some of a media item's torrents from qBittorrent while keeping the media item,
+// e.g. the cross-seeds whose tracker requirements are met while another tracker still needs seeding.
+// A torrent's data is only deleted when no remaining torrent uses it and it is not the library file itself.
+// Protected items are refused with ErrProtected.
+func (s *DeletionService) RemoveTorrents(ctx context.Context, mediaID int, hashes []string, userID int) error {
+	if s.qbittorrent == nil {
+		return fmt.Errorf("qbittorrent integration not enabled")
+	}
+	if len(hashes) == 0 {
+		return fmt.Errorf("no torrents to remove")
+	}
+
+	var (
+		title     string
+		mediaType string
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT title, type FROM media_items WHERE id = $1`,
+		mediaID,
+	).Scan(&title, &mediaType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("media item not found: %d", mediaID)
+		}
+		return fmt.Errorf("failed to get media item: %w", err)
+	}
+	if err := checkNotProtected(ctx, s.db, mediaID); err != nil {
+		return err
+	}
+
+	linked, err := loadTorrents(ctx, s.db, `
+		SELECT hash, COALESCE(cross_seed_group, ''), COALESCE(content_path, '')
+		FROM torrents WHERE media_item_id = $1
+	`, mediaID)
+	if err != nil {
+		return fmt.Errorf("failed to load torrents: %w", err)
+	}
+
+	byHash := make(map[string]torrentData)
+	for _, t := range linked {
+		byHash[t.hash] = t
+	}
+	remove := make(map[string]bool)
+	for _, hash := range hashes {
+		if _, ok := byHash[hash]; !ok {
+			return fmt.Errorf("torrent %s is not linked to media item %d", hash, mediaID)
+		}
+		remove[hash] = true
+	}
+
+	// Every torrent that stays, linked to this media item or not, keeps its data, and so do library files
+	kept, _, err := loadKeptData(ctx, s.db, remove, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Removed torrents are recorded even if ctx is cancelled partway; the remaining qBittorrent calls give up
+	record := context.WithoutCancel(ctx)
+
+	var errors []string
+	var removed []string
+	for _, hash := range hashes {
+		t := byHash[hash]
+		deleteData := !kept.uses(t)
+
+		if err := s.qbittorrent.DeleteTorrent(ctx, hash, deleteData); err != nil {
+			errors = append(errors, fmt.Sprintf("failed to delete torrent %s: %v", hash, err))
+			slog.ErrorContext(ctx, "Failed to delete torrent", "hash", hash, "error", err)
+			continue
+		}
+		slog.InfoContext(ctx, "Removed torrent", "hash", hash, "media_id", mediaID, "delete_data", deleteData)
+
+		if _, err := s.db.ExecContext(record, `DELETE FROM torrents WHERE hash = $1`, hash); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete torrent record", "hash", hash, "error", err)
+		}
+		removed = append(removed, hash)
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"hashes": removed,
+		"errors": errors,
+	})
+	_, err = s.db.ExecContext(record, `
+		INSERT INTO audit_logs (user_id, action, media_item_id, media_title, media_type, details)
+		VALUES (NULLIF($1, 0), 'remove_torrents', $2, $3, $4, $5)
+	`, userID, mediaID, title, mediaType, string(details))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create audit log", "error", err)
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("torrent removal completed with errors: %v", errors)
+	}
+
+	return nil
+}
+
+// deleteAuditDetails is the audit log details of a media deletion, a JSON object
+// size_bytes is summed by the statistics page to report freed space.
+func deleteAuditDetails(filePath string, sizeBytes int64, hashes []string, errors []string) string {
+	details, _ := json.Marshal(map[string]interface{}{
+		"file_path":  filePath,
+		"size_bytes": sizeBytes,
+		"torrents":   hashes,
+		"errors":     errors,
+	})
+	return string(details)
+}
+
+// torrentData is the part of a torrent row needed to decide whether its data can be deleted
+type torrentData struct {
+	hash        string
+	group       string
+	contentPath string
+}
+
+func loadTorrents(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]torrentData, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var torrents []torrentData
+	for rows.Next() {
+		var t torrentData
+		if err := rows.Scan(&t.hash, &t.group, &t.contentPath); err != nil {
+			return nil, err
+		}
+		torrents = append(torrents, t)
+	}
+	return torrents, rows.Err()
+}
+
+// loadKeptData loads every torrent and the library paths of every media item but exceptMediaID (0 for none),
+// and collects the data still in use once the torrents in remove are gone. The torrents loaded are returned too.
+func loadKeptData(ctx context.Context, db *sql.DB, remove map[string]bool, exceptMediaID int) (*keptData, []torrentData, error) {
+	all, err := loadTorrents(ctx, db, `
+		SELECT hash, COALESCE(cross_seed_group, ''), COALESCE(content_path, '')
+		FROM torrents
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load torrents: %w", err)
+	}
+	libraryPaths, err := loadLibraryPaths(ctx, db, exceptMediaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newKeptData(all, remove, libraryPaths), all, nil
+}
+
+// keptData is the data still in use after some torrents are removed: the cross-seed groups and content paths
+// of the torrents that stay, and library files
+type keptData struct {
+	groups map[string]bool
+	paths  []string
+}
+
+// newKeptData collects the data used by every torrent in all that isn't in remove, plus the library paths
+func newKeptData(all []torrentData, remove map[string]bool, libraryPaths []string) *keptData {
+	k := &keptData{groups: make(map[string]bool)}
+	for _, t := range all {
+		if remove[t.hash] {
+			continue
+		}
+		if t.group != "" {
+			k.groups[t.group] = true
+		}
+		if t.contentPath != "" {
+			k.paths = append(k.paths, t.contentPath)
+		}
+	}
+	for _, path := range libraryPaths {
+		if path != "" {
+			k.paths = append(k.paths, path)
+		}
+	}
+	return k
+}
+
+// uses reports whether a removed torrent's data is still in use, so it has to stay on disk
+func (k *keptData) uses(t torrentData) bool {
+	if t.group != "" && k.groups[t.group] {
+		return true
+	}
+	for _, path := range k.paths {
+		if pathsOverlap(t.contentPath, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathsOverlap reports whether one path is the same as or inside the other
+func pathsOverlap(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	sep := string(filepath.Separator)
+	return a == b || strings.HasPrefix(a, b+sep) || strings.HasPrefix(b, a+sep)
+}
+
 // deleteFiles deletes files from the filesystem
 // This is a critical step - files MUST be deleted from disk as per requirements
 func (s *DeletionService) deleteFiles(filePath string) error {
@@ -277,4 +593,3 @@ func (s *DeletionService) deleteFiles(filePath string) error {
 
 	return nil
 }
-
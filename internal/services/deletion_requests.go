@@ -0,0 +1,512 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Deletion request states
+// A request moves from requested to approved or rejected. Once the media item is eligible for deletion,
+// an approved request moves to executed when the media is deleted, or to scheduled when there is a grace period
+// and its deletion is scheduled; the scheduled deletion takes it from there
+const (
+	DeletionRequestRequested = "requested"
+	DeletionRequestApproved  = "approved"
+	DeletionRequestRejected  = "rejected"
+	DeletionRequestScheduled = "scheduled"
+	DeletionRequestExecuted  = "executed"
+)
+
+// approvedStep is what executing an approved request does
+type approvedStep int
+
+const (
+	approvedSkip         approvedStep = iota // no longer approved
+	approvedMarkExecuted                     // the media item was deleted some other way
+	approvedWait                             // the media item isn't eligible yet
+	approvedSchedule                         // delete after the grace period
+	approvedDelete
+)
+
+// nextApprovedStep decides what executing a deletion request does, from its status, whether its media item
+// still exists and is eligible, and the grace period
+func nextApprovedStep(status string, hasMedia, eligible bool, grace time.Duration) approvedStep {
+	switch {
+	case status != DeletionRequestApproved:
+		return approvedSkip
+	case !hasMedia:
+		return approvedMarkExecuted
+	case !eligible:
+		return approvedWait
+	case grace > 0:
+		return approvedSchedule
+	default:
+		return approvedDelete
+	}
+}
+
+// ErrInvalidTransition is returned when a deletion request is not in a state that allows the change
+var ErrInvalidTransition = errors.New("invalid deletion request state")
+
+// ErrOpenRequestExists is returned when a media item already has a pending or approved deletion request
+var ErrOpenRequestExists = errors.New("media item already has an open deletion request")
+
+// DeletionRequestService manages deletion requests: users flag media they are done with,
+// admins approve or reject them, and approved requests are executed once the media is eligible
+type DeletionRequestService struct {
+	db          *sql.DB
+	eligibility *EligibilityService
+	deletion    *DeletionService
+	scheduled   *ScheduledDeletionService
+}
+
+func NewDeletionRequestService(db *sql.DB, eligibility *EligibilityService, deletion *DeletionService, scheduled *ScheduledDeletionService) *DeletionRequestService {
+	return &DeletionRequestService{
+		db:          db,
+		eligibility: eligibility,
+		deletion:    deletion,
+		scheduled:   scheduled,
+	}
+}
+
+// DeletionRequest is a user's request to delete a media item
+type DeletionRequest struct {
+	ID                int        `json:"id"`
+	MediaItemID       *int       `json:"media_item_id,omitempty"` // nil once the media item is deleted
+	MediaTitle        string     `json:"media_title"`
+	MediaType         string     `json:"media_type"`
+	RequestedByUserID *int       `json:"requested_by_user_id,omitempty"`
+	RequestedBy       string     `json:"requested_by,omitempty"` // username
+	Status            string     `json:"status"`
+	Note              string     `json:"note,omitempty"`
+	ReviewedBy        string     `json:"reviewed_by,omitempty"` // username
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote        string     `json:"review_note,omitempty"`
+	ExecutedAt        *time.Time `json:"executed_at,omitempty"`
+	LastError         string     `json:"last_error,omitempty"` // why the last execution attempt failed
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// DeletionRequestFilter narrows List; zero values match everything
+type DeletionRequestFilter struct {
+	Status string
+	UserID int // requester
+}
+
+const deletionRequestColumns = `
+	d.id, d.media_item_id, d.media_title, d.media_type, d.requested_by_user_id, COALESCE(ru.username, ''),
+	d.status, COALESCE(d.note, ''), COALESCE(vu.username, ''), d.reviewed_at, COALESCE(d.review_note, ''),
+	d.executed_at, COALESCE(d.last_error, ''), d.created_at`
+
+const deletionRequestJoins = `
+	FROM deletion_requests d
+	LEFT JOIN users ru ON ru.id = d.requested_by_user_id
+	LEFT JOIN users vu ON vu.id = d.reviewed_by_user_id`
+
+// scanDeletionRequest scans a row selected with deletionRequestColumns
+func scanDeletionRequest(row interface{ Scan(...interface{}) error }) (*DeletionRequest, error) {
+	var (
+		request     DeletionRequest
+		mediaID     sql.NullInt64
+		requesterID sql.NullInt64
+		reviewedAt  sql.NullTime
+		executedAt  sql.NullTime
+		createdAt   sql.NullTime
+	)
+	if err := row.Scan(&request.ID, &mediaID, &request.MediaTitle, &request.MediaType, &requesterID,
+		&request.RequestedBy, &request.Status, &request.Note, &request.ReviewedBy, &reviewedAt,
+		&request.ReviewNote, &executedAt, &request.LastError, &createdAt); err != nil {
+		return nil, err
+	}
+
+	if mediaID.Valid {
+		id := int(mediaID.Int64)
+		request.MediaItemID = &id
+	}
+	if requesterID.Valid {
+		id := int(requesterID.Int64)
+		request.RequestedByUserID = &id
+	}
+	if reviewedAt.Valid {
+		request.ReviewedAt = &reviewedAt.Time
+	}
+	if executedAt.Valid {
+		request.ExecutedAt = &executedAt.Time
+	}
+	request.CreatedAt = createdAt.Time
+	return &request, nil
+}
+
+// Get returns a deletion request
+func (s *DeletionRequestService) Get(ctx context.Context, id int) (*DeletionRequest, error) {
+	request, err := scanDeletionRequest(s.db.QueryRowContext(ctx,
+		"SELECT "+deletionRequestColumns+deletionRequestJoins+" WHERE d.id = $1", id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("deletion request not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get deletion request: %w", err)
+	}
+	return request, nil
+}
+
+// List returns deletion requests, open ones first, then newest first
+func (s *DeletionRequestService) List(ctx context.Context, filter DeletionRequestFilter) ([]DeletionRequest, error) {
+	query := "SELECT " + deletionRequestColumns + deletionRequestJoins + " WHERE 1=1"
+	args := []interface{}{}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND d.status = $%d", len(args))
+	}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		query += fmt.Sprintf(" AND d.requested_by_user_id = $%d", len(args))
+	}
+	query += ` ORDER BY CASE d.status WHEN 'requested' THEN 0 WHEN 'approved' THEN 1 ELSE 2 END, d.created_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deletion requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []DeletionRequest{}
+	for rows.Next() {
+		request, err := scanDeletionRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deletion request: %w", err)
+		}
+		requests = append(requests, *request)
+	}
+	return requests, rows.Err()
+}
+
+// OpenRequestsByMedia returns the requested or approved deletion request of each media item, keyed by media item ID
+func (s *DeletionRequestService) OpenRequestsByMedia(ctx context.Context) (map[int]DeletionRequest, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+deletionRequestColumns+deletionRequestJoins+
+			" WHERE d.status IN ('requested', 'approved') AND d.media_item_id IS NOT NULL",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deletion requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := make(map[int]DeletionRequest)
+	for rows.Next() {
+		request, err := scanDeletionRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deletion request: %w", err)
+		}
+		requests[*request.MediaItemID] = *request
+	}
+	return requests, rows.Err()
+}
+
+// Create records a user's request to delete a media item
+// Protected items can't be requested, and a media item has at most one open request
+func (s *DeletionRequestService) Create(ctx context.Context, mediaID int, userID int, note string) (*DeletionRequest, error) {
+	if err := checkNotProtected(ctx, s.db, mediaID); err != nil {
+		return nil, err
+	}
+
+	var open bool
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM deletion_requests WHERE media_item_id = $1 AND status IN ('requested', 'approved'))`,
+		mediaID,
+	).Scan(&open); err != nil {
+		return nil, fmt.Errorf("failed to check deletion requests: %w", err)
+	}
+	if open {
+		return nil, ErrOpenRequestExists
+	}
+
+	var id int
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO deletion_requests (media_item_id, media_title, media_type, requested_by_user_id, note)
+		SELECT id, title, type, $2, NULLIF($3, '') FROM media_items WHERE id = $1
+		RETURNING id`,
+		mediaID, userID, strings.TrimSpace(note),
+	).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("media item not found: %d", mediaID)
+		}
+		return nil, fmt.Errorf("failed to create deletion request: %w", err)
+	}
+
+	s.audit(ctx, id, userID, "deletion_request", map[string]interface{}{"note": strings.TrimSpace(note)})
+	slog.InfoContext(ctx, "Created deletion request", "request_id", id, "media_id", mediaID, "user_id", userID)
+	return s.Get(ctx, id)
+}
+
+// Withdraw removes a deletion request that hasn't been reviewed yet
+func (s *DeletionRequestService) Withdraw(ctx context.Context, id int, userID int) error {
+	// Audit first, the row is gone afterwards
+	request, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if request.Status != DeletionRequestRequested {
+		return fmt.Errorf("%w: request is %s", ErrInvalidTransition, request.Status)
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM deletion_requests WHERE id = $1 AND status = 'requested'`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to withdraw deletion request: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: request was reviewed meanwhile", ErrInvalidTransition)
+	}
+
+	s.auditMedia(ctx, request, userID, "deletion_request_withdraw", map[string]interface{}{})
+	slog.InfoContext(ctx, "Withdrew deletion request", "request_id", id, "user_id", userID)
+	return nil
+}
+
+// Approve approves a requested deletion and executes it right away if the media item is eligible
+// Otherwise it is executed by a later ExecuteApproved, once the item becomes eligible
+// With a grace period, executing schedules the deletion instead, see execute
+func (s *DeletionRequestService) Approve(ctx context.Context, id int, adminID int, note string) error {
+	if err := s.review(ctx, id, adminID, DeletionRequestApproved, note); err != nil {
+		return err
+	}
+	if _, err := s.execute(ctx, id); err != nil {
+		slog.WarnContext(ctx, "Approved deletion request not executed yet", "request_id", id, "error", err)
+	}
+	return nil
+}
+
+// Reject rejects a requested deletion
+func (s *DeletionRequestService) Reject(ctx context.Context, id int, adminID int, note string) error {
+	return s.review(ctx, id, adminID, DeletionRequestRejected, note)
+}
+
+// review moves a requested deletion to approved or rejected
+func (s *DeletionRequestService) review(ctx context.Context, id int, adminID int, status string, note string) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE deletion_requests SET
+			status = $2,
+			reviewed_by_user_id = $3,
+			reviewed_at = CURRENT_TIMESTAMP,
+			review_note = NULLIF($4, ''),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'requested'`,
+		id, status, adminID, strings.TrimSpace(note),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to review deletion request: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		request, err := s.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: request is already %s", ErrInvalidTransition, request.Status)
+	}
+
+	action := "deletion_request_approve"
+	if status == DeletionRequestRejected {
+		action = "deletion_request_reject"
+	}
+	s.audit(ctx, id, adminID, action, map[string]interface{}{"note": strings.TrimSpace(note)})
+	slog.InfoContext(ctx, "Reviewed deletion request", "request_id", id, "status", status, "admin_id", adminID)
+	return nil
+}
+
+// ExecuteApproved deletes the media of every approved request whose media item is eligible
+// It is run after each periodic sync; returns how many requests were executed
+func (s *DeletionRequestService) ExecuteApproved(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id FROM deletion_requests WHERE status = 'approved' ORDER BY reviewed_at`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query approved deletion requests: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan deletion request: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query approved deletion requests: %w", err)
+	}
+
+	executed := 0
+	for _, id := range ids {
+		// Stop between deletions on shutdown; the rest are picked up by the next run
+		if err := ctx.Err(); err != nil {
+			return executed, err
+		}
+		done, err := s.execute(ctx, id)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to execute deletion request", "request_id", id, "error", err)
+		}
+		if done {
+			executed++
+		}
+	}
+	return executed, nil
+}
+
+// execute deletes an approved request's media item if it is eligible, and marks the request executed
+// Returns whether the request was executed; a media item that is not eligible yet is not an error
+// With a grace period, the deletion is scheduled instead so the media's requester gets a heads-up,
+// and the request is marked scheduled
+func (s *DeletionRequestService) execute(ctx context.Context, id int) (bool, error) {
+	var (
+		status     string
+		mediaID    sql.NullInt64
+		reviewerID sql.NullInt64
+	)
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT status, media_item_id, reviewed_by_user_id FROM deletion_requests WHERE id = $1`,
+		id,
+	).Scan(&status, &mediaID, &reviewerID); err != nil {
+		return false, fmt.Errorf("failed to get deletion request: %w", err)
+	}
+
+	eligible := false
+	if status == DeletionRequestApproved && mediaID.Valid {
+		eligibility, err := s.eligibility.CheckEligibility(ctx, int(mediaID.Int64))
+		if err != nil {
+			return false, s.recordError(ctx, id, err)
+		}
+		eligible = eligibility.IsEligible
+		if !eligible {
+			slog.DebugContext(ctx, "Approved deletion request waiting for eligibility", "request_id", id, "reason", eligibility.Reason)
+		}
+	}
+
+	var grace time.Duration
+	if eligible {
+		grace = s.scheduled.GracePeriod(ctx)
+	}
+
+	switch nextApprovedStep(status, mediaID.Valid, eligible, grace) {
+	case approvedSkip:
+		return false, fmt.Errorf("%w: request is %s", ErrInvalidTransition, status)
+	case approvedMarkExecuted:
+		return true, s.markExecuted(ctx, id, "")
+	case approvedWait:
+		return false, nil
+	case approvedSchedule:
+		// A deletion already scheduled for the media item, e.g. by an admin, covers the request too
+		_, err := s.scheduled.Schedule(ctx, int(mediaID.Int64), int(reviewerID.Int64), grace)
+		if err != nil && !errors.Is(err, ErrAlreadyScheduled) {
+			return false, s.recordError(ctx, id, err)
+		}
+		return false, s.markScheduled(ctx, id)
+	}
+
+	// The approving admin is recorded as the user who deleted the media
+	deleteErr := s.deletion.DeleteMediaItem(ctx, int(mediaID.Int64), int(reviewerID.Int64))
+	// Whatever the deletion got done is recorded, even if ctx was cancelled during it
+	ctx = context.WithoutCancel(ctx)
+
+	// DeleteMediaItem removes the media item even when some steps fail
+	var exists bool
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM media_items WHERE id = $1)`,
+		mediaID.Int64,
+	).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check media item: %w", err)
+	}
+	if exists {
+		return false, s.recordError(ctx, id, deleteErr)
+	}
+
+	lastError := ""
+	if deleteErr != nil {
+		lastError = deleteErr.Error()
+	}
+	if err := s.markExecuted(ctx, id, lastError); err != nil {
+		return true, err
+	}
+	slog.InfoContext(ctx, "Executed deletion request", "request_id", id, "media_id", mediaID.Int64)
+	return true, deleteErr
+}
+
+// markExecuted moves an approved request to executed
+func (s *DeletionRequestService) markExecuted(ctx context.Context, id int, lastError string) error {
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE deletion_requests SET
+			status = 'executed',
+			executed_at = CURRENT_TIMESTAMP,
+			last_error = NULLIF($2, ''),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'approved'`,
+		id, lastError,
+	); err != nil {
+		return fmt.Errorf("failed to mark deletion request executed: %w", err)
+	}
+	return nil
+}
+
+// markScheduled moves an approved request to scheduled, once its deletion is scheduled
+func (s *DeletionRequestService) markScheduled(ctx context.Context, id int) error {
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE deletion_requests SET
+			status = 'scheduled',
+			last_error = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'approved'`,
+		id,
+	); err != nil {
+		return fmt.Errorf("failed to mark deletion request scheduled: %w", err)
+	}
+	slog.InfoContext(ctx, "Scheduled deletion request", "request_id", id)
+	return nil
+}
+
+// recordError stores why executing a request failed, so admins can see it, and returns err
+func (s *DeletionRequestService) recordError(ctx context.Context, id int, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, dbErr := s.db.ExecContext(ctx,
+		`UPDATE deletion_requests SET last_error = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		id, err.Error(),
+	); dbErr != nil {
+		slog.ErrorContext(ctx, "Failed to record deletion request error", "request_id", id, "error", dbErr)
+	}
+	return err
+}
+
+// audit records a deletion request change in the audit log
+func (s *DeletionRequestService) audit(ctx context.Context, id int, userID int, action string, details map[string]interface{}) {
+	request, err := s.Get(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to log deletion request change", "request_id", id, "action", action, "error", err)
+		return
+	}
+	s.auditMedia(ctx, request, userID, action, details)
+}
+
+// auditMedia records a deletion request change in the audit log, for an already loaded request
+func (s *DeletionRequestService) auditMedia(ctx context.Context, request *DeletionRequest, userID int, action string, details map[string]interface{}) {
+	details["request_id"] = request.ID
+	detailsJSON, _ := json.Marshal(details)
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_logs (user_id, action, media_item_id, media_title, media_type, details)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		userID, action, request.MediaItemID, request.MediaTitle, request.MediaType, string(detailsJSON),
+	); err != nil {
+		slog.ErrorContext(ctx, "Failed to log deletion request change", "request_id", request.ID, "action", action, "error", err)
+	}
+}
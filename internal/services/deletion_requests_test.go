@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextApprovedStep(t *testing.T) {
+	grace := 72 * time.Hour
+	tests := []struct {
+		name     string
+		status   string
+		hasMedia bool
+		eligible bool
+		grace    time.Duration
+		want     approvedStep
+	}{
+		{"approved and eligible", DeletionRequestApproved, true, true, 0, approvedDelete},
+		{"approved with a grace period", DeletionRequestApproved, true, true, grace, approvedSchedule},
+		{"approved, not eligible yet", DeletionRequestApproved, true, false, grace, approvedWait},
+		{"approved, media deleted some other way", DeletionRequestApproved, false, false, grace, approvedMarkExecuted},
+		{"awaiting review", DeletionRequestRequested, true, true, 0, approvedSkip},
+		{"rejected", DeletionRequestRejected, true, true, 0, approvedSkip},
+		// Once its deletion is scheduled a request is done, it isn't scheduled again on the next run
+		{"scheduled", DeletionRequestScheduled, true, true, grace, approvedSkip},
+		{"executed", DeletionRequestExecuted, false, false, 0, approvedSkip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextApprovedStep(tt.status, tt.hasMedia, tt.eligible, tt.grace); got != tt.want {
+				t.Errorf("nextApprovedStep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
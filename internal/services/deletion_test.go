@@ -0,0 +1,143 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestKeptDataUses(t *testing.T) {
+	all := []torrentData{
+		{hash: "a", group: "g1", contentPath: "/data/torrents/Movie (2020)"},
+		{hash: "b", group: "g1", contentPath: "/data/cross/Movie (2020)"},
+		{hash: "c", contentPath: "/data/torrents/Show S01"},
+		{hash: "d", contentPath: "/data/torrents/Other"},
+		{hash: "e", contentPath: "/data/torrents/Linked"},
+	}
+	libraryPaths := []string{"/data/torrents/Linked/Linked.mkv", ""}
+
+	tests := []struct {
+		name   string
+		remove []string
+		hash   string
+		want   bool
+	}{
+		{"cross-seed kept", []string{"a"}, "a", true},
+		{"whole group removed", []string{"a", "b"}, "a", false},
+		{"own data", []string{"c"}, "c", false},
+		{"library file inside", []string{"e"}, "e", true},
+		{"nothing else uses it", []string{"d"}, "d", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remove := make(map[string]bool)
+			for _, hash := range tt.remove {
+				remove[hash] = true
+			}
+			kept := newKeptData(all, remove, libraryPaths)
+
+			var torrent torrentData
+			for _, candidate := range all {
+				if candidate.hash == tt.hash {
+					torrent = candidate
+				}
+			}
+			if got := kept.uses(torrent); got != tt.want {
+				t.Errorf("uses(%s) = %v, want %v", tt.hash, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeptDataUsesSharedPath(t *testing.T) {
+	// Same content path without a cross-seed group yet, e.g. before the next sync groups them
+	all := []torrentData{
+		{hash: "a", contentPath: "/data/torrents/Movie"},
+		{hash: "b", contentPath: "/data/torrents/Movie/"},
+	}
+	kept := newKeptData(all, map[string]bool{"a": true}, nil)
+	if !kept.uses(all[0]) {
+		t.Error("data shared with a kept torrent would be deleted")
+	}
+}
+
+func TestPathsOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"/data/Movie", "/data/Movie", true},
+		{"/data/Movie/", "/data/Movie", true},
+		{"/data/Movie", "/data/Movie/Movie.mkv", true},
+		{"/data/Movie/Movie.mkv", "/data/Movie", true},
+		{"/data/Movie", "/data/Movie 2", false},
+		{"/data/Movie", "/data/Other", false},
+		{"", "/data/Movie", false},
+		{"/data/Movie", "", false},
+	}
+	for _, tt := range tests {
+		if got := pathsOverlap(tt.a, tt.b); got != tt.want {
+			t.Errorf("pathsOverlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteMediaItemDataDecision(t *testing.T) {
+	// Media item 1 owns a and c; b cross-seeds a but is linked to media item 2, and media item 3's
+	// library file sits inside c's content path. d is an unlinked cross-seed of e, which media item 1 also owns.
+	all := []torrentData{
+		{hash: "a", group: "g1", contentPath: "/data/torrents/Movie (2020)"},
+		{hash: "b", group: "g1", contentPath: "/data/cross/Movie (2020)"},
+		{hash: "c", contentPath: "/data/torrents/Collection"},
+		{hash: "d", group: "g2", contentPath: "/data/cross/Show S01"},
+		{hash: "e", group: "g2", contentPath: "/data/torrents/Show S01"},
+	}
+	remove := map[string]bool{"a": true, "c": true, "d": true, "e": true}
+	libraryPaths := []string{"/data/torrents/Collection/Other (2019).mkv"}
+	kept := newKeptData(all, remove, libraryPaths)
+
+	tests := []struct {
+		hash       string
+		deleteData bool
+	}{
+		{"a", false}, // b is linked to another media item and still seeds it
+		{"c", false}, // another media item's library file
+		{"d", true},
+		{"e", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			var torrent torrentData
+			for _, candidate := range all {
+				if candidate.hash == tt.hash {
+					torrent = candidate
+				}
+			}
+			if got := !kept.uses(torrent); got != tt.deleteData {
+				t.Errorf("delete data of %s = %v, want %v", tt.hash, got, tt.deleteData)
+			}
+		})
+	}
+}
+
+func TestDeleteAuditDetails(t *testing.T) {
+	details := deleteAuditDetails("/movies/Movie.mkv", 1234, []string{"a", "b"}, nil)
+
+	// The statistics page reads size_bytes with details->>'size_bytes', so it must be a top-level number
+	var got struct {
+		FilePath  string   `json:"file_path"`
+		SizeBytes *int64   `json:"size_bytes"`
+		Torrents  []string `json:"torrents"`
+	}
+	if err := json.Unmarshal([]byte(details), &got); err != nil {
+		t.Fatalf("details are not a JSON object: %v", err)
+	}
+	if got.SizeBytes == nil || *got.SizeBytes != 1234 {
+		t.Errorf("size_bytes = %v, want 1234", got.SizeBytes)
+	}
+	if got.FilePath != "/movies/Movie.mkv" {
+		t.Errorf("file_path = %q, want /movies/Movie.mkv", got.FilePath)
+	}
+	if len(got.Torrents) != 2 || got.Torrents[0] != "a" || got.Torrents[1] != "b" {
+		t.Errorf("torrents = %v, want [a b]", got.Torrents)
+	}
+}
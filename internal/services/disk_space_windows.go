@@ -0,0 +1,10 @@
+//go:build windows
+
+package services
+
+import "errors"
+
+// diskSpace is not implemented on Windows; snapshots are recorded without disk usage
+func diskSpace(path string) (total, free uint64, err error) {
+	return 0, 0, errors.New("disk space is not supported on windows")
+}
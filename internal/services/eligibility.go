@@ -6,119 +6,223 @@ import (
 	"fmt"
 	"time"
 
+	"removarr/internal/events"
 	"removarr/internal/integrations"
 )
 
 type EligibilityService struct {
-	db          *sql.DB
+	db           *sql.DB
 	integrations *integrations.Client
+	events       *events.Bus
 }
 
 type EligibilityStatus struct {
-	IsEligible      bool
-	Reason          string
-	SeedingTime     int64  // in seconds
-	RequiredTime    *int64 // in seconds, nil means infinite
-	SeedingRatio    float64
-	RequiredRatio   *float64
-	TrackerType     string // "public" or "private"
-	IsSeeding       bool
-	LastWatched     *time.Time
-	PlayCount       int
-}
-
-func NewEligibilityService(db *sql.DB, integrationsClient *integrations.Client) *EligibilityService {
+	IsEligible    bool
+	Reason        string
+	SeedingTime   int64  // in seconds
+	RequiredTime  *int64 // in seconds, nil means infinite
+	SeedingRatio  float64
+	RequiredRatio *float64
+	TrackerType   string // "public" or "private"
+	IsSeeding     bool
+	Protection    *Protection // nil unless protected; protected items are never eligible
+	LastWatched   *time.Time
+	PlayCount     int
+	Torrents      []TorrentStatus // one entry per torrent, cross-seeds included
+	// EstimatedEligibleAt is when the last blocking torrent should meet its requirements
+	// nil if already eligible or if it can't be estimated
+	EstimatedEligibleAt *time.Time
+}
+
+// TorrentStatus is the eligibility of a single torrent of a media item
+type TorrentStatus struct {
+	Hash           string   `json:"hash"`
+	Name           string   `json:"name"`
+	TrackerName    string   `json:"tracker_name"`
+	TrackerType    string   `json:"tracker_type"`
+	CrossSeedGroup string   `json:"cross_seed_group,omitempty"`
+	SeedingTime    int64    `json:"seeding_time"`            // in seconds
+	RequiredTime   *int64   `json:"required_time,omitempty"` // in seconds, nil means none (infinite for private trackers)
+	SeedingRatio   float64  `json:"seeding_ratio"`
+	RequiredRatio  *float64 `json:"required_ratio,omitempty"`
+	IsSeeding      bool     `json:"is_seeding"`
+	IsEligible     bool     `json:"is_eligible"`
+	Reason         string   `json:"reason"`
+	// EstimatedEligibleAt is nil if the torrent is already eligible or the date can't be estimated
+	EstimatedEligibleAt *time.Time `json:"estimated_eligible_at,omitempty"`
+}
+
+// TorrentGroup is a set of torrents sharing the same data (cross-seeds)
+// A torrent that is not cross-seeded forms a group of its own
+type TorrentGroup struct {
+	ID            string
+	Torrents      []TorrentStatus
+	EligibleCount int
+}
+
+// torrentRecord is a torrent row as needed for eligibility checks
+type torrentRecord struct {
+	Hash           string
+	Name           string
+	TrackerID      *int
+	TrackerName    *string
+	TrackerType    *string
+	SeedingTime    int64
+	Ratio          float64
+	RequiredTime   *int64
+	RequiredRatio  *float64
+	IsSeeding      bool
+	CrossSeedGroup string
+	AddedDate      *time.Time
+	UploadBytes    int64
+	DownloadBytes  int64
+	SizeBytes      int64
+	UploadRate     *float64 // bytes per second, nil until observed over two syncs
+}
+
+func NewEligibilityService(db *sql.DB, integrationsClient *integrations.Client, bus *events.Bus) *EligibilityService {
 	return &EligibilityService{
-		db:          db,
+		db:           db,
 		integrations: integrationsClient,
+		events:       bus,
 	}
 }
 
 // CheckEligibility determines if a media item is eligible for deletion
 func (s *EligibilityService) CheckEligibility(ctx context.Context, mediaItemID int) (*EligibilityStatus, error) {
-	status := &EligibilityStatus{
-		IsEligible: false,
+	statuses, err := s.CheckEligibilities(ctx, []int{mediaItemID})
+	if err != nil {
+		return nil, err
 	}
+	status, ok := statuses[mediaItemID]
+	if !ok {
+		return nil, fmt.Errorf("media item not found: %d", mediaItemID)
+	}
+	return status, nil
+}
 
-	// Get media item
-	var mediaItem struct {
-		ID       int
-		Type     string
-		SonarrID *int
-		RadarrID *int
-		FilePath string
+// CheckEligibilities determines the eligibility of several media items, keyed by media item ID
+// Protection, watch history and torrents are each loaded for every item in one query, so listing many items
+// costs three queries rather than several per item. Items that don't exist are left out.
+func (s *EligibilityService) CheckEligibilities(ctx context.Context, mediaItemIDs []int) (map[int]*EligibilityStatus, error) {
+	protections, err := loadProtections(ctx, s.db, mediaItemIDs)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make(map[int]*EligibilityStatus, len(protections))
+	for id := range protections {
+		statuses[id] = &EligibilityStatus{}
 	}
 
-	err := s.db.QueryRowContext(ctx,
-		"SELECT id, type, sonarr_id, radarr_id, file_path FROM media_items WHERE id = $1",
-		mediaItemID,
-	).Scan(&mediaItem.ID, &mediaItem.Type, &mediaItem.SonarrID, &mediaItem.RadarrID, &mediaItem.FilePath)
+	// Watch history across all users, synced from Tautulli
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT media_item_id, MAX(last_watched_at), COALESCE(SUM(play_count), 0)
+		FROM tautulli_history
+		WHERE media_item_id = ANY($1)
+		GROUP BY media_item_id`,
+		mediaItemIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watch history: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, playCount int
+		var lastWatched sql.NullTime
+		if err := rows.Scan(&id, &lastWatched, &playCount); err != nil {
+			return nil, fmt.Errorf("failed to query watch history: %w", err)
+		}
+		status, ok := statuses[id]
+		if !ok {
+			continue
+		}
+		status.PlayCount = playCount
+		if lastWatched.Valid {
+			status.LastWatched = &lastWatched.Time
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query watch history: %w", err)
+	}
 
+	torrents, err := s.loadTorrentRecords(ctx, mediaItemIDs)
 	if err != nil {
-		return nil, fmt.Errorf("media item not found: %w", err)
+		return nil, err
 	}
 
-	// Get all torrents for this media item
+	now := time.Now()
+	for id, status := range statuses {
+		s.applyTorrents(status, torrents[id], now)
+		applyProtection(status, protections[id])
+	}
+	return statuses, nil
+}
+
+// loadTorrentRecords loads the torrents of the given media items, keyed by media item ID,
+// leaving out the ones gone from qBittorrent
+func (s *EligibilityService) loadTorrentRecords(ctx context.Context, mediaItemIDs []int) (map[int][]torrentRecord, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT hash, tracker_id, tracker_name, tracker_type, 
-			seeding_time_seconds, ratio, seeding_required_seconds, 
-			seeding_required_ratio, is_seeding
-		FROM torrents WHERE media_item_id = $1`,
-		mediaItemID,
+		`SELECT media_item_id, hash, name, tracker_id, tracker_name, tracker_type,
+			seeding_time_seconds, ratio, seeding_required_seconds,
+			seeding_required_ratio, is_seeding, cross_seed_group,
+			added_date, upload_bytes, download_bytes, size_bytes, upload_rate
+		FROM torrents WHERE media_item_id = ANY($1) AND missing_since IS NULL
+		ORDER BY media_item_id, cross_seed_group NULLS LAST, hash`,
+		mediaItemIDs,
 	)
-	if err != nil && err != sql.ErrNoRows {
+	if err != nil {
 		return nil, fmt.Errorf("failed to query torrents: %w", err)
 	}
 	defer rows.Close()
 
-	var torrents []struct {
-		Hash                string
-		TrackerID           *int
-		TrackerName         *string
-		TrackerType         *string
-		SeedingTime         int64
-		Ratio               float64
-		RequiredTime        *int64
-		RequiredRatio       *float64
-		IsSeeding           bool
-	}
+	torrents := make(map[int][]torrentRecord)
 
 	for rows.Next() {
 		var t struct {
-			Hash                string
-			TrackerID           sql.NullInt64
-			TrackerName         sql.NullString
-			TrackerType         sql.NullString
-			SeedingTime         int64
-			Ratio               sql.NullFloat64 // Use NullFloat64 to handle NUMERIC properly
-			RequiredTime        sql.NullInt64
-			RequiredRatio       sql.NullFloat64
-			IsSeeding           bool
-		}
-
-		err := rows.Scan(&t.Hash, &t.TrackerID, &t.TrackerName, &t.TrackerType,
-			&t.SeedingTime, &t.Ratio, &t.RequiredTime, &t.RequiredRatio, &t.IsSeeding)
+			MediaItemID    int
+			Hash           string
+			Name           sql.NullString
+			TrackerID      sql.NullInt64
+			TrackerName    sql.NullString
+			TrackerType    sql.NullString
+			SeedingTime    int64
+			Ratio          sql.NullFloat64 // Use NullFloat64 to handle NUMERIC properly
+			RequiredTime   sql.NullInt64
+			RequiredRatio  sql.NullFloat64
+			IsSeeding      bool
+			CrossSeedGroup sql.NullString
+			AddedDate      sql.NullTime
+			UploadBytes    sql.NullInt64
+			DownloadBytes  sql.NullInt64
+			SizeBytes      sql.NullInt64
+			UploadRate     sql.NullFloat64
+		}
+
+		err := rows.Scan(&t.MediaItemID, &t.Hash, &t.Name, &t.TrackerID, &t.TrackerName, &t.TrackerType,
+			&t.SeedingTime, &t.Ratio, &t.RequiredTime, &t.RequiredRatio, &t.IsSeeding, &t.CrossSeedGroup,
+			&t.AddedDate, &t.UploadBytes, &t.DownloadBytes, &t.SizeBytes, &t.UploadRate)
 		if err != nil {
 			continue
 		}
+		torrent := torrentRecord{
+			Hash:           t.Hash,
+			Name:           t.Name.String,
+			SeedingTime:    t.SeedingTime,
+			Ratio:          0.0, // Default to 0 if not valid
+			IsSeeding:      t.IsSeeding,
+			CrossSeedGroup: t.CrossSeedGroup.String,
+			UploadBytes:    t.UploadBytes.Int64,
+			DownloadBytes:  t.DownloadBytes.Int64,
+			SizeBytes:      t.SizeBytes.Int64,
+		}
+		if t.AddedDate.Valid {
+			torrent.AddedDate = &t.AddedDate.Time
+		}
+		if t.UploadRate.Valid {
+			rate := t.UploadRate.Float64
+			torrent.UploadRate = &rate
+		}
 
-		torrent := struct {
-			Hash                string
-			TrackerID           *int
-			TrackerName         *string
-			TrackerType         *string
-			SeedingTime         int64
-			Ratio               float64
-			RequiredTime        *int64
-			RequiredRatio       *float64
-			IsSeeding           bool
-		}{
-			Hash:        t.Hash,
-			SeedingTime: t.SeedingTime,
-			Ratio:       0.0, // Default to 0 if not valid
-			IsSeeding:   t.IsSeeding,
-		}
-		
 		// Convert ratio from NullFloat64
 		if t.Ratio.Valid {
 			torrent.Ratio = t.Ratio.Float64
@@ -143,22 +247,59 @@ func (s *EligibilityService) CheckEligibility(ctx context.Context, mediaItemID i
 			torrent.RequiredRatio = &rr
 		}
 
-		torrents = append(torrents, torrent)
+		torrents[t.MediaItemID] = append(torrents[t.MediaItemID], torrent)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query torrents: %w", err)
+	}
+	return torrents, nil
+}
 
+// applyTorrents works out a media item's eligibility from its torrents
+func (s *EligibilityService) applyTorrents(status *EligibilityStatus, torrents []torrentRecord, now time.Time) {
 	if len(torrents) == 0 {
 		status.Reason = "No torrents found for this media item"
-		return status, nil
+		return
 	}
 
 	// Check each torrent's eligibility
+	// Every torrent (including each cross-seed) must be eligible for the media item to be eligible
 	allEligible := true
+	estimable := true
 	for _, torrent := range torrents {
 		torrentEligible, reason := s.checkTorrentEligibility(torrent)
+
+		var estimate *time.Time
 		if !torrentEligible {
+			estimate = estimateEligibleAt(torrent, now)
+			if estimate == nil {
+				estimable = false
+			} else if status.EstimatedEligibleAt == nil || estimate.After(*status.EstimatedEligibleAt) {
+				status.EstimatedEligibleAt = estimate
+			}
+		}
+
+		status.Torrents = append(status.Torrents, TorrentStatus{
+			Hash:                torrent.Hash,
+			Name:                torrent.Name,
+			TrackerName:         torrentTrackerName(torrent),
+			TrackerType:         torrentTrackerType(torrent),
+			CrossSeedGroup:      torrent.CrossSeedGroup,
+			SeedingTime:         torrent.SeedingTime,
+			RequiredTime:        torrent.RequiredTime,
+			SeedingRatio:        torrent.Ratio,
+			RequiredRatio:       torrent.RequiredRatio,
+			IsSeeding:           torrent.IsSeeding,
+			IsEligible:          torrentEligible,
+			Reason:              reason,
+			EstimatedEligibleAt: estimate,
+		})
+		if !torrentEligible && allEligible {
 			allEligible = false
 			status.Reason = reason
-			break
+			if len(torrents) > 1 {
+				status.Reason = fmt.Sprintf("%s: %s", torrentTrackerName(torrent), reason)
+			}
 		}
 	}
 
@@ -166,19 +307,22 @@ func (s *EligibilityService) CheckEligibility(ctx context.Context, mediaItemID i
 	if allEligible {
 		status.Reason = "All seeding requirements met"
 	}
+	if !estimable {
+		status.EstimatedEligibleAt = nil
+	}
 
 	// Use the torrent with the highest seeding time (most active) for display
 	if len(torrents) > 0 {
 		var maxSeedingTime int64 = 0
 		var maxSeedingIdx int = 0
-		
+
 		for i, t := range torrents {
 			if t.SeedingTime > maxSeedingTime {
 				maxSeedingTime = t.SeedingTime
 				maxSeedingIdx = i
 			}
 		}
-		
+
 		// Use the torrent with max seeding time for display
 		t := torrents[maxSeedingIdx]
 		status.SeedingTime = t.SeedingTime
@@ -195,21 +339,132 @@ func (s *EligibilityService) CheckEligibility(ctx context.Context, mediaItemID i
 		}
 		status.IsSeeding = t.IsSeeding
 	}
+}
 
-	return status, nil
+// applyProtection makes a protected item ineligible, whatever its torrents' state
+// Torrent statuses are kept, so finished cross-seeds are still shown as such
+func applyProtection(status *EligibilityStatus, protection *Protection) {
+	if !protection.IsProtected {
+		return
+	}
+	status.Protection = protection
+	status.IsEligible = false
+	status.Reason = protection.Reason()
+	status.EstimatedEligibleAt = nil
 }
 
-func (s *EligibilityService) checkTorrentEligibility(torrent struct {
-	Hash                string
-	TrackerID           *int
-	TrackerName         *string
-	TrackerType         *string
-	SeedingTime         int64
-	Ratio               float64
-	RequiredTime        *int64
-	RequiredRatio       *float64
-	IsSeeding           bool
-}) (bool, string) {
+// EligibleTorrentCount returns how many of the media item's torrents are eligible
+func (s *EligibilityStatus) EligibleTorrentCount() int {
+	count := 0
+	for _, t := range s.Torrents {
+		if t.IsEligible {
+			count++
+		}
+	}
+	return count
+}
+
+// TorrentGroups returns the torrents grouped by the data they share
+// Cross-seed groups come first, in the order their torrents were returned
+func (s *EligibilityStatus) TorrentGroups() []TorrentGroup {
+	var groups []TorrentGroup
+	index := make(map[string]int)
+
+	for _, t := range s.Torrents {
+		key := t.CrossSeedGroup
+		if key == "" {
+			key = t.Hash
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, TorrentGroup{ID: key})
+		}
+
+		groups[i].Torrents = append(groups[i].Torrents, t)
+		if t.IsEligible {
+			groups[i].EligibleCount++
+		}
+	}
+
+	return groups
+}
+
+// maxEstimate caps estimates; anything further out is reported as unknown
+const maxEstimate = 10 * 365 * 24 * time.Hour
+
+// estimateEligibleAt estimates when a torrent that is not eligible yet will meet its requirements
+// Seeding time is extrapolated from the share of time since the torrent was added that it spent seeding,
+// ratio from the upload rate observed between syncs.
+// Returns nil if the torrent is not seeding, requires infinite seeding, or has no observed upload rate
+// while still short of its ratio.
+func estimateEligibleAt(torrent torrentRecord, now time.Time) *time.Time {
+	if !torrent.IsSeeding {
+		return nil
+	}
+	isPublic := torrent.TrackerType != nil && *torrent.TrackerType == "public"
+	if torrent.RequiredTime == nil && !isPublic {
+		return nil
+	}
+
+	var wait time.Duration
+
+	if torrent.RequiredTime != nil && torrent.SeedingTime < *torrent.RequiredTime {
+		remaining := float64(*torrent.RequiredTime - torrent.SeedingTime)
+		share := 1.0
+		if torrent.AddedDate != nil && torrent.SeedingTime > 0 {
+			if elapsed := now.Sub(*torrent.AddedDate).Seconds(); elapsed > float64(torrent.SeedingTime) {
+				share = float64(torrent.SeedingTime) / elapsed
+			}
+		}
+		if remaining/share > maxEstimate.Seconds() {
+			return nil
+		}
+		wait = time.Duration(remaining / share * float64(time.Second))
+	}
+
+	if torrent.RequiredRatio != nil && torrent.Ratio < *torrent.RequiredRatio {
+		// qBittorrent's ratio is uploaded / downloaded, or uploaded / size if nothing was downloaded
+		base := torrent.DownloadBytes
+		if base == 0 {
+			base = torrent.SizeBytes
+		}
+		remaining := *torrent.RequiredRatio*float64(base) - float64(torrent.UploadBytes)
+		if remaining > 0 {
+			if torrent.UploadRate == nil || *torrent.UploadRate <= 0 {
+				return nil
+			}
+			seconds := remaining / *torrent.UploadRate
+			if seconds > maxEstimate.Seconds() {
+				return nil
+			}
+			if ratioWait := time.Duration(seconds * float64(time.Second)); ratioWait > wait {
+				wait = ratioWait
+			}
+		}
+	}
+
+	at := now.Add(wait)
+	return &at
+}
+
+func torrentTrackerName(torrent torrentRecord) string {
+	if torrent.TrackerName != nil && *torrent.TrackerName != "" {
+		return *torrent.TrackerName
+	}
+	return "unknown"
+}
+
+func torrentTrackerType(torrent torrentRecord) string {
+	if torrent.TrackerType != nil {
+		return *torrent.TrackerType
+	}
+	return "unknown"
+}
+
+func (s *EligibilityService) checkTorrentEligibility(torrent torrentRecord) (bool, string) {
 	// Public trackers: eligible by default (unless overridden)
 	if torrent.TrackerType != nil && *torrent.TrackerType == "public" {
 		// Check if there's an override
@@ -250,3 +505,145 @@ func (s *EligibilityService) checkTorrentEligibility(torrent struct {
 	return true, "All requirements met"
 }
 
+// estimateTolerance is how far a stored eligibility estimate may drift before it is written again
+// Estimates are extrapolated on every check, so they move a little even when nothing changed.
+const estimateTolerance = time.Minute
+
+// mediaEligibility is a media item's stored eligibility, as RecordTransitions last wrote it
+type mediaEligibility struct {
+	id          int
+	title       string
+	mediaType   string
+	requesterID sql.NullInt64
+	wasEligible bool
+	estimate    *time.Time
+}
+
+// eligibilityWrite is a media item whose stored eligibility or estimate changed
+type eligibilityWrite struct {
+	id       int
+	eligible bool
+	estimate *time.Time
+}
+
+// eligibilityChanges compares the stored eligibility of items with their current statuses
+// Returns the items that became eligible, to announce, and the rows to write.
+// Items missing from statuses were deleted since they were listed, and are left alone.
+func eligibilityChanges(items []mediaEligibility, statuses map[int]*EligibilityStatus) ([]mediaEligibility, []eligibilityWrite) {
+	var eligible []mediaEligibility
+	var writes []eligibilityWrite
+	for _, item := range items {
+		status, ok := statuses[item.id]
+		if !ok {
+			continue
+		}
+		var estimate *time.Time
+		if !status.IsEligible {
+			estimate = status.EstimatedEligibleAt
+		}
+		if status.IsEligible == item.wasEligible && sameEstimate(item.estimate, estimate) {
+			continue
+		}
+		if status.IsEligible && !item.wasEligible {
+			eligible = append(eligible, item)
+		}
+		writes = append(writes, eligibilityWrite{id: item.id, eligible: status.IsEligible, estimate: estimate})
+	}
+	return eligible, writes
+}
+
+// sameEstimate reports whether two estimates are within estimateTolerance, or both unknown
+func sameEstimate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	diff := a.Sub(*b)
+	return diff > -estimateTolerance && diff < estimateTolerance
+}
+
+// RecordTransitions checks every media item's eligibility and publishes a MediaEligible event
+// for each item that became eligible since the last check
+// eligible_since remembers the state, so an item is announced once per transition. The estimate is stored
+// next to it, for the dashboard to sort and filter by. Returns the statuses, keyed by media item ID.
+func (s *EligibilityService) RecordTransitions(ctx context.Context) (map[int]*EligibilityStatus, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, title, type, requested_by_user_id, eligible_since IS NOT NULL, estimated_eligible_at
+		FROM media_items WHERE missing_since IS NULL`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media items: %w", err)
+	}
+
+	var items []mediaEligibility
+	for rows.Next() {
+		var item mediaEligibility
+		var estimate sql.NullTime
+		if err := rows.Scan(&item.id, &item.title, &item.mediaType, &item.requesterID, &item.wasEligible, &estimate); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan media item: %w", err)
+		}
+		if estimate.Valid {
+			item.estimate = &estimate.Time
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query media items: %w", err)
+	}
+
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.id
+	}
+	statuses, err := s.CheckEligibilities(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check eligibility: %w", err)
+	}
+
+	eligible, writes := eligibilityChanges(items, statuses)
+	if len(writes) == 0 {
+		return statuses, nil
+	}
+
+	// Missing estimates are sent as the zero time with has_estimate false, and written as NULL
+	writeIDs := make([]int, len(writes))
+	eligibleFlags := make([]bool, len(writes))
+	hasEstimates := make([]bool, len(writes))
+	estimates := make([]time.Time, len(writes))
+	for i, w := range writes {
+		writeIDs[i] = w.id
+		eligibleFlags[i] = w.eligible
+		if w.estimate != nil {
+			hasEstimates[i] = true
+			estimates[i] = *w.estimate
+		}
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE media_items m SET
+			eligible_since = CASE WHEN u.eligible THEN COALESCE(m.eligible_since, CURRENT_TIMESTAMP) END,
+			estimated_eligible_at = CASE WHEN u.has_estimate THEN u.estimate END
+		FROM UNNEST($1::int[], $2::bool[], $3::bool[], $4::timestamp[]) AS u(id, eligible, has_estimate, estimate)
+		WHERE m.id = u.id`,
+		writeIDs, eligibleFlags, hasEstimates, estimates,
+	); err != nil {
+		return nil, fmt.Errorf("failed to record eligibility: %w", err)
+	}
+
+	for _, item := range eligible {
+		id := item.id
+		event := events.Event{
+			Type:        events.MediaEligible,
+			MediaItemID: &id,
+			MediaTitle:  item.title,
+			MediaType:   item.mediaType,
+		}
+		if item.requesterID.Valid {
+			requesterID := int(item.requesterID.Int64)
+			event.RequesterID = &requesterID
+		}
+		s.events.Publish(ctx, event)
+	}
+
+	return statuses, nil
+}
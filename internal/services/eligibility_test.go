@@ -0,0 +1,186 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateEligibleAt(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	public, private := "public", "private"
+	int64p := func(v int64) *int64 { return &v }
+	float64p := func(v float64) *float64 { return &v }
+	timep := func(v time.Time) *time.Time { return &v }
+
+	tests := []struct {
+		name    string
+		torrent torrentRecord
+		want    *time.Duration // wait from now, nil for never
+	}{
+		{
+			"time left, seeding all along",
+			torrentRecord{IsSeeding: true, TrackerType: &private, RequiredTime: int64p(7200), SeedingTime: 3600},
+			durationp(time.Hour),
+		},
+		{
+			"time left, seeding half the time since added",
+			torrentRecord{IsSeeding: true, TrackerType: &private, RequiredTime: int64p(7200), SeedingTime: 3600,
+				AddedDate: timep(now.Add(-2 * time.Hour))},
+			durationp(2 * time.Hour),
+		},
+		{
+			"ratio left at the current upload rate",
+			torrentRecord{IsSeeding: true, TrackerType: &public, RequiredRatio: float64p(1), Ratio: 0.5,
+				DownloadBytes: 1000, UploadBytes: 500, UploadRate: float64p(10)},
+			durationp(50 * time.Second),
+		},
+		{
+			"ratio of a torrent nothing was downloaded for is against its size",
+			torrentRecord{IsSeeding: true, TrackerType: &public, RequiredRatio: float64p(1), Ratio: 0.5,
+				SizeBytes: 2000, UploadBytes: 1000, UploadRate: float64p(10)},
+			durationp(100 * time.Second),
+		},
+		{
+			"time and ratio left, the longer wait wins",
+			torrentRecord{IsSeeding: true, TrackerType: &private, RequiredTime: int64p(7200), SeedingTime: 7100,
+				RequiredRatio: float64p(1), Ratio: 0.5, DownloadBytes: 1000, UploadBytes: 500, UploadRate: float64p(1)},
+			durationp(500 * time.Second),
+		},
+		{
+			"requirements met",
+			torrentRecord{IsSeeding: true, TrackerType: &private, RequiredTime: int64p(3600), SeedingTime: 7200,
+				RequiredRatio: float64p(1), Ratio: 1.5},
+			durationp(0),
+		},
+		{
+			"public tracker without requirements",
+			torrentRecord{IsSeeding: true, TrackerType: &public},
+			durationp(0),
+		},
+		{
+			"not seeding",
+			torrentRecord{TrackerType: &private, RequiredTime: int64p(7200), SeedingTime: 3600},
+			nil,
+		},
+		{
+			"private tracker without a required time",
+			torrentRecord{IsSeeding: true, TrackerType: &private, RequiredRatio: float64p(1)},
+			nil,
+		},
+		{
+			"ratio left without upload",
+			torrentRecord{IsSeeding: true, TrackerType: &public, RequiredRatio: float64p(1), Ratio: 0.5,
+				DownloadBytes: 1000, UploadBytes: 500, UploadRate: float64p(0)},
+			nil,
+		},
+		{
+			"ratio left beyond the estimate cap",
+			torrentRecord{IsSeeding: true, TrackerType: &public, RequiredRatio: float64p(2), Ratio: 0,
+				DownloadBytes: 1 << 40, UploadRate: float64p(1)},
+			nil,
+		},
+		{
+			// Seeding one second a day needs centuries for the two days left
+			"time left beyond the estimate cap",
+			torrentRecord{IsSeeding: true, TrackerType: &private, RequiredTime: int64p(2 * 24 * 3600), SeedingTime: 1,
+				AddedDate: timep(now.Add(-24 * time.Hour))},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := estimateEligibleAt(tt.torrent, now)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("estimateEligibleAt() = %s, want never", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("estimateEligibleAt() = never, want %s from now", *tt.want)
+			}
+			if want := now.Add(*tt.want); !got.Equal(want) {
+				t.Errorf("estimateEligibleAt() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func durationp(d time.Duration) *time.Duration {
+	return &d
+}
+
+func TestEligibilityChanges(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	soon := now.Add(time.Hour)
+	later := now.Add(48 * time.Hour)
+	drifted := soon.Add(time.Second)
+
+	items := []mediaEligibility{
+		{id: 1, title: "Became eligible", estimate: &soon},
+		{id: 2, title: "Still eligible", wasEligible: true},
+		{id: 3, title: "No longer eligible", wasEligible: true},
+		{id: 4, title: "Estimate moved", estimate: &soon},
+		{id: 5, title: "Estimate drifted", estimate: &soon},
+		{id: 6, title: "Deleted meanwhile"},
+	}
+	statuses := map[int]*EligibilityStatus{
+		1: {IsEligible: true, EstimatedEligibleAt: &now},
+		2: {IsEligible: true, EstimatedEligibleAt: &now},
+		3: {EstimatedEligibleAt: &later},
+		4: {EstimatedEligibleAt: &later},
+		5: {EstimatedEligibleAt: &drifted},
+	}
+
+	eligible, writes := eligibilityChanges(items, statuses)
+	if len(eligible) != 1 || eligible[0].id != 1 {
+		t.Errorf("eligible = %v, want only item 1 announced", eligible)
+	}
+
+	want := map[int]eligibilityWrite{
+		// Eligible items have nothing left to estimate
+		1: {id: 1, eligible: true},
+		3: {id: 3, estimate: &later},
+		4: {id: 4, estimate: &later},
+	}
+	if len(writes) != len(want) {
+		t.Fatalf("writes = %v, want %d", writes, len(want))
+	}
+	for _, w := range writes {
+		expected, ok := want[w.id]
+		if !ok {
+			t.Errorf("unexpected write for item %d", w.id)
+			continue
+		}
+		if w.eligible != expected.eligible || !sameEstimate(w.estimate, expected.estimate) {
+			t.Errorf("write for item %d = %+v, want %+v", w.id, w, expected)
+		}
+	}
+}
+
+func TestSameEstimate(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	near := now.Add(estimateTolerance / 2)
+	far := now.Add(estimateTolerance)
+
+	tests := []struct {
+		name string
+		a, b *time.Time
+		want bool
+	}{
+		{"both unknown", nil, nil, true},
+		{"one unknown", &now, nil, false},
+		{"other unknown", nil, &now, false},
+		{"within tolerance", &now, &near, true},
+		{"within tolerance, reversed", &near, &now, true},
+		{"beyond tolerance", &now, &far, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameEstimate(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameEstimate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"removarr/internal/integrations"
+)
+
+// Integration check statuses
+const (
+	IntegrationCheckOK           = "ok"
+	IntegrationCheckUnauthorized = "unauthorized" // the integration rejected the API key or credentials
+	IntegrationCheckUnreachable  = "unreachable"  // no response, or the circuit is open
+	IntegrationCheckError        = "error"        // any other failure, e.g. a 5xx or an unexpected response
+)
+
+const (
+	integrationCheckTimeout   = 15 * time.Second
+	integrationCheckRetention = 7 * 24 * time.Hour
+	integrationCheckHistory   = 20 // recent checks returned per integration
+)
+
+// IntegrationCheck is the result of one health check of an integration
+type IntegrationCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Status    string    `json:"status"`
+	Version   string    `json:"version,omitempty"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// IntegrationStatus is the health of one configured integration, from its recent checks and its circuit breaker
+type IntegrationStatus struct {
+	Name         string                         `json:"name"`
+	Title        string                         `json:"title"`
+	Healthy      bool                           `json:"healthy"`    // the last check succeeded
+	LastCheck    *IntegrationCheck              `json:"last_check"` // nil until the integration has been checked
+	Recent       []IntegrationCheck             `json:"recent_checks"`
+	Uptime       float64                        `json:"uptime_percent"` // of the recent checks that succeeded
+	AvgLatencyMs int64                          `json:"avg_latency_ms"` // of the recent checks that succeeded
+	Circuit      integrations.IntegrationHealth `json:"circuit"`
+}
+
+// IntegrationHealthService checks that each configured integration is reachable, accepts its credentials and reports
+// its version, and keeps the recent results
+type IntegrationHealthService struct {
+	db           *sql.DB
+	integrations *integrations.Client
+}
+
+func NewIntegrationHealthService(db *sql.DB, integrations *integrations.Client) *IntegrationHealthService {
+	return &IntegrationHealthService{db: db, integrations: integrations}
+}
+
+// versionChecks returns the version lookup of each configured integration, by name
+func (s *IntegrationHealthService) versionChecks() map[string]func(ctx context.Context) (string, error) {
+	checks := make(map[string]func(ctx context.Context) (string, error))
+	if s.integrations.Overseerr != nil {
+		checks["overseerr"] = s.integrations.Overseerr.GetVersion
+	}
+	if s.integrations.Sonarr != nil {
+		checks["sonarr"] = s.integrations.Sonarr.GetVersion
+	}
+	if s.integrations.Radarr != nil {
+		checks["radarr"] = s.integrations.Radarr.GetVersion
+	}
+	if s.integrations.Prowlarr != nil {
+		checks["prowlarr"] = s.integrations.Prowlarr.GetVersion
+	}
+	if s.integrations.QBittorrent != nil {
+		checks["qbittorrent"] = s.integrations.QBittorrent.GetVersion
+	}
+	if s.integrations.Tautulli != nil {
+		checks["tautulli"] = s.integrations.Tautulli.GetVersion
+	}
+	if s.integrations.Plex != nil {
+		checks["plex"] = s.integrations.Plex.GetVersion
+	}
+	return checks
+}
+
+// CheckAll checks every configured integration at once and records the results
+// Checks cut short because ctx was cancelled aren't recorded. Returns an error if results couldn't be recorded,
+// not when integrations fail their check.
+func (s *IntegrationHealthService) CheckAll(ctx context.Context) error {
+	checks := s.versionChecks()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for name, version := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			check := checkIntegration(ctx, version)
+			if ctx.Err() != nil {
+				return
+			}
+			if check.Status != IntegrationCheckOK {
+				slog.WarnContext(ctx, "Integration health check failed", "integration", name, "status", check.Status, "error", check.Error)
+			}
+			if err := s.record(ctx, name, check); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() == nil {
+		if _, err := s.db.ExecContext(ctx,
+			"DELETE FROM integration_checks WHERE checked_at < CURRENT_TIMESTAMP - $1::bigint * INTERVAL '1 second'",
+			int64(integrationCheckRetention.Seconds()),
+		); err != nil {
+			slog.WarnContext(ctx, "Failed to prune integration checks", "error", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkIntegration looks up an integration's version and classifies how it failed, if it did
+func checkIntegration(ctx context.Context, version func(ctx context.Context) (string, error)) IntegrationCheck {
+	ctx, cancel := context.WithTimeout(ctx, integrationCheckTimeout)
+	defer cancel()
+
+	started := time.Now()
+	v, err := version(ctx)
+	check := IntegrationCheck{
+		CheckedAt: started,
+		Status:    IntegrationCheckOK,
+		Version:   v,
+		LatencyMs: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		var urlErr *url.Error
+		switch {
+		case errors.Is(err, integrations.ErrUnauthorized):
+			check.Status = IntegrationCheckUnauthorized
+		case errors.Is(err, integrations.ErrCircuitOpen), errors.Is(err, context.DeadlineExceeded), errors.As(err, &urlErr):
+			check.Status = IntegrationCheckUnreachable
+		default:
+			check.Status = IntegrationCheckError
+		}
+		check.Version = ""
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func (s *IntegrationHealthService) record(ctx context.Context, name string, check IntegrationCheck) error {
+	var version, errText *string
+	if check.Version != "" {
+		version = &check.Version
+	}
+	if check.Error != "" {
+		errText = &check.Error
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO integration_checks (integration, status, version, latency_ms, error)
+		VALUES ($1, $2, $3, $4, $5)`,
+		name, check.Status, version, check.LatencyMs, errText,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record %s health check: %w", name, err)
+	}
+	return nil
+}
+
+// Status returns the health of each configured integration, in settings page order
+func (s *IntegrationHealthService) Status(ctx context.Context) ([]IntegrationStatus, error) {
+	circuits := s.integrations.Health()
+	statuses := make([]IntegrationStatus, 0, len(circuits))
+	names := make([]string, 0, len(circuits))
+	for _, circuit := range circuits {
+		statuses = append(statuses, IntegrationStatus{
+			Name:    circuit.Name,
+			Title:   circuit.Title,
+			Recent:  []IntegrationCheck{},
+			Circuit: circuit,
+		})
+		names = append(names, circuit.Name)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT integration, checked_at, status, COALESCE(version, ''), latency_ms, COALESCE(error, '')
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY integration ORDER BY checked_at DESC) AS n
+			FROM integration_checks
+			WHERE integration = ANY($1)
+		) recent
+		WHERE n <= $2
+		ORDER BY integration, checked_at DESC`,
+		names, integrationCheckHistory,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query integration checks: %w", err)
+	}
+	defer rows.Close()
+
+	recent := make(map[string][]IntegrationCheck)
+	for rows.Next() {
+		var name string
+		var check IntegrationCheck
+		if err := rows.Scan(&name, &check.CheckedAt, &check.Status, &check.Version, &check.LatencyMs, &check.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan integration check: %w", err)
+		}
+		recent[name] = append(recent[name], check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range statuses {
+		checks := recent[statuses[i].Name]
+		if len(checks) == 0 {
+			continue
+		}
+		statuses[i].Recent = checks
+		statuses[i].LastCheck = &checks[0]
+		statuses[i].Healthy = checks[0].Status == IntegrationCheckOK
+
+		var ok int
+		var latency int64
+		for _, check := range checks {
+			if check.Status == IntegrationCheckOK {
+				ok++
+				latency += check.LatencyMs
+			}
+		}
+		statuses[i].Uptime = float64(ok) / float64(len(checks)) * 100
+		if ok > 0 {
+			statuses[i].AvgLatencyMs = latency / int64(ok)
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"removarr/internal/integrations"
+)
+
+// mediaMatcher matches torrents to media items, by file path and then by name
+// Media items are loaded once per sync so matching any number of torrents costs one query.
+type mediaMatcher struct {
+	items []matchableMedia
+}
+
+type matchableMedia struct {
+	id       int64
+	filePath string
+	title    string
+}
+
+// loadMediaMatcher loads every media item's file path and title
+func loadMediaMatcher(ctx context.Context, db querier) (*mediaMatcher, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, COALESCE(file_path, ''), title FROM media_items ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load media items to match torrents: %w", err)
+	}
+	defer rows.Close()
+
+	m := &mediaMatcher{}
+	for rows.Next() {
+		var item matchableMedia
+		if err := rows.Scan(&item.id, &item.filePath, &item.title); err != nil {
+			return nil, fmt.Errorf("failed to scan media item: %w", err)
+		}
+		m.items = append(m.items, item)
+	}
+	return m, rows.Err()
+}
+
+// match finds the media item a torrent belongs to; the first media item wins a tie
+func (m *mediaMatcher) match(torrent integrations.QBittorrentTorrent) sql.NullInt64 {
+	if cp := torrent.ContentPath; cp != "" {
+		// Exact match
+		if id, ok := m.find(func(item matchableMedia) bool { return item.filePath == cp }); ok {
+			return id
+		}
+		// Media item file inside the torrent's content path
+		// (e.g., torrent: /data/downloads/Movie Title (2023), media: /data/downloads/Movie Title (2023)/Movie.Title.2023.mkv)
+		if id, ok := m.find(func(item matchableMedia) bool {
+			return item.filePath != "" && strings.HasPrefix(item.filePath, cp)
+		}); ok {
+			return id
+		}
+		// Torrent content inside the media item's path
+		if id, ok := m.find(func(item matchableMedia) bool {
+			return item.filePath != "" && strings.HasPrefix(cp, item.filePath)
+		}); ok {
+			return id
+		}
+		// Content path anywhere in the media item's path, a fallback for when paths don't match exactly;
+		// the shortest path wins
+		var best *matchableMedia
+		for i, item := range m.items {
+			if item.filePath != "" && strings.Contains(item.filePath, cp) &&
+				(best == nil || len(item.filePath) < len(best.filePath)) {
+				best = &m.items[i]
+			}
+		}
+		if best != nil {
+			return sql.NullInt64{Int64: best.id, Valid: true}
+		}
+	}
+
+	// Last resort: the torrent name contains a media title, a whole word first
+	// This is heuristic-based and may have false positives
+	if torrent.Name != "" {
+		words := strings.Split(torrent.Name, " ")
+		if id, ok := m.find(func(item matchableMedia) bool {
+			for _, word := range words {
+				if item.title != "" && word == item.title {
+					return true
+				}
+			}
+			return false
+		}); ok {
+			return id
+		}
+		if id, ok := m.find(func(item matchableMedia) bool {
+			return item.title != "" && strings.Contains(torrent.Name, item.title)
+		}); ok {
+			return id
+		}
+	}
+	return sql.NullInt64{}
+}
+
+// find returns the first media item matching
+func (m *mediaMatcher) find(matches func(matchableMedia) bool) (sql.NullInt64, bool) {
+	for _, item := range m.items {
+		if matches(item) {
+			return sql.NullInt64{Int64: item.id, Valid: true}, true
+		}
+	}
+	return sql.NullInt64{}, false
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"removarr/internal/integrations"
+)
+
+func TestMediaMatcher(t *testing.T) {
+	m := &mediaMatcher{items: []matchableMedia{
+		{id: 1, filePath: "/data/movies/Movie Title (2023)/Movie.Title.2023.mkv", title: "Movie Title"},
+		{id: 2, filePath: "/data/movies/Other (2020)", title: "Other"},
+		{id: 3, filePath: "/data/tv/Show/Season 01", title: "Show"},
+		{id: 4, filePath: "/data/tv/Show", title: "Show"},
+		{id: 5, filePath: "", title: "Unmatched"},
+		{id: 6, filePath: "/library/Inception (2010)/Inception.mkv", title: "Inception"},
+	}}
+
+	tests := []struct {
+		name    string
+		torrent integrations.QBittorrentTorrent
+		want    int64 // 0 for no match
+	}{
+		{"exact path", integrations.QBittorrentTorrent{ContentPath: "/data/movies/Other (2020)"}, 2},
+		{"media file inside the torrent", integrations.QBittorrentTorrent{ContentPath: "/data/movies/Movie Title (2023)"}, 1},
+		{"torrent inside the media path", integrations.QBittorrentTorrent{ContentPath: "/data/movies/Other (2020)/Other.mkv"}, 2},
+		{"first media item wins", integrations.QBittorrentTorrent{ContentPath: "/data/tv/Show/Season 01/E01.mkv"}, 3},
+		{"path anywhere in the media path", integrations.QBittorrentTorrent{ContentPath: "Inception (2010)"}, 6},
+		{"title as a word of the name", integrations.QBittorrentTorrent{Name: "Unmatched 2019 1080p", ContentPath: "/downloads/x"}, 5},
+		{"title inside the name", integrations.QBittorrentTorrent{Name: "Movie Title.2023.1080p"}, 1},
+		{"no match", integrations.QBittorrentTorrent{Name: "Something.Else", ContentPath: "/downloads/Something.Else"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.match(tt.torrent)
+			if got.Valid != (tt.want != 0) || got.Int64 != tt.want {
+				t.Errorf("match() = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMediaMatcherEmptyTitle(t *testing.T) {
+	// An empty title is in every name, it mustn't match everything
+	m := &mediaMatcher{items: []matchableMedia{{id: 1, title: ""}}}
+	if got := m.match(integrations.QBittorrentTorrent{Name: "Anything"}); got.Valid {
+		t.Errorf("match() = %v, want no match", got)
+	}
+}
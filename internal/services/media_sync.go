@@ -3,8 +3,10 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"removarr/internal/integrations"
@@ -38,177 +40,652 @@ func NewMediaSyncService(db *sql.DB, integrationsClient *integrations.Client) *M
 }
 
 // SyncFromSonarr fetches series from Sonarr and updates the database
-func (s *MediaSyncService) SyncFromSonarr(ctx context.Context) error {
+// Series that haven't changed since the last sync are skipped, and all writes go in one transaction
+func (s *MediaSyncService) SyncFromSonarr(ctx context.Context) (SyncCounts, error) {
 	if s.integrations.Sonarr == nil {
-		return fmt.Errorf("sonarr integration not enabled")
+		return SyncCounts{}, fmt.Errorf("sonarr integration not enabled")
 	}
 
-	slog.Info("Syncing media from Sonarr...")
-	series, err := s.integrations.Sonarr.GetSeries()
+	slog.InfoContext(ctx, "Syncing media from Sonarr...")
+	series, err := s.integrations.Sonarr.GetSeries(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch series from Sonarr: %w", err)
+		return SyncCounts{}, fmt.Errorf("failed to fetch series from Sonarr: %w", err)
 	}
 
+	tagLabels, profileNames := s.sonarrLookups(ctx)
+	protectionLabel := protectionTag(ctx, s.db)
+
+	rows := make([]mediaRow, 0, len(series))
 	for _, ser := range series {
-		size := int64(0)
-		if ser.Statistics != nil {
-			size = ser.Statistics.SizeOnDisk
+		rows = append(rows, seriesRow(ser, tagLabels, profileNames, protectionLabel))
+	}
+	return s.syncMediaRows(ctx, "Sonarr", SyncSourceSonarr, "sonarr_id", rows)
+}
+
+// SyncFromRadarr fetches movies from Radarr and updates the database
+// Movies that haven't changed since the last sync are skipped, and all writes go in one transaction
+func (s *MediaSyncService) SyncFromRadarr(ctx context.Context) (SyncCounts, error) {
+	if s.integrations.Radarr == nil {
+		return SyncCounts{}, fmt.Errorf("radarr integration not enabled")
+	}
+
+	slog.InfoContext(ctx, "Syncing media from Radarr...")
+	movies, err := s.integrations.Radarr.GetMovies(ctx)
+	if err != nil {
+		return SyncCounts{}, fmt.Errorf("failed to fetch movies from Radarr: %w", err)
+	}
+
+	tagLabels, profileNames := s.radarrLookups(ctx)
+	protectionLabel := protectionTag(ctx, s.db)
+
+	rows := make([]mediaRow, 0, len(movies))
+	for _, movie := range movies {
+		rows = append(rows, movieRow(movie, tagLabels, profileNames, protectionLabel))
+	}
+	return s.syncMediaRows(ctx, "Radarr", SyncSourceRadarr, "radarr_id", rows)
+}
+
+// syncedMedia is the stored state of a media item the sync compares against
+type syncedMedia struct {
+	id   int
+	hash string
+}
+
+// syncMediaRows writes the media items of a full Sonarr/Radarr sync in one transaction
+// Stored hashes are loaded in one query up front; unchanged items only get last_synced_at bumped, in one statement.
+// A failing item is logged and rolled back on its own, the rest of the sync still commits.
+// Every item seen is stamped with the sync's generation, and items of the source that weren't seen are marked missing.
+func (s *MediaSyncService) syncMediaRows(ctx context.Context, name, source, idColumn string, rows []mediaRow) (SyncCounts, error) {
+	counts := SyncCounts{Seen: len(rows)}
+
+	stored := make(map[int]syncedMedia)
+	existing, err := s.db.QueryContext(ctx,
+		"SELECT "+idColumn+", id, COALESCE(sync_hash, '') FROM media_items WHERE "+idColumn+" IS NOT NULL",
+	)
+	if err != nil {
+		return counts, fmt.Errorf("failed to load stored media items: %w", err)
+	}
+	for existing.Next() {
+		var arrID int
+		var item syncedMedia
+		if err := existing.Scan(&arrID, &item.id, &item.hash); err != nil {
+			existing.Close()
+			return counts, fmt.Errorf("failed to load stored media items: %w", err)
 		}
+		stored[arrID] = item
+	}
+	existing.Close()
+	if err := existing.Err(); err != nil {
+		return counts, fmt.Errorf("failed to load stored media items: %w", err)
+	}
 
-		addedDate, _ := time.Parse(time.RFC3339, ser.Added)
-
-		// Series is downloaded if it has files (size > 0 and path exists)
-		// Note: We still sync all series, even if not downloaded (monitored but not yet available)
-
-		// Check if media item exists
-		var existingID int
-		err := s.db.QueryRowContext(ctx,
-			"SELECT id FROM media_items WHERE sonarr_id = $1",
-			ser.ID,
-		).Scan(&existingID)
-
-		if err == sql.ErrNoRows {
-			// Insert new media item
-			_, err = s.db.ExecContext(ctx,
-				`INSERT INTO media_items 
-					(title, type, sonarr_id, tvdb_id, file_path, file_size, added_date, last_synced_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
-				ON CONFLICT (sonarr_id) WHERE sonarr_id IS NOT NULL DO UPDATE SET
-					title = EXCLUDED.title,
-					file_path = EXCLUDED.file_path,
-					file_size = EXCLUDED.file_size,
-					last_synced_at = CURRENT_TIMESTAMP`,
-				ser.Title,
-				"series",
-				ser.ID,
-				ser.TVDBID,
-				ser.Path,
-				size,
-				addedDate,
-			)
-			if err != nil {
-				slog.Error("Failed to insert media item", "error", err, "title", ser.Title)
-				continue
-			}
-		} else if err == nil {
-			// Update existing media item
-			// Note: We preserve overseerr_request_id and requested_by_user_id if they exist
-			_, err = s.db.ExecContext(ctx,
-				`UPDATE media_items SET
-					title = $2,
-					file_path = $3,
-					file_size = $4,
-					last_synced_at = CURRENT_TIMESTAMP
-				WHERE id = $1`,
-				existingID,
-				ser.Title,
-				ser.Path,
-				size,
-			)
-			if err != nil {
-				slog.Error("Failed to update media item", "error", err, "id", existingID)
-				continue
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return counts, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	generation, err := nextSyncGeneration(ctx, tx, source)
+	if err != nil {
+		return counts, err
+	}
+
+	var unchanged, failed []int64
+	for _, row := range rows {
+		hash := row.hash()
+		item, ok := stored[row.arrID]
+		if ok && hash != "" && item.hash == hash {
+			unchanged = append(unchanged, int64(item.id))
+			continue
+		}
+		if err := inSavepoint(ctx, tx, func() error {
+			return writeMediaRow(ctx, tx, row, item.id, hash, generation)
+		}); err != nil {
+			slog.ErrorContext(ctx, "Failed to sync "+row.mediaType, "title", row.title, "error", err)
+			counts.Failed++
+			if ok {
+				failed = append(failed, int64(item.id)) // still there, just not updated
 			}
+			continue
+		}
+		if ok {
+			counts.Updated++
+		} else {
+			counts.Added++
+		}
+	}
+
+	if len(unchanged) > 0 {
+		if err := markSeen(ctx, tx, "media_items", unchanged, generation, true); err != nil {
+			return counts, fmt.Errorf("failed to mark unchanged media items synced: %w", err)
+		}
+	}
+	if len(failed) > 0 {
+		if err := markSeen(ctx, tx, "media_items", failed, generation, false); err != nil {
+			return counts, fmt.Errorf("failed to mark media items seen: %w", err)
+		}
+	}
+
+	// An empty library next to stored items is more likely a misconfigured instance than everything deleted
+	if len(rows) > 0 || len(stored) == 0 {
+		counts.Missing, err = markMissing(ctx, tx, "media_items", idColumn+" IS NOT NULL", generation)
+		if err != nil {
+			return counts, err
+		}
+	} else {
+		slog.WarnContext(ctx, name+" returned no media, not marking stored media items missing", "stored", len(stored))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return counts, fmt.Errorf("failed to commit: %w", err)
+	}
+	counts.Unchanged = len(unchanged)
+
+	slog.InfoContext(ctx, name+" sync complete", "count", len(rows), "added", counts.Added, "updated", counts.Updated,
+		"unchanged", counts.Unchanged, "failed", counts.Failed, "missing", counts.Missing)
+	return counts, nil
+}
+
+// syncMediaRow writes the media item of a single series or movie, unless it hasn't changed
+func (s *MediaSyncService) syncMediaRow(ctx context.Context, row mediaRow) error {
+	var item syncedMedia
+	var missing bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, COALESCE(sync_hash, ''), missing_since IS NOT NULL FROM media_items WHERE "+row.idColumn+" = $1",
+		row.arrID,
+	).Scan(&item.id, &item.hash, &missing)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get media item: %w", err)
+	}
+
+	hash := row.hash()
+	if item.id != 0 && !missing && hash != "" && item.hash == hash {
+		return nil
+	}
+	return writeMediaRow(ctx, s.db, row, item.id, hash, 0)
+}
+
+// mediaRow is what a sync writes for one Sonarr series or Radarr movie
+type mediaRow struct {
+	mediaType      string // "series" or "movie"
+	idColumn       string // "sonarr_id" or "radarr_id"
+	externalColumn string // "tvdb_id" or "tmdb_id"
+	arrID          int
+	externalID     int
+	title          string
+	path           string
+	size           int64
+	addedDate      time.Time
+	meta           arrMetadata
+}
+
+// hash fingerprints every field the row writes
+func (r mediaRow) hash() string {
+	return syncHash(r.externalID, r.title, r.path, r.size, r.addedDate,
+		r.meta.tags, r.meta.protectedByTag, r.meta.genres, r.meta.year, r.meta.rating, r.meta.qualityProfile, r.meta.quality)
+}
+
+// writeMediaRow inserts the media item (existingID 0) or updates it
+// generation is that of the full sync writing it, 0 outside full syncs to keep the stored one
+func writeMediaRow(ctx context.Context, db execer, row mediaRow, existingID int, hash string, generation int64) error {
+	if existingID == 0 {
+		// Insert new media item (even if not downloaded - we track all monitored media)
+		// Use INSERT ... ON CONFLICT with the unique index
+		_, err := db.ExecContext(ctx,
+			`INSERT INTO media_items
+				(title, type, `+row.idColumn+`, `+row.externalColumn+`, file_path, file_size, added_date,
+				protected_by_tag, tags, genres, year, rating, quality_profile, quality, sync_hash, sync_generation, last_synced_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7,
+				COALESCE($8, FALSE), COALESCE($9::jsonb, '[]'), $10::jsonb, $11, $12, $13, $14, $15, NULLIF($16::bigint, 0), CURRENT_TIMESTAMP)
+			ON CONFLICT (`+row.idColumn+`) WHERE `+row.idColumn+` IS NOT NULL DO UPDATE SET
+				title = EXCLUDED.title,
+				file_path = EXCLUDED.file_path,
+				file_size = EXCLUDED.file_size,
+				protected_by_tag = COALESCE($8, media_items.protected_by_tag),
+				tags = COALESCE($9::jsonb, media_items.tags),
+				genres = EXCLUDED.genres,
+				year = EXCLUDED.year,
+				rating = EXCLUDED.rating,
+				quality_profile = COALESCE($13, media_items.quality_profile),
+				quality = EXCLUDED.quality,
+				sync_hash = EXCLUDED.sync_hash,
+				sync_generation = COALESCE(EXCLUDED.sync_generation, media_items.sync_generation),
+				missing_since = NULL,
+				last_synced_at = CURRENT_TIMESTAMP`,
+			row.title,
+			row.mediaType,
+			row.arrID,
+			row.externalID,
+			row.path,
+			row.size,
+			row.addedDate,
+			row.meta.protectedByTag,
+			row.meta.tags,
+			row.meta.genres,
+			row.meta.year,
+			row.meta.rating,
+			row.meta.qualityProfile,
+			row.meta.quality,
+			hash,
+			generation,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert media item %s: %w", row.title, err)
 		}
+		return nil
 	}
 
-	slog.Info("Sonarr sync complete", "count", len(series))
+	// Update existing media item
+	// Note: We preserve overseerr_request_id and requested_by_user_id if they exist
+	_, err := db.ExecContext(ctx,
+		`UPDATE media_items SET
+			title = $2,
+			file_path = $3,
+			file_size = $4,
+			protected_by_tag = COALESCE($5, protected_by_tag),
+			tags = COALESCE($6::jsonb, tags),
+			genres = $7::jsonb,
+			year = $8,
+			rating = $9,
+			quality_profile = COALESCE($10, quality_profile),
+			quality = $11,
+			sync_hash = $12,
+			sync_generation = COALESCE(NULLIF($13::bigint, 0), sync_generation),
+			missing_since = NULL,
+			last_synced_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		existingID,
+		row.title,
+		row.path,
+		row.size,
+		row.meta.protectedByTag,
+		row.meta.tags,
+		row.meta.genres,
+		row.meta.year,
+		row.meta.rating,
+		row.meta.qualityProfile,
+		row.meta.quality,
+		hash,
+		generation,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update media item %d: %w", existingID, err)
+	}
 	return nil
 }
 
-// SyncFromRadarr fetches movies from Radarr and updates the database
-func (s *MediaSyncService) SyncFromRadarr(ctx context.Context) error {
+// sonarrLookups returns Sonarr's tag labels and quality profile names by ID
+// Either is nil if it couldn't be fetched, so the stored values (and tag protection) are left as is
+func (s *MediaSyncService) sonarrLookups(ctx context.Context) (map[int]string, map[int]string) {
+	var tagLabels map[int]string
+	if tags, err := s.integrations.Sonarr.GetTags(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to fetch Sonarr tags, keeping stored tags unchanged", "error", err)
+	} else {
+		tagLabels = make(map[int]string)
+		for _, tag := range tags {
+			tagLabels[tag.ID] = tag.Label
+		}
+	}
+	var profileNames map[int]string
+	if profiles, err := s.integrations.Sonarr.GetQualityProfiles(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to fetch Sonarr quality profiles, keeping stored profiles unchanged", "error", err)
+	} else {
+		profileNames = make(map[int]string)
+		for _, profile := range profiles {
+			profileNames[profile.ID] = profile.Name
+		}
+	}
+	return tagLabels, profileNames
+}
+
+// seriesRow builds the media item of a Sonarr series
+// Series are synced even if not downloaded yet (monitored but not yet available)
+func seriesRow(ser integrations.SonarrSeries, tagLabels, profileNames map[int]string, protectionLabel string) mediaRow {
+	row := mediaRow{
+		mediaType:      "series",
+		idColumn:       "sonarr_id",
+		externalColumn: "tvdb_id",
+		arrID:          ser.ID,
+		externalID:     ser.TVDBID,
+		title:          ser.Title,
+		path:           ser.Path,
+		meta:           arrMetadata{genres: jsonArray(ser.Genres)},
+	}
+	if ser.Statistics != nil {
+		row.size = ser.Statistics.SizeOnDisk
+	}
+	row.addedDate, _ = time.Parse(time.RFC3339, ser.Added)
+
+	row.meta.tags, row.meta.protectedByTag = resolveTags(ser.Tags, tagLabels, protectionLabel)
+	if ser.Year > 0 {
+		row.meta.year = &ser.Year
+	}
+	if ser.Ratings.Votes > 0 {
+		row.meta.rating = &ser.Ratings.Value
+	}
+	if name, ok := profileNames[ser.QualityProfileID]; ok {
+		row.meta.qualityProfile = &name
+	}
+	return row
+}
+
+// radarrLookups returns Radarr's tag labels and quality profile names by ID
+// Either is nil if it couldn't be fetched, so the stored values (and tag protection) are left as is
+func (s *MediaSyncService) radarrLookups(ctx context.Context) (map[int]string, map[int]string) {
+	var tagLabels map[int]string
+	if tags, err := s.integrations.Radarr.GetTags(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to fetch Radarr tags, keeping stored tags unchanged", "error", err)
+	} else {
+		tagLabels = make(map[int]string)
+		for _, tag := range tags {
+			tagLabels[tag.ID] = tag.Label
+		}
+	}
+	var profileNames map[int]string
+	if profiles, err := s.integrations.Radarr.GetQualityProfiles(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to fetch Radarr quality profiles, keeping stored profiles unchanged", "error", err)
+	} else {
+		profileNames = make(map[int]string)
+		for _, profile := range profiles {
+			profileNames[profile.ID] = profile.Name
+		}
+	}
+	return tagLabels, profileNames
+}
+
+// movieRow builds the media item of a Radarr movie
+// All movies are synced, including monitored but not yet downloaded; the "downloaded" status is
+// determined in the API response based on file_size and file_path
+func movieRow(movie integrations.RadarrMovie, tagLabels, profileNames map[int]string, protectionLabel string) mediaRow {
+	row := mediaRow{
+		mediaType:      "movie",
+		idColumn:       "radarr_id",
+		externalColumn: "tmdb_id",
+		arrID:          movie.ID,
+		externalID:     movie.TMDBID,
+		title:          movie.Title,
+		path:           movie.Path,
+		meta:           arrMetadata{genres: jsonArray(movie.Genres)},
+	}
+	if movie.Statistics != nil {
+		row.size = movie.Statistics.SizeOnDisk
+	}
+	row.addedDate, _ = time.Parse(time.RFC3339, movie.Added)
+
+	row.meta.tags, row.meta.protectedByTag = resolveTags(movie.Tags, tagLabels, protectionLabel)
+	if movie.Year > 0 {
+		row.meta.year = &movie.Year
+	}
+	// IMDb first, it is what most people go by
+	if rating := movie.Ratings.IMDB; rating != nil && rating.Votes > 0 {
+		row.meta.rating = &rating.Value
+	} else if rating := movie.Ratings.TMDB; rating != nil && rating.Votes > 0 {
+		row.meta.rating = &rating.Value
+	}
+	if name, ok := profileNames[movie.QualityProfileID]; ok {
+		row.meta.qualityProfile = &name
+	}
+	if movie.MovieFile != nil && movie.MovieFile.Quality.Quality.Name != "" {
+		row.meta.quality = &movie.MovieFile.Quality.Quality.Name
+	}
+	return row
+}
+
+// SyncSeries fetches one series from Sonarr and updates its media item
+// Used by Sonarr webhooks, so imports and renames show up without waiting for the next full sync
+func (s *MediaSyncService) SyncSeries(ctx context.Context, sonarrID int) error {
+	if s.integrations.Sonarr == nil {
+		return fmt.Errorf("sonarr integration not enabled")
+	}
+
+	series, err := s.integrations.Sonarr.GetSeriesByID(ctx, sonarrID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch series %d from Sonarr: %w", sonarrID, err)
+	}
+
+	tagLabels, profileNames := s.sonarrLookups(ctx)
+	return s.syncMediaRow(ctx, seriesRow(*series, tagLabels, profileNames, protectionTag(ctx, s.db)))
+}
+
+// SyncMovie fetches one movie from Radarr and updates its media item
+// Used by Radarr webhooks, so imports and renames show up without waiting for the next full sync
+func (s *MediaSyncService) SyncMovie(ctx context.Context, radarrID int) error {
 	if s.integrations.Radarr == nil {
 		return fmt.Errorf("radarr integration not enabled")
 	}
 
-	slog.Info("Syncing media from Radarr...")
-	movies, err := s.integrations.Radarr.GetMovies()
+	movie, err := s.integrations.Radarr.GetMovieByID(ctx, radarrID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch movies from Radarr: %w", err)
+		return fmt.Errorf("failed to fetch movie %d from Radarr: %w", radarrID, err)
 	}
 
-	for _, movie := range movies {
-		size := int64(0)
-		if movie.Statistics != nil {
-			size = movie.Statistics.SizeOnDisk
-		}
+	tagLabels, profileNames := s.radarrLookups(ctx)
+	return s.syncMediaRow(ctx, movieRow(*movie, tagLabels, profileNames, protectionTag(ctx, s.db)))
+}
 
-		addedDate, _ := time.Parse(time.RFC3339, movie.Added)
-
-		// Note: We sync ALL movies from Radarr, including monitored but not yet downloaded
-		// The "downloaded" status is determined in the API response based on file_size and file_path
-
-		// Check if media item exists
-		var existingID int
-		err := s.db.QueryRowContext(ctx,
-			"SELECT id FROM media_items WHERE radarr_id = $1",
-			movie.ID,
-		).Scan(&existingID)
-
-		if err == sql.ErrNoRows {
-			// Insert new media item (even if not downloaded - we track all monitored media)
-			// Use INSERT ... ON CONFLICT with the unique index
-			_, err = s.db.ExecContext(ctx,
-				`INSERT INTO media_items 
-					(title, type, radarr_id, tmdb_id, file_path, file_size, added_date, last_synced_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
-				ON CONFLICT (radarr_id) WHERE radarr_id IS NOT NULL DO UPDATE SET
-					title = EXCLUDED.title,
-					file_path = EXCLUDED.file_path,
-					file_size = EXCLUDED.file_size,
-					last_synced_at = CURRENT_TIMESTAMP`,
-				movie.Title,
-				"movie",
-				movie.ID,
-				movie.TMDBID,
-				movie.Path,
-				size,
-				addedDate,
-			)
-			if err != nil {
-				slog.Error("Failed to insert media item", "error", err, "title", movie.Title)
-				continue
-			}
-		} else if err == nil {
-			// Update existing media item
-			// Note: We preserve overseerr_request_id and requested_by_user_id if they exist
-			_, err = s.db.ExecContext(ctx,
-				`UPDATE media_items SET
-					title = $2,
-					file_path = $3,
-					file_size = $4,
-					last_synced_at = CURRENT_TIMESTAMP
-				WHERE id = $1`,
-				existingID,
-				movie.Title,
-				movie.Path,
-				size,
-			)
-			if err != nil {
-				slog.Error("Failed to update media item", "error", err, "id", existingID)
-				continue
-			}
-		}
+// RemoveSeries marks the media item of a series that was deleted in Sonarr as missing
+func (s *MediaSyncService) RemoveSeries(ctx context.Context, sonarrID int) error {
+	return s.markMediaMissing(ctx, "sonarr_id", sonarrID)
+}
+
+// RemoveMovie marks the media item of a movie that was deleted in Radarr as missing
+func (s *MediaSyncService) RemoveMovie(ctx context.Context, radarrID int) error {
+	return s.markMediaMissing(ctx, "radarr_id", radarrID)
+}
+
+// markMediaMissing marks a media item missing by its Sonarr/Radarr ID, as a full sync that no longer sees it would
+// It is purged once the stale retention period is over, see StaleService.Purge
+func (s *MediaSyncService) markMediaMissing(ctx context.Context, idColumn string, arrID int) error {
+	var (
+		id    int
+		title string
+	)
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE media_items SET missing_since = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE `+idColumn+` = $1 AND missing_since IS NULL
+		RETURNING id, title`,
+		arrID,
+	).Scan(&id, &title)
+	if err == sql.ErrNoRows {
+		return nil // never synced or already missing
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark media item missing: %w", err)
 	}
 
-	slog.Info("Radarr sync complete", "count", len(movies))
+	slog.InfoContext(ctx, "Media item deleted outside removarr marked missing", "media_id", id, "title", title, idColumn, arrID)
 	return nil
 }
 
+// arrMetadata is the descriptive metadata synced from Radarr/Sonarr
+// A nil tags, protectedByTag or qualityProfile couldn't be resolved this sync and keeps its stored value
+type arrMetadata struct {
+	tags           *string // JSON array of tag labels
+	protectedByTag *bool
+	genres         string // JSON array
+	year           *int
+	rating         *float64 // 0-10
+	qualityProfile *string
+	quality        *string // quality of the file on disk, Radarr only
+}
+
+// resolveTags turns tag IDs into a JSON array of labels and reports whether one of them is the protection tag
+// Both are nil if the tag labels are unknown
+func resolveTags(ids []int, tagLabels map[int]string, protectionLabel string) (*string, *bool) {
+	if tagLabels == nil {
+		return nil, nil
+	}
+	labels := []string{}
+	protected := false
+	for _, id := range ids {
+		label, ok := tagLabels[id]
+		if !ok {
+			continue
+		}
+		labels = append(labels, label)
+		if protectionLabel != "" && strings.EqualFold(label, protectionLabel) {
+			protected = true
+		}
+	}
+	tags := jsonArray(labels)
+	return &tags, &protected
+}
+
+// jsonArray encodes values as a JSON array, "[]" if there are none
+func jsonArray(values []string) string {
+	if values == nil {
+		values = []string{}
+	}
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
+
+// protectionTag returns the Radarr/Sonarr tag label that protects media, "" if tag protection is disabled
+func protectionTag(ctx context.Context, db *sql.DB) string {
+	var label string
+	err := db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = 'protection.tag'").Scan(&label)
+	if err == sql.ErrNoRows {
+		return DefaultProtectionTag
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get protection tag setting", "error", err)
+		return DefaultProtectionTag
+	}
+	return strings.TrimSpace(label)
+}
+
 // SyncOverseerrRequests links Overseerr requests to existing media items
-func (s *MediaSyncService) SyncOverseerrRequests(ctx context.Context) error {
+// Media items are loaded in one query and only those whose request changed are written, in one statement.
+// Updated counts the media items whose request changed, Unchanged the ones linked already.
+func (s *MediaSyncService) SyncOverseerrRequests(ctx context.Context) (SyncCounts, error) {
 	if s.integrations.Overseerr == nil {
-		return nil // Overseerr not enabled, skip
+		return SyncCounts{}, nil // Overseerr not enabled, skip
 	}
 
-	slog.Info("Syncing Overseerr requests...")
-	requests, err := s.integrations.Overseerr.GetRequests()
+	slog.InfoContext(ctx, "Syncing Overseerr requests...")
+	requests, err := s.integrations.Overseerr.GetRequests(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch Overseerr requests: %w", err)
+		return SyncCounts{}, fmt.Errorf("failed to fetch Overseerr requests: %w", err)
 	}
+	counts := SyncCounts{Seen: len(requests)}
 
-	linkedCount := 0
+	links := overseerrLinks(requests)
+	if len(links) == 0 {
+		return counts, nil
+	}
+	var tmdbIDs, tvdbIDs []int
+	for key := range links {
+		if key.mediaType == "movie" {
+			tmdbIDs = append(tmdbIDs, key.id)
+		} else {
+			tvdbIDs = append(tvdbIDs, key.id)
+		}
+	}
+
+	// Find matching media items by TMDB ID (movies) or TVDB ID (series), with the request they're linked to
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, type, COALESCE(tmdb_id, 0), COALESCE(tvdb_id, 0), COALESCE(overseerr_request_id, 0),
+			COALESCE(requested_by_user_id, 0), overseerr_request_open, COALESCE(requester_email, '')
+		FROM media_items
+		WHERE (type = 'movie' AND tmdb_id = ANY($1)) OR (type = 'series' AND tvdb_id = ANY($2))
+		ORDER BY id`,
+		tmdbIDs, tvdbIDs,
+	)
+	if err != nil {
+		return counts, fmt.Errorf("failed to load media items for Overseerr requests: %w", err)
+	}
+	var changed []int // media item IDs
+	var changedLinks []overseerrLink
+	seen := make(map[overseerrKey]bool)
+	for rows.Next() {
+		var (
+			id     int
+			key    overseerrKey
+			tmdbID int
+			tvdbID int
+			stored overseerrLink
+		)
+		if err := rows.Scan(&id, &key.mediaType, &tmdbID, &tvdbID, &stored.requestID, &stored.requesterID,
+			&stored.open, &stored.email); err != nil {
+			rows.Close()
+			return counts, fmt.Errorf("failed to scan media item: %w", err)
+		}
+		key.id = tvdbID
+		if key.mediaType == "movie" {
+			key.id = tmdbID
+		}
+		// The oldest media item wins if several share an ID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		link := links[key]
+		if link == stored {
+			counts.Unchanged++
+			continue
+		}
+		changed = append(changed, id)
+		changedLinks = append(changedLinks, link)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return counts, fmt.Errorf("failed to load media items for Overseerr requests: %w", err)
+	}
+	if len(changed) == 0 {
+		slog.InfoContext(ctx, "Overseerr request sync complete", "linked", 0, "unchanged", counts.Unchanged,
+			"total_requests", len(requests))
+		return counts, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return counts, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	failed := writeBatch(ctx, tx, len(changed), func(from, to int) error {
+		return updateOverseerrLinks(ctx, tx, changed[from:to], changedLinks[from:to])
+	})
+	for i, id := range changed {
+		if err, ok := failed[i]; ok {
+			slog.ErrorContext(ctx, "Failed to update media item with Overseerr request",
+				"error", err,
+				"media_item_id", id,
+				"request_id", changedLinks[i].requestID)
+			counts.Failed++
+			continue
+		}
+		counts.Updated++
+		slog.DebugContext(ctx, "Linked Overseerr request to media item",
+			"request_id", changedLinks[i].requestID,
+			"media_item_id", id)
+	}
+	if err := tx.Commit(); err != nil {
+		return counts, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Overseerr request sync complete", "linked", counts.Updated, "unchanged", counts.Unchanged,
+		"failed", counts.Failed, "total_requests", len(requests))
+	return counts, nil
+}
+
+// overseerrKey identifies the media item a request is for: a movie by TMDB ID, a series by TVDB ID
+type overseerrKey struct {
+	mediaType string // "movie" or "series"
+	id        int
+}
+
+// overseerrLink is the request a media item is linked to, as stored on the media item
+type overseerrLink struct {
+	requestID   int
+	requesterID int
+	open        bool
+	email       string
+}
+
+// overseerrLinks works out the request to link to each media item; a later request wins over an earlier one
+func overseerrLinks(requests []integrations.OverseerrRequest) map[overseerrKey]overseerrLink {
+	links := make(map[overseerrKey]overseerrLink)
 	for _, req := range requests {
 		// Determine media type (Overseerr uses "movie" or "tv")
 		mediaType := req.MediaType
@@ -219,86 +696,148 @@ func (s *MediaSyncService) SyncOverseerrRequests(ctx context.Context) error {
 			mediaType = "series" // Convert to our internal type
 		}
 
-		// Find matching media item by TMDB ID (movies) or TVDB ID (series)
-		var mediaItemID int
-		var queryErr error
-
-		if mediaType == "movie" && req.Media.TMDBID > 0 {
-			queryErr = s.db.QueryRowContext(ctx,
-				"SELECT id FROM media_items WHERE tmdb_id = $1 AND type = 'movie'",
-				req.Media.TMDBID,
-			).Scan(&mediaItemID)
-		} else if mediaType == "series" && req.Media.TVDBID != nil && *req.Media.TVDBID > 0 {
-			queryErr = s.db.QueryRowContext(ctx,
-				"SELECT id FROM media_items WHERE tvdb_id = $1 AND type = 'series'",
-				*req.Media.TVDBID,
-			).Scan(&mediaItemID)
-		}
-
-		if queryErr == sql.ErrNoRows {
-			// No matching media item found, skip
-			continue
-		}
-		if queryErr != nil {
-			slog.Warn("Failed to find media item for Overseerr request",
-				"request_id", req.ID,
-				"tmdb_id", req.Media.TMDBID,
-				"tvdb_id", req.Media.TVDBID,
-				"error", queryErr)
+		var key overseerrKey
+		switch {
+		case mediaType == "movie" && req.Media.TMDBID > 0:
+			key = overseerrKey{mediaType: "movie", id: req.Media.TMDBID}
+		case mediaType == "series" && req.Media.TVDBID != nil && *req.Media.TVDBID > 0:
+			key = overseerrKey{mediaType: "series", id: *req.Media.TVDBID}
+		default:
 			continue
 		}
 
-		// Update the media item with Overseerr request info
-		_, err = s.db.ExecContext(ctx,
-			`UPDATE media_items SET
-				overseerr_request_id = $1,
-				requested_by_user_id = $2,
-				last_synced_at = CURRENT_TIMESTAMP
-			WHERE id = $3`,
-			req.ID,
-			req.RequestedBy.ID,
-			mediaItemID,
-		)
-		if err != nil {
-			slog.Error("Failed to update media item with Overseerr request",
-				"error", err,
-				"media_item_id", mediaItemID,
-				"request_id", req.ID)
-			continue
+		links[key] = overseerrLink{
+			requestID:   req.ID,
+			requesterID: req.RequestedBy.ID,
+			// A request is open while it is pending or approved and the media isn't fully available yet
+			open:  (req.Status == 1 || req.Status == 2) && req.Media.Status != 5,
+			email: req.RequestedBy.Email,
 		}
-
-		linkedCount++
-		slog.Debug("Linked Overseerr request to media item",
-			"request_id", req.ID,
-			"media_item_id", mediaItemID,
-			"title", req.Media.Title)
 	}
+	return links
+}
 
-	slog.Info("Overseerr request sync complete", "linked", linkedCount, "total_requests", len(requests))
+// updateOverseerrLinks links media items to their requests, in one statement
+func updateOverseerrLinks(ctx context.Context, db execer, mediaItemIDs []int, links []overseerrLink) error {
+	var (
+		requestIDs   = make([]int, len(links))
+		requesterIDs = make([]int, len(links))
+		open         = make([]bool, len(links))
+		emails       = make([]string, len(links))
+	)
+	for i, link := range links {
+		requestIDs[i], requesterIDs[i] = link.requestID, link.requesterID
+		open[i], emails[i] = link.open, link.email
+	}
+	_, err := db.ExecContext(ctx,
+		`UPDATE media_items m SET
+			overseerr_request_id = l.request_id,
+			requested_by_user_id = l.requested_by_user_id,
+			overseerr_request_open = l.request_open,
+			requester_email = NULLIF(l.email, ''),
+			last_synced_at = CURRENT_TIMESTAMP
+		FROM UNNEST($1::int[], $2::int[], $3::int[], $4::bool[], $5::text[])
+			AS l(id, request_id, requested_by_user_id, request_open, email)
+		WHERE m.id = l.id`,
+		mediaItemIDs, requestIDs, requesterIDs, open, emails,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to link Overseerr requests: %w", err)
+	}
 	return nil
 }
 
-// SyncAll syncs media from all enabled services
-func (s *MediaSyncService) SyncAll(ctx context.Context) error {
-	if s.integrations.Sonarr != nil {
-		if err := s.SyncFromSonarr(ctx); err != nil {
-			slog.Error("Sonarr sync failed", "error", err)
-		}
+// SyncTautulliHistory rebuilds the watch history cache from Tautulli
+// Plays are matched to media items by TMDB ID (movies) or their show's TVDB ID (episodes), read from the play's
+// GUID or the metadata of its rating key, and to users by Plex user ID or username.
+// Plays by users removarr doesn't know are combined into one row without a user.
+// Media items and users are each resolved in one query. Updated counts the media item and user pairs stored.
+func (s *MediaSyncService) SyncTautulliHistory(ctx context.Context) (SyncCounts, error) {
+	if s.integrations.Tautulli == nil {
+		return SyncCounts{}, nil // Tautulli not enabled, skip
 	}
 
-	if s.integrations.Radarr != nil {
-		if err := s.SyncFromRadarr(ctx); err != nil {
-			slog.Error("Radarr sync failed", "error", err)
+	slog.InfoContext(ctx, "Syncing Tautulli watch history...")
+	history, err := s.integrations.Tautulli.GetHistory(ctx)
+	if err != nil {
+		return SyncCounts{}, fmt.Errorf("failed to fetch Tautulli history: %w", err)
+	}
+
+	// Plays of items Plex matched with a legacy agent carry the IDs in their GUID; the rest are looked up
+	// once per movie or show
+	lookedUp := make(map[string]integrations.ExternalIDs)
+	plays := make([]tautulliPlay, 0, len(history))
+	for _, h := range history {
+		play, ratingKey, ok := newTautulliPlay(h)
+		if !ok {
+			continue
 		}
+		if play.tmdbID == 0 && play.tvdbID == 0 {
+			if ratingKey == "" {
+				continue
+			}
+			ids, ok := lookedUp[ratingKey]
+			if !ok {
+				if ids, err = s.integrations.Tautulli.GetExternalIDs(ctx, ratingKey); err != nil {
+					slog.WarnContext(ctx, "Failed to look up Tautulli item", "title", h.Title, "rating_key", ratingKey, "error", err)
+				}
+				lookedUp[ratingKey] = ids
+			}
+			play.setIDs(ids)
+			if play.tmdbID == 0 && play.tvdbID == 0 {
+				continue
+			}
+		}
+		plays = append(plays, play)
 	}
 
-	// Link Overseerr requests after syncing from Radarr/Sonarr
-	// This ensures media items exist before we try to link requests
-	if s.integrations.Overseerr != nil {
-		if err := s.SyncOverseerrRequests(ctx); err != nil {
-			slog.Error("Overseerr request sync failed", "error", err)
+	mediaItems, err := s.loadTautulliMediaItems(ctx, plays)
+	if err != nil {
+		return SyncCounts{}, err
+	}
+	users, err := s.loadTautulliUsers(ctx, plays)
+	if err != nil {
+		return SyncCounts{}, err
+	}
+	watches := aggregateTautulliPlays(plays, mediaItems, users)
+
+	// Tautulli returns the full history, so the cache is replaced rather than merged
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SyncCounts{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tautulli_history"); err != nil {
+		return SyncCounts{}, fmt.Errorf("failed to clear Tautulli history: %w", err)
+	}
+	if len(watches) > 0 {
+		var (
+			mediaItemIDs = make([]int, 0, len(watches))
+			userIDs      = make([]int, 0, len(watches))
+			lastWatched  = make([]time.Time, 0, len(watches))
+			playCounts   = make([]int, 0, len(watches))
+		)
+		for key, stats := range watches {
+			mediaItemIDs = append(mediaItemIDs, key.mediaItemID)
+			userIDs = append(userIDs, key.userID)
+			lastWatched = append(lastWatched, stats.lastWatched)
+			playCounts = append(playCounts, stats.playCount)
 		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO tautulli_history (media_item_id, user_id, last_watched_at, play_count)
+			SELECT w.media_item_id, NULLIF(w.user_id, 0), w.last_watched_at, w.play_count
+			FROM UNNEST($1::int[], $2::int[], $3::timestamp[], $4::int[])
+				AS w(media_item_id, user_id, last_watched_at, play_count)`,
+			mediaItemIDs, userIDs, lastWatched, playCounts,
+		); err != nil {
+			return SyncCounts{}, fmt.Errorf("failed to store Tautulli history: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return SyncCounts{}, err
 	}
 
-	return nil
+	slog.InfoContext(ctx, "Tautulli history sync complete", "matched", len(watches), "total_history", len(history))
+	return SyncCounts{Seen: len(history), Updated: len(watches)}, nil
 }
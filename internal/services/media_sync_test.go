@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"removarr/internal/integrations"
+)
+
+func overseerrRequest(id int, mediaType string, tmdbID int, tvdbID *int, status, mediaStatus, requesterID int) integrations.OverseerrRequest {
+	var req integrations.OverseerrRequest
+	req.ID = id
+	req.MediaType = mediaType
+	req.Status = status
+	req.RequestedBy.ID = requesterID
+	req.RequestedBy.Email = "user@example.com"
+	req.Media.TMDBID = tmdbID
+	req.Media.TVDBID = tvdbID
+	req.Media.Status = mediaStatus
+	return req
+}
+
+func TestOverseerrLinks(t *testing.T) {
+	tvdbID := 81189
+	zero := 0
+	series := overseerrRequest(3, "", 1399, &tvdbID, 1, 4, 8)
+	series.Media.MediaType = "tv" // type only on the media
+
+	links := overseerrLinks([]integrations.OverseerrRequest{
+		overseerrRequest(1, "movie", 603, nil, 2, 3, 7),
+		overseerrRequest(2, "movie", 603, nil, 2, 5, 9), // later request for the same movie, now available
+		series,
+		overseerrRequest(4, "tv", 1400, &zero, 1, 2, 7), // no TVDB ID
+		overseerrRequest(5, "movie", 0, nil, 1, 2, 7),   // no TMDB ID
+		overseerrRequest(6, "movie", 604, nil, 3, 2, 7), // declined
+	})
+
+	want := map[overseerrKey]overseerrLink{
+		{mediaType: "movie", id: 603}:    {requestID: 2, requesterID: 9, open: false, email: "user@example.com"},
+		{mediaType: "series", id: 81189}: {requestID: 3, requesterID: 8, open: true, email: "user@example.com"},
+		{mediaType: "movie", id: 604}:    {requestID: 6, requesterID: 7, open: false, email: "user@example.com"},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("overseerrLinks() = %+v, want %+v", links, want)
+	}
+	for key, link := range want {
+		if got, ok := links[key]; !ok || got != link {
+			t.Errorf("link of %+v = %+v, want %+v", key, got, link)
+		}
+	}
+}
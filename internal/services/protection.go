@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// DefaultProtectionTag is the Radarr/Sonarr tag that protects media until another tag is configured
+const DefaultProtectionTag = "removarr-keep"
+
+// ErrProtected is returned when deleting a protected media item or its torrents
+var ErrProtected = errors.New("media item is protected")
+
+// ProtectionService manages the keep list: media items that must never be deleted
+type ProtectionService struct {
+	db *sql.DB
+}
+
+func NewProtectionService(db *sql.DB) *ProtectionService {
+	return &ProtectionService{db: db}
+}
+
+// Protection is a media item's protection state
+type Protection struct {
+	// IsProtected is true if the item is protected manually (and the protection hasn't expired) or by tag
+	IsProtected       bool       `json:"is_protected"`
+	Manual            bool       `json:"manual"`
+	ByTag             bool       `json:"by_tag"`
+	Until             *time.Time `json:"until,omitempty"` // manual protection expiry, nil means no expiry
+	Note              string     `json:"note,omitempty"`
+	ProtectedByUserID *int       `json:"protected_by_user_id,omitempty"`
+	ProtectedBy       string     `json:"protected_by,omitempty"` // username
+	ByAdmin           bool       `json:"by_admin"`               // the manual protection was set by an admin
+}
+
+// ChangeableBy reports whether a user may replace or clear the manual protection
+// A protection an admin set can only be changed by an admin, until it expires.
+func (p *Protection) ChangeableBy(isAdmin bool) bool {
+	return isAdmin || !p.Manual || !p.ByAdmin
+}
+
+// Reason describes why the item is protected, for eligibility and deletion messages
+func (p *Protection) Reason() string {
+	var reason string
+	switch {
+	case p.Manual && p.Until != nil:
+		reason = fmt.Sprintf("Protected until %s", p.Until.Format("Jan 2, 2006"))
+	case p.Manual:
+		reason = "Protected"
+	default:
+		reason = "Protected by Radarr/Sonarr tag"
+	}
+	if p.Manual && p.Note != "" {
+		reason += ": " + p.Note
+	}
+	return reason
+}
+
+// manualProtectionCondition matches media items with a manual protection that hasn't expired
+// Expired protection is kept until cleared, so the note stays visible, but no longer applies.
+const manualProtectionCondition = `(is_protected AND (protected_until IS NULL OR protected_until > CURRENT_TIMESTAMP))`
+
+// protectedCondition matches media items that are protected by tag or by an unexpired manual protection
+// It is the one definition of protected: loadProtections reads it, and SQL filtering on protection uses it.
+const protectedCondition = `(protected_by_tag OR ` + manualProtectionCondition + `)`
+
+// loadProtection reads a media item's protection state
+func loadProtection(ctx context.Context, db *sql.DB, mediaID int) (*Protection, error) {
+	protections, err := loadProtections(ctx, db, []int{mediaID})
+	if err != nil {
+		return nil, err
+	}
+	protection, ok := protections[mediaID]
+	if !ok {
+		return nil, fmt.Errorf("media item not found: %d", mediaID)
+	}
+	return protection, nil
+}
+
+// loadProtections reads the protection state of several media items in one query, keyed by media item ID
+// Items that don't exist are left out
+func loadProtections(ctx context.Context, db *sql.DB, mediaIDs []int) (map[int]*Protection, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT m.id, `+manualProtectionCondition+`, m.protected_by_tag, m.protected_until, m.protection_note,
+			m.protected_by_user_id, u.username, m.protected_by_admin
+		FROM media_items m
+		LEFT JOIN users u ON u.id = m.protected_by_user_id
+		WHERE m.id = ANY($1)`,
+		mediaIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get protection: %w", err)
+	}
+	defer rows.Close()
+
+	protections := make(map[int]*Protection, len(mediaIDs))
+	for rows.Next() {
+		var (
+			id         int
+			protection Protection
+			until      sql.NullTime
+			note       sql.NullString
+			userID     sql.NullInt64
+			username   sql.NullString
+		)
+		if err := rows.Scan(&id, &protection.Manual, &protection.ByTag, &until, &note, &userID, &username, &protection.ByAdmin); err != nil {
+			return nil, fmt.Errorf("failed to get protection: %w", err)
+		}
+
+		if until.Valid {
+			protection.Until = &until.Time
+		}
+		protection.Note = note.String
+		if userID.Valid {
+			byUserID := int(userID.Int64)
+			protection.ProtectedByUserID = &byUserID
+		}
+		protection.ProtectedBy = username.String
+		protection.IsProtected = protection.Manual || protection.ByTag
+		protections[id] = &protection
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get protection: %w", err)
+	}
+	return protections, nil
+}
+
+// checkNotProtected returns ErrProtected if the media item is protected
+func checkNotProtected(ctx context.Context, db *sql.DB, mediaID int) error {
+	protection, err := loadProtection(ctx, db, mediaID)
+	if err != nil {
+		return err
+	}
+	if protection.IsProtected {
+		return fmt.Errorf("%w: %s", ErrProtected, protection.Reason())
+	}
+	return nil
+}
+
+// Get returns a media item's protection state
+func (s *ProtectionService) Get(ctx context.Context, mediaID int) (*Protection, error) {
+	return loadProtection(ctx, s.db, mediaID)
+}
+
+// Protect adds a media item to the keep list, replacing any previous manual protection
+// until is optional; the protection lapses after it. userID 0 records no user, e.g. for a requester's link
+// byAdmin records that an admin set it, so only admins can clear it.
+func (s *ProtectionService) Protect(ctx context.Context, mediaID int, userID int, byAdmin bool, until *time.Time, note string) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE media_items SET
+			is_protected = TRUE,
+			protected_until = $2,
+			protection_note = NULLIF($3, ''),
+			protected_by_user_id = NULLIF($4, 0),
+			protected_by_admin = $5,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		mediaID, until, strings.TrimSpace(note), userID, byAdmin,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to protect media item: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("media item not found: %d", mediaID)
+	}
+
+	details := map[string]interface{}{"note": strings.TrimSpace(note)}
+	if until != nil {
+		details["until"] = until
+	}
+	s.audit(ctx, mediaID, userID, "protect", details)
+	slog.InfoContext(ctx, "Protected media item", "media_id", mediaID, "user_id", userID, "until", until)
+	return nil
+}
+
+// Unprotect removes a media item's manual protection
+// Tag protection can only be removed by removing the tag in Radarr/Sonarr
+func (s *ProtectionService) Unprotect(ctx context.Context, mediaID int, userID int) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE media_items SET
+			is_protected = FALSE,
+			protected_until = NULL,
+			protection_note = NULL,
+			protected_by_user_id = NULL,
+			protected_by_admin = FALSE,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		mediaID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to unprotect media item: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("media item not found: %d", mediaID)
+	}
+
+	s.audit(ctx, mediaID, userID, "unprotect", map[string]interface{}{})
+	slog.InfoContext(ctx, "Unprotected media item", "media_id", mediaID, "user_id", userID)
+	return nil
+}
+
+// audit records a protection change in the audit log
+func (s *ProtectionService) audit(ctx context.Context, mediaID int, userID int, action string, details map[string]interface{}) {
+	detailsJSON, _ := json.Marshal(details)
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_logs (user_id, action, media_item_id, media_title, media_type, details)
+		SELECT NULLIF($1, 0), $2, id, title, type, $3 FROM media_items WHERE id = $4`,
+		userID, action, string(detailsJSON), mediaID,
+	); err != nil {
+		slog.ErrorContext(ctx, "Failed to log protection change", "media_id", mediaID, "action", action, "error", err)
+	}
+}
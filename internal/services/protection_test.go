@@ -0,0 +1,26 @@
+package services
+
+import "testing"
+
+func TestProtectionChangeableBy(t *testing.T) {
+	tests := []struct {
+		name       string
+		protection Protection
+		isAdmin    bool
+		want       bool
+	}{
+		{"admin clears admin protection", Protection{Manual: true, ByAdmin: true}, true, true},
+		{"requester cannot clear admin protection", Protection{Manual: true, ByAdmin: true}, false, false},
+		{"requester clears own protection", Protection{Manual: true}, false, true},
+		{"admin clears requester protection", Protection{Manual: true}, true, true},
+		{"requester replaces expired admin protection", Protection{ByAdmin: true}, false, true},
+		{"tag protection only", Protection{ByTag: true}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.protection.ChangeableBy(tt.isAdmin); got != tt.want {
+				t.Errorf("ChangeableBy(%v) = %v, want %v", tt.isAdmin, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"removarr/internal/integrations"
+)
+
+// ReportService cross-references torrents, media items and the library root
+// folders to find content that is no longer tracked by one side or the other
+type ReportService struct {
+	db           *sql.DB
+	integrations *integrations.Client
+}
+
+// OrphanReport is the result of a single orphan scan
+type OrphanReport struct {
+	GeneratedAt      time.Time          `json:"generated_at"`
+	RootFolders      []string           `json:"root_folders"`
+	OrphanedTorrents []OrphanedTorrent  `json:"orphaned_torrents"`
+	UnseededMedia    []UnseededMedia    `json:"unseeded_media"`
+	UntrackedFiles   []UntrackedFile    `json:"untracked_files"`
+	Totals           OrphanReportTotals `json:"totals"`
+	Warnings         []string           `json:"warnings"`
+}
+
+// OrphanReportTotals summarises counts and sizes per report section
+type OrphanReportTotals struct {
+	OrphanedTorrentCount int   `json:"orphaned_torrent_count"`
+	OrphanedTorrentBytes int64 `json:"orphaned_torrent_bytes"`
+	UnseededMediaCount   int   `json:"unseeded_media_count"`
+	UnseededMediaBytes   int64 `json:"unseeded_media_bytes"`
+	UntrackedFileCount   int   `json:"untracked_file_count"`
+	UntrackedFileBytes   int64 `json:"untracked_file_bytes"`
+}
+
+// OrphanedTorrent is a torrent in qBittorrent with no library entry
+type OrphanedTorrent struct {
+	Hash        string     `json:"hash"`
+	Name        string     `json:"name"`
+	TrackerName string     `json:"tracker_name"`
+	ContentPath string     `json:"content_path"`
+	SizeBytes   int64      `json:"size_bytes"`
+	AddedDate   *time.Time `json:"added_date,omitempty"`
+	IsSeeding   bool       `json:"is_seeding"`
+}
+
+// UnseededMedia is a downloaded media item that no torrent is seeding
+// TorrentCount > 0 means torrents exist but none of them are seeding
+type UnseededMedia struct {
+	MediaItemID  int        `json:"media_item_id"`
+	Title        string     `json:"title"`
+	Type         string     `json:"type"`
+	FilePath     string     `json:"file_path"`
+	SizeBytes    int64      `json:"size_bytes"`
+	AddedDate    *time.Time `json:"added_date,omitempty"`
+	TorrentCount int        `json:"torrent_count"`
+}
+
+// UntrackedFile is a top-level entry in a root folder that no media item or torrent references
+type UntrackedFile struct {
+	Path       string    `json:"path"`
+	RootFolder string    `json:"root_folder"`
+	SizeBytes  int64     `json:"size_bytes"`
+	IsDir      bool      `json:"is_dir"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+func NewReportService(db *sql.DB, integrationsClient *integrations.Client) *ReportService {
+	return &ReportService{
+		db:           db,
+		integrations: integrationsClient,
+	}
+}
+
+// GenerateOrphanReport builds the orphaned torrent, unseeded media and untracked file lists
+// configuredRoots are merged with the root folders reported by Sonarr and Radarr
+func (s *ReportService) GenerateOrphanReport(ctx context.Context, configuredRoots []string) (*OrphanReport, error) {
+	report := &OrphanReport{
+		GeneratedAt:      time.Now(),
+		OrphanedTorrents: []OrphanedTorrent{},
+		UnseededMedia:    []UnseededMedia{},
+		UntrackedFiles:   []UntrackedFile{},
+		Warnings:         []string{},
+	}
+
+	var err error
+	if report.OrphanedTorrents, err = s.findOrphanedTorrents(ctx); err != nil {
+		return nil, err
+	}
+	if report.UnseededMedia, err = s.findUnseededMedia(ctx); err != nil {
+		return nil, err
+	}
+
+	roots, warnings := s.RootFolders(ctx, configuredRoots)
+	report.RootFolders = roots
+	report.Warnings = append(report.Warnings, warnings...)
+
+	known, err := s.loadKnownPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range roots {
+		files, err := findUntrackedFiles(root, known)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to scan root folder", "root", root, "error", err)
+			report.Warnings = append(report.Warnings, fmt.Sprintf("Failed to scan %s: %v", root, err))
+			continue
+		}
+		report.UntrackedFiles = append(report.UntrackedFiles, files...)
+	}
+	sort.Slice(report.UntrackedFiles, func(i, j int) bool {
+		return report.UntrackedFiles[i].SizeBytes > report.UntrackedFiles[j].SizeBytes
+	})
+
+	for _, t := range report.OrphanedTorrents {
+		report.Totals.OrphanedTorrentBytes += t.SizeBytes
+	}
+	for _, m := range report.UnseededMedia {
+		report.Totals.UnseededMediaBytes += m.SizeBytes
+	}
+	for _, f := range report.UntrackedFiles {
+		report.Totals.UntrackedFileBytes += f.SizeBytes
+	}
+	report.Totals.OrphanedTorrentCount = len(report.OrphanedTorrents)
+	report.Totals.UnseededMediaCount = len(report.UnseededMedia)
+	report.Totals.UntrackedFileCount = len(report.UntrackedFiles)
+
+	return report, nil
+}
+
+// RootFolders returns the cleaned, de-duplicated list of library root folders
+// Failures to reach Sonarr or Radarr are returned as warnings rather than errors
+func (s *ReportService) RootFolders(ctx context.Context, configuredRoots []string) ([]string, []string) {
+	var warnings []string
+	paths := append([]string{}, configuredRoots...)
+
+	if s.integrations.Sonarr != nil {
+		folders, err := s.integrations.Sonarr.GetRootFolders(ctx)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Failed to get Sonarr root folders: %v", err))
+		}
+		for _, f := range folders {
+			paths = append(paths, f.Path)
+		}
+	}
+	if s.integrations.Radarr != nil {
+		folders, err := s.integrations.Radarr.GetRootFolders(ctx)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Failed to get Radarr root folders: %v", err))
+		}
+		for _, f := range folders {
+			paths = append(paths, f.Path)
+		}
+	}
+
+	seen := make(map[string]bool)
+	roots := []string{}
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" || !filepath.IsAbs(p) {
+			continue
+		}
+		p = filepath.Clean(p)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		roots = append(roots, p)
+	}
+	sort.Strings(roots)
+
+	return roots, warnings
+}
+
+func (s *ReportService) findOrphanedTorrents(ctx context.Context) ([]OrphanedTorrent, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT hash, name, COALESCE(tracker_name, tracker_url), content_path, size_bytes, added_date, is_seeding
+		FROM torrents
+		WHERE media_item_id IS NULL AND NOT is_ignored
+		ORDER BY size_bytes DESC NULLS LAST, hash`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orphaned torrents: %w", err)
+	}
+	defer rows.Close()
+
+	torrents := []OrphanedTorrent{}
+	for rows.Next() {
+		var (
+			t           OrphanedTorrent
+			name        sql.NullString
+			trackerName sql.NullString
+			contentPath sql.NullString
+			sizeBytes   sql.NullInt64
+			addedDate   sql.NullTime
+			isSeeding   sql.NullBool
+		)
+		if err := rows.Scan(&t.Hash, &name, &trackerName, &contentPath, &sizeBytes, &addedDate, &isSeeding); err != nil {
+			return nil, fmt.Errorf("failed to scan torrent: %w", err)
+		}
+		t.Name = name.String
+		t.TrackerName = trackerName.String
+		t.ContentPath = contentPath.String
+		t.SizeBytes = sizeBytes.Int64
+		t.IsSeeding = isSeeding.Bool
+		if addedDate.Valid {
+			t.AddedDate = &addedDate.Time
+		}
+		torrents = append(torrents, t)
+	}
+
+	return torrents, rows.Err()
+}
+
+func (s *ReportService) findUnseededMedia(ctx context.Context) ([]UnseededMedia, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT m.id, m.title, m.type, COALESCE(m.file_path, ''), COALESCE(m.file_size, 0), m.added_date,
+			(SELECT COUNT(*) FROM torrents t WHERE t.media_item_id = m.id)
+		FROM media_items m
+		WHERE COALESCE(m.file_size, 0) > 0
+			AND NOT EXISTS (SELECT 1 FROM torrents t WHERE t.media_item_id = m.id AND t.is_seeding)
+		ORDER BY m.file_size DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unseeded media: %w", err)
+	}
+	defer rows.Close()
+
+	media := []UnseededMedia{}
+	for rows.Next() {
+		var (
+			m         UnseededMedia
+			addedDate sql.NullTime
+		)
+		if err := rows.Scan(&m.MediaItemID, &m.Title, &m.Type, &m.FilePath, &m.SizeBytes, &addedDate, &m.TorrentCount); err != nil {
+			return nil, fmt.Errorf("failed to scan media item: %w", err)
+		}
+		if addedDate.Valid {
+			m.AddedDate = &addedDate.Time
+		}
+		media = append(media, m)
+	}
+
+	return media, rows.Err()
+}
+
+// knownPaths indexes every media file path and torrent content path
+// so a root folder entry can be checked without comparing against every row
+type knownPaths struct {
+	paths     map[string]bool // paths referenced directly
+	ancestors map[string]bool // referenced paths and all of their parent directories
+}
+
+func (s *ReportService) loadKnownPaths(ctx context.Context) (*knownPaths, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT file_path FROM media_items WHERE file_path IS NOT NULL AND file_path <> ''
+		UNION
+		SELECT content_path FROM torrents WHERE content_path IS NOT NULL AND content_path <> ''`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query known paths: %w", err)
+	}
+	defer rows.Close()
+
+	known := &knownPaths{
+		paths:     make(map[string]bool),
+		ancestors: make(map[string]bool),
+	}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("failed to scan path: %w", err)
+		}
+		p = filepath.Clean(p)
+		known.paths[p] = true
+		for dir := p; ; dir = filepath.Dir(dir) {
+			known.ancestors[dir] = true
+			if dir == filepath.Dir(dir) {
+				break
+			}
+		}
+	}
+
+	return known, rows.Err()
+}
+
+// loadLibraryPaths returns the file path of every media item but exceptMediaID (0 for none)
+func loadLibraryPaths(ctx context.Context, db *sql.DB, exceptMediaID int) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT file_path FROM media_items WHERE file_path IS NOT NULL AND file_path <> '' AND id <> $1`,
+		exceptMediaID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media file paths: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("failed to scan path: %w", err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// isTracked reports whether path is referenced, contains a referenced path,
+// or lives inside a referenced path
+func (k *knownPaths) isTracked(path string) bool {
+	if k.ancestors[path] {
+		return true
+	}
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if k.paths[dir] {
+			return true
+		}
+		if dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
+
+// findUntrackedFiles lists the top-level entries of root that nothing references
+// Hidden entries (e.g. .stfolder, .DS_Store) are skipped
+func findUntrackedFiles(root string, known *knownPaths) ([]UntrackedFile, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []UntrackedFile
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(root, entry.Name())
+		if known.isTracked(path) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			slog.Warn("Failed to stat untracked path", "path", path, "error", err)
+			continue
+		}
+
+		files = append(files, UntrackedFile{
+			Path:       path,
+			RootFolder: root,
+			SizeBytes:  diskUsage(path, info),
+			IsDir:      entry.IsDir(),
+			ModifiedAt: info.ModTime(),
+		})
+	}
+
+	return files, nil
+}
+
+// diskUsage returns the total size of a file or directory tree
+func diskUsage(path string, info fs.FileInfo) int64 {
+	if !info.IsDir() {
+		return info.Size()
+	}
+
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if !d.IsDir() {
+			if fi, err := d.Info(); err == nil {
+				total += fi.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// DeleteOrphanedTorrent removes an orphaned torrent from qBittorrent, and its files unless another torrent
+// or the library still uses them
+func (s *ReportService) DeleteOrphanedTorrent(ctx context.Context, hash string, userID int) error {
+	if s.integrations.QBittorrent == nil {
+		return fmt.Errorf("qbittorrent integration not enabled")
+	}
+
+	var (
+		mediaItemID sql.NullInt64
+		name        sql.NullString
+		contentPath sql.NullString
+		sizeBytes   sql.NullInt64
+	)
+	err := s.db.QueryRowContext(ctx,
+		"SELECT media_item_id, name, content_path, size_bytes FROM torrents WHERE hash = $1",
+		hash,
+	).Scan(&mediaItemID, &name, &contentPath, &sizeBytes)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("torrent not found: %s", hash)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get torrent: %w", err)
+	}
+	if mediaItemID.Valid {
+		return fmt.Errorf("torrent is linked to media item %d, delete the media item instead", mediaItemID.Int64)
+	}
+
+	// Its data stays if a cross-seed or another torrent on the same files is kept, or it holds a library file
+	kept, all, err := loadKeptData(ctx, s.db, map[string]bool{hash: true}, 0)
+	if err != nil {
+		return err
+	}
+	var orphan torrentData
+	for _, t := range all {
+		if t.hash == hash {
+			orphan = t
+		}
+	}
+	deleteData := !kept.uses(orphan)
+
+	if err := s.integrations.QBittorrent.DeleteTorrent(ctx, hash, deleteData); err != nil {
+		return fmt.Errorf("failed to delete torrent from qBittorrent: %w", err)
+	}
+	slog.InfoContext(ctx, "Deleted orphaned torrent", "hash", hash, "name", name.String, "delete_data", deleteData)
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM torrents WHERE hash = $1", hash); err != nil {
+		return fmt.Errorf("failed to delete torrent from database: %w", err)
+	}
+
+	s.logAudit(ctx, userID, "delete_torrent", name.String, "torrent", map[string]interface{}{
+		"hash":         hash,
+		"content_path": contentPath.String,
+		"size_bytes":   sizeBytes.Int64,
+		"source":       "orphan_report",
+		"delete_data":  deleteData,
+	})
+
+	return nil
+}
+
+// DeleteUntrackedFile removes an untracked top-level entry from a root folder
+// The path must sit directly inside one of the root folders and still be untracked
+func (s *ReportService) DeleteUntrackedFile(ctx context.Context, path string, configuredRoots []string, userID int) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("path must be absolute: %s", path)
+	}
+	path = filepath.Clean(path)
+
+	roots, _ := s.RootFolders(ctx, configuredRoots)
+	inRoot := false
+	for _, root := range roots {
+		if filepath.Dir(path) == root {
+			inRoot = true
+			break
+		}
+	}
+	if !inRoot {
+		return fmt.Errorf("path is not inside a library root folder: %s", path)
+	}
+
+	known, err := s.loadKnownPaths(ctx)
+	if err != nil {
+		return err
+	}
+	if known.isTracked(path) {
+		return fmt.Errorf("path is referenced by a media item or torrent: %s", path)
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat path: %w", err)
+	}
+	size := diskUsage(path, info)
+
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to delete path: %w", err)
+	}
+	slog.InfoContext(ctx, "Deleted untracked path", "path", path, "size", size)
+
+	s.logAudit(ctx, userID, "delete_file", filepath.Base(path), "file", map[string]interface{}{
+		"path":       path,
+		"size_bytes": size,
+		"source":     "orphan_report",
+	})
+
+	return nil
+}
+
+func (s *ReportService) logAudit(ctx context.Context, userID int, action, title, mediaType string, details map[string]interface{}) {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encode audit details", "error", err)
+		return
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_logs (user_id, action, media_title, media_type, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, action, title, mediaType, string(detailsJSON)); err != nil {
+		slog.ErrorContext(ctx, "Failed to create audit log", "error", err)
+	}
+}
@@ -0,0 +1,588 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"removarr/internal/events"
+)
+
+// Scheduled deletion states
+// A deletion is scheduled until its grace period is over, then executed, unless it is cancelled first
+const (
+	ScheduledDeletionScheduled = "scheduled"
+	ScheduledDeletionCancelled = "cancelled"
+	ScheduledDeletionExecuted  = "executed"
+)
+
+// DefaultGracePeriod is how long scheduled deletions wait until another grace period is configured
+const DefaultGracePeriod = 72 * time.Hour
+
+// MaxPostpones is how many times a requester can postpone a scheduled deletion
+const MaxPostpones = 3
+
+// ErrAlreadyScheduled is returned when a media item already has a pending scheduled deletion
+var ErrAlreadyScheduled = errors.New("media item is already scheduled for deletion")
+
+// ErrNotScheduled is returned when a scheduled deletion is no longer pending
+var ErrNotScheduled = errors.New("deletion is no longer scheduled")
+
+// ScheduledDeletionService manages deletions that wait for a grace period, during which the media's
+// requester is notified and can postpone the deletion or keep the media
+type ScheduledDeletionService struct {
+	db         *sql.DB
+	deletion   *DeletionService
+	protection *ProtectionService
+	events     *events.Bus
+}
+
+func NewScheduledDeletionService(db *sql.DB, deletion *DeletionService, protection *ProtectionService, bus *events.Bus) *ScheduledDeletionService {
+	return &ScheduledDeletionService{
+		db:         db,
+		deletion:   deletion,
+		protection: protection,
+		events:     bus,
+	}
+}
+
+// ScheduledDeletion is a media item waiting to be deleted
+type ScheduledDeletion struct {
+	ID             int        `json:"id"`
+	MediaItemID    *int       `json:"media_item_id,omitempty"` // nil once the media item is deleted
+	MediaTitle     string     `json:"media_title"`
+	MediaType      string     `json:"media_type"`
+	ScheduledBy    string     `json:"scheduled_by,omitempty"` // username
+	Status         string     `json:"status"`
+	ExecuteAfter   time.Time  `json:"execute_after"`
+	Token          string     `json:"-"` // authorizes the requester's postpone/keep link
+	NotifiedEmail  string     `json:"notified_email,omitempty"`
+	PostponedCount int        `json:"postponed_count"`
+	CancelReason   string     `json:"cancel_reason,omitempty"`
+	LastError      string     `json:"last_error,omitempty"` // why the last execution attempt failed
+	ExecutedAt     *time.Time `json:"executed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CanPostpone reports whether the requester can still postpone the deletion
+func (d *ScheduledDeletion) CanPostpone() bool {
+	return d.Status == ScheduledDeletionScheduled && d.PostponedCount < MaxPostpones
+}
+
+// checkScheduled returns ErrNotScheduled if the deletion was cancelled or executed
+func (d *ScheduledDeletion) checkScheduled() error {
+	if d.Status != ScheduledDeletionScheduled {
+		return fmt.Errorf("%w: it was %s", ErrNotScheduled, d.Status)
+	}
+	return nil
+}
+
+// checkCanKeep returns ErrNotScheduled if the requester can no longer keep the media
+func (d *ScheduledDeletion) checkCanKeep() error {
+	if err := d.checkScheduled(); err != nil {
+		return err
+	}
+	if d.MediaItemID == nil {
+		return fmt.Errorf("%w: the media item was deleted", ErrNotScheduled)
+	}
+	return nil
+}
+
+// postponedUntil is when a deletion postponed at now executes: a grace period after it was due,
+// or after now if it is already overdue
+func postponedUntil(executeAfter, now time.Time, grace time.Duration) time.Time {
+	if executeAfter.Before(now) {
+		executeAfter = now
+	}
+	return executeAfter.Add(grace)
+}
+
+// dueStep is what executing a due deletion does
+type dueStep int
+
+const (
+	dueSkip         dueStep = iota // cancelled or executed since it was loaded, left alone
+	dueMarkExecuted                // the media item was deleted some other way
+	dueCancel                      // the media was protected meanwhile
+	dueDelete
+)
+
+// nextDueStep decides what executing a due deletion does, from its current state
+// protection is the media item's, nil if it has none.
+func nextDueStep(deletion *ScheduledDeletion, protection *Protection) dueStep {
+	switch {
+	case deletion.Status != ScheduledDeletionScheduled:
+		return dueSkip
+	case deletion.MediaItemID == nil:
+		return dueMarkExecuted
+	case protection != nil && protection.IsProtected:
+		return dueCancel
+	default:
+		return dueDelete
+	}
+}
+
+const scheduledDeletionColumns = `
+	d.id, d.media_item_id, d.media_title, d.media_type, COALESCE(u.username, ''), d.status, d.execute_after,
+	d.token, COALESCE(d.notified_email, ''), d.postponed_count, COALESCE(d.cancel_reason, ''),
+	COALESCE(d.last_error, ''), d.executed_at, d.created_at
+	FROM scheduled_deletions d
+	LEFT JOIN users u ON u.id = d.scheduled_by_user_id`
+
+// scanScheduledDeletion scans a row selected with scheduledDeletionColumns
+func scanScheduledDeletion(row interface{ Scan(...interface{}) error }) (*ScheduledDeletion, error) {
+	var (
+		deletion   ScheduledDeletion
+		mediaID    sql.NullInt64
+		executedAt sql.NullTime
+		createdAt  sql.NullTime
+	)
+	if err := row.Scan(&deletion.ID, &mediaID, &deletion.MediaTitle, &deletion.MediaType, &deletion.ScheduledBy,
+		&deletion.Status, &deletion.ExecuteAfter, &deletion.Token, &deletion.NotifiedEmail, &deletion.PostponedCount,
+		&deletion.CancelReason, &deletion.LastError, &executedAt, &createdAt); err != nil {
+		return nil, err
+	}
+
+	if mediaID.Valid {
+		id := int(mediaID.Int64)
+		deletion.MediaItemID = &id
+	}
+	if executedAt.Valid {
+		deletion.ExecutedAt = &executedAt.Time
+	}
+	deletion.CreatedAt = createdAt.Time
+	return &deletion, nil
+}
+
+// GracePeriod returns how long deletions wait before they are executed, 0 if they are executed right away
+// Stored as a duration, e.g. "72h"
+func (s *ScheduledDeletionService) GracePeriod(ctx context.Context) time.Duration {
+	var raw string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = 'deletion.grace_period'").Scan(&raw)
+	if err == sql.ErrNoRows {
+		return DefaultGracePeriod
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get deletion grace period setting", "error", err)
+		return DefaultGracePeriod
+	}
+	grace, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil || grace < 0 {
+		slog.WarnContext(ctx, "Invalid deletion grace period setting, using default", "value", raw)
+		return DefaultGracePeriod
+	}
+	return grace
+}
+
+// Get returns a scheduled deletion
+func (s *ScheduledDeletionService) Get(ctx context.Context, id int) (*ScheduledDeletion, error) {
+	deletion, err := scanScheduledDeletion(s.db.QueryRowContext(ctx,
+		"SELECT "+scheduledDeletionColumns+" WHERE d.id = $1", id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("scheduled deletion not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get scheduled deletion: %w", err)
+	}
+	return deletion, nil
+}
+
+// GetByToken returns the scheduled deletion a requester's link points to
+func (s *ScheduledDeletionService) GetByToken(ctx context.Context, token string) (*ScheduledDeletion, error) {
+	deletion, err := scanScheduledDeletion(s.db.QueryRowContext(ctx,
+		"SELECT "+scheduledDeletionColumns+" WHERE d.token = $1", token,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("scheduled deletion not found")
+		}
+		return nil, fmt.Errorf("failed to get scheduled deletion: %w", err)
+	}
+	return deletion, nil
+}
+
+// List returns scheduled deletions with the given status (all if empty), pending ones first by execution time
+func (s *ScheduledDeletionService) List(ctx context.Context, status string) ([]ScheduledDeletion, error) {
+	query := "SELECT " + scheduledDeletionColumns
+	args := []interface{}{}
+	if status != "" {
+		args = append(args, status)
+		query += " WHERE d.status = $1"
+	}
+	query += ` ORDER BY CASE d.status WHEN 'scheduled' THEN 0 ELSE 1 END, d.execute_after`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled deletions: %w", err)
+	}
+	defer rows.Close()
+
+	deletions := []ScheduledDeletion{}
+	for rows.Next() {
+		deletion, err := scanScheduledDeletion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled deletion: %w", err)
+		}
+		deletions = append(deletions, *deletion)
+	}
+	return deletions, rows.Err()
+}
+
+// PendingByMedia returns the pending scheduled deletion of each media item, keyed by media item ID
+func (s *ScheduledDeletionService) PendingByMedia(ctx context.Context) (map[int]ScheduledDeletion, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+scheduledDeletionColumns+" WHERE d.status = 'scheduled' AND d.media_item_id IS NOT NULL",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled deletions: %w", err)
+	}
+	defer rows.Close()
+
+	deletions := make(map[int]ScheduledDeletion)
+	for rows.Next() {
+		deletion, err := scanScheduledDeletion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled deletion: %w", err)
+		}
+		deletions[*deletion.MediaItemID] = *deletion
+	}
+	return deletions, rows.Err()
+}
+
+// Schedule schedules a media item's deletion once the grace period is over, and notifies its requester
+// The heads-up goes to the requester's removarr email, or to the email Overseerr has for them
+func (s *ScheduledDeletionService) Schedule(ctx context.Context, mediaID int, userID int, grace time.Duration) (*ScheduledDeletion, error) {
+	if err := checkNotProtected(ctx, s.db, mediaID); err != nil {
+		return nil, err
+	}
+
+	var pending bool
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM scheduled_deletions WHERE media_item_id = $1 AND status = 'scheduled')`,
+		mediaID,
+	).Scan(&pending); err != nil {
+		return nil, fmt.Errorf("failed to check scheduled deletions: %w", err)
+	}
+	if pending {
+		return nil, ErrAlreadyScheduled
+	}
+
+	token, err := newScheduleToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	var (
+		requesterID    sql.NullInt64
+		requesterEmail string
+	)
+	err = s.db.QueryRowContext(ctx,
+		`SELECT m.requested_by_user_id, COALESCE(NULLIF(u.email, ''), m.requester_email, '')
+		FROM media_items m
+		LEFT JOIN users u ON u.id = m.requested_by_user_id AND u.is_active
+		WHERE m.id = $1`,
+		mediaID,
+	).Scan(&requesterID, &requesterEmail)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("media item not found: %d", mediaID)
+		}
+		return nil, fmt.Errorf("failed to get media item: %w", err)
+	}
+
+	var id int
+	if err := s.db.QueryRowContext(ctx,
+		`INSERT INTO scheduled_deletions (media_item_id, media_title, media_type, scheduled_by_user_id, execute_after, token, notified_email)
+		SELECT id, title, type, NULLIF($2, 0), $3, $4, NULLIF($5, '') FROM media_items WHERE id = $1
+		RETURNING id`,
+		mediaID, userID, time.Now().Add(grace), token, requesterEmail,
+	).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to schedule deletion: %w", err)
+	}
+
+	deletion, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, deletion, userID, "deletion_schedule", map[string]interface{}{
+		"execute_after":  deletion.ExecuteAfter,
+		"notified_email": requesterEmail,
+	})
+	slog.InfoContext(ctx, "Scheduled deletion", "scheduled_deletion_id", id, "media_id", mediaID, "execute_after", deletion.ExecuteAfter)
+
+	event := events.Event{
+		Type:           events.DeletionScheduled,
+		MediaItemID:    &mediaID,
+		MediaTitle:     deletion.MediaTitle,
+		MediaType:      deletion.MediaType,
+		Data:           map[string]interface{}{"execute_after": deletion.ExecuteAfter.Format("Mon, Jan 2 2006 15:04 MST")},
+		Path:           "/scheduled/" + token,
+		RecipientEmail: requesterEmail,
+	}
+	if requesterID.Valid {
+		requester := int(requesterID.Int64)
+		event.RequesterID = &requester
+	}
+	if userID != 0 {
+		event.ActorID = &userID
+	}
+	s.events.Publish(ctx, event)
+
+	return deletion, nil
+}
+
+// newScheduleToken returns a random token for a requester's postpone/keep link
+func newScheduleToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Postpone pushes a pending deletion back by the grace period, at most MaxPostpones times
+// Called from the requester's link, so there is no user
+func (s *ScheduledDeletionService) Postpone(ctx context.Context, token string, grace time.Duration) (*ScheduledDeletion, error) {
+	deletion, err := s.GetByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := deletion.checkScheduled(); err != nil {
+		return nil, err
+	}
+	if !deletion.CanPostpone() {
+		return nil, fmt.Errorf("deletion was already postponed %d times", deletion.PostponedCount)
+	}
+
+	// Only applies if nobody postponed, kept or executed it since it was loaded
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE scheduled_deletions SET
+			execute_after = $2,
+			postponed_count = postponed_count + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'scheduled' AND postponed_count = $3`,
+		deletion.ID, postponedUntil(deletion.ExecuteAfter, time.Now(), grace), deletion.PostponedCount,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to postpone deletion: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("%w: it changed meanwhile", ErrNotScheduled)
+	}
+
+	deletion, err = s.Get(ctx, deletion.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, deletion, 0, "deletion_postpone", map[string]interface{}{"execute_after": deletion.ExecuteAfter})
+	slog.InfoContext(ctx, "Postponed scheduled deletion", "scheduled_deletion_id", deletion.ID, "execute_after", deletion.ExecuteAfter)
+	return deletion, nil
+}
+
+// Keep protects the media of a pending deletion and cancels the deletion
+// Called from the requester's link, so there is no user
+func (s *ScheduledDeletionService) Keep(ctx context.Context, token string) error {
+	deletion, err := s.GetByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := deletion.checkCanKeep(); err != nil {
+		return err
+	}
+
+	if err := s.protection.Protect(ctx, *deletion.MediaItemID, 0, false, nil, "Kept by the requester from a scheduled deletion"); err != nil {
+		return err
+	}
+	return s.cancel(ctx, deletion, 0, "Kept by the requester")
+}
+
+// Cancel cancels a pending deletion
+func (s *ScheduledDeletionService) Cancel(ctx context.Context, id int, userID int, reason string) error {
+	deletion, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.cancel(ctx, deletion, userID, reason)
+}
+
+// cancel moves a pending deletion to cancelled
+func (s *ScheduledDeletionService) cancel(ctx context.Context, deletion *ScheduledDeletion, userID int, reason string) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE scheduled_deletions SET
+			status = 'cancelled',
+			cancel_reason = NULLIF($2, ''),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'scheduled'`,
+		deletion.ID, strings.TrimSpace(reason),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scheduled deletion: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: it was %s", ErrNotScheduled, deletion.Status)
+	}
+
+	s.audit(ctx, deletion, userID, "deletion_cancel", map[string]interface{}{"reason": strings.TrimSpace(reason)})
+	slog.InfoContext(ctx, "Cancelled scheduled deletion", "scheduled_deletion_id", deletion.ID, "reason", reason)
+	return nil
+}
+
+// ProcessDue executes the pending deletions whose grace period is over
+// It is run after each periodic sync; returns how many deletions were executed
+// Media that was protected meanwhile is not deleted, and its deletion is cancelled
+func (s *ScheduledDeletionService) ProcessDue(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+scheduledDeletionColumns+" WHERE d.status = 'scheduled' AND d.execute_after <= CURRENT_TIMESTAMP ORDER BY d.execute_after",
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query due deletions: %w", err)
+	}
+	var due []ScheduledDeletion
+	for rows.Next() {
+		deletion, err := scanScheduledDeletion(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan scheduled deletion: %w", err)
+		}
+		due = append(due, *deletion)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query due deletions: %w", err)
+	}
+
+	executed := 0
+	for i := range due {
+		// Stop between deletions on shutdown; the rest are picked up by the next run
+		if err := ctx.Err(); err != nil {
+			return executed, err
+		}
+		done, err := s.execute(ctx, &due[i])
+		if errors.Is(err, ErrNotScheduled) {
+			// Cancelled or executed since it was loaded; nothing went wrong
+			slog.DebugContext(ctx, "Skipped scheduled deletion", "scheduled_deletion_id", due[i].ID, "reason", err)
+			continue
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to execute scheduled deletion", "scheduled_deletion_id", due[i].ID, "error", err)
+		}
+		if done {
+			executed++
+		}
+	}
+	return executed, nil
+}
+
+// execute deletes a due deletion's media item and marks the deletion executed
+// Eligibility isn't checked again: deletion requests are only scheduled once eligible, and admins may schedule any item
+// A deletion cancelled since it was loaded returns ErrNotScheduled, without recording an error.
+func (s *ScheduledDeletionService) execute(ctx context.Context, deletion *ScheduledDeletion) (bool, error) {
+	current, err := s.Get(ctx, deletion.ID)
+	if err != nil {
+		return false, err
+	}
+	*deletion = *current
+
+	var protection *Protection
+	if deletion.Status == ScheduledDeletionScheduled && deletion.MediaItemID != nil {
+		if protection, err = loadProtection(ctx, s.db, *deletion.MediaItemID); err != nil {
+			return false, s.recordError(ctx, deletion.ID, err)
+		}
+	}
+
+	switch nextDueStep(deletion, protection) {
+	case dueSkip:
+		return false, deletion.checkScheduled()
+	case dueMarkExecuted:
+		return true, s.markExecuted(ctx, deletion.ID, "")
+	case dueCancel:
+		return false, s.cancel(ctx, deletion, 0, fmt.Sprintf("%v: %s", ErrProtected, protection.Reason()))
+	}
+	mediaID := *deletion.MediaItemID
+
+	var scheduledBy sql.NullInt64
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT scheduled_by_user_id FROM scheduled_deletions WHERE id = $1`,
+		deletion.ID,
+	).Scan(&scheduledBy); err != nil {
+		return false, fmt.Errorf("failed to get scheduled deletion: %w", err)
+	}
+
+	// The user who scheduled the deletion is recorded as the user who deleted the media
+	deleteErr := s.deletion.DeleteMediaItem(ctx, mediaID, int(scheduledBy.Int64))
+	// Whatever the deletion got done is recorded, even if ctx was cancelled during it
+	ctx = context.WithoutCancel(ctx)
+
+	// DeleteMediaItem removes the media item even when some steps fail
+	var exists bool
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM media_items WHERE id = $1)`,
+		mediaID,
+	).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check media item: %w", err)
+	}
+	if exists {
+		return false, s.recordError(ctx, deletion.ID, deleteErr)
+	}
+
+	lastError := ""
+	if deleteErr != nil {
+		lastError = deleteErr.Error()
+	}
+	if err := s.markExecuted(ctx, deletion.ID, lastError); err != nil {
+		return true, err
+	}
+	slog.InfoContext(ctx, "Executed scheduled deletion", "scheduled_deletion_id", deletion.ID, "media_id", mediaID)
+	return true, deleteErr
+}
+
+// markExecuted moves a pending deletion to executed
+func (s *ScheduledDeletionService) markExecuted(ctx context.Context, id int, lastError string) error {
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE scheduled_deletions SET
+			status = 'executed',
+			executed_at = CURRENT_TIMESTAMP,
+			last_error = NULLIF($2, ''),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'scheduled'`,
+		id, lastError,
+	); err != nil {
+		return fmt.Errorf("failed to mark scheduled deletion executed: %w", err)
+	}
+	return nil
+}
+
+// recordError stores why executing a pending deletion failed, so admins can see it, and returns err
+func (s *ScheduledDeletionService) recordError(ctx context.Context, id int, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, dbErr := s.db.ExecContext(ctx,
+		`UPDATE scheduled_deletions SET last_error = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND status = 'scheduled'`,
+		id, err.Error(),
+	); dbErr != nil {
+		slog.ErrorContext(ctx, "Failed to record scheduled deletion error", "scheduled_deletion_id", id, "error", dbErr)
+	}
+	return err
+}
+
+// audit records a scheduled deletion change in the audit log; userID 0 is the requester's link
+func (s *ScheduledDeletionService) audit(ctx context.Context, deletion *ScheduledDeletion, userID int, action string, details map[string]interface{}) {
+	details["scheduled_deletion_id"] = deletion.ID
+	detailsJSON, _ := json.Marshal(details)
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_logs (user_id, action, media_item_id, media_title, media_type, details)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6)`,
+		userID, action, deletion.MediaItemID, deletion.MediaTitle, deletion.MediaType, string(detailsJSON),
+	); err != nil {
+		slog.ErrorContext(ctx, "Failed to log scheduled deletion change", "scheduled_deletion_id", deletion.ID, "action", action, "error", err)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduledDeletionPostpone(t *testing.T) {
+	tests := []struct {
+		name        string
+		deletion    ScheduledDeletion
+		wantErr     error
+		canPostpone bool
+	}{
+		{"scheduled", ScheduledDeletion{Status: ScheduledDeletionScheduled}, nil, true},
+		{"postponed before", ScheduledDeletion{Status: ScheduledDeletionScheduled, PostponedCount: MaxPostpones - 1}, nil, true},
+		{"postponed too often", ScheduledDeletion{Status: ScheduledDeletionScheduled, PostponedCount: MaxPostpones}, nil, false},
+		{"cancelled", ScheduledDeletion{Status: ScheduledDeletionCancelled}, ErrNotScheduled, false},
+		{"executed", ScheduledDeletion{Status: ScheduledDeletionExecuted}, ErrNotScheduled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.deletion.checkScheduled(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkScheduled() = %v, want %v", err, tt.wantErr)
+			}
+			if got := tt.deletion.CanPostpone(); got != tt.canPostpone {
+				t.Errorf("CanPostpone() = %v, want %v", got, tt.canPostpone)
+			}
+		})
+	}
+}
+
+func TestPostponedUntil(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	grace := 72 * time.Hour
+
+	// A pending deletion moves a grace period past when it was due
+	due := now.Add(24 * time.Hour)
+	if got, want := postponedUntil(due, now, grace), due.Add(grace); !got.Equal(want) {
+		t.Errorf("postponedUntil(pending) = %s, want %s", got, want)
+	}
+	// An overdue one gets a full grace period from now
+	if got, want := postponedUntil(now.Add(-time.Hour), now, grace), now.Add(grace); !got.Equal(want) {
+		t.Errorf("postponedUntil(overdue) = %s, want %s", got, want)
+	}
+}
+
+func TestScheduledDeletionKeep(t *testing.T) {
+	mediaID := 5
+	tests := []struct {
+		name     string
+		deletion ScheduledDeletion
+		wantErr  error
+	}{
+		{"scheduled", ScheduledDeletion{Status: ScheduledDeletionScheduled, MediaItemID: &mediaID}, nil},
+		{"media item deleted", ScheduledDeletion{Status: ScheduledDeletionScheduled}, ErrNotScheduled},
+		{"cancelled", ScheduledDeletion{Status: ScheduledDeletionCancelled, MediaItemID: &mediaID}, ErrNotScheduled},
+		{"executed", ScheduledDeletion{Status: ScheduledDeletionExecuted}, ErrNotScheduled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.deletion.checkCanKeep(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkCanKeep() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextDueStep(t *testing.T) {
+	mediaID := 5
+	tests := []struct {
+		name       string
+		deletion   ScheduledDeletion
+		protection *Protection
+		want       dueStep
+	}{
+		{"due", ScheduledDeletion{Status: ScheduledDeletionScheduled, MediaItemID: &mediaID}, &Protection{}, dueDelete},
+		{"cancelled meanwhile", ScheduledDeletion{Status: ScheduledDeletionCancelled, MediaItemID: &mediaID}, nil, dueSkip},
+		{"executed meanwhile", ScheduledDeletion{Status: ScheduledDeletionExecuted}, nil, dueSkip},
+		{"media item deleted some other way", ScheduledDeletion{Status: ScheduledDeletionScheduled}, nil, dueMarkExecuted},
+		{"protected meanwhile", ScheduledDeletion{Status: ScheduledDeletionScheduled, MediaItemID: &mediaID},
+			&Protection{IsProtected: true, Manual: true}, dueCancel},
+		{"protected by tag", ScheduledDeletion{Status: ScheduledDeletionScheduled, MediaItemID: &mediaID},
+			&Protection{IsProtected: true, ByTag: true}, dueCancel},
+		{"protection expired", ScheduledDeletion{Status: ScheduledDeletionScheduled, MediaItemID: &mediaID},
+			&Protection{Until: &time.Time{}}, dueDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDueStep(&tt.deletion, tt.protection); got != tt.want {
+				t.Errorf("nextDueStep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Scoring horizons: a factor reaches its maximum once the measured period is this long
+const (
+	scoreUploadWindow        = 7 * 24 * time.Hour   // upload is measured over this period
+	scoreEligibilityHorizon  = 30 * 24 * time.Hour  // items eligible within this period get partial credit
+	scoreUnwatchedHorizon    = 365 * 24 * time.Hour // not watched for this long counts as never watched
+	scoreAgeHorizon          = 365 * 24 * time.Hour
+	scoreMaxEligibleSoonPart = 0.5 // credit for an item about to become eligible, relative to an eligible one
+)
+
+// ScoringService ranks media items by how much of a deletion candidate ("dead weight") they are
+type ScoringService struct {
+	db      *sql.DB
+	history *TorrentHistoryService
+}
+
+func NewScoringService(db *sql.DB, history *TorrentHistoryService) *ScoringService {
+	return &ScoringService{
+		db:      db,
+		history: history,
+	}
+}
+
+// ScoreWeights is the relative importance of each score factor
+// Only the ratios between weights matter; a weight of 0 disables the factor
+type ScoreWeights struct {
+	Size         float64 `json:"size"`
+	Eligibility  float64 `json:"eligibility"`
+	RecentUpload float64 `json:"recent_upload"`
+	LastWatched  float64 `json:"last_watched"`
+	PlayCount    float64 `json:"play_count"`
+	Age          float64 `json:"age"`
+	OpenRequest  float64 `json:"open_request"`
+}
+
+// DefaultScoreWeights are used until weights are configured in Settings
+func DefaultScoreWeights() ScoreWeights {
+	return ScoreWeights{
+		Size:         2,
+		Eligibility:  3,
+		RecentUpload: 2,
+		LastWatched:  2,
+		PlayCount:    1,
+		Age:          1,
+		OpenRequest:  2,
+	}
+}
+
+// ScoreFactors are the individual score components, each from 0 (keep) to 1 (delete)
+type ScoreFactors struct {
+	Size         float64 `json:"size"`          // file size relative to the largest item, on a log scale
+	Eligibility  float64 `json:"eligibility"`   // 1 if eligible, partial if eligible soon
+	RecentUpload float64 `json:"recent_upload"` // 1 if nothing was uploaded over the last week
+	LastWatched  float64 `json:"last_watched"`  // 1 if never watched or not watched for a year
+	PlayCount    float64 `json:"play_count"`    // 1 if never played
+	Age          float64 `json:"age"`           // 1 if added a year ago or more
+	OpenRequest  float64 `json:"open_request"`  // 0 while an Overseerr request is still open
+}
+
+// MediaScore is a media item's dead weight score, from 0 to 100
+type MediaScore struct {
+	Score   float64      `json:"score"`
+	Factors ScoreFactors `json:"factors"`
+}
+
+// Score computes the score of every media item in statuses, keyed by media item ID
+// statuses are the items' eligibility, as returned by EligibilityService.CheckEligibility
+func (s *ScoringService) Score(ctx context.Context, statuses map[int]*EligibilityStatus, weights ScoreWeights) (map[int]MediaScore, error) {
+	now := time.Now()
+
+	recentUploads, err := s.history.RecentUploadByMedia(ctx, now.Add(-scoreUploadWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	// Every item is needed for the library's largest size, which the size factor is relative to
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, COALESCE(file_size, 0), added_date, overseerr_request_open FROM media_items",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media items: %w", err)
+	}
+	defer rows.Close()
+
+	facts := make(map[int]mediaFacts)
+	var maxSize int64
+	for rows.Next() {
+		var id int
+		var f mediaFacts
+		if err := rows.Scan(&id, &f.size, &f.addedDate, &f.requestOpen); err != nil {
+			return nil, fmt.Errorf("failed to scan media item: %w", err)
+		}
+		maxSize = max(maxSize, f.size)
+		if _, ok := statuses[id]; ok {
+			facts[id] = f
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query media items: %w", err)
+	}
+
+	scores := make(map[int]MediaScore, len(statuses))
+	for id, status := range statuses {
+		// Protected items are never deletion candidates, so they aren't scored
+		f, ok := facts[id]
+		if !ok || status.Protection != nil {
+			continue
+		}
+
+		factors := scoreFactors(status, f, maxSize, recentUploads[id], now)
+		scores[id] = MediaScore{
+			Score:   weightedScore(factors, weights),
+			Factors: factors,
+		}
+	}
+
+	return scores, nil
+}
+
+// Record scores every media item in statuses and stores the scores, for the dashboard to sort by
+// Items that aren't scored, e.g. protected ones, get no score. Run after each sync and when the weights change.
+func (s *ScoringService) Record(ctx context.Context, statuses map[int]*EligibilityStatus, weights ScoreWeights) error {
+	scores, err := s.Score(ctx, statuses, weights)
+	if err != nil {
+		return err
+	}
+
+	// Unscored items are sent as 0 with scored false, and written as NULL
+	ids := make([]int, 0, len(statuses))
+	scored := make([]bool, 0, len(statuses))
+	values := make([]float64, 0, len(statuses))
+	for id := range statuses {
+		score, ok := scores[id]
+		ids = append(ids, id)
+		scored = append(scored, ok)
+		values = append(values, score.Score)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE media_items m SET score = u.score
+		FROM (
+			SELECT id, CASE WHEN scored THEN score END AS score
+			FROM UNNEST($1::int[], $2::bool[], $3::float8[]) AS u(id, scored, score)
+		) u
+		WHERE m.id = u.id AND m.score IS DISTINCT FROM u.score`,
+		ids, scored, values,
+	); err != nil {
+		return fmt.Errorf("failed to record scores: %w", err)
+	}
+	return nil
+}
+
+// mediaFacts is what scoring needs of a media item besides its eligibility
+type mediaFacts struct {
+	size        int64
+	addedDate   sql.NullTime
+	requestOpen bool
+}
+
+// scoreFactors computes an item's score factors
+// maxSize is the library's largest item, uploaded what the item's torrents uploaded over scoreUploadWindow
+func scoreFactors(status *EligibilityStatus, f mediaFacts, maxSize, uploaded int64, now time.Time) ScoreFactors {
+	var factors ScoreFactors
+
+	if f.size > 0 && maxSize > 0 {
+		factors.Size = math.Log1p(float64(f.size)) / math.Log1p(float64(maxSize))
+	}
+
+	switch {
+	case status.IsEligible:
+		factors.Eligibility = 1
+	case status.EstimatedEligibleAt != nil:
+		if until := status.EstimatedEligibleAt.Sub(now); until < scoreEligibilityHorizon {
+			factors.Eligibility = scoreMaxEligibleSoonPart * (1 - fraction(until, scoreEligibilityHorizon))
+		}
+	}
+
+	// Upload is compared to the item's size, so a weekly ratio of 1 or more counts as fully active
+	switch {
+	case f.size > 0:
+		factors.RecentUpload = 1 - math.Min(1, float64(uploaded)/float64(f.size))
+	case uploaded == 0:
+		factors.RecentUpload = 1
+	}
+
+	if status.LastWatched == nil {
+		factors.LastWatched = 1
+	} else {
+		factors.LastWatched = fraction(now.Sub(*status.LastWatched), scoreUnwatchedHorizon)
+	}
+
+	factors.PlayCount = 1 / float64(1+status.PlayCount)
+
+	if f.addedDate.Valid {
+		factors.Age = fraction(now.Sub(f.addedDate.Time), scoreAgeHorizon)
+	}
+
+	if !f.requestOpen {
+		factors.OpenRequest = 1
+	}
+
+	return factors
+}
+
+// weightedScore combines the factors into a score from 0 to 100
+func weightedScore(factors ScoreFactors, weights ScoreWeights) float64 {
+	terms := []struct{ factor, weight float64 }{
+		{factors.Size, weights.Size},
+		{factors.Eligibility, weights.Eligibility},
+		{factors.RecentUpload, weights.RecentUpload},
+		{factors.LastWatched, weights.LastWatched},
+		{factors.PlayCount, weights.PlayCount},
+		{factors.Age, weights.Age},
+		{factors.OpenRequest, weights.OpenRequest},
+	}
+
+	var sum, totalWeight float64
+	for _, term := range terms {
+		if term.weight <= 0 {
+			continue
+		}
+		sum += term.factor * term.weight
+		totalWeight += term.weight
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	return math.Round(sum/totalWeight*1000) / 10
+}
+
+// fraction returns d as a fraction of horizon, clamped to [0, 1]
+func fraction(d, horizon time.Duration) float64 {
+	return math.Max(0, math.Min(1, float64(d)/float64(horizon)))
+}
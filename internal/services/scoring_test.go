@@ -0,0 +1,120 @@
+package services
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestScoreFactors(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	const gb = int64(1 << 30)
+	watched := now.Add(-scoreUnwatchedHorizon / 2)
+	soon := now.Add(scoreEligibilityHorizon / 2)
+	later := now.Add(2 * scoreEligibilityHorizon)
+
+	tests := []struct {
+		name     string
+		status   EligibilityStatus
+		facts    mediaFacts
+		uploaded int64
+		want     ScoreFactors
+	}{
+		{
+			name:   "eligible, never watched, old, idle",
+			status: EligibilityStatus{IsEligible: true},
+			facts:  mediaFacts{size: 10 * gb, addedDate: sql.NullTime{Time: now.Add(-2 * scoreAgeHorizon), Valid: true}},
+			want:   ScoreFactors{Size: 1, Eligibility: 1, RecentUpload: 1, LastWatched: 1, PlayCount: 1, Age: 1, OpenRequest: 1},
+		},
+		{
+			name:     "eligible soon, watched, uploading half its size, request open",
+			status:   EligibilityStatus{EstimatedEligibleAt: &soon, LastWatched: &watched, PlayCount: 3},
+			facts:    mediaFacts{size: 10 * gb, requestOpen: true},
+			uploaded: 5 * gb,
+			want: ScoreFactors{Size: 1, Eligibility: scoreMaxEligibleSoonPart / 2, RecentUpload: 0.5, LastWatched: 0.5,
+				PlayCount: 0.25},
+		},
+		{
+			name:     "eligible past the horizon, uploaded more than its size",
+			status:   EligibilityStatus{EstimatedEligibleAt: &later},
+			facts:    mediaFacts{size: 10 * gb},
+			uploaded: 20 * gb,
+			want:     ScoreFactors{Size: 1, LastWatched: 1, PlayCount: 1, OpenRequest: 1},
+		},
+		{
+			name:     "no file, still uploading",
+			status:   EligibilityStatus{},
+			uploaded: gb,
+			want:     ScoreFactors{LastWatched: 1, PlayCount: 1, OpenRequest: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scoreFactors(&tt.status, tt.facts, 10*gb, tt.uploaded, now)
+			if !closeFactors(got, tt.want) {
+				t.Errorf("scoreFactors() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreFactorsSizeIsRelative(t *testing.T) {
+	now := time.Now()
+	small := scoreFactors(&EligibilityStatus{}, mediaFacts{size: 1 << 20}, 1<<40, 0, now)
+	large := scoreFactors(&EligibilityStatus{}, mediaFacts{size: 1 << 35}, 1<<40, 0, now)
+	if !(0 < small.Size && small.Size < large.Size && large.Size < 1) {
+		t.Errorf("size factors %v and %v, want 0 < small < large < 1", small.Size, large.Size)
+	}
+}
+
+func TestWeightedScore(t *testing.T) {
+	factors := ScoreFactors{Size: 1, Eligibility: 0.5}
+
+	if got := weightedScore(factors, ScoreWeights{Size: 1, Eligibility: 1}); got != 75 {
+		t.Errorf("equal weights = %v, want 75", got)
+	}
+	if got := weightedScore(factors, ScoreWeights{Size: 3, Eligibility: 1}); got != 87.5 {
+		t.Errorf("size weighted = %v, want 87.5", got)
+	}
+	// A weight of 0 leaves the factor out rather than counting it as 0
+	if got := weightedScore(factors, ScoreWeights{Size: 1}); got != 100 {
+		t.Errorf("size only = %v, want 100", got)
+	}
+	if got := weightedScore(factors, ScoreWeights{}); got != 0 {
+		t.Errorf("no weights = %v, want 0", got)
+	}
+	if got := weightedScore(ScoreFactors{Size: 1}, ScoreWeights{Size: 1, Age: -1}); got != 100 {
+		t.Errorf("negative weight = %v, want it ignored", got)
+	}
+}
+
+func TestFraction(t *testing.T) {
+	horizon := 10 * time.Hour
+	for _, tt := range []struct {
+		d    time.Duration
+		want float64
+	}{
+		{-time.Hour, 0},
+		{0, 0},
+		{5 * time.Hour, 0.5},
+		{20 * time.Hour, 1},
+	} {
+		if got := fraction(tt.d, horizon); got != tt.want {
+			t.Errorf("fraction(%v) = %v, want %v", tt.d, got, tt.want)
+		}
+	}
+}
+
+func closeFactors(a, b ScoreFactors) bool {
+	pairs := [][2]float64{
+		{a.Size, b.Size}, {a.Eligibility, b.Eligibility}, {a.RecentUpload, b.RecentUpload},
+		{a.LastWatched, b.LastWatched}, {a.PlayCount, b.PlayCount}, {a.Age, b.Age}, {a.OpenRequest, b.OpenRequest},
+	}
+	for _, p := range pairs {
+		if math.Abs(p[0]-p[1]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
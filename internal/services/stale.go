@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Sources with their own full sync generation
+const (
+	SyncSourceSonarr      = "sonarr"
+	SyncSourceRadarr      = "radarr"
+	SyncSourceQBittorrent = "qbittorrent"
+)
+
+// DefaultStaleRetention is how long missing media items and torrents are kept until another retention is configured
+const DefaultStaleRetention = 7 * 24 * time.Hour
+
+// ErrNotStale is returned when removing a media item or torrent that isn't missing from its source
+var ErrNotStale = errors.New("not missing from its source")
+
+// nextSyncGeneration starts a full sync of a source and returns its generation
+// Taken in the sync's transaction, so a sync that fails doesn't count
+func nextSyncGeneration(ctx context.Context, tx *sql.Tx, source string) (int64, error) {
+	var generation int64
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO sync_generations (source, generation) VALUES ($1, 1)
+		ON CONFLICT (source) DO UPDATE SET
+			generation = sync_generations.generation + 1,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING generation`,
+		source,
+	).Scan(&generation)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start sync generation: %w", err)
+	}
+	return generation, nil
+}
+
+// markSeen stamps rows of table (by id) with the full sync of generation that saw them
+// Rows that were missing are back, so their missing_since is cleared. synced also bumps last_synced_at,
+// for rows that were seen but not written because they didn't change.
+func markSeen(ctx context.Context, tx execer, table string, ids []int64, generation int64, synced bool) error {
+	set := "sync_generation = $2, missing_since = NULL"
+	if synced {
+		set = "last_synced_at = CURRENT_TIMESTAMP, " + set
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE `+table+` SET `+set+` WHERE id = ANY($1)`, ids, generation)
+	return err
+}
+
+// markMissing marks the rows of a source (table, filtered by scope) that the full sync of generation didn't see
+// Returns how many rows went missing with this sync
+func markMissing(ctx context.Context, tx execer, table, scope string, generation int64) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE `+table+` SET missing_since = CURRENT_TIMESTAMP
+		WHERE `+scope+` AND missing_since IS NULL AND (sync_generation IS NULL OR sync_generation < $1)`,
+		generation,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark missing rows: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// StaleService lists and purges media items and torrents that are gone from Radarr/Sonarr or qBittorrent
+// Syncs mark them missing; they are purged once they have been missing for the retention period
+type StaleService struct {
+	db *sql.DB
+}
+
+func NewStaleService(db *sql.DB) *StaleService {
+	return &StaleService{db: db}
+}
+
+// StaleMediaItem is a media item the last full sync of its source didn't see
+type StaleMediaItem struct {
+	ID           int       `json:"id"`
+	Title        string    `json:"title"`
+	Type         string    `json:"type"`
+	Source       string    `json:"source"` // sonarr or radarr
+	FileSize     int64     `json:"file_size"`
+	MissingSince time.Time `json:"missing_since"`
+	PurgeAt      time.Time `json:"purge_at"`
+	Protected    bool      `json:"protected"` // kept past purge_at until unprotected or removed by hand
+}
+
+// StaleTorrent is a torrent the last full sync didn't see in qBittorrent
+type StaleTorrent struct {
+	Hash         string    `json:"hash"`
+	Name         string    `json:"name"`
+	MediaTitle   *string   `json:"media_title,omitempty"`
+	SizeBytes    int64     `json:"size_bytes"`
+	MissingSince time.Time `json:"missing_since"`
+	PurgeAt      time.Time `json:"purge_at"`
+}
+
+// Retention returns how long missing rows are kept before they are purged, 0 to purge them on the next sync
+// Stored as a duration, e.g. "168h"
+func (s *StaleService) Retention(ctx context.Context) time.Duration {
+	var raw string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = 'sync.stale_retention'").Scan(&raw)
+	if err == sql.ErrNoRows {
+		return DefaultStaleRetention
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get stale retention setting", "error", err)
+		return DefaultStaleRetention
+	}
+	retention, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil || retention < 0 {
+		slog.WarnContext(ctx, "Invalid stale retention setting, using default", "value", raw)
+		return DefaultStaleRetention
+	}
+	return retention
+}
+
+// ListMedia returns the missing media items, longest missing first
+func (s *StaleService) ListMedia(ctx context.Context) ([]StaleMediaItem, error) {
+	retention := s.Retention(ctx)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, title, type, CASE WHEN sonarr_id IS NOT NULL THEN 'sonarr' ELSE 'radarr' END,
+			COALESCE(file_size, 0), missing_since, `+protectedCondition+`
+		FROM media_items
+		WHERE missing_since IS NOT NULL
+		ORDER BY missing_since, title`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale media items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []StaleMediaItem{}
+	for rows.Next() {
+		var item StaleMediaItem
+		if err := rows.Scan(&item.ID, &item.Title, &item.Type, &item.Source, &item.FileSize, &item.MissingSince, &item.Protected); err != nil {
+			return nil, fmt.Errorf("failed to scan stale media item: %w", err)
+		}
+		item.PurgeAt = item.MissingSince.Add(retention)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ListTorrents returns the missing torrents, longest missing first
+func (s *StaleService) ListTorrents(ctx context.Context) ([]StaleTorrent, error) {
+	retention := s.Retention(ctx)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT t.hash, COALESCE(t.name, ''), m.title, COALESCE(t.size_bytes, 0), t.missing_since
+		FROM torrents t
+		LEFT JOIN media_items m ON m.id = t.media_item_id
+		WHERE t.missing_since IS NOT NULL
+		ORDER BY t.missing_since, t.name`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale torrents: %w", err)
+	}
+	defer rows.Close()
+
+	torrents := []StaleTorrent{}
+	for rows.Next() {
+		var t StaleTorrent
+		var mediaTitle sql.NullString
+		if err := rows.Scan(&t.Hash, &t.Name, &mediaTitle, &t.SizeBytes, &t.MissingSince); err != nil {
+			return nil, fmt.Errorf("failed to scan stale torrent: %w", err)
+		}
+		if mediaTitle.Valid {
+			t.MediaTitle = &mediaTitle.String
+		}
+		t.PurgeAt = t.MissingSince.Add(retention)
+		torrents = append(torrents, t)
+	}
+	return torrents, rows.Err()
+}
+
+// RemoveMedia purges a missing media item right away
+func (s *StaleService) RemoveMedia(ctx context.Context, id int) error {
+	n, err := s.purgeMedia(ctx, "id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotStale
+	}
+	return nil
+}
+
+// RemoveTorrent purges a missing torrent right away, along with its samples
+func (s *StaleService) RemoveTorrent(ctx context.Context, hash string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM torrents WHERE hash = $1 AND missing_since IS NOT NULL", hash)
+	if err != nil {
+		return fmt.Errorf("failed to delete torrent: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotStale
+	}
+	return nil
+}
+
+// Purge deletes the media items and torrents that have been missing for longer than the retention period
+func (s *StaleService) Purge(ctx context.Context) (media int64, torrents int64, err error) {
+	cutoff := int64(s.Retention(ctx).Seconds())
+
+	media, err = s.purgeMedia(ctx, expiredStaleMedia, cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM torrents WHERE missing_since <= CURRENT_TIMESTAMP - $1::bigint * INTERVAL '1 second'",
+		cutoff,
+	)
+	if err != nil {
+		return media, 0, fmt.Errorf("failed to purge stale torrents: %w", err)
+	}
+	torrents, _ = result.RowsAffected()
+
+	if media > 0 || torrents > 0 {
+		slog.InfoContext(ctx, "Purged stale rows", "media_items", media, "torrents", torrents)
+	}
+	return media, torrents, nil
+}
+
+// expiredStaleMedia matches the media items missing for longer than the retention ($1, in seconds)
+// Protected media is only purged by hand
+const expiredStaleMedia = "missing_since <= CURRENT_TIMESTAMP - $1::bigint * INTERVAL '1 second' AND NOT " + protectedCondition
+
+// purgeMedia deletes the missing media items matching filter, in one transaction
+func (s *StaleService) purgeMedia(ctx context.Context, filter string, args ...interface{}) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	n, err := purgeMediaRows(ctx, tx, filter, args...)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return n, nil
+}
+
+// purgeMediaRows deletes the missing media items matching filter
+// Their torrents are unlinked first rather than cascaded, since they may still be seeding in qBittorrent,
+// and their open deletion requests and scheduled deletions are closed, since there is nothing left to delete
+func purgeMediaRows(ctx context.Context, tx execer, filter string, args ...interface{}) (int64, error) {
+	stale := "SELECT id FROM media_items WHERE missing_since IS NOT NULL AND " + filter
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE torrents SET media_item_id = NULL, link_source = 'auto', updated_at = CURRENT_TIMESTAMP
+		WHERE media_item_id IN (`+stale+`)`,
+		args...,
+	); err != nil {
+		return 0, fmt.Errorf("failed to unlink torrents: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE deletion_requests SET
+			status = 'rejected',
+			review_note = 'Removed from Radarr/Sonarr',
+			reviewed_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE status IN ('requested', 'approved') AND media_item_id IN (`+stale+`)`,
+		args...,
+	); err != nil {
+		return 0, fmt.Errorf("failed to close deletion requests: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE scheduled_deletions SET
+			status = 'cancelled',
+			cancel_reason = 'Removed from Radarr/Sonarr',
+			updated_at = CURRENT_TIMESTAMP
+		WHERE status = 'scheduled' AND media_item_id IN (`+stale+`)`,
+		args...,
+	); err != nil {
+		return 0, fmt.Errorf("failed to cancel scheduled deletions: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM media_items WHERE id IN ("+stale+")", args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge stale media items: %w", err)
+	}
+	return result.RowsAffected()
+}
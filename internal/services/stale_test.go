@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recordingExecer records the statements run against it, failing those that contain failOn
+type recordingExecer struct {
+	queries      []string
+	args         [][]interface{}
+	failOn       string
+	rowsAffected int64
+}
+
+func (e *recordingExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.queries = append(e.queries, query)
+	e.args = append(e.args, args)
+	if e.failOn != "" && strings.Contains(query, e.failOn) {
+		return nil, errors.New("statement failed")
+	}
+	return driver.RowsAffected(e.rowsAffected), nil
+}
+
+func TestMarkMissing(t *testing.T) {
+	e := &recordingExecer{rowsAffected: 3}
+	n, err := markMissing(context.Background(), e, "media_items", "radarr_id IS NOT NULL", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("markMissing() = %d, want 3", n)
+	}
+
+	query := e.queries[0]
+	for _, want := range []string{
+		"UPDATE media_items SET missing_since = CURRENT_TIMESTAMP",
+		"radarr_id IS NOT NULL",
+		// Rows already missing keep the time they went missing, so the retention isn't restarted
+		"missing_since IS NULL",
+		"sync_generation IS NULL OR sync_generation < $1",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query %q doesn't contain %q", query, want)
+		}
+	}
+	if len(e.args[0]) != 1 || e.args[0][0] != int64(42) {
+		t.Errorf("args = %v, want [42]", e.args[0])
+	}
+}
+
+func TestMarkSeenClearsMissing(t *testing.T) {
+	tests := []struct {
+		name       string
+		synced     bool
+		wantSynced bool
+	}{
+		{"unchanged rows are synced", true, true},
+		{"failed rows are only seen", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &recordingExecer{}
+			if err := markSeen(context.Background(), e, "media_items", []int64{1, 2}, 7, tt.synced); err != nil {
+				t.Fatal(err)
+			}
+			query := e.queries[0]
+			if !strings.Contains(query, "missing_since = NULL") {
+				t.Errorf("query %q doesn't clear missing_since", query)
+			}
+			if !strings.Contains(query, "sync_generation = $2") {
+				t.Errorf("query %q doesn't stamp the generation", query)
+			}
+			if got := strings.Contains(query, "last_synced_at"); got != tt.wantSynced {
+				t.Errorf("query %q bumps last_synced_at = %v, want %v", query, got, tt.wantSynced)
+			}
+			if e.args[0][1] != int64(7) {
+				t.Errorf("generation = %v, want 7", e.args[0][1])
+			}
+		})
+	}
+}
+
+func TestExpiredStaleMediaSkipsProtected(t *testing.T) {
+	if !strings.HasSuffix(expiredStaleMedia, "AND NOT "+protectedCondition) {
+		t.Errorf("expiredStaleMedia = %q, want protected media left out", expiredStaleMedia)
+	}
+}
+
+func TestPurgeMediaRowsOrder(t *testing.T) {
+	e := &recordingExecer{rowsAffected: 2}
+	n, err := purgeMediaRows(context.Background(), e, expiredStaleMedia, int64(3600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("purgeMediaRows() = %d, want 2", n)
+	}
+
+	// Torrents are unlinked and requests and schedules closed before the media items go
+	want := []string{
+		"UPDATE torrents SET media_item_id = NULL",
+		"UPDATE deletion_requests SET",
+		"UPDATE scheduled_deletions SET",
+		"DELETE FROM media_items",
+	}
+	if len(e.queries) != len(want) {
+		t.Fatalf("ran %d statements, want %d", len(e.queries), len(want))
+	}
+	for i, prefix := range want {
+		query := e.queries[i]
+		if !strings.HasPrefix(query, prefix) {
+			t.Errorf("statement %d = %q, want %s", i, query, prefix)
+		}
+		// Every step works on the same stale media items; protected ones are left alone
+		if !strings.Contains(query, "missing_since IS NOT NULL AND "+expiredStaleMedia) {
+			t.Errorf("statement %d doesn't filter on stale, unprotected media items", i)
+		}
+	}
+}
+
+func TestPurgeMediaRowsStopsOnError(t *testing.T) {
+	e := &recordingExecer{failOn: "UPDATE deletion_requests"}
+	if _, err := purgeMediaRows(context.Background(), e, "id = $1", 5); err == nil {
+		t.Fatal("purgeMediaRows() succeeded, want the failed step's error")
+	}
+	for _, query := range e.queries {
+		if strings.HasPrefix(query, "DELETE FROM media_items") {
+			t.Error("media items deleted although closing their deletion requests failed")
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// StorageStatsService records periodic storage snapshots and builds usage history from them
+type StorageStatsService struct {
+	db          *sql.DB
+	eligibility *EligibilityService
+	reports     *ReportService
+}
+
+func NewStorageStatsService(db *sql.DB, eligibility *EligibilityService, reports *ReportService) *StorageStatsService {
+	return &StorageStatsService{
+		db:          db,
+		eligibility: eligibility,
+		reports:     reports,
+	}
+}
+
+// StorageSnapshot is the storage usage at a point in time
+type StorageSnapshot struct {
+	TakenAt            time.Time               `json:"taken_at"`
+	LibraryBytes       int64                   `json:"library_bytes"`
+	LibraryBytesByType map[string]int64        `json:"library_bytes_by_type"`
+	TorrentCount       int                     `json:"torrent_count"`
+	TorrentBytes       int64                   `json:"torrent_bytes"`
+	Trackers           map[string]TrackerUsage `json:"trackers"`
+	EligibleBytes      int64                   `json:"eligible_bytes"`
+	Disks              map[string]DiskUsage    `json:"disks"`
+}
+
+// TrackerUsage is the number and total size of torrents on a tracker
+type TrackerUsage struct {
+	Count int   `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// DiskUsage is the size and free space of the filesystem holding a root folder
+type DiskUsage struct {
+	TotalBytes int64 `json:"total_bytes"`
+	FreeBytes  int64 `json:"free_bytes"`
+}
+
+// MonthlyFreed is the space freed by deletions in a month
+type MonthlyFreed struct {
+	Month string `json:"month"` // "2006-01"
+	Bytes int64  `json:"bytes"`
+	Items int    `json:"items"`
+}
+
+// StorageHistory is the data behind the statistics page
+type StorageHistory struct {
+	Snapshots    []StorageSnapshot `json:"snapshots"`
+	FreedByMonth []MonthlyFreed    `json:"freed_by_month"`
+	// GrowthBytesPerDay is the library growth over the projection window (see projectQuota)
+	GrowthBytesPerDay  float64    `json:"growth_bytes_per_day"`
+	QuotaBytes         int64      `json:"quota_bytes,omitempty"`
+	ProjectedQuotaDate *time.Time `json:"projected_quota_date,omitempty"`
+}
+
+// projectionWindow is how far back library growth is measured for the quota projection
+const projectionWindow = 30 * 24 * time.Hour
+
+// SnapshotIfDue records a snapshot if the last one is older than interval
+func (s *StorageStatsService) SnapshotIfDue(ctx context.Context, configuredRoots []string, interval time.Duration) error {
+	var last sql.NullTime
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT MAX(taken_at) FROM storage_snapshots",
+	).Scan(&last); err != nil {
+		return fmt.Errorf("failed to get last snapshot: %w", err)
+	}
+	if last.Valid && time.Since(last.Time) < interval {
+		return nil
+	}
+
+	_, err := s.TakeSnapshot(ctx, configuredRoots)
+	return err
+}
+
+// TakeSnapshot measures current storage usage and stores it
+func (s *StorageStatsService) TakeSnapshot(ctx context.Context, configuredRoots []string) (*StorageSnapshot, error) {
+	snapshot := &StorageSnapshot{
+		TakenAt:            time.Now(),
+		LibraryBytesByType: make(map[string]int64),
+		Trackers:           make(map[string]TrackerUsage),
+		Disks:              make(map[string]DiskUsage),
+	}
+
+	// Library size by type, and eligible bytes
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, type, file_size FROM media_items WHERE file_size > 0`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media items: %w", err)
+	}
+	type mediaSize struct {
+		id        int
+		mediaType string
+		size      int64
+	}
+	var media []mediaSize
+	for rows.Next() {
+		var m mediaSize
+		if err := rows.Scan(&m.id, &m.mediaType, &m.size); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan media item: %w", err)
+		}
+		media = append(media, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query media items: %w", err)
+	}
+
+	for _, m := range media {
+		snapshot.LibraryBytes += m.size
+		snapshot.LibraryBytesByType[m.mediaType] += m.size
+
+		status, err := s.eligibility.CheckEligibility(ctx, m.id)
+		if err != nil {
+			slog.DebugContext(ctx, "Eligibility check failed during snapshot", "media_id", m.id, "error", err)
+			continue
+		}
+		if status.IsEligible {
+			snapshot.EligibleBytes += m.size
+		}
+	}
+
+	// Torrents by tracker
+	rows, err = s.db.QueryContext(ctx,
+		`SELECT COALESCE(NULLIF(tracker_name, ''), 'unknown'), COUNT(*), COALESCE(SUM(size_bytes), 0)
+		FROM torrents
+		GROUP BY 1`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrents: %w", err)
+	}
+	for rows.Next() {
+		var tracker string
+		var usage TrackerUsage
+		if err := rows.Scan(&tracker, &usage.Count, &usage.Bytes); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan torrent usage: %w", err)
+		}
+		snapshot.Trackers[tracker] = usage
+		snapshot.TorrentCount += usage.Count
+		snapshot.TorrentBytes += usage.Bytes
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query torrents: %w", err)
+	}
+
+	// Free space on each root folder
+	roots, warnings := s.reports.RootFolders(ctx, configuredRoots)
+	for _, warning := range warnings {
+		slog.WarnContext(ctx, "Storage snapshot", "warning", warning)
+	}
+	for _, root := range roots {
+		total, free, err := diskSpace(root)
+		if err != nil {
+			slog.DebugContext(ctx, "Failed to get disk space", "path", root, "error", err)
+			continue
+		}
+		snapshot.Disks[root] = DiskUsage{TotalBytes: int64(total), FreeBytes: int64(free)}
+	}
+
+	byType, _ := json.Marshal(snapshot.LibraryBytesByType)
+	trackers, _ := json.Marshal(snapshot.Trackers)
+	disks, _ := json.Marshal(snapshot.Disks)
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO storage_snapshots
+			(taken_at, library_bytes, library_bytes_by_type, torrent_count, torrent_bytes, trackers, eligible_bytes, disks)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		snapshot.TakenAt,
+		snapshot.LibraryBytes,
+		string(byType),
+		snapshot.TorrentCount,
+		snapshot.TorrentBytes,
+		string(trackers),
+		snapshot.EligibleBytes,
+		string(disks),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Recorded storage snapshot",
+		"library_bytes", snapshot.LibraryBytes,
+		"torrent_count", snapshot.TorrentCount,
+		"eligible_bytes", snapshot.EligibleBytes)
+
+	return snapshot, nil
+}
+
+// History returns the snapshots taken since the given time, space freed per month,
+// and when the library is projected to reach quotaBytes (0 disables the projection)
+func (s *StorageStatsService) History(ctx context.Context, since time.Time, quotaBytes int64) (*StorageHistory, error) {
+	history := &StorageHistory{
+		Snapshots:    []StorageSnapshot{},
+		FreedByMonth: []MonthlyFreed{},
+		QuotaBytes:   quotaBytes,
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT taken_at, library_bytes, library_bytes_by_type, torrent_count, torrent_bytes, trackers, eligible_bytes, disks
+		FROM storage_snapshots
+		WHERE taken_at >= $1
+		ORDER BY taken_at`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snapshot StorageSnapshot
+		var byType, trackers, disks []byte
+		if err := rows.Scan(&snapshot.TakenAt, &snapshot.LibraryBytes, &byType, &snapshot.TorrentCount,
+			&snapshot.TorrentBytes, &trackers, &snapshot.EligibleBytes, &disks); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		json.Unmarshal(byType, &snapshot.LibraryBytesByType)
+		json.Unmarshal(trackers, &snapshot.Trackers)
+		json.Unmarshal(disks, &snapshot.Disks)
+		history.Snapshots = append(history.Snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+
+	// Space freed by media deletions and orphan cleanups, from the audit log
+	// Deletions recorded before details carried size_bytes count as items only
+	freedRows, err := s.db.QueryContext(ctx,
+		`SELECT TO_CHAR(DATE_TRUNC('month', created_at), 'YYYY-MM'),
+			COALESCE(SUM((details->>'size_bytes')::BIGINT), 0),
+			COUNT(*)
+		FROM audit_logs
+		WHERE action IN ('delete', 'delete_torrent', 'delete_file')
+			AND created_at >= DATE_TRUNC('month', $1::TIMESTAMP)
+		GROUP BY 1
+		ORDER BY 1`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query freed space: %w", err)
+	}
+	defer freedRows.Close()
+
+	for freedRows.Next() {
+		var month MonthlyFreed
+		if err := freedRows.Scan(&month.Month, &month.Bytes, &month.Items); err != nil {
+			return nil, fmt.Errorf("failed to scan freed space: %w", err)
+		}
+		history.FreedByMonth = append(history.FreedByMonth, month)
+	}
+	if err := freedRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query freed space: %w", err)
+	}
+
+	history.GrowthBytesPerDay, history.ProjectedQuotaDate = projectQuota(history.Snapshots, quotaBytes)
+
+	return history, nil
+}
+
+// projectQuota fits a line through the library size of the snapshots in the projection window
+// and returns the growth per day and the date the library reaches quotaBytes.
+// The date is nil if there is no quota, the library is not growing, or the quota is already reached.
+func projectQuota(snapshots []StorageSnapshot, quotaBytes int64) (float64, *time.Time) {
+	if len(snapshots) < 2 {
+		return 0, nil
+	}
+
+	last := snapshots[len(snapshots)-1]
+	windowStart := last.TakenAt.Add(-projectionWindow)
+
+	// Least squares over (days since window start, library bytes)
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, snapshot := range snapshots {
+		if snapshot.TakenAt.Before(windowStart) {
+			continue
+		}
+		x := snapshot.TakenAt.Sub(windowStart).Hours() / 24
+		y := float64(snapshot.LibraryBytes)
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return 0, nil
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+
+	if quotaBytes <= 0 || slope <= 0 || last.LibraryBytes >= quotaBytes {
+		return slope, nil
+	}
+
+	days := float64(quotaBytes-last.LibraryBytes) / slope
+	if days > maxEstimate.Hours()/24 {
+		return slope, nil
+	}
+	projected := last.TakenAt.Add(time.Duration(days * 24 * float64(time.Hour)))
+	return slope, &projected
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"removarr/internal/metrics"
+	"removarr/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Sources synced without a generation of their own
+const (
+	SyncSourceOverseerr = "overseerr"
+	SyncSourceTautulli  = "tautulli"
+)
+
+// ErrSyncStopped is the error of a sync triggered after the coordinator was closed, which never runs
+var ErrSyncStopped = errors.New("sync coordinator stopped")
+
+// syncRunRetention is how long sync_runs rows are kept
+const syncRunRetention = 30 * 24 * time.Hour
+
+// SyncStep is the sync of one source, as part of a full sync
+type SyncStep struct {
+	Source string
+	Run    func(ctx context.Context) (SyncCounts, error)
+}
+
+// FullSyncSteps returns the steps of a full sync of the enabled integrations, in order
+// Overseerr and Tautulli come after Radarr/Sonarr, so media items exist before requests and plays are matched to them
+func FullSyncSteps(media *MediaSyncService, torrents *TorrentSyncService) []SyncStep {
+	var steps []SyncStep
+	if media.integrations.Sonarr != nil {
+		steps = append(steps, SyncStep{Source: SyncSourceSonarr, Run: media.SyncFromSonarr})
+	}
+	if media.integrations.Radarr != nil {
+		steps = append(steps, SyncStep{Source: SyncSourceRadarr, Run: media.SyncFromRadarr})
+	}
+	if media.integrations.Overseerr != nil {
+		steps = append(steps, SyncStep{Source: SyncSourceOverseerr, Run: media.SyncOverseerrRequests})
+	}
+	if media.integrations.Tautulli != nil {
+		steps = append(steps, SyncStep{Source: SyncSourceTautulli, Run: media.SyncTautulliHistory})
+	}
+	if torrents.integrations.QBittorrent != nil {
+		steps = append(steps, SyncStep{Source: SyncSourceQBittorrent, Run: torrents.SyncFromQBittorrent})
+	}
+	return steps
+}
+
+// SyncRun is one full sync, shared by every trigger that arrived while it was queued
+type SyncRun struct {
+	Trigger   string
+	StartedAt time.Time // when it was queued, then when it started
+	Sources   []SyncSourceResult
+	coalesced int   // triggers that joined it
+	err       error // why it didn't run, e.g. ErrSyncStopped
+	done      chan struct{}
+}
+
+func newSyncRun(trigger string) *SyncRun {
+	return &SyncRun{Trigger: trigger, StartedAt: time.Now(), done: make(chan struct{})}
+}
+
+// SyncSourceResult is how the sync of one source went in a run
+type SyncSourceResult struct {
+	Source   string
+	Counts   SyncCounts
+	Duration time.Duration
+	Err      error
+}
+
+// Done is closed once the run has finished
+func (r *SyncRun) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err joins the errors of the sources that failed, nil if they all synced
+func (r *SyncRun) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	var errs []error
+	for _, source := range r.Sources {
+		if source.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Source, source.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SyncCoordinator runs full syncs one at a time
+// A trigger that arrives while a sync is running queues one more sync to run after it, so changes made after the
+// running sync started aren't missed; later triggers join the queued sync instead of queuing another.
+// Concurrent triggers (the ticker, dashboard loads, the sync button, the API) never sync the same rows at once,
+// and incremental updates of a source (see SyncOne) wait while a full sync is syncing that source.
+// Each source's sync is recorded in sync_runs.
+type SyncCoordinator struct {
+	ctx   context.Context // runs are cancelled with it, e.g. on shutdown
+	db    *sql.DB
+	steps func() []SyncStep // looked up per run, the services are rebuilt when integrations change
+	wg    sync.WaitGroup
+
+	mu           sync.Mutex
+	current      *SyncRun
+	next         *SyncRun // queued to run after current
+	source       string   // source the current run is syncing
+	lastFinished time.Time
+	locks        map[string]chan struct{} // per source, held while it is synced
+	closed       bool                     // set by Close, no more runs are started
+}
+
+func NewSyncCoordinator(ctx context.Context, db *sql.DB, steps func() []SyncStep) *SyncCoordinator {
+	return &SyncCoordinator{ctx: ctx, db: db, steps: steps, locks: make(map[string]chan struct{})}
+}
+
+// Close stops syncs from being started, then blocks until the running sync and the one queued after it, if any,
+// have finished. Syncs triggered afterwards finish right away with ErrSyncStopped.
+func (c *SyncCoordinator) Close() {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.wg.Wait()
+}
+
+// Start starts a full sync in the background, or queues one after the sync already running
+// started reports whether this trigger started or queued the run, rather than joining a queued one
+func (c *SyncCoordinator) Start(trigger string) (run *SyncRun, started bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.start(trigger)
+}
+
+// StartUnlessFresh is Start, unless a sync finished less than fresh ago; run is nil then
+// A sync that is running counts as fresh, it is joined rather than queuing another
+func (c *SyncCoordinator) StartUnlessFresh(trigger string, fresh time.Duration) (run *SyncRun, started bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current != nil {
+		return c.current, false
+	}
+	if time.Since(c.lastFinished) < fresh {
+		return nil, false
+	}
+	return c.start(trigger)
+}
+
+// Sync runs a full sync, or queues one after the sync already running, and waits for it to finish
+// If ctx is done first its error is returned; the run carries on regardless
+func (c *SyncCoordinator) Sync(ctx context.Context, trigger string) (run *SyncRun, started bool, err error) {
+	run, started = c.Start(trigger)
+	select {
+	case <-run.done:
+		return run, started, nil
+	case <-ctx.Done():
+		return nil, started, ctx.Err()
+	}
+}
+
+// start is Start with c.mu held
+// Nothing is added to c.wg once closed, so it can't race with Close waiting on it
+func (c *SyncCoordinator) start(trigger string) (*SyncRun, bool) {
+	switch {
+	case c.closed:
+		run := newSyncRun(trigger)
+		run.err = ErrSyncStopped
+		close(run.done)
+		return run, false
+	case c.current == nil:
+		run := newSyncRun(trigger)
+		c.current = run
+		c.wg.Add(1)
+		go c.run(run)
+		return run, true
+	case c.next == nil:
+		c.next = newSyncRun(trigger)
+		c.wg.Add(1)
+		slog.Debug("Sync already running, queued another after it", "trigger", trigger, "running_trigger", c.current.Trigger)
+		return c.next, true
+	default:
+		c.next.coalesced++
+		slog.Debug("Sync already queued, joining it", "trigger", trigger, "queued_trigger", c.next.Trigger)
+		return c.next, false
+	}
+}
+
+// sourceLock returns the lock held while a source is synced, by a full sync or an incremental update
+// It is a channel with room for one, so waiting for it can give up when a context is done
+func (c *SyncCoordinator) sourceLock(source string) chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lock, ok := c.locks[source]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		c.locks[source] = lock
+	}
+	return lock
+}
+
+// SyncOne runs an incremental update of a source, e.g. for a webhook, once no full sync is syncing that source
+// A full sync's generation marking assumes nothing else writes the source's rows while it runs.
+// If ctx is done while waiting, update isn't run and ctx's error is returned.
+func (c *SyncCoordinator) SyncOne(ctx context.Context, source string, update func(ctx context.Context) error) error {
+	lock := c.sourceLock(source)
+	select {
+	case lock <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-lock }()
+	return update(ctx)
+}
+
+// run syncs every source in turn, then starts the queued run, if any
+// Runs on the coordinator's context rather than the trigger's: the trigger that started it may be a request that
+// goes away while the others wait. Once that context is cancelled the remaining sources are skipped.
+func (c *SyncCoordinator) run(run *SyncRun) {
+	defer c.wg.Done()
+	ctx, span := tracing.Start(c.ctx, "sync", trace.WithAttributes(attribute.String("sync.trigger", run.Trigger)))
+	slog.InfoContext(ctx, "Starting sync", "trigger", run.Trigger)
+
+	for _, step := range c.steps() {
+		// Sources left when the context is cancelled are skipped, not started
+		result := SyncSourceResult{Source: step.Source, Err: ctx.Err()}
+		if result.Err == nil {
+			c.mu.Lock()
+			c.source = step.Source
+			c.mu.Unlock()
+
+			result = c.runStep(ctx, run.Trigger, step)
+		}
+
+		c.mu.Lock()
+		run.Sources = append(run.Sources, result)
+		c.mu.Unlock()
+	}
+
+	if ctx.Err() == nil {
+		if _, err := c.db.ExecContext(ctx,
+			"DELETE FROM sync_runs WHERE started_at < CURRENT_TIMESTAMP - $1::bigint * INTERVAL '1 second'",
+			int64(syncRunRetention.Seconds()),
+		); err != nil {
+			slog.WarnContext(ctx, "Failed to prune sync runs", "error", err)
+		}
+	}
+
+	c.mu.Lock()
+	coalesced := run.coalesced
+	c.current = c.next
+	c.next = nil
+	c.source = ""
+	c.lastFinished = time.Now()
+	if c.current != nil {
+		c.current.StartedAt = time.Now()
+		go c.run(c.current) // counted in c.wg when it was queued
+	}
+	c.mu.Unlock()
+	close(run.done)
+
+	slog.InfoContext(ctx, "Sync finished", "trigger", run.Trigger, "duration", time.Since(run.StartedAt).Round(time.Millisecond),
+		"sources", len(run.Sources), "coalesced", coalesced)
+	span.SetAttributes(attribute.Int("sync.coalesced", coalesced))
+	tracing.End(span, run.Err())
+}
+
+// runStep syncs one source and records it in sync_runs
+// Failing to record is logged, it doesn't stop the sync. A sync cancelled partway is still recorded, with its error.
+func (c *SyncCoordinator) runStep(ctx context.Context, trigger string, step SyncStep) (result SyncSourceResult) {
+	ctx, span := tracing.Start(ctx, "sync "+step.Source, trace.WithAttributes(attribute.String("sync.source", step.Source)))
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("sync.rows_seen", result.Counts.Seen),
+			attribute.Int("sync.rows_added", result.Counts.Added),
+			attribute.Int("sync.rows_updated", result.Counts.Updated),
+			attribute.Int("sync.rows_failed", result.Counts.Failed),
+			attribute.Int64("sync.rows_missing", result.Counts.Missing),
+		)
+		tracing.End(span, result.Err)
+	}()
+
+	// Incremental updates of the source wait until it is synced
+	lock := c.sourceLock(step.Source)
+	select {
+	case lock <- struct{}{}:
+	case <-ctx.Done():
+		return SyncSourceResult{Source: step.Source, Err: ctx.Err()}
+	}
+	defer func() { <-lock }()
+
+	record := context.WithoutCancel(ctx)
+	var runID int64
+	if err := c.db.QueryRowContext(record,
+		"INSERT INTO sync_runs (source, trigger) VALUES ($1, $2) RETURNING id",
+		step.Source, trigger,
+	).Scan(&runID); err != nil {
+		slog.WarnContext(ctx, "Failed to record sync run", "source", step.Source, "error", err)
+	}
+
+	started := time.Now()
+	counts, err := step.Run(ctx)
+	result = SyncSourceResult{Source: step.Source, Counts: counts, Duration: time.Since(started), Err: err}
+	if err != nil {
+		slog.ErrorContext(ctx, "Sync failed", "source", step.Source, "trigger", trigger, "error", err)
+	}
+	observeSync(result)
+
+	if runID != 0 {
+		var errText *string
+		if err != nil {
+			msg := err.Error()
+			errText = &msg
+		}
+		if _, err := c.db.ExecContext(record,
+			`UPDATE sync_runs SET
+				finished_at = CURRENT_TIMESTAMP,
+				duration_ms = $2,
+				error = $3,
+				rows_seen = $4,
+				rows_added = $5,
+				rows_updated = $6,
+				rows_unchanged = $7,
+				rows_failed = $8,
+				rows_missing = $9
+			WHERE id = $1`,
+			runID, result.Duration.Milliseconds(), errText,
+			counts.Seen, counts.Added, counts.Updated, counts.Unchanged, counts.Failed, counts.Missing,
+		); err != nil {
+			slog.WarnContext(ctx, "Failed to record sync run", "source", step.Source, "error", err)
+		}
+	}
+	return result
+}
+
+// observeSync records a source's sync in the Prometheus metrics
+func observeSync(result SyncSourceResult) {
+	outcome := "success"
+	if result.Err != nil {
+		outcome = "error"
+	} else {
+		metrics.SyncLastSuccess.WithLabelValues(result.Source).SetToCurrentTime()
+	}
+	metrics.SyncRuns.WithLabelValues(result.Source, outcome).Inc()
+	metrics.SyncDuration.WithLabelValues(result.Source).Observe(result.Duration.Seconds())
+
+	rows := map[string]float64{
+		"added":     float64(result.Counts.Added),
+		"updated":   float64(result.Counts.Updated),
+		"unchanged": float64(result.Counts.Unchanged),
+		"failed":    float64(result.Counts.Failed),
+		"missing":   float64(result.Counts.Missing),
+	}
+	for outcome, n := range rows {
+		metrics.SyncRows.WithLabelValues(result.Source, outcome).Add(n)
+	}
+}
+
+// SyncSourceStatus is the last finished sync of one source
+type SyncSourceStatus struct {
+	Source     string    `json:"source"`
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      *string   `json:"error,omitempty"`
+	SyncCounts
+}
+
+// SyncStatus is whether a sync is running or queued, and how each source's last sync went
+type SyncStatus struct {
+	Running       bool               `json:"running"`
+	Trigger       string             `json:"trigger,omitempty"`        // what started the running sync
+	StartedAt     *time.Time         `json:"started_at,omitempty"`     // when the running sync started
+	Source        string             `json:"source,omitempty"`         // source the running sync is on
+	Queued        bool               `json:"queued"`                   // another sync runs after this one
+	QueuedTrigger string             `json:"queued_trigger,omitempty"` // what queued it
+	Coalesced     int                `json:"coalesced"`                // triggers that joined the queued sync
+	Sources       []SyncSourceStatus `json:"sources"`
+}
+
+// Status returns the running sync, if any, and the last finished sync of each source
+func (c *SyncCoordinator) Status(ctx context.Context) (SyncStatus, error) {
+	status := SyncStatus{Sources: []SyncSourceStatus{}}
+
+	c.mu.Lock()
+	if c.current != nil {
+		startedAt := c.current.StartedAt
+		status.Running = true
+		status.Trigger = c.current.Trigger
+		status.StartedAt = &startedAt
+		status.Source = c.source
+	}
+	if c.next != nil {
+		status.Queued = true
+		status.QueuedTrigger = c.next.Trigger
+		status.Coalesced = c.next.coalesced
+	}
+	c.mu.Unlock()
+
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT DISTINCT ON (source) source, trigger, started_at, finished_at, COALESCE(duration_ms, 0), error,
+			rows_seen, rows_added, rows_updated, rows_unchanged, rows_failed, rows_missing
+		FROM sync_runs
+		WHERE finished_at IS NOT NULL
+		ORDER BY source, started_at DESC`,
+	)
+	if err != nil {
+		return status, fmt.Errorf("failed to query sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source SyncSourceStatus
+		var errText sql.NullString
+		if err := rows.Scan(&source.Source, &source.Trigger, &source.StartedAt, &source.FinishedAt, &source.DurationMs, &errText,
+			&source.Seen, &source.Added, &source.Updated, &source.Unchanged, &source.Failed, &source.Missing); err != nil {
+			return status, fmt.Errorf("failed to scan sync run: %w", err)
+		}
+		if errText.Valid {
+			source.Error = &errText.String
+		}
+		status.Sources = append(status.Sources, source)
+	}
+	return status, rows.Err()
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSyncCoordinatorClose(t *testing.T) {
+	// A cancelled context skips every step, so runs finish without a database
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := NewSyncCoordinator(ctx, nil, func() []SyncStep { return nil })
+
+	// Triggers racing with Close either run before it returns or are refused, never counted after it waits
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run, _ := c.Start("test")
+			<-run.Done()
+		}()
+	}
+	c.Close()
+	wg.Wait()
+
+	run, started := c.Start("after close")
+	select {
+	case <-run.Done():
+	default:
+		t.Fatal("run triggered after Close isn't done")
+	}
+	if started || !errors.Is(run.Err(), ErrSyncStopped) {
+		t.Errorf("Start after Close = started %v, error %v; want not started, ErrSyncStopped", started, run.Err())
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// SyncCounts is what the sync of one source did with the rows it got
+type SyncCounts struct {
+	Seen      int   `json:"seen"`
+	Added     int   `json:"added"`
+	Updated   int   `json:"updated"`
+	Unchanged int   `json:"unchanged"`
+	Failed    int   `json:"failed"`
+	Missing   int64 `json:"missing"` // rows that went missing from the source with this sync
+}
+
+// execer is what sync writes go through, either the database or the sync's transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// querier is an execer that can also query, a *sql.DB or a *sql.Tx
+type querier interface {
+	execer
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// syncHash fingerprints the values a sync writes for one row
+// Rows whose stored hash matches are skipped. "" if the values can't be encoded, which never counts as unchanged.
+func syncHash(values ...interface{}) string {
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// inSavepoint runs writes in a savepoint, so a failing write is rolled back without aborting the rest of the sync transaction
+func inSavepoint(ctx context.Context, tx *sql.Tx, write func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT sync_row"); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	if err := write(); err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT sync_row"); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT sync_row"); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// writeBatch writes n rows with write(0, n), one statement, in a savepoint. If that fails, the rows are written one
+// at a time with write(i, i+1), each in its own savepoint, so a failing row is rolled back without losing the rest.
+// Returns the error of each row that failed, by index.
+func writeBatch(ctx context.Context, tx *sql.Tx, n int, write func(from, to int) error) map[int]error {
+	if n == 0 {
+		return nil
+	}
+	if err := inSavepoint(ctx, tx, func() error { return write(0, n) }); err == nil {
+		return nil
+	}
+	failed := make(map[int]error)
+	for i := 0; i < n; i++ {
+		if err := inSavepoint(ctx, tx, func() error { return write(i, i+1) }); err != nil {
+			failed[i] = err
+		}
+	}
+	return failed
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"removarr/internal/integrations"
+)
+
+func TestSyncHash(t *testing.T) {
+	base := syncHash("Movie", int64(1024), 1.5, true)
+	if base == "" {
+		t.Fatal("syncHash() returned empty")
+	}
+	if again := syncHash("Movie", int64(1024), 1.5, true); again != base {
+		t.Errorf("same values hashed differently: %s, %s", base, again)
+	}
+
+	for name, hash := range map[string]string{
+		"changed value": syncHash("Movie", int64(2048), 1.5, true),
+		"swapped order": syncHash(int64(1024), "Movie", 1.5, true),
+		"extra value":   syncHash("Movie", int64(1024), 1.5, true, nil),
+	} {
+		if hash == base {
+			t.Errorf("%s: hash didn't change", name)
+		}
+	}
+}
+
+func TestSyncHashKeepsValuesApart(t *testing.T) {
+	if syncHash("Movie", "/data") == syncHash("Movie/", "data") {
+		t.Error("values running into each other hash the same")
+	}
+}
+
+func TestSyncHashNilAndZero(t *testing.T) {
+	// An optional value going from unset to 0 has to be written
+	var unset *int64
+	zero := int64(0)
+	if syncHash(unset) == syncHash(&zero) {
+		t.Error("nil and a pointer to 0 hash the same")
+	}
+}
+
+func TestSyncHashUnencodable(t *testing.T) {
+	if hash := syncHash(math.NaN()); hash != "" {
+		t.Errorf("syncHash(NaN) = %q, want empty so the row is written", hash)
+	}
+}
+
+func TestTorrentHash(t *testing.T) {
+	torrent := integrations.QBittorrentTorrent{
+		Hash:        "abc",
+		Name:        "Movie.2020.1080p",
+		ContentPath: "/data/torrents/Movie.2020.1080p",
+		Size:        1 << 30,
+		Uploaded:    1 << 20,
+		State:       "uploading",
+	}
+	base := torrentHash(torrent, trackerInfo{})
+
+	moved := torrent
+	moved.ContentPath = "/data/movies/Movie.2020.1080p"
+	if torrentHash(moved, trackerInfo{}) == base {
+		t.Error("content path change not detected")
+	}
+
+	renamed := torrent
+	renamed.Name = "Movie.2020.2160p"
+	if torrentHash(renamed, trackerInfo{}) == base {
+		t.Error("name change not detected")
+	}
+
+	// Counters change every poll of an active torrent and are written for every torrent anyway,
+	// so they mustn't make a torrent look changed; neither must swarm counts, which aren't written
+	active := torrent
+	active.Uploaded += 1
+	active.Downloaded += 1
+	active.SeedingTime += 60
+	active.Ratio += 0.01
+	active.State = "pausedUP"
+	active.NumComplete = 40
+	if torrentHash(active, trackerInfo{}) != base {
+		t.Error("counters changed the hash")
+	}
+
+	ratio := 2.0
+	if torrentHash(torrent, trackerInfo{requiredRatio: &ratio}) == base {
+		t.Error("tracker requirement change not detected")
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"removarr/internal/integrations"
+)
+
+// tautulliPlay is a Tautulli history row reduced to what matching needs
+type tautulliPlay struct {
+	movie      bool
+	tmdbID     int // movies
+	tvdbID     int // episodes, of their show
+	plexUserID int
+	user       string
+	lastPlayed time.Time
+	count      int
+}
+
+// newTautulliPlay reads a history row, with the IDs its GUID carries
+// Also returns the rating key of the movie or show, to look the IDs up by when the GUID has none.
+// Rows that aren't movies or episodes are skipped.
+func newTautulliPlay(h integrations.TautulliHistory) (tautulliPlay, string, bool) {
+	play := tautulliPlay{
+		plexUserID: h.UserID,
+		user:       h.User,
+		lastPlayed: time.Unix(h.Date, 0),
+		count:      h.GroupCount,
+	}
+	if h.Stopped > 0 {
+		play.lastPlayed = time.Unix(h.Stopped, 0)
+	}
+	if play.count <= 0 {
+		play.count = 1 // each history row is at least one play
+	}
+
+	var ratingKey string
+	switch h.MediaType {
+	case "movie":
+		play.movie = true
+		ratingKey = string(h.RatingKey)
+	case "episode":
+		ratingKey = string(h.GrandparentRatingKey)
+	default:
+		return tautulliPlay{}, "", false
+	}
+	play.setIDs(integrations.ParseGUIDs(h.GUID))
+	return play, ratingKey, true
+}
+
+// setIDs takes the ID a play is matched by: the TMDB ID of a movie, or the TVDB ID of a show
+// A legacy TMDB GUID of an episode is the show's TMDB TV ID, which media items don't store
+func (p *tautulliPlay) setIDs(ids integrations.ExternalIDs) {
+	if p.movie {
+		p.tmdbID = ids.TMDBID
+	} else {
+		p.tvdbID = ids.TVDBID
+	}
+}
+
+// tautulliMediaItems maps TMDB IDs to movie media items and TVDB IDs to series media items
+type tautulliMediaItems struct {
+	movies map[int]int
+	series map[int]int
+}
+
+func (m tautulliMediaItems) find(play tautulliPlay) (int, bool) {
+	if play.movie {
+		id, ok := m.movies[play.tmdbID]
+		return id, ok
+	}
+	id, ok := m.series[play.tvdbID]
+	return id, ok
+}
+
+// loadTautulliMediaItems loads the media items the plays match, in one query
+func (s *MediaSyncService) loadTautulliMediaItems(ctx context.Context, plays []tautulliPlay) (tautulliMediaItems, error) {
+	items := tautulliMediaItems{movies: make(map[int]int), series: make(map[int]int)}
+	var tmdbIDs, tvdbIDs []int
+	for _, play := range plays {
+		if play.movie {
+			tmdbIDs = append(tmdbIDs, play.tmdbID)
+		} else {
+			tvdbIDs = append(tvdbIDs, play.tvdbID)
+		}
+	}
+	if len(tmdbIDs) == 0 && len(tvdbIDs) == 0 {
+		return items, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, type, COALESCE(tmdb_id, 0), COALESCE(tvdb_id, 0)
+		FROM media_items
+		WHERE (type = 'movie' AND tmdb_id = ANY($1)) OR (type = 'series' AND tvdb_id = ANY($2))
+		ORDER BY id`,
+		tmdbIDs, tvdbIDs,
+	)
+	if err != nil {
+		return items, fmt.Errorf("failed to load media items for Tautulli history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id             int
+			mediaType      string
+			tmdbID, tvdbID int
+		)
+		if err := rows.Scan(&id, &mediaType, &tmdbID, &tvdbID); err != nil {
+			return items, fmt.Errorf("failed to scan media item: %w", err)
+		}
+		// The oldest media item wins if several share an ID
+		if mediaType == "movie" {
+			if _, ok := items.movies[tmdbID]; !ok {
+				items.movies[tmdbID] = id
+			}
+		} else if _, ok := items.series[tvdbID]; !ok {
+			items.series[tvdbID] = id
+		}
+	}
+	return items, rows.Err()
+}
+
+// tautulliUsers maps Plex user IDs, Plex usernames and usernames to removarr users
+type tautulliUsers struct {
+	byPlexID       map[int]int
+	byPlexUsername map[string]int
+	byUsername     map[string]int
+}
+
+// find returns the removarr user of a play, 0 if it has none
+// The Plex user ID is the most reliable, then the Plex username, then a local account with the same name
+func (u tautulliUsers) find(play tautulliPlay) int {
+	if id, ok := u.byPlexID[play.plexUserID]; ok && play.plexUserID != 0 {
+		return id
+	}
+	if id, ok := u.byPlexUsername[play.user]; ok {
+		return id
+	}
+	return u.byUsername[play.user]
+}
+
+// loadTautulliUsers loads the users the plays match, in one query
+func (s *MediaSyncService) loadTautulliUsers(ctx context.Context, plays []tautulliPlay) (tautulliUsers, error) {
+	users := tautulliUsers{
+		byPlexID:       make(map[int]int),
+		byPlexUsername: make(map[string]int),
+		byUsername:     make(map[string]int),
+	}
+	plexIDs := make(map[int]bool)
+	names := make(map[string]bool)
+	for _, play := range plays {
+		if play.plexUserID != 0 {
+			plexIDs[play.plexUserID] = true
+		}
+		if play.user != "" {
+			names[play.user] = true
+		}
+	}
+	if len(plexIDs) == 0 && len(names) == 0 {
+		return users, nil
+	}
+	plexIDList := make([]int, 0, len(plexIDs))
+	for id := range plexIDs {
+		plexIDList = append(plexIDList, id)
+	}
+	nameList := make([]string, 0, len(names))
+	for name := range names {
+		nameList = append(nameList, name)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, COALESCE(plex_id, 0), COALESCE(plex_username, ''), username
+		FROM users
+		WHERE plex_id = ANY($1) OR plex_username = ANY($2) OR username = ANY($2)
+		ORDER BY id`,
+		plexIDList, nameList,
+	)
+	if err != nil {
+		return users, fmt.Errorf("failed to load users for Tautulli history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id                     int
+			plexID                 int
+			plexUsername, username string
+		)
+		if err := rows.Scan(&id, &plexID, &plexUsername, &username); err != nil {
+			return users, fmt.Errorf("failed to scan user: %w", err)
+		}
+		if plexID != 0 {
+			users.byPlexID[plexID] = id
+		}
+		if _, ok := users.byPlexUsername[plexUsername]; !ok && plexUsername != "" {
+			users.byPlexUsername[plexUsername] = id
+		}
+		users.byUsername[username] = id
+	}
+	return users, rows.Err()
+}
+
+type watchKey struct {
+	mediaItemID int
+	userID      int // 0 if the Tautulli user has no removarr account
+}
+
+type watchStats struct {
+	lastWatched time.Time
+	playCount   int
+}
+
+// aggregateTautulliPlays adds up the plays per media item and user
+// Plays of items removarr doesn't track are dropped.
+func aggregateTautulliPlays(plays []tautulliPlay, items tautulliMediaItems, users tautulliUsers) map[watchKey]*watchStats {
+	watches := make(map[watchKey]*watchStats)
+	for _, play := range plays {
+		mediaItemID, ok := items.find(play)
+		if !ok {
+			continue
+		}
+		key := watchKey{mediaItemID: mediaItemID, userID: users.find(play)}
+		stats, ok := watches[key]
+		if !ok {
+			stats = &watchStats{}
+			watches[key] = stats
+		}
+		stats.playCount += play.count
+		if play.lastPlayed.After(stats.lastWatched) {
+			stats.lastWatched = play.lastPlayed
+		}
+	}
+	return watches
+}
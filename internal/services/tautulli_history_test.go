@@ -0,0 +1,99 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"removarr/internal/integrations"
+)
+
+func TestNewTautulliPlay(t *testing.T) {
+	tests := []struct {
+		name      string
+		history   integrations.TautulliHistory
+		want      tautulliPlay
+		ratingKey string
+		ok        bool
+	}{
+		{
+			name: "movie with a legacy GUID",
+			history: integrations.TautulliHistory{MediaType: "movie", User: "neo", UserID: 1, Date: 100, Stopped: 200,
+				GroupCount: 3, RatingKey: "101", GUID: "com.plexapp.agents.themoviedb://603?lang=en"},
+			want:      tautulliPlay{movie: true, tmdbID: 603, plexUserID: 1, user: "neo", lastPlayed: time.Unix(200, 0), count: 3},
+			ratingKey: "101",
+			ok:        true,
+		},
+		{
+			name: "episode is looked up by its show",
+			history: integrations.TautulliHistory{MediaType: "episode", User: "neo", Date: 100,
+				RatingKey: "202", GrandparentRatingKey: "200", GUID: "plex://episode/5d9c"},
+			want:      tautulliPlay{user: "neo", lastPlayed: time.Unix(100, 0), count: 1},
+			ratingKey: "200",
+			ok:        true,
+		},
+		{
+			name: "legacy TMDB GUID of an episode is ignored",
+			history: integrations.TautulliHistory{MediaType: "episode", Date: 100, GroupCount: 1,
+				GrandparentRatingKey: "200", GUID: "com.plexapp.agents.themoviedb://1399/1/1?lang=en"},
+			want:      tautulliPlay{lastPlayed: time.Unix(100, 0), count: 1},
+			ratingKey: "200",
+			ok:        true,
+		},
+		{
+			name:    "music is skipped",
+			history: integrations.TautulliHistory{MediaType: "track", RatingKey: "300"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			play, ratingKey, ok := newTautulliPlay(tt.history)
+			if ok != tt.ok || ratingKey != tt.ratingKey {
+				t.Fatalf("newTautulliPlay() = %q, %v, want %q, %v", ratingKey, ok, tt.ratingKey, tt.ok)
+			}
+			if ok && play != tt.want {
+				t.Errorf("newTautulliPlay() = %+v, want %+v", play, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateTautulliPlays(t *testing.T) {
+	items := tautulliMediaItems{
+		movies: map[int]int{603: 1},
+		series: map[int]int{81189: 2},
+	}
+	users := tautulliUsers{
+		byPlexID:       map[int]int{1234: 10},
+		byPlexUsername: map[string]int{"trinity": 11},
+		byUsername:     map[string]int{"morpheus": 12, "trinity": 13},
+	}
+	plays := []tautulliPlay{
+		{movie: true, tmdbID: 603, plexUserID: 1234, user: "renamed", lastPlayed: time.Unix(100, 0), count: 2},
+		{movie: true, tmdbID: 603, plexUserID: 1234, user: "neo", lastPlayed: time.Unix(300, 0), count: 1},
+		{tvdbID: 81189, plexUserID: 99, user: "trinity", lastPlayed: time.Unix(200, 0), count: 1},
+		{tvdbID: 81189, user: "morpheus", lastPlayed: time.Unix(50, 0), count: 1},
+		{tvdbID: 81189, user: "guest", lastPlayed: time.Unix(60, 0), count: 4},
+		{movie: true, tmdbID: 999, user: "neo", lastPlayed: time.Unix(400, 0), count: 1}, // not in the library
+	}
+
+	watches := aggregateTautulliPlays(plays, items, users)
+	want := map[watchKey]watchStats{
+		{mediaItemID: 1, userID: 10}: {lastWatched: time.Unix(300, 0), playCount: 3},
+		{mediaItemID: 2, userID: 11}: {lastWatched: time.Unix(200, 0), playCount: 1},
+		{mediaItemID: 2, userID: 12}: {lastWatched: time.Unix(50, 0), playCount: 1},
+		{mediaItemID: 2, userID: 0}:  {lastWatched: time.Unix(60, 0), playCount: 4},
+	}
+	if len(watches) != len(want) {
+		t.Fatalf("got %d watches, want %d", len(watches), len(want))
+	}
+	for key, stats := range want {
+		got, ok := watches[key]
+		if !ok {
+			t.Errorf("missing watch %+v", key)
+			continue
+		}
+		if !got.lastWatched.Equal(stats.lastWatched) || got.playCount != stats.playCount {
+			t.Errorf("watch %+v = %+v, want %+v", key, *got, stats)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"removarr/internal/integrations"
+)
+
+// Torrent history downsampling and retention
+// Counters are cumulative, so a downsampled bucket keeps the last sample it contains
+const (
+	rawSampleRetention    = 48 * time.Hour      // raw samples older than this become hourly samples
+	hourlySampleRetention = 30 * 24 * time.Hour // hourly samples older than this become daily samples
+	dailySampleRetention  = 365 * 24 * time.Hour
+)
+
+// deadTorrentWindow is how long a seeding torrent may go without uploading before it is considered dead
+const deadTorrentWindow = 14 * 24 * time.Hour
+
+// recordSamples stores the current counters of the synced torrents, in one statement
+// Only torrents that made it into the torrents table, so a failed insert doesn't break the foreign key
+func recordSamples(ctx context.Context, db execer, torrents []integrations.QBittorrentTorrent) (int64, error) {
+	if len(torrents) == 0 {
+		return 0, nil
+	}
+	var (
+		hashes                      = make([]string, len(torrents))
+		uploaded, downloaded        = make([]int64, len(torrents)), make([]int64, len(torrents))
+		ratios                      = make([]float64, len(torrents))
+		seedingTimes                = make([]int64, len(torrents))
+		swarmSeeders, swarmLeechers = make([]int, len(torrents)), make([]int, len(torrents))
+	)
+	for i, t := range torrents {
+		hashes[i] = t.Hash
+		uploaded[i], downloaded[i] = t.Uploaded, t.Downloaded
+		ratios[i] = t.Ratio
+		seedingTimes[i] = t.SeedingTime
+		swarmSeeders[i], swarmLeechers[i] = t.NumComplete, t.NumIncomplete
+	}
+
+	result, err := db.ExecContext(ctx,
+		`INSERT INTO torrent_samples
+			(torrent_hash, upload_bytes, download_bytes, ratio, seeding_time_seconds, swarm_seeders, swarm_leechers)
+		SELECT s.hash, s.upload_bytes, s.download_bytes, s.ratio, s.seeding_time_seconds, s.swarm_seeders, s.swarm_leechers
+		FROM UNNEST($1::text[], $2::bigint[], $3::bigint[], $4::float8[], $5::bigint[], $6::int[], $7::int[])
+			AS s(hash, upload_bytes, download_bytes, ratio, seeding_time_seconds, swarm_seeders, swarm_leechers)
+		JOIN torrents t ON t.hash = s.hash`,
+		hashes, uploaded, downloaded, ratios, seedingTimes, swarmSeeders, swarmLeechers,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record torrent samples: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// compactSamples downsamples aged torrent samples and drops those past retention
+// Cutoffs are aligned to bucket boundaries so every bucket is compacted exactly once
+func (s *TorrentSyncService) compactSamples(ctx context.Context) {
+	hourCutoff, dayCutoff, expiry := sampleCutoffs(time.Now())
+
+	if err := s.downsample(ctx, "raw", "hour", hourCutoff); err != nil {
+		slog.ErrorContext(ctx, "Failed to downsample raw torrent samples", "error", err)
+	}
+	if err := s.downsample(ctx, "hour", "day", dayCutoff); err != nil {
+		slog.ErrorContext(ctx, "Failed to downsample hourly torrent samples", "error", err)
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM torrent_samples WHERE sampled_at < $1`,
+		expiry,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired torrent samples", "error", err)
+	} else if n, err := result.RowsAffected(); err == nil && n > 0 {
+		slog.InfoContext(ctx, "Deleted expired torrent samples", "count", n)
+	}
+}
+
+// sampleCutoffs returns when samples taken before become hourly, become daily, and are deleted
+// The first two are aligned to hour and day boundaries, so a bucket is never split between two compactions
+func sampleCutoffs(now time.Time) (hour, day, expiry time.Time) {
+	return now.Add(-rawSampleRetention).Truncate(time.Hour),
+		now.Add(-hourlySampleRetention).Truncate(24 * time.Hour),
+		now.Add(-dailySampleRetention)
+}
+
+// downsample replaces the samples of one resolution older than cutoff with one sample per bucket of the next
+func (s *TorrentSyncService) downsample(ctx context.Context, from, to string, cutoff time.Time) error {
+	bucket := "hour"
+	if to == "day" {
+		bucket = "day"
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO torrent_samples
+			(torrent_hash, sampled_at, resolution, upload_bytes, download_bytes, ratio,
+			seeding_time_seconds, swarm_seeders, swarm_leechers)
+		SELECT DISTINCT ON (torrent_hash, DATE_TRUNC($3, sampled_at))
+			torrent_hash, DATE_TRUNC($3, sampled_at), $2, upload_bytes, download_bytes, ratio,
+			seeding_time_seconds, swarm_seeders, swarm_leechers
+		FROM torrent_samples
+		WHERE resolution = $1 AND sampled_at < $4
+		ORDER BY torrent_hash, DATE_TRUNC($3, sampled_at), sampled_at DESC`,
+		from, to, bucket, cutoff,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert %s samples: %w", to, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM torrent_samples WHERE resolution = $1 AND sampled_at < $2`,
+		from, cutoff,
+	); err != nil {
+		return fmt.Errorf("failed to delete %s samples: %w", from, err)
+	}
+
+	return tx.Commit()
+}
+
+// TorrentHistoryService reads the torrent sample history recorded by TorrentSyncService
+type TorrentHistoryService struct {
+	db *sql.DB
+}
+
+func NewTorrentHistoryService(db *sql.DB) *TorrentHistoryService {
+	return &TorrentHistoryService{db: db}
+}
+
+// TorrentSample is a torrent's counters at a point in time
+type TorrentSample struct {
+	SampledAt     time.Time `json:"sampled_at"`
+	Resolution    string    `json:"resolution"`
+	UploadBytes   int64     `json:"upload_bytes"`
+	DownloadBytes int64     `json:"download_bytes"`
+	Ratio         float64   `json:"ratio"`
+	SeedingTime   int64     `json:"seeding_time"`
+	SwarmSeeders  int       `json:"swarm_seeders"`
+	SwarmLeechers int       `json:"swarm_leechers"`
+	// UploadRate is the average upload rate since the previous sample, in bytes per second
+	UploadRate float64 `json:"upload_rate"`
+}
+
+// DeadTorrent is a seeding torrent that no longer uploads or whose tracker is not working
+type DeadTorrent struct {
+	Hash         string     `json:"hash"`
+	Name         string     `json:"name"`
+	TrackerName  string     `json:"tracker_name"`
+	SizeBytes    int64      `json:"size_bytes"`
+	MediaItemID  *int       `json:"media_item_id,omitempty"`
+	MediaTitle   string     `json:"media_title,omitempty"`
+	LastUploadAt *time.Time `json:"last_upload_at,omitempty"`
+	Reason       string     `json:"reason"`
+}
+
+// History returns a torrent's samples since the given time, oldest first
+func (s *TorrentHistoryService) History(ctx context.Context, hash string, since time.Time) ([]TorrentSample, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT sampled_at, resolution, upload_bytes, download_bytes, COALESCE(ratio, 0),
+			COALESCE(seeding_time_seconds, 0), COALESCE(swarm_seeders, 0), COALESCE(swarm_leechers, 0)
+		FROM torrent_samples
+		WHERE torrent_hash = $1 AND sampled_at >= $2
+		ORDER BY sampled_at`,
+		hash, since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrent samples: %w", err)
+	}
+	defer rows.Close()
+
+	samples := []TorrentSample{}
+	for rows.Next() {
+		var sample TorrentSample
+		if err := rows.Scan(&sample.SampledAt, &sample.Resolution, &sample.UploadBytes, &sample.DownloadBytes,
+			&sample.Ratio, &sample.SeedingTime, &sample.SwarmSeeders, &sample.SwarmLeechers); err != nil {
+			return nil, fmt.Errorf("failed to scan torrent sample: %w", err)
+		}
+
+		if n := len(samples); n > 0 {
+			prev := samples[n-1]
+			elapsed := sample.SampledAt.Sub(prev.SampledAt).Seconds()
+			// A counter going backwards means the torrent was re-added; skip the rate for that interval
+			if elapsed > 0 && sample.UploadBytes >= prev.UploadBytes {
+				sample.UploadRate = float64(sample.UploadBytes-prev.UploadBytes) / elapsed
+			}
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// RecentUploadByMedia returns how much each media item's torrents uploaded since the given time
+// Torrents added after since count their whole upload
+func (s *TorrentHistoryService) RecentUploadByMedia(ctx context.Context, since time.Time) (map[int]int64, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT t.media_item_id, COALESCE(SUM(GREATEST(t.upload_bytes - COALESCE(before.upload_bytes, 0), 0)), 0)
+		FROM torrents t
+		LEFT JOIN LATERAL (
+			SELECT upload_bytes FROM torrent_samples
+			WHERE torrent_hash = t.hash AND sampled_at <= $1
+			ORDER BY sampled_at DESC
+			LIMIT 1
+		) before ON TRUE
+		WHERE t.media_item_id IS NOT NULL
+		GROUP BY t.media_item_id`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent upload: %w", err)
+	}
+	defer rows.Close()
+
+	uploads := make(map[int]int64)
+	for rows.Next() {
+		var mediaID int
+		var uploaded int64
+		if err := rows.Scan(&mediaID, &uploaded); err != nil {
+			return nil, fmt.Errorf("failed to scan recent upload: %w", err)
+		}
+		uploads[mediaID] = uploaded
+	}
+
+	return uploads, rows.Err()
+}
+
+// DeadTorrents lists seeding torrents whose tracker is not working, or that have been
+// seeding for longer than deadTorrentWindow without uploading anything in that time
+func (s *TorrentHistoryService) DeadTorrents(ctx context.Context) ([]DeadTorrent, error) {
+	windowStart := time.Now().Add(-deadTorrentWindow)
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT t.hash, COALESCE(t.name, ''), COALESCE(t.tracker_name, ''), COALESCE(t.size_bytes, 0),
+			t.media_item_id, COALESCE(m.title, ''), COALESCE(t.tracker_url, ''),
+			before.upload_bytes, t.upload_bytes,
+			(SELECT MIN(sampled_at) FROM torrent_samples
+				WHERE torrent_hash = t.hash AND upload_bytes = t.upload_bytes) AS last_upload_at
+		FROM torrents t
+		LEFT JOIN media_items m ON m.id = t.media_item_id
+		LEFT JOIN LATERAL (
+			SELECT upload_bytes FROM torrent_samples
+			WHERE torrent_hash = t.hash AND sampled_at <= $1
+			ORDER BY sampled_at DESC
+			LIMIT 1
+		) before ON TRUE
+		WHERE t.is_seeding AND NOT t.is_ignored
+		ORDER BY t.size_bytes DESC`,
+		windowStart,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrents: %w", err)
+	}
+	defer rows.Close()
+
+	dead := []DeadTorrent{}
+	for rows.Next() {
+		var (
+			torrent      DeadTorrent
+			mediaID      sql.NullInt64
+			trackerURL   string
+			uploadBefore sql.NullInt64
+			uploadNow    int64
+			lastUploadAt sql.NullTime
+		)
+		if err := rows.Scan(&torrent.Hash, &torrent.Name, &torrent.TrackerName, &torrent.SizeBytes,
+			&mediaID, &torrent.MediaTitle, &trackerURL, &uploadBefore, &uploadNow, &lastUploadAt); err != nil {
+			return nil, fmt.Errorf("failed to scan torrent: %w", err)
+		}
+
+		switch {
+		case trackerURL == "":
+			// qBittorrent reports no tracker when none of the torrent's trackers is working
+			torrent.Reason = "Tracker not working"
+		case uploadBefore.Valid && uploadNow <= uploadBefore.Int64:
+			torrent.Reason = fmt.Sprintf("No upload in %d days", int(deadTorrentWindow.Hours()/24))
+		default:
+			continue
+		}
+
+		if mediaID.Valid {
+			id := int(mediaID.Int64)
+			torrent.MediaItemID = &id
+		}
+		if lastUploadAt.Valid {
+			torrent.LastUploadAt = &lastUploadAt.Time
+		}
+		dead = append(dead, torrent)
+	}
+
+	return dead, rows.Err()
+}
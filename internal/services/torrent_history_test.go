@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSampleCutoffs(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 42, 17, 0, time.UTC)
+	hour, day, expiry := sampleCutoffs(now)
+
+	if want := time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC); !hour.Equal(want) {
+		t.Errorf("hour cutoff = %v, want %v", hour, want)
+	}
+	if want := time.Date(2024, 2, 14, 0, 0, 0, 0, time.UTC); !day.Equal(want) {
+		t.Errorf("day cutoff = %v, want %v", day, want)
+	}
+	if want := now.Add(-dailySampleRetention); !expiry.Equal(want) {
+		t.Errorf("expiry = %v, want %v", expiry, want)
+	}
+}
+
+func TestSampleCutoffsStableWithinBucket(t *testing.T) {
+	// Compactions within the same hour downsample the same raw samples, never part of a bucket
+	start := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	firstHour, firstDay, _ := sampleCutoffs(start)
+	for _, offset := range []time.Duration{time.Minute, 30 * time.Minute, 59*time.Minute + 59*time.Second} {
+		hour, day, _ := sampleCutoffs(start.Add(offset))
+		if !hour.Equal(firstHour) {
+			t.Errorf("hour cutoff moved after %v: %v, want %v", offset, hour, firstHour)
+		}
+		if !day.Equal(firstDay) {
+			t.Errorf("day cutoff moved after %v: %v, want %v", offset, day, firstDay)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"removarr/internal/events"
+)
+
+// Link sources for torrents.link_source
+const (
+	LinkSourceAuto   = "auto"
+	LinkSourceManual = "manual"
+)
+
+// maxSuggestions is the number of suggested media matches returned per torrent
+const maxSuggestions = 3
+
+// minSuggestionScore is the lowest score a media item needs to be suggested
+const minSuggestionScore = 0.4
+
+// TorrentLinkService manages manual links between torrents and media items
+type TorrentLinkService struct {
+	db     *sql.DB
+	events *events.Bus
+}
+
+// TorrentLinkInfo describes a torrent as shown on the torrent linking page
+type TorrentLinkInfo struct {
+	Hash        string            `json:"hash"`
+	Name        string            `json:"name"`
+	TrackerName string            `json:"tracker_name"`
+	ContentPath string            `json:"content_path"`
+	SizeBytes   int64             `json:"size_bytes"`
+	AddedDate   *time.Time        `json:"added_date,omitempty"`
+	MediaItemID *int              `json:"media_item_id,omitempty"`
+	MediaTitle  string            `json:"media_title,omitempty"`
+	LinkSource  string            `json:"link_source"`
+	IsIgnored   bool              `json:"is_ignored"`
+	Suggestions []MediaSuggestion `json:"suggestions"`
+}
+
+// MediaSuggestion is a media item that may match an unlinked torrent
+type MediaSuggestion struct {
+	MediaItemID int     `json:"media_item_id"`
+	Title       string  `json:"title"`
+	Type        string  `json:"type"`
+	FilePath    string  `json:"file_path"`
+	Score       float64 `json:"score"`
+}
+
+type mediaCandidate struct {
+	ID       int
+	Title    string
+	Type     string
+	FilePath string
+	tokens   []string
+}
+
+func NewTorrentLinkService(db *sql.DB, bus *events.Bus) *TorrentLinkService {
+	return &TorrentLinkService{db: db, events: bus}
+}
+
+// ListUnlinkedTorrents returns torrents without a media item, with suggested matches
+// Ignored torrents are only included when includeIgnored is true
+func (s *TorrentLinkService) ListUnlinkedTorrents(ctx context.Context, includeIgnored bool) ([]TorrentLinkInfo, error) {
+	query := `SELECT hash, name, COALESCE(tracker_name, tracker_url), content_path, size_bytes,
+			added_date, media_item_id, NULL::text, link_source, is_ignored
+		FROM torrents t
+		WHERE media_item_id IS NULL AND missing_since IS NULL`
+	if !includeIgnored {
+		query += " AND NOT is_ignored"
+	}
+	query += " ORDER BY size_bytes DESC NULLS LAST, hash"
+
+	torrents, err := s.queryTorrents(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.loadMediaCandidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range torrents {
+		torrents[i].Suggestions = suggestMatches(torrents[i], candidates)
+	}
+
+	return torrents, nil
+}
+
+// ListManualLinks returns torrents whose link was set or cleared by an admin
+func (s *TorrentLinkService) ListManualLinks(ctx context.Context) ([]TorrentLinkInfo, error) {
+	return s.queryTorrents(ctx,
+		`SELECT t.hash, t.name, COALESCE(t.tracker_name, t.tracker_url), t.content_path, t.size_bytes,
+			t.added_date, t.media_item_id, m.title, t.link_source, t.is_ignored
+		FROM torrents t
+		LEFT JOIN media_items m ON m.id = t.media_item_id
+		WHERE t.link_source = 'manual'
+		ORDER BY t.updated_at DESC`)
+}
+
+func (s *TorrentLinkService) queryTorrents(ctx context.Context, query string, args ...interface{}) ([]TorrentLinkInfo, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrents: %w", err)
+	}
+	defer rows.Close()
+
+	torrents := []TorrentLinkInfo{}
+	for rows.Next() {
+		var (
+			t           TorrentLinkInfo
+			name        sql.NullString
+			trackerName sql.NullString
+			contentPath sql.NullString
+			sizeBytes   sql.NullInt64
+			addedDate   sql.NullTime
+			mediaItemID sql.NullInt64
+			mediaTitle  sql.NullString
+		)
+		if err := rows.Scan(&t.Hash, &name, &trackerName, &contentPath, &sizeBytes,
+			&addedDate, &mediaItemID, &mediaTitle, &t.LinkSource, &t.IsIgnored); err != nil {
+			return nil, fmt.Errorf("failed to scan torrent: %w", err)
+		}
+
+		t.Name = name.String
+		t.TrackerName = trackerName.String
+		t.ContentPath = contentPath.String
+		t.SizeBytes = sizeBytes.Int64
+		t.MediaTitle = mediaTitle.String
+		if addedDate.Valid {
+			t.AddedDate = &addedDate.Time
+		}
+		if mediaItemID.Valid {
+			id := int(mediaItemID.Int64)
+			t.MediaItemID = &id
+		}
+		t.Suggestions = []MediaSuggestion{}
+		torrents = append(torrents, t)
+	}
+
+	return torrents, rows.Err()
+}
+
+// LinkTorrent manually links a torrent to a media item
+// The link is kept by future syncs
+func (s *TorrentLinkService) LinkTorrent(ctx context.Context, hash string, mediaItemID int) error {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM media_items WHERE id = $1)",
+		mediaItemID,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check media item: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("media item not found: %d", mediaItemID)
+	}
+
+	return s.updateTorrent(ctx, hash,
+		`UPDATE torrents SET
+			media_item_id = $2,
+			link_source = 'manual',
+			is_ignored = FALSE,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE hash = $1`,
+		mediaItemID,
+	)
+}
+
+// UnlinkTorrent manually removes the link between a torrent and its media item
+// Future syncs will not re-link it automatically
+func (s *TorrentLinkService) UnlinkTorrent(ctx context.Context, hash string, userID int) error {
+	// Remember what it was linked to for the event
+	var (
+		name        string
+		mediaItemID sql.NullInt64
+		mediaTitle  sql.NullString
+		mediaType   sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT t.name, m.id, m.title, m.type
+		FROM torrents t
+		LEFT JOIN media_items m ON m.id = t.media_item_id
+		WHERE t.hash = $1`,
+		hash,
+	).Scan(&name, &mediaItemID, &mediaTitle, &mediaType)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("torrent not found: %s", hash)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get torrent: %w", err)
+	}
+
+	if err := s.updateTorrent(ctx, hash,
+		`UPDATE torrents SET
+			media_item_id = NULL,
+			link_source = 'manual',
+			updated_at = CURRENT_TIMESTAMP
+		WHERE hash = $1`,
+	); err != nil {
+		return err
+	}
+
+	event := events.Event{
+		Type:       events.TorrentUnlinked,
+		MediaTitle: mediaTitle.String,
+		MediaType:  mediaType.String,
+		ActorID:    &userID,
+		Message:    fmt.Sprintf("Torrent %s was unlinked manually", name),
+		Data: map[string]interface{}{
+			"torrents": []map[string]interface{}{{"hash": hash, "name": name}},
+		},
+	}
+	if mediaItemID.Valid {
+		id := int(mediaItemID.Int64)
+		event.MediaItemID = &id
+	}
+	s.events.Publish(ctx, event)
+	return nil
+}
+
+// ResetTorrentLink hands the torrent back to automatic matching on the next sync
+func (s *TorrentLinkService) ResetTorrentLink(ctx context.Context, hash string) error {
+	return s.updateTorrent(ctx, hash,
+		`UPDATE torrents SET
+			link_source = 'auto',
+			updated_at = CURRENT_TIMESTAMP
+		WHERE hash = $1`,
+	)
+}
+
+// SetTorrentIgnored hides (or unhides) an unlinked torrent from the unlinked list
+func (s *TorrentLinkService) SetTorrentIgnored(ctx context.Context, hash string, ignored bool) error {
+	return s.updateTorrent(ctx, hash,
+		`UPDATE torrents SET
+			is_ignored = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE hash = $1`,
+		ignored,
+	)
+}
+
+func (s *TorrentLinkService) updateTorrent(ctx context.Context, hash string, query string, args ...interface{}) error {
+	result, err := s.db.ExecContext(ctx, query, append([]interface{}{hash}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update torrent: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("torrent not found: %s", hash)
+	}
+	return nil
+}
+
+// SearchMedia finds media items by title for the manual link picker
+func (s *TorrentLinkService) SearchMedia(ctx context.Context, query string, limit int) ([]MediaSuggestion, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, title, type, COALESCE(file_path, '') FROM media_items
+		WHERE title ILIKE '%' || $1 || '%'
+		ORDER BY title
+		LIMIT $2`,
+		query, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search media items: %w", err)
+	}
+	defer rows.Close()
+
+	results := []MediaSuggestion{}
+	for rows.Next() {
+		var m MediaSuggestion
+		if err := rows.Scan(&m.MediaItemID, &m.Title, &m.Type, &m.FilePath); err != nil {
+			return nil, fmt.Errorf("failed to scan media item: %w", err)
+		}
+		results = append(results, m)
+	}
+
+	return results, rows.Err()
+}
+
+func (s *TorrentLinkService) loadMediaCandidates(ctx context.Context) ([]mediaCandidate, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, title, type, COALESCE(file_path, '') FROM media_items",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media items: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []mediaCandidate
+	for rows.Next() {
+		var c mediaCandidate
+		if err := rows.Scan(&c.ID, &c.Title, &c.Type, &c.FilePath); err != nil {
+			return nil, fmt.Errorf("failed to scan media item: %w", err)
+		}
+		c.tokens = tokenize(c.Title)
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+// suggestMatches scores media items against a torrent's content path and name
+func suggestMatches(torrent TorrentLinkInfo, candidates []mediaCandidate) []MediaSuggestion {
+	nameTokens := make(map[string]bool)
+	for _, token := range tokenize(torrent.Name) {
+		nameTokens[token] = true
+	}
+	torrentBase := normalizeName(filepath.Base(torrent.ContentPath))
+
+	suggestions := []MediaSuggestion{}
+	for _, c := range candidates {
+		score := 0.0
+
+		// Path matching: containment either way is a near-certain match
+		if torrent.ContentPath != "" && c.FilePath != "" {
+			if strings.HasPrefix(c.FilePath, torrent.ContentPath) || strings.HasPrefix(torrent.ContentPath, c.FilePath) {
+				score = 1.0
+			} else if torrentBase != "" && torrentBase == normalizeName(filepath.Base(c.FilePath)) {
+				score = 0.9
+			}
+		}
+
+		// Title matching: fraction of the title's words found in the torrent name
+		if score < 0.8 && len(c.tokens) > 0 {
+			matched := 0
+			for _, token := range c.tokens {
+				if nameTokens[token] {
+					matched++
+				}
+			}
+			if titleScore := 0.8 * float64(matched) / float64(len(c.tokens)); titleScore > score {
+				score = titleScore
+			}
+		}
+
+		if score >= minSuggestionScore {
+			suggestions = append(suggestions, MediaSuggestion{
+				MediaItemID: c.ID,
+				Title:       c.Title,
+				Type:        c.Type,
+				FilePath:    c.FilePath,
+				Score:       score,
+			})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+
+	return suggestions
+}
+
+// tokenize splits a title or release name into lowercase words
+// (e.g. "Movie.Title.2023.1080p" -> ["movie", "title", "2023", "1080p"])
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func normalizeName(s string) string {
+	return strings.Join(tokenize(s), " ")
+}
@@ -7,301 +7,640 @@ import (
 	"log/slog"
 	"time"
 
+	"removarr/internal/events"
 	"removarr/internal/integrations"
 )
 
 type TorrentSyncService struct {
-	db          *sql.DB
+	db           *sql.DB
 	integrations *integrations.Client
+	events       *events.Bus
 }
 
-func NewTorrentSyncService(db *sql.DB, integrationsClient *integrations.Client) *TorrentSyncService {
+func NewTorrentSyncService(db *sql.DB, integrationsClient *integrations.Client, bus *events.Bus) *TorrentSyncService {
 	return &TorrentSyncService{
-		db:          db,
+		db:           db,
 		integrations: integrationsClient,
+		events:       bus,
 	}
 }
 
-// SyncFromQBittorrent fetches torrents from qBittorrent and updates the database
-func (s *TorrentSyncService) SyncFromQBittorrent(ctx context.Context) error {
+// SyncFromQBittorrent fetches what changed in qBittorrent since the last sync and updates the database
+// Only changed torrents and removed hashes are transferred (sync/maindata) and written; the rest only get
+// last_synced_at bumped. All writes go in one transaction, and torrents removed from qBittorrent are marked missing
+func (s *TorrentSyncService) SyncFromQBittorrent(ctx context.Context) (SyncCounts, error) {
+	qb := s.integrations.QBittorrent
+	if qb == nil {
+		return SyncCounts{}, fmt.Errorf("qbittorrent integration not enabled")
+	}
+
+	slog.InfoContext(ctx, "Syncing torrents from qBittorrent...")
+	data, err := qb.SyncMainData(ctx)
+	if err != nil {
+		return SyncCounts{}, fmt.Errorf("failed to fetch torrents from qBittorrent: %w", err)
+	}
+
+	result, err := s.syncTorrents(ctx, torrentUpdate{
+		torrents: data.Changed,
+		full:     data.FullUpdate,
+		delta:    !data.FullUpdate,
+		removed:  data.Removed,
+		samples:  data.Torrents,
+	}, s.prowlarrIndexers(ctx))
+	// The next delta won't repeat what wasn't stored, start over from a full update
+	if err != nil || result.counts.Failed > 0 {
+		qb.ResetMainData()
+	}
+	if err != nil {
+		return result.counts, err
+	}
+
+	slog.InfoContext(ctx, "qBittorrent sync complete", "count", len(data.Torrents), "full_update", data.FullUpdate,
+		"changed", len(data.Changed), "added", result.counts.Added, "updated", result.counts.Updated,
+		"unchanged", result.counts.Unchanged, "failed", result.counts.Failed, "removed", len(data.Removed),
+		"missing", result.counts.Missing)
+
+	// Downsample the upload/ratio history the sync recorded as it ages
+	s.compactSamples(ctx)
+
+	// After syncing, try to link any unlinked torrents to media items
+	// This helps catch cases where file paths didn't match initially
+	s.logUnlinkedTorrents(ctx)
+
+	s.publishUnlinked(ctx, result.newUnlinked)
+
+	return result.counts, nil
+}
+
+// SyncTorrents fetches the given torrents from qBittorrent and updates them in the database
+// Used by the qBittorrent completion callback, so a finished download shows up without waiting for the next full sync
+func (s *TorrentSyncService) SyncTorrents(ctx context.Context, hashes []string) error {
 	if s.integrations.QBittorrent == nil {
 		return fmt.Errorf("qbittorrent integration not enabled")
 	}
 
-	slog.Info("Syncing torrents from qBittorrent...")
-	torrents, err := s.integrations.QBittorrent.GetTorrents()
+	torrents, err := s.integrations.QBittorrent.GetTorrentsByHash(ctx, hashes)
 	if err != nil {
 		return fmt.Errorf("failed to fetch torrents from qBittorrent: %w", err)
 	}
 
-	// Get all indexers from Prowlarr to map tracker names to IDs
+	result, err := s.syncTorrents(ctx, torrentUpdate{torrents: torrents, samples: torrents}, s.prowlarrIndexers(ctx))
+	if err != nil {
+		return err
+	}
+	s.publishUnlinked(ctx, result.newUnlinked)
+
+	slog.InfoContext(ctx, "Synced torrents from qBittorrent callback", "requested", len(hashes), "found", len(torrents))
+	return nil
+}
+
+// prowlarrIndexers returns the Prowlarr indexers by name, to map tracker names to IDs
+// Empty if Prowlarr isn't configured or can't be reached
+func (s *TorrentSyncService) prowlarrIndexers(ctx context.Context) map[string]*integrations.ProwlarrIndexer {
 	indexerMap := make(map[string]*integrations.ProwlarrIndexer)
 	if s.integrations.Prowlarr != nil {
-		indexers, err := s.integrations.Prowlarr.GetIndexers()
+		indexers, err := s.integrations.Prowlarr.GetIndexers(ctx)
 		if err == nil {
 			for i := range indexers {
 				indexerMap[indexers[i].Name] = &indexers[i]
 			}
 		}
 	}
+	return indexerMap
+}
+
+// publishUnlinked publishes one event for the new torrents a sync couldn't link
+func (s *TorrentSyncService) publishUnlinked(ctx context.Context, torrents []map[string]interface{}) {
+	if len(torrents) == 0 {
+		return
+	}
+	s.events.Publish(ctx, events.Event{
+		Type:    events.TorrentUnlinked,
+		Message: fmt.Sprintf("%d new torrent(s) couldn't be linked to a media item", len(torrents)),
+		Data: map[string]interface{}{
+			"torrents": torrents,
+		},
+	})
+}
+
+// storedTorrent is the stored state of a torrent the sync compares against
+type storedTorrent struct {
+	syncHash    string
+	mediaItemID sql.NullInt64
+	linkSource  string
+	name        string
+	contentPath string
+}
+
+// torrentWrite is a torrent the sync may write more than counters for
+type torrentWrite struct {
+	torrent     integrations.QBittorrentTorrent
+	tracker     trackerInfo
+	hash        string // torrentHash
+	exists      bool
+	prev        storedTorrent
+	sameHash    bool          // name, path, size and tracker are unchanged
+	match       bool          // matched to a media item again
+	mediaItemID sql.NullInt64 // the match, if any
+}
+
+// torrentSyncResult counts what a sync wrote
+type torrentSyncResult struct {
+	counts      SyncCounts
+	newUnlinked []map[string]interface{} // new torrents that couldn't be linked to a media item
+	changed     map[string]bool          // torrents added or moved, whose cross-seed group has to be worked out again
+}
+
+// torrentUpdate is what a sync writes
+type torrentUpdate struct {
+	torrents []integrations.QBittorrentTorrent // torrents to write
+	full     bool                              // torrents is everything qBittorrent has
+	delta    bool                              // torrents and removed are everything that changed since the previous sync
+	removed  []string                          // hashes of torrents removed from qBittorrent
+	samples  []integrations.QBittorrentTorrent // torrents to record upload/ratio history for
+}
 
+// storedTorrents loads the stored state of the given torrents in one query, of all torrents if hashes is nil
+func (s *TorrentSyncService) storedTorrents(ctx context.Context, hashes []string) (map[string]storedTorrent, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT hash, COALESCE(sync_hash, ''), media_item_id, link_source, COALESCE(name, ''), COALESCE(content_path, '')
+		FROM torrents
+		WHERE $1::text[] IS NULL OR hash = ANY($1)`,
+		hashes,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[string]storedTorrent)
+	for rows.Next() {
+		var hash string
+		var t storedTorrent
+		if err := rows.Scan(&hash, &t.syncHash, &t.mediaItemID, &t.linkSource, &t.name, &t.contentPath); err != nil {
+			return nil, err
+		}
+		stored[hash] = t
+	}
+	return stored, rows.Err()
+}
+
+// syncTorrents writes an update in one transaction, along with history samples and cross-seed groups
+// Counters (seeding time, transfer, ratio) change on every poll, so they are written for every known torrent in
+// one statement. Name, path, size and tracker only when they changed, and torrents are only matched to media
+// items when new, unlinked, renamed or moved; new and changed torrents are each written in one statement too.
+// If one of those fails, its torrents are written one at a time so a failing torrent is logged and rolled back
+// on its own, and the rest of the sync still commits; so do failing samples and cross-seed grouping.
+// A full update gets a generation stamped on every torrent seen, and stored torrents that weren't seen are
+// marked missing. A delta marks the removed torrents missing, and every other stored torrent unchanged.
+func (s *TorrentSyncService) syncTorrents(ctx context.Context, update torrentUpdate, indexerMap map[string]*integrations.ProwlarrIndexer) (torrentSyncResult, error) {
+	torrents := update.torrents
+	result := torrentSyncResult{counts: SyncCounts{Seen: len(torrents)}, changed: make(map[string]bool)}
+
+	var hashes []string
+	if !update.full {
+		hashes = make([]string, 0, len(torrents))
+		for _, torrent := range torrents {
+			hashes = append(hashes, torrent.Hash)
+		}
+	}
+	stored, err := s.storedTorrents(ctx, hashes)
+	if err != nil {
+		return result, fmt.Errorf("failed to load stored torrents: %w", err)
+	}
+
+	var known []integrations.QBittorrentTorrent // counters are written for all of them
+	var writes []torrentWrite                   // torrents whose name, path, size, tracker or link may change
+	needMatch := false
 	for _, torrent := range torrents {
-		// Try to match torrent to media item by file path
-		// Use multiple matching strategies for better reliability
-		var mediaItemID sql.NullInt64
-		
-		if torrent.ContentPath != "" {
-			// Strategy 1: Exact match
-			err := s.db.QueryRowContext(ctx,
-				`SELECT id FROM media_items 
-				WHERE file_path = $1
-				LIMIT 1`,
-				torrent.ContentPath,
-			).Scan(&mediaItemID)
-			
-			if err == nil && mediaItemID.Valid {
-				// Found exact match
-			} else if err == sql.ErrNoRows {
-				// Strategy 2: Media item path is contained in torrent content path
-				// (e.g., torrent: /data/downloads/Movie Title (2023), media: /data/downloads/Movie Title (2023)/Movie.Title.2023.mkv)
-				err = s.db.QueryRowContext(ctx,
-					`SELECT id FROM media_items 
-					WHERE file_path LIKE $1 || '%' AND file_path != ''
-					LIMIT 1`,
-					torrent.ContentPath,
-				).Scan(&mediaItemID)
-				
-				if err == nil && mediaItemID.Valid {
-					// Found by containment
-				} else if err == sql.ErrNoRows {
-					// Strategy 3: Torrent content path is contained in media item path
-					// (e.g., torrent: /data/downloads/Movie Title (2023), media: /data/downloads/Movie Title (2023)/Movie.Title.2023.mkv)
-					err = s.db.QueryRowContext(ctx,
-						`SELECT id FROM media_items 
-						WHERE $1 LIKE file_path || '%' AND file_path != ''
-						LIMIT 1`,
-						torrent.ContentPath,
-					).Scan(&mediaItemID)
-					
-					if err == nil && mediaItemID.Valid {
-						// Found by reverse containment
-					} else if err == sql.ErrNoRows {
-						// Strategy 4: Match by directory name (basename of parent directory)
-						// Extract the directory name from the torrent path
-						// This is a fallback for when paths don't match exactly
-						err = s.db.QueryRowContext(ctx,
-							`SELECT id FROM media_items 
-							WHERE file_path LIKE '%' || $1 || '%' AND file_path != ''
-							ORDER BY 
-								CASE WHEN file_path LIKE $1 || '%' THEN 1 ELSE 2 END,
-								LENGTH(file_path) ASC
-							LIMIT 1`,
-							torrent.ContentPath,
-						).Scan(&mediaItemID)
-					}
-				}
-			}
-			
-			if err != nil && err != sql.ErrNoRows {
-				slog.Debug("Error matching torrent to media", "hash", torrent.Hash, "error", err)
-			}
+		prev, exists := stored[torrent.Hash]
+		if exists {
+			known = append(known, torrent)
 		}
-		
-		// If still no match, try to match by torrent name (contains media title)
-		// This is a last resort fallback
-		if !mediaItemID.Valid && torrent.Name != "" {
-			// Extract a potential title from torrent name (remove common suffixes)
-			// This is heuristic-based and may have false positives
-			err := s.db.QueryRowContext(ctx,
-				`SELECT id FROM media_items 
-				WHERE title = ANY(string_to_array($1, ' ')) 
-				   OR $1 LIKE '%' || title || '%'
-				ORDER BY 
-					CASE WHEN title = ANY(string_to_array($1, ' ')) THEN 1 ELSE 2 END
-				LIMIT 1`,
-				torrent.Name,
-			).Scan(&mediaItemID)
-			
-			if err != nil && err != sql.ErrNoRows {
-				slog.Debug("Error matching torrent by name", "hash", torrent.Hash, "name", torrent.Name, "error", err)
-			}
+		tracker := s.resolveTracker(torrent, indexerMap)
+		w := torrentWrite{torrent: torrent, tracker: tracker, hash: torrentHash(torrent, tracker), exists: exists, prev: prev}
+		w.sameHash = exists && w.hash != "" && prev.syncHash == w.hash
+
+		// Manual links (and manual unlinks) made by an admin are never overwritten
+		linked := exists && (prev.mediaItemID.Valid || prev.linkSource == "manual")
+		if linked && w.sameHash {
+			continue
 		}
+		w.match = !linked || prev.contentPath != torrent.ContentPath || prev.name != torrent.Name
+		needMatch = needMatch || w.match
+		writes = append(writes, w)
+	}
 
-		// Get tracker info from Prowlarr if available
-		var trackerID *int
-		var trackerName *string
-		var trackerType *string
-		var requiredTime *int64
-		var requiredRatio *float64
-
-		if torrent.Tracker != "" && s.integrations.Prowlarr != nil {
-			// Try to find matching indexer
-			for name, indexer := range indexerMap {
-				if torrent.Tracker == name || torrent.Tracker == indexer.Name {
-					trackerID = &indexer.ID
-					trackerName = &indexer.Name
-					trackerType = &indexer.Privacy
-					
-					if indexer.MinSeedTime != nil {
-						rt := *indexer.MinSeedTime
-						requiredTime = &rt
-					}
-					if indexer.MinRatio != nil {
-						rr := *indexer.MinRatio
-						requiredRatio = &rr
-					}
-					break
-				}
-			}
-			
-			// If not found, try to determine if it's public/private from URL
-			if trackerType == nil {
-				isPublic := s.isPublicTracker(torrent.Tracker)
-				trackerTypeStr := "private"
-				if isPublic {
-					trackerTypeStr = "public"
-				}
-				trackerType = &trackerTypeStr
-				trackerName = &torrent.Tracker
-			}
+	// Media items are loaded once for all the torrents to match
+	var matcher *mediaMatcher
+	if needMatch {
+		if matcher, err = loadMediaMatcher(ctx, s.db); err != nil {
+			return result, err
+		}
+	}
+	var inserts, updates []torrentWrite
+	for _, w := range writes {
+		if w.match {
+			w.mediaItemID = matcher.match(w.torrent)
+		}
+		switch {
+		case !w.exists:
+			inserts = append(inserts, w)
+		case !w.sameHash || w.mediaItemID.Valid:
+			updates = append(updates, w)
 		}
+		// otherwise still unlinked, nothing else changed
+	}
 
-		// Check if torrent exists
-		var existingHash string
-		err = s.db.QueryRowContext(ctx,
-			"SELECT hash FROM torrents WHERE hash = $1",
-			torrent.Hash,
-		).Scan(&existingHash)
-
-		addedDate := time.Unix(torrent.AddedOn, 0)
-		isSeeding := torrent.State == "uploading" || torrent.State == "stalledUP"
-
-		if err == sql.ErrNoRows {
-			// Insert new torrent
-			var mediaID interface{}
-			if mediaItemID.Valid {
-				mediaID = mediaItemID.Int64
-			}
-			
-			var trackerIDVal interface{}
-			if trackerID != nil {
-				trackerIDVal = *trackerID
-			}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var generation int64
+	if update.full {
+		if generation, err = nextSyncGeneration(ctx, tx, SyncSourceQBittorrent); err != nil {
+			return result, err
+		}
+	}
+
+	failedInserts := writeBatch(ctx, tx, len(inserts), func(from, to int) error {
+		return insertTorrents(ctx, tx, inserts[from:to], generation)
+	})
+	for i, w := range inserts {
+		if err, ok := failedInserts[i]; ok {
+			slog.ErrorContext(ctx, "Failed to sync torrent", "error", err, "hash", w.torrent.Hash)
+			result.counts.Failed++
+			continue
+		}
+		result.counts.Added++
+		result.changed[w.torrent.Hash] = true
+		if !w.mediaItemID.Valid {
+			result.newUnlinked = append(result.newUnlinked, map[string]interface{}{
+				"hash": w.torrent.Hash,
+				"name": w.torrent.Name,
+			})
+		}
+	}
 
-			_, err = s.db.ExecContext(ctx,
-				`INSERT INTO torrents 
-					(media_item_id, hash, tracker_id, tracker_name, tracker_type,
-					added_date, seeding_time_seconds, upload_bytes, download_bytes,
-					ratio, seeding_required_seconds, seeding_required_ratio, is_seeding,
-					last_synced_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, CURRENT_TIMESTAMP)`,
-				mediaID,
-				torrent.Hash,
-				trackerIDVal,
-				trackerName,
-				trackerType,
-				addedDate,
-				torrent.SeedingTime,
-				torrent.Uploaded,
-				torrent.Downloaded,
-				torrent.Ratio,
-				requiredTime,
-				requiredRatio,
-				isSeeding,
+	failedUpdates := writeBatch(ctx, tx, len(updates), func(from, to int) error {
+		return updateTorrents(ctx, tx, updates[from:to])
+	})
+	for i, w := range updates {
+		if err, ok := failedUpdates[i]; ok {
+			// Still there, its counters are written below
+			slog.ErrorContext(ctx, "Failed to sync torrent", "error", err, "hash", w.torrent.Hash)
+			result.counts.Failed++
+			continue
+		}
+		result.counts.Updated++
+		if w.prev.contentPath != w.torrent.ContentPath {
+			result.changed[w.torrent.Hash] = true
+		}
+		if w.mediaItemID.Valid && w.prev.linkSource != "manual" && w.prev.mediaItemID.Int64 != w.mediaItemID.Int64 {
+			slog.DebugContext(ctx, "Linked existing torrent to media item",
+				"hash", w.torrent.Hash,
+				"media_item_id", w.mediaItemID.Int64,
+				"content_path", w.torrent.ContentPath)
+		}
+	}
+
+	if err := updateCounters(ctx, tx, known, generation); err != nil {
+		return result, err
+	}
+	unchangedCount := int64(len(known) - len(updates))
+	if update.delta {
+		if len(update.removed) > 0 {
+			res, err := tx.ExecContext(ctx,
+				"UPDATE torrents SET missing_since = CURRENT_TIMESTAMP WHERE hash = ANY($1) AND missing_since IS NULL",
+				update.removed,
 			)
 			if err != nil {
-				slog.Error("Failed to insert torrent", "error", err, "hash", torrent.Hash)
-				continue
-			}
-		} else if err == nil {
-			// Update existing torrent
-			var trackerIDVal interface{}
-			if trackerID != nil {
-				trackerIDVal = *trackerID
+				return result, fmt.Errorf("failed to mark removed torrents missing: %w", err)
 			}
-			
-			var mediaIDVal interface{}
-			// If torrent exists but wasn't linked before, try to link it now
-			if mediaItemID.Valid {
-				// Check if torrent already has a different media_item_id
-				var currentMediaID sql.NullInt64
-				err := s.db.QueryRowContext(ctx,
-					"SELECT media_item_id FROM torrents WHERE hash = $1",
-					torrent.Hash,
-				).Scan(&currentMediaID)
-				
-				if err == nil {
-					// If no media_item_id set, or if it's different and the new one is valid, update it
-					if !currentMediaID.Valid || (mediaItemID.Valid && currentMediaID.Int64 != mediaItemID.Int64) {
-						mediaIDVal = mediaItemID.Int64
-					} else {
-						mediaIDVal = currentMediaID.Int64 // Keep existing link
-					}
-				}
+			if result.counts.Missing, err = res.RowsAffected(); err != nil {
+				return result, err
 			}
+		}
+		// Every torrent the delta didn't mention is still there, unchanged; same decay the counters
+		// apply for an interval without uploads
+		res, err := tx.ExecContext(ctx,
+			`UPDATE torrents SET
+				upload_rate = CASE
+					WHEN last_synced_at IS NULL OR CURRENT_TIMESTAMP <= last_synced_at THEN upload_rate
+					ELSE COALESCE(upload_rate / 2, 0)
+				END,
+				last_synced_at = CURRENT_TIMESTAMP
+			WHERE missing_since IS NULL AND hash <> ALL($1)`,
+			hashes,
+		)
+		if err != nil {
+			return result, fmt.Errorf("failed to mark unchanged torrents synced: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return result, err
+		}
+		unchangedCount += n
+		result.counts.Seen += int(n)
+	}
+	// An empty client next to stored torrents is more likely a misconfigured instance than everything removed
+	if generation != 0 && len(torrents) == 0 && len(stored) > 0 {
+		slog.WarnContext(ctx, "qBittorrent returned no torrents, not marking stored torrents missing", "stored", len(stored))
+	} else if generation != 0 {
+		if result.counts.Missing, err = markMissing(ctx, tx, "torrents", "TRUE", generation); err != nil {
+			return result, err
+		}
+	}
 
-			_, err = s.db.ExecContext(ctx,
-				`UPDATE torrents SET
-					media_item_id = COALESCE($2, media_item_id),
-					tracker_id = $3,
-					tracker_name = $4,
-					tracker_type = $5,
-					added_date = $6,
-					seeding_time_seconds = $7,
-					upload_bytes = $8,
-					download_bytes = $9,
-					ratio = $10,
-					seeding_required_seconds = $11,
-					seeding_required_ratio = $12,
-					is_seeding = $13,
-					last_synced_at = CURRENT_TIMESTAMP
-				WHERE hash = $1`,
-				torrent.Hash,
-				mediaIDVal,
-				trackerIDVal,
-				trackerName,
-				trackerType,
-				addedDate,
-				torrent.SeedingTime,
-				torrent.Uploaded,
-				torrent.Downloaded,
-				torrent.Ratio,
-				requiredTime,
-				requiredRatio,
-				isSeeding,
-			)
-			if err != nil {
-				slog.Error("Failed to update torrent", "error", err, "hash", torrent.Hash)
-				continue
+	// Keep upload/ratio history
+	if err := inSavepoint(ctx, tx, func() error {
+		n, err := recordSamples(ctx, tx, update.samples)
+		slog.DebugContext(ctx, "Recorded torrent samples", "count", n)
+		return err
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to record torrent samples", "error", err)
+	}
+
+	// Group cross-seeded torrents (same data on several trackers), when torrents were added, moved or went missing
+	if len(result.changed) > 0 || result.counts.Missing > 0 {
+		if err := inSavepoint(ctx, tx, func() error {
+			return s.groupCrossSeeds(ctx, tx, result.changed)
+		}); err != nil {
+			slog.ErrorContext(ctx, "Failed to group cross-seeded torrents", "error", err)
+		}
+	}
+	if err := inSavepoint(ctx, tx, func() error {
+		n, err := linkCrossSeeds(ctx, tx)
+		if n > 0 {
+			slog.InfoContext(ctx, "Linked cross-seeded torrents to their group's media item", "count", n)
+		}
+		return err
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to link cross-seeded torrents", "error", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	result.counts.Unchanged = int(unchangedCount)
+	return result, nil
+}
+
+// trackerInfo is the tracker of a torrent as resolved through Prowlarr
+type trackerInfo struct {
+	id            *int
+	name          *string
+	trackerType   *string
+	requiredTime  *int64
+	requiredRatio *float64
+}
+
+// resolveTracker looks up a torrent's tracker in Prowlarr, guessing public/private from the URL if Prowlarr doesn't know it
+func (s *TorrentSyncService) resolveTracker(torrent integrations.QBittorrentTorrent, indexerMap map[string]*integrations.ProwlarrIndexer) trackerInfo {
+	var tracker trackerInfo
+	if torrent.Tracker == "" || s.integrations.Prowlarr == nil {
+		return tracker
+	}
+
+	// Try to find matching indexer
+	for name, indexer := range indexerMap {
+		if torrent.Tracker == name || torrent.Tracker == indexer.Name {
+			tracker.id = &indexer.ID
+			tracker.name = &indexer.Name
+			tracker.trackerType = &indexer.Privacy
+
+			if indexer.MinSeedTime != nil {
+				rt := *indexer.MinSeedTime
+				tracker.requiredTime = &rt
 			}
-			
-			if mediaItemID.Valid && mediaIDVal != nil {
-				slog.Debug("Linked existing torrent to media item", 
-					"hash", torrent.Hash, 
-					"media_item_id", mediaItemID.Int64,
-					"content_path", torrent.ContentPath)
+			if indexer.MinRatio != nil {
+				rr := *indexer.MinRatio
+				tracker.requiredRatio = &rr
 			}
+			break
 		}
 	}
 
-	slog.Info("qBittorrent sync complete", "count", len(torrents))
-	
-	// After syncing, try to link any unlinked torrents to media items
-	// This helps catch cases where file paths didn't match initially
-	s.logUnlinkedTorrents(ctx)
-	
+	// If not found, try to determine if it's public/private from URL
+	if tracker.trackerType == nil {
+		trackerTypeStr := "private"
+		if s.isPublicTracker(torrent.Tracker) {
+			trackerTypeStr = "public"
+		}
+		tracker.trackerType = &trackerTypeStr
+		tracker.name = &torrent.Tracker
+	}
+	return tracker
+}
+
+// torrentHash fingerprints what identifies a torrent and matches it to a media item: name, path, size and
+// tracker. Counters change on every poll of an active torrent, so they are left out and written for every torrent.
+func torrentHash(torrent integrations.QBittorrentTorrent, tracker trackerInfo) string {
+	return syncHash(torrent.Name, torrent.ContentPath, torrent.Size, torrent.Tracker, torrent.AddedOn,
+		tracker.id, tracker.name, tracker.trackerType, tracker.requiredTime, tracker.requiredRatio)
+}
+
+func isSeeding(torrent integrations.QBittorrentTorrent) bool {
+	return torrent.State == "uploading" || torrent.State == "stalledUP"
+}
+
+// torrentColumns holds the columns of a batch of torrents, one slice per column, for UNNEST
+// Missing values are sent as 0, "" or -1 and turned back into NULL by the statement
+type torrentColumns struct {
+	hashes         []string
+	mediaItemIDs   []int64
+	trackerIDs     []int
+	trackerNames   []string
+	trackerTypes   []string
+	addedDates     []time.Time
+	requiredTimes  []int64
+	requiredRatios []float64
+	names          []string
+	contentPaths   []string
+	sizes          []int64
+	trackerURLs    []string
+	syncHashes     []string
+}
+
+func newTorrentColumns(writes []torrentWrite) torrentColumns {
+	var c torrentColumns
+	for _, w := range writes {
+		trackerID, trackerName, trackerType := 0, "", ""
+		if w.tracker.id != nil {
+			trackerID = *w.tracker.id
+		}
+		if w.tracker.name != nil {
+			trackerName = *w.tracker.name
+		}
+		if w.tracker.trackerType != nil {
+			trackerType = *w.tracker.trackerType
+		}
+		requiredTime, requiredRatio := int64(-1), float64(-1)
+		if w.tracker.requiredTime != nil {
+			requiredTime = *w.tracker.requiredTime
+		}
+		if w.tracker.requiredRatio != nil {
+			requiredRatio = *w.tracker.requiredRatio
+		}
+
+		c.hashes = append(c.hashes, w.torrent.Hash)
+		c.mediaItemIDs = append(c.mediaItemIDs, w.mediaItemID.Int64)
+		c.trackerIDs = append(c.trackerIDs, trackerID)
+		c.trackerNames = append(c.trackerNames, trackerName)
+		c.trackerTypes = append(c.trackerTypes, trackerType)
+		c.addedDates = append(c.addedDates, time.Unix(w.torrent.AddedOn, 0))
+		c.requiredTimes = append(c.requiredTimes, requiredTime)
+		c.requiredRatios = append(c.requiredRatios, requiredRatio)
+		c.names = append(c.names, w.torrent.Name)
+		c.contentPaths = append(c.contentPaths, w.torrent.ContentPath)
+		c.sizes = append(c.sizes, w.torrent.Size)
+		c.trackerURLs = append(c.trackerURLs, w.torrent.Tracker)
+		c.syncHashes = append(c.syncHashes, w.hash)
+	}
+	return c
+}
+
+// insertTorrents inserts torrents qBittorrent reported for the first time, in one statement
+func insertTorrents(ctx context.Context, db execer, writes []torrentWrite, generation int64) error {
+	c := newTorrentColumns(writes)
+	var (
+		seedingTimes         = make([]int64, len(writes))
+		uploaded, downloaded = make([]int64, len(writes)), make([]int64, len(writes))
+		ratios               = make([]float64, len(writes))
+		seeding              = make([]bool, len(writes))
+	)
+	for i, w := range writes {
+		seedingTimes[i] = w.torrent.SeedingTime
+		uploaded[i], downloaded[i] = w.torrent.Uploaded, w.torrent.Downloaded
+		ratios[i] = w.torrent.Ratio
+		seeding[i] = isSeeding(w.torrent)
+	}
+
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO torrents
+			(media_item_id, hash, tracker_id, tracker_name, tracker_type,
+			added_date, seeding_time_seconds, upload_bytes, download_bytes,
+			ratio, seeding_required_seconds, seeding_required_ratio, is_seeding,
+			name, content_path, size_bytes, tracker_url, sync_hash, sync_generation, last_synced_at)
+		SELECT NULLIF(t.media_item_id, 0), t.hash, NULLIF(t.tracker_id, 0), NULLIF(t.tracker_name, ''),
+			NULLIF(t.tracker_type, ''), t.added_date, t.seeding_time_seconds, t.upload_bytes, t.download_bytes,
+			t.ratio, NULLIF(t.seeding_required_seconds, -1), NULLIF(t.seeding_required_ratio, -1), t.is_seeding,
+			t.name, t.content_path, t.size_bytes, t.tracker_url, t.sync_hash, NULLIF($19::bigint, 0), CURRENT_TIMESTAMP
+		FROM UNNEST($1::bigint[], $2::text[], $3::int[], $4::text[], $5::text[], $6::timestamp[], $7::bigint[],
+			$8::bigint[], $9::bigint[], $10::float8[], $11::bigint[], $12::float8[], $13::bool[], $14::text[],
+			$15::text[], $16::bigint[], $17::text[], $18::text[])
+			AS t(media_item_id, hash, tracker_id, tracker_name, tracker_type, added_date, seeding_time_seconds,
+			upload_bytes, download_bytes, ratio, seeding_required_seconds, seeding_required_ratio, is_seeding,
+			name, content_path, size_bytes, tracker_url, sync_hash)`,
+		c.mediaItemIDs, c.hashes, c.trackerIDs, c.trackerNames, c.trackerTypes, c.addedDates, seedingTimes,
+		uploaded, downloaded, ratios, c.requiredTimes, c.requiredRatios, seeding, c.names,
+		c.contentPaths, c.sizes, c.trackerURLs, c.syncHashes, generation,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert torrents: %w", err)
+	}
+	return nil
+}
+
+// updateTorrents writes the name, path, size and tracker of known torrents in one statement, linking each to its
+// mediaItemID if valid. Counters are written by updateCounters.
+func updateTorrents(ctx context.Context, db execer, writes []torrentWrite) error {
+	c := newTorrentColumns(writes)
+	// Manual links (and manual unlinks) made by an admin are never overwritten
+	_, err := db.ExecContext(ctx,
+		`UPDATE torrents t SET
+			media_item_id = CASE WHEN t.link_source = 'manual' THEN t.media_item_id
+				ELSE COALESCE(NULLIF(u.media_item_id, 0), t.media_item_id) END,
+			tracker_id = NULLIF(u.tracker_id, 0),
+			tracker_name = NULLIF(u.tracker_name, ''),
+			tracker_type = NULLIF(u.tracker_type, ''),
+			added_date = u.added_date,
+			seeding_required_seconds = NULLIF(u.seeding_required_seconds, -1),
+			seeding_required_ratio = NULLIF(u.seeding_required_ratio, -1),
+			name = u.name,
+			content_path = u.content_path,
+			size_bytes = u.size_bytes,
+			tracker_url = u.tracker_url,
+			sync_hash = u.sync_hash,
+			updated_at = CURRENT_TIMESTAMP
+		FROM UNNEST($1::text[], $2::bigint[], $3::int[], $4::text[], $5::text[], $6::timestamp[], $7::bigint[],
+			$8::float8[], $9::text[], $10::text[], $11::bigint[], $12::text[], $13::text[])
+			AS u(hash, media_item_id, tracker_id, tracker_name, tracker_type, added_date, seeding_required_seconds,
+			seeding_required_ratio, name, content_path, size_bytes, tracker_url, sync_hash)
+		WHERE t.hash = u.hash`,
+		c.hashes, c.mediaItemIDs, c.trackerIDs, c.trackerNames, c.trackerTypes, c.addedDates, c.requiredTimes,
+		c.requiredRatios, c.names, c.contentPaths, c.sizes, c.trackerURLs, c.syncHashes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update torrents: %w", err)
+	}
+	return nil
+}
+
+// updateCounters writes the seeding time, transfer, ratio and seeding state of known torrents in one statement,
+// along with their upload rate, and stamps generation on them (0 outside full syncs to keep the stored one)
+func updateCounters(ctx context.Context, db execer, torrents []integrations.QBittorrentTorrent, generation int64) error {
+	if len(torrents) == 0 {
+		return nil
+	}
+	var (
+		hashes               = make([]string, len(torrents))
+		seedingTimes         = make([]int64, len(torrents))
+		uploaded, downloaded = make([]int64, len(torrents)), make([]int64, len(torrents))
+		ratios               = make([]float64, len(torrents))
+		seeding              = make([]bool, len(torrents))
+	)
+	for i, t := range torrents {
+		hashes[i] = t.Hash
+		seedingTimes[i] = t.SeedingTime
+		uploaded[i], downloaded[i] = t.Uploaded, t.Downloaded
+		ratios[i] = t.Ratio
+		seeding[i] = isSeeding(t)
+	}
+
+	_, err := db.ExecContext(ctx,
+		`UPDATE torrents t SET
+			seeding_time_seconds = c.seeding_time_seconds,
+			upload_bytes = c.upload_bytes,
+			download_bytes = c.download_bytes,
+			ratio = c.ratio,
+			is_seeding = c.is_seeding,
+			sync_generation = COALESCE(NULLIF($7::bigint, 0), t.sync_generation),
+			missing_since = NULL,
+			upload_rate = CASE
+				-- Skip the sample if counters were reset or no time has passed
+				WHEN t.last_synced_at IS NULL OR c.upload_bytes < t.upload_bytes OR CURRENT_TIMESTAMP <= t.last_synced_at
+					THEN t.upload_rate
+				WHEN t.upload_rate IS NULL
+					THEN (c.upload_bytes - t.upload_bytes) / EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - t.last_synced_at)
+				-- Average with the previous rate so one idle interval doesn't zero it
+				ELSE (t.upload_rate + (c.upload_bytes - t.upload_bytes) / EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - t.last_synced_at)) / 2
+			END,
+			last_synced_at = CURRENT_TIMESTAMP
+		FROM UNNEST($1::text[], $2::bigint[], $3::bigint[], $4::bigint[], $5::float8[], $6::bool[])
+			AS c(hash, seeding_time_seconds, upload_bytes, download_bytes, ratio, is_seeding)
+		WHERE t.hash = c.hash`,
+		hashes, seedingTimes, uploaded, downloaded, ratios, seeding, generation,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update torrent counters: %w", err)
+	}
 	return nil
 }
 
 // logUnlinkedTorrents logs statistics about unlinked torrents for debugging
+// Ignored torrents are not counted
 func (s *TorrentSyncService) logUnlinkedTorrents(ctx context.Context) {
 	var unlinkedCount int
 	err := s.db.QueryRowContext(ctx,
-		"SELECT COUNT(*) FROM torrents WHERE media_item_id IS NULL",
+		"SELECT COUNT(*) FROM torrents WHERE media_item_id IS NULL AND NOT is_ignored AND missing_since IS NULL",
 	).Scan(&unlinkedCount)
 	if err == nil && unlinkedCount > 0 {
-		slog.Warn("Unlinked torrents detected", "count", unlinkedCount,
-			"hint", "Torrents may not match media item file paths. Check file path configurations.")
+		slog.WarnContext(ctx, "Unlinked torrents detected", "count", unlinkedCount,
+			"hint", "Link them manually from Admin > Torrents, or check file path configurations.")
 	}
 }
 
@@ -327,4 +666,3 @@ func (s *TorrentSyncService) isPublicTracker(trackerURL string) bool {
 
 	return false
 }
-
@@ -0,0 +1,368 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is marked failed
+	MaxAttempts = 6
+	// retryBase is the wait before the first retry; each later retry waits 4 times longer (30s, 2m, 8m, 32m, ~2h)
+	retryBase = 30 * time.Second
+	// attemptLease keeps other workers from picking up a delivery while it is being attempted
+	attemptLease = time.Minute
+	// retryInterval is how often due retries are looked for
+	retryInterval = 30 * time.Second
+	// deliveryRetention is how long the delivery log is kept
+	deliveryRetention = 30 * 24 * time.Hour
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Delivery is one event sent (or being sent) to one webhook
+type Delivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	WebhookName    string          `json:"webhook_name"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"response_status,omitempty"` // HTTP status of the last attempt
+	LastError      string          `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// DeliveryFilter narrows Deliveries; zero values match everything
+type DeliveryFilter struct {
+	WebhookID int
+	Status    string
+	Limit     int // defaults to 100
+}
+
+const deliveryColumns = `
+	d.id, d.webhook_id, w.name, d.event, d.payload, d.status, d.attempts, d.response_status,
+	COALESCE(d.last_error, ''), d.next_attempt_at, d.delivered_at, d.created_at
+	FROM webhook_deliveries d
+	JOIN webhooks w ON w.id = d.webhook_id`
+
+// scanDelivery scans a row selected with deliveryColumns
+func scanDelivery(row interface{ Scan(...interface{}) error }) (*Delivery, error) {
+	var (
+		delivery       Delivery
+		payload        []byte
+		responseStatus sql.NullInt64
+		nextAttemptAt  sql.NullTime
+		deliveredAt    sql.NullTime
+		createdAt      sql.NullTime
+	)
+	if err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.WebhookName, &delivery.Event, &payload,
+		&delivery.Status, &delivery.Attempts, &responseStatus, &delivery.LastError, &nextAttemptAt,
+		&deliveredAt, &createdAt); err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = payload
+	if responseStatus.Valid {
+		status := int(responseStatus.Int64)
+		delivery.ResponseStatus = &status
+	}
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	delivery.CreatedAt = createdAt.Time
+	return &delivery, nil
+}
+
+// GetDelivery returns a delivery
+func (s *Service) GetDelivery(ctx context.Context, id int64) (*Delivery, error) {
+	delivery, err := scanDelivery(s.db.QueryRowContext(ctx, "SELECT "+deliveryColumns+" WHERE d.id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("delivery %d: %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// Deliveries returns the delivery log, newest first
+func (s *Service) Deliveries(ctx context.Context, filter DeliveryFilter) ([]Delivery, error) {
+	query := "SELECT " + deliveryColumns + " WHERE 1=1"
+	args := []interface{}{}
+	if filter.WebhookID != 0 {
+		args = append(args, filter.WebhookID)
+		query += fmt.Sprintf(" AND d.webhook_id = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND d.status = $%d", len(args))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY d.created_at DESC, d.id DESC LIMIT $%d", len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// Redeliver sends a delivery again right away, with a fresh set of attempts
+func (s *Service) Redeliver(ctx context.Context, id int64) (*Delivery, error) {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET
+			status = 'pending',
+			attempts = 0,
+			next_attempt_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeliver: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("delivery %d: %w", id, ErrNotFound)
+	}
+	s.attempt(ctx, id)
+	return s.GetDelivery(ctx, id)
+}
+
+// enqueue records a pending delivery, due right away
+func (s *Service) enqueue(ctx context.Context, webhookID int, event string, payload []byte) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		RETURNING id`,
+		webhookID, event, string(payload),
+	).Scan(&id)
+	return id, err
+}
+
+// attempt sends a due delivery once and records the outcome
+// The delivery is claimed first, so a retry running at the same time doesn't send it twice
+func (s *Service) attempt(ctx context.Context, id int64) {
+	var (
+		webhookURL string
+		secret     string
+		event      string
+		payload    []byte
+		attempts   int
+	)
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE webhook_deliveries d SET
+			next_attempt_at = CURRENT_TIMESTAMP + $2::bigint * INTERVAL '1 second'
+		FROM webhooks w
+		WHERE d.id = $1 AND w.id = d.webhook_id AND d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP
+		RETURNING w.url, w.secret, d.event, d.payload, d.attempts`,
+		id, int64(attemptLease/time.Second),
+	).Scan(&webhookURL, &secret, &event, &payload, &attempts)
+	if err == sql.ErrNoRows {
+		return // not due, or claimed by someone else
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to claim webhook delivery", "delivery_id", id, "error", err)
+		return
+	}
+
+	statusCode, sendErr := send(ctx, webhookURL, secret, id, event, payload)
+	attempts++
+
+	var responseStatus interface{}
+	if statusCode != 0 {
+		responseStatus = statusCode
+	}
+	if sendErr == nil {
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE webhook_deliveries SET
+				status = 'succeeded',
+				attempts = $2,
+				response_status = $3,
+				last_error = NULL,
+				next_attempt_at = NULL,
+				delivered_at = CURRENT_TIMESTAMP,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1`,
+			id, attempts, responseStatus,
+		)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to record webhook delivery", "delivery_id", id, "error", err)
+		}
+		slog.DebugContext(ctx, "Delivered webhook", "delivery_id", id, "event", event, "status", statusCode)
+		return
+	}
+
+	status := DeliveryPending
+	if attempts >= MaxAttempts {
+		status = DeliveryFailed
+	}
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET
+			status = $2,
+			attempts = $3,
+			response_status = $4,
+			last_error = $5,
+			next_attempt_at = CASE WHEN $2::text = 'pending' THEN CURRENT_TIMESTAMP + $6::bigint * INTERVAL '1 second' END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		id, status, attempts, responseStatus, sendErr.Error(), int64(retryDelay(attempts)/time.Second),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record webhook delivery", "delivery_id", id, "error", err)
+	}
+	slog.WarnContext(ctx, "Webhook delivery failed", "delivery_id", id, "event", event, "attempt", attempts, "status", status, "error", sendErr)
+}
+
+// retryDelay is the wait after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return retryBase << (2 * (attempts - 1))
+}
+
+// Sign returns the X-Removarr-Signature-256 header value for a body: the hex HMAC-SHA256 of the body
+// keyed with the webhook secret, prefixed with "sha256=". Receivers recompute it to check the request.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// send posts a payload to a webhook URL; any non-2xx response is an error
+// Returns the response status, 0 if there was no response
+func send(ctx context.Context, webhookURL, secret string, deliveryID int64, event string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "removarr-webhooks")
+	req.Header.Set("X-Removarr-Event", event)
+	req.Header.Set("X-Removarr-Delivery", strconv.FormatInt(deliveryID, 10))
+	req.Header.Set("X-Removarr-Signature-256", Sign(secret, payload))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("%s - %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp.StatusCode, nil
+}
+
+// RetryDue attempts every pending delivery whose retry is due, for enabled webhooks
+func (s *Service) RetryDue(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT d.id FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP AND w.enabled
+		ORDER BY d.next_attempt_at
+		LIMIT 100`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query due deliveries: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query due deliveries: %w", err)
+	}
+
+	for _, id := range ids {
+		s.attempt(ctx, id)
+	}
+	return nil
+}
+
+// pruneDeliveries removes finished deliveries older than the retention period
+func (s *Service) pruneDeliveries(ctx context.Context) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < CURRENT_TIMESTAMP - $1::bigint * INTERVAL '1 second'`,
+		int64(deliveryRetention/time.Second),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to prune deliveries: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		slog.InfoContext(ctx, "Pruned webhook delivery log", "deleted", n)
+	}
+	return nil
+}
+
+// Run retries failed deliveries and prunes the delivery log until ctx is cancelled
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+	var lastPrune time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.RetryDue(ctx); err != nil {
+				slog.ErrorContext(ctx, "Failed to retry webhook deliveries", "error", err)
+			}
+			if time.Since(lastPrune) >= time.Hour {
+				if err := s.pruneDeliveries(ctx); err != nil {
+					slog.ErrorContext(ctx, "Failed to prune webhook deliveries", "error", err)
+				}
+				lastPrune = time.Now()
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"ping"}`)
+	want := "sha256=4f4bb3a54e99c4a20e243485229f9b08c66e09104ba6f79c23ce647242a4ce84"
+	if got := Sign("secret", body); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+	if Sign("other", body) == want {
+		t.Error("signature doesn't depend on the secret")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	want := []time.Duration{30 * time.Second, 2 * time.Minute, 8 * time.Minute, 32 * time.Minute, 128 * time.Minute}
+	for i, delay := range want {
+		if got := retryDelay(i + 1); got != delay {
+			t.Errorf("retryDelay(%d) = %v, want %v", i+1, got, delay)
+		}
+	}
+	if got := retryDelay(0); got != retryBase {
+		t.Errorf("retryDelay(0) = %v, want %v", got, retryBase)
+	}
+}
+
+func TestSend(t *testing.T) {
+	payload := []byte(`{"event":"media.deleted"}`)
+	var got *http.Request
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := send(context.Background(), server.URL, "secret", 42, "media.deleted", payload)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("send() = %d, %v, want 204, nil", status, err)
+	}
+	if event := got.Header.Get("X-Removarr-Event"); event != "media.deleted" {
+		t.Errorf("X-Removarr-Event = %q", event)
+	}
+	if id := got.Header.Get("X-Removarr-Delivery"); id != "42" {
+		t.Errorf("X-Removarr-Delivery = %q", id)
+	}
+
+	// What a receiver does to check the request
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(gotBody)
+	if signature := got.Header.Get("X-Removarr-Signature-256"); signature != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("signature %q doesn't match the body received", signature)
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	status, err := send(context.Background(), server.URL, "secret", 1, PingEvent, []byte(`{}`))
+	if err == nil || status != http.StatusBadGateway {
+		t.Errorf("send() = %d, %v, want 502 and an error", status, err)
+	}
+}
@@ -0,0 +1,319 @@
+// Package webhooks posts removarr events to external URLs, e.g. Home Assistant or scripts
+// Each webhook subscribes to event names (media.deleted, sync.failed...) and receives a signed JSON Payload;
+// failed deliveries are retried with backoff and every delivery is kept in a log for the admin UI
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"removarr/internal/events"
+)
+
+// eventNames maps event types to the names webhooks subscribe to
+var eventNames = map[events.Type]string{
+	events.MediaDeleted:      "media.deleted",
+	events.MediaEligible:     "media.eligible",
+	events.DeletionScheduled: "deletion.scheduled",
+	events.SyncCompleted:     "sync.completed",
+	events.SyncFailed:        "sync.failed",
+	events.TorrentUnlinked:   "torrent.unlinked",
+}
+
+// PingEvent is the event name of test deliveries
+const PingEvent = "ping"
+
+// EventNames lists every event name webhooks can subscribe to, in display order
+func EventNames() []string {
+	names := make([]string, 0, len(events.AllTypes))
+	for _, eventType := range events.AllTypes {
+		if name, ok := eventNames[eventType]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isEventName reports whether name is an event webhooks can subscribe to
+func isEventName(name string) bool {
+	for _, known := range eventNames {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNotFound is returned for a webhook or delivery that doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// Webhook is an external URL that receives the events it subscribes to
+type Webhook struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"` // HMAC-SHA256 key for the X-Removarr-Signature-256 header
+	Events    []string  `json:"events"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the webhook can be saved
+func (w *Webhook) Validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL must be an http(s) URL")
+	}
+	for _, name := range w.Events {
+		if !isEventName(name) {
+			return fmt.Errorf("unknown event: %s", name)
+		}
+	}
+	return nil
+}
+
+// Payload is the JSON body of every webhook request
+type Payload struct {
+	ID        string      `json:"id"`    // unique per event, the same for every webhook it is delivered to
+	Event     string      `json:"event"` // e.g. media.deleted
+	Timestamp time.Time   `json:"timestamp"`
+	Data      PayloadData `json:"data"`
+}
+
+// PayloadData is what happened; fields that don't apply to the event are omitted
+type PayloadData struct {
+	Media       *PayloadMedia          `json:"media,omitempty"`
+	RequesterID *int                   `json:"requester_id,omitempty"` // user who requested the media
+	ActorID     *int                   `json:"actor_id,omitempty"`     // user whose action caused the event
+	Message     string                 `json:"message,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty"` // event specific, e.g. execute_after or torrents
+}
+
+// PayloadMedia is the media item an event is about
+type PayloadMedia struct {
+	ID    *int   `json:"id,omitempty"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+// newPayload builds the payload for an event
+func newPayload(name string, event events.Event) Payload {
+	payload := Payload{
+		ID:        newID(),
+		Event:     name,
+		Timestamp: event.Time.UTC(),
+		Data: PayloadData{
+			RequesterID: event.RequesterID,
+			ActorID:     event.ActorID,
+			Message:     event.Message,
+			Details:     event.Data,
+		},
+	}
+	if event.MediaTitle != "" {
+		payload.Data.Media = &PayloadMedia{ID: event.MediaItemID, Title: event.MediaTitle, Type: event.MediaType}
+	}
+	return payload
+}
+
+// newID returns a random identifier for payloads and secrets
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Service manages webhooks and delivers events to them
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+const webhookColumns = `id, name, url, secret, events, enabled, created_at FROM webhooks`
+
+// scanWebhook scans a row selected with webhookColumns
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*Webhook, error) {
+	var (
+		webhook   Webhook
+		rawEvents []byte
+		createdAt sql.NullTime
+	)
+	if err := row.Scan(&webhook.ID, &webhook.Name, &webhook.URL, &webhook.Secret, &rawEvents,
+		&webhook.Enabled, &createdAt); err != nil {
+		return nil, err
+	}
+	webhook.Events = []string{}
+	json.Unmarshal(rawEvents, &webhook.Events)
+	webhook.CreatedAt = createdAt.Time
+	return &webhook, nil
+}
+
+// List returns every webhook
+func (s *Service) List(ctx context.Context) ([]Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+webhookColumns+" ORDER BY name, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// Get returns a webhook
+func (s *Service) Get(ctx context.Context, id int) (*Webhook, error) {
+	webhook, err := scanWebhook(s.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook %d: %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// Create saves a new webhook, generating its secret if none is given
+func (s *Service) Create(ctx context.Context, webhook Webhook) (*Webhook, error) {
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = newID()
+	}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	eventsJSON, _ := json.Marshal(webhook.Events)
+
+	var id int
+	if err := s.db.QueryRowContext(ctx,
+		`INSERT INTO webhooks (name, url, secret, events, enabled) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		strings.TrimSpace(webhook.Name), webhook.URL, webhook.Secret, string(eventsJSON), webhook.Enabled,
+	).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	slog.InfoContext(ctx, "Created webhook", "webhook_id", id, "name", webhook.Name, "events", webhook.Events)
+	return s.Get(ctx, id)
+}
+
+// Update saves changes to a webhook; an empty secret keeps the current one
+func (s *Service) Update(ctx context.Context, id int, webhook Webhook) (*Webhook, error) {
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	eventsJSON, _ := json.Marshal(webhook.Events)
+
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE webhooks SET
+			name = $2,
+			url = $3,
+			secret = COALESCE(NULLIF($4, ''), secret),
+			events = $5,
+			enabled = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		id, strings.TrimSpace(webhook.Name), webhook.URL, webhook.Secret, string(eventsJSON), webhook.Enabled,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("webhook %d: %w", id, ErrNotFound)
+	}
+	slog.InfoContext(ctx, "Updated webhook", "webhook_id", id, "events", webhook.Events, "enabled", webhook.Enabled)
+	return s.Get(ctx, id)
+}
+
+// Delete removes a webhook and its delivery log
+func (s *Service) Delete(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("webhook %d: %w", id, ErrNotFound)
+	}
+	slog.InfoContext(ctx, "Deleted webhook", "webhook_id", id)
+	return nil
+}
+
+// Handle delivers an event to every enabled webhook subscribed to it
+// It is subscribed to the event bus
+func (s *Service) Handle(ctx context.Context, event events.Event) {
+	name, ok := eventNames[event.Type]
+	if !ok {
+		return
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id FROM webhooks WHERE enabled AND events @> jsonb_build_array($1::text)`,
+		name,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get webhooks", "event", name, "error", err)
+		return
+	}
+	var webhookIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			webhookIDs = append(webhookIDs, id)
+		}
+	}
+	rows.Close()
+	if len(webhookIDs) == 0 {
+		return
+	}
+
+	payload, _ := json.Marshal(newPayload(name, event))
+	for _, webhookID := range webhookIDs {
+		deliveryID, err := s.enqueue(ctx, webhookID, name, payload)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to queue webhook delivery", "webhook_id", webhookID, "event", name, "error", err)
+			continue
+		}
+		s.attempt(ctx, deliveryID)
+	}
+}
+
+// Ping sends a test delivery to a webhook right away, and returns it with the outcome
+func (s *Service) Ping(ctx context.Context, webhookID int) (*Delivery, error) {
+	if _, err := s.Get(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	payload, _ := json.Marshal(Payload{
+		ID:        newID(),
+		Event:     PingEvent,
+		Timestamp: time.Now().UTC(),
+		Data:      PayloadData{Message: "removarr webhook test"},
+	})
+	deliveryID, err := s.enqueue(ctx, webhookID, PingEvent, payload)
+	if err != nil {
+		return nil, err
+	}
+	s.attempt(ctx, deliveryID)
+	return s.GetDelivery(ctx, deliveryID)
+}
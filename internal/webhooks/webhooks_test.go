@@ -0,0 +1,40 @@
+package webhooks
+
+import "testing"
+
+func TestWebhookValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook Webhook
+		wantErr bool
+	}{
+		{"valid", Webhook{Name: "Discord relay", URL: "https://example.com/hook", Events: []string{"media.deleted"}}, false},
+		{"no events", Webhook{Name: "All", URL: "http://localhost:9000"}, false},
+		{"missing name", Webhook{Name: " ", URL: "https://example.com/hook"}, true},
+		{"not http", Webhook{Name: "FTP", URL: "ftp://example.com/hook"}, true},
+		{"no host", Webhook{Name: "Relative", URL: "/hook"}, true},
+		{"unknown event", Webhook{Name: "Typo", URL: "https://example.com/hook", Events: []string{"media.deletd"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.webhook.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEventNames(t *testing.T) {
+	names := EventNames()
+	if len(names) == 0 {
+		t.Fatal("no event names")
+	}
+	for _, name := range names {
+		if !isEventName(name) {
+			t.Errorf("%s is listed but not accepted", name)
+		}
+	}
+	if isEventName(PingEvent) {
+		t.Error("ping can't be subscribed to")
+	}
+}
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_torrents_unlinked;
+
+ALTER TABLE torrents DROP COLUMN IF EXISTS is_ignored;
+ALTER TABLE torrents DROP COLUMN IF EXISTS link_source;
+ALTER TABLE torrents DROP COLUMN IF EXISTS tracker_url;
+ALTER TABLE torrents DROP COLUMN IF EXISTS size_bytes;
+ALTER TABLE torrents DROP COLUMN IF EXISTS content_path;
+ALTER TABLE torrents DROP COLUMN IF EXISTS name;
@@ -0,0 +1,14 @@
+-- Store the qBittorrent details needed to link torrents by hand
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS name TEXT;
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS content_path TEXT;
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS size_bytes BIGINT DEFAULT 0;
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS tracker_url TEXT;
+
+-- How the torrent was linked to its media item: 'auto' (sync matching) or 'manual' (admin)
+-- Manual links (including manual unlinks) are never overwritten by sync
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS link_source VARCHAR(20) NOT NULL DEFAULT 'auto';
+
+-- Ignored torrents are hidden from the unlinked torrents list
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS is_ignored BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_torrents_unlinked ON torrents(media_item_id) WHERE media_item_id IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_torrents_cross_seed_group;
+
+ALTER TABLE torrents DROP COLUMN IF EXISTS cross_seed_group;
@@ -0,0 +1,5 @@
+-- Torrents that share the same data (cross-seeds to several trackers) get the same group ID
+-- NULL means the torrent does not share its data with any other torrent
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS cross_seed_group VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_torrents_cross_seed_group ON torrents(cross_seed_group) WHERE cross_seed_group IS NOT NULL;
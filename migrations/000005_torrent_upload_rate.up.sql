@@ -0,0 +1,2 @@
+-- Average upload rate observed between syncs, used to estimate when ratio requirements will be met
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS upload_rate DOUBLE PRECISION; -- bytes per second
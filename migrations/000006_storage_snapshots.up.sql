@@ -0,0 +1,14 @@
+-- Periodic snapshots of library, torrent and disk usage for the statistics page
+CREATE TABLE IF NOT EXISTS storage_snapshots (
+    id SERIAL PRIMARY KEY,
+    taken_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    library_bytes BIGINT NOT NULL DEFAULT 0,
+    library_bytes_by_type JSONB, -- {"movie": bytes, "series": bytes}
+    torrent_count INTEGER NOT NULL DEFAULT 0,
+    torrent_bytes BIGINT NOT NULL DEFAULT 0,
+    trackers JSONB, -- {"tracker name": {"count": n, "bytes": bytes}}
+    eligible_bytes BIGINT NOT NULL DEFAULT 0, -- library bytes eligible for deletion
+    disks JSONB -- {"/path": {"total_bytes": bytes, "free_bytes": bytes}}
+);
+
+CREATE INDEX IF NOT EXISTS idx_storage_snapshots_taken_at ON storage_snapshots(taken_at);
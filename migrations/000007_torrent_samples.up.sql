@@ -0,0 +1,17 @@
+-- Time series of torrent counters, recorded on every qBittorrent sync
+-- Raw samples are downsampled to hourly and then daily samples as they age (see TorrentSyncService.compactSamples)
+CREATE TABLE IF NOT EXISTS torrent_samples (
+    id BIGSERIAL PRIMARY KEY,
+    torrent_hash VARCHAR(64) NOT NULL REFERENCES torrents(hash) ON DELETE CASCADE,
+    sampled_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolution VARCHAR(10) NOT NULL DEFAULT 'raw', -- 'raw', 'hour' or 'day'
+    upload_bytes BIGINT NOT NULL DEFAULT 0,
+    download_bytes BIGINT NOT NULL DEFAULT 0,
+    ratio DECIMAL(10, 2) DEFAULT 0,
+    seeding_time_seconds BIGINT DEFAULT 0,
+    swarm_seeders INTEGER, -- seeders in the swarm as reported by the tracker
+    swarm_leechers INTEGER -- leechers in the swarm as reported by the tracker
+);
+
+CREATE INDEX IF NOT EXISTS idx_torrent_samples_hash_time ON torrent_samples(torrent_hash, sampled_at);
+CREATE INDEX IF NOT EXISTS idx_torrent_samples_resolution_time ON torrent_samples(resolution, sampled_at);
@@ -0,0 +1 @@
+ALTER TABLE media_items DROP COLUMN IF EXISTS overseerr_request_open;
@@ -0,0 +1,2 @@
+-- Whether the Overseerr request linked to a media item is still waiting to be fulfilled
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS overseerr_request_open BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_media_items_protected;
+ALTER TABLE media_items DROP COLUMN IF EXISTS protected_by_tag;
+ALTER TABLE media_items DROP COLUMN IF EXISTS protected_by_user_id;
+ALTER TABLE media_items DROP COLUMN IF EXISTS protection_note;
+ALTER TABLE media_items DROP COLUMN IF EXISTS protected_until;
+ALTER TABLE media_items DROP COLUMN IF EXISTS is_protected;
@@ -0,0 +1,9 @@
+-- Protected media items are never deleted
+-- Manual protection is set from removarr and may expire; tag protection follows the Radarr/Sonarr tag on each sync
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS is_protected BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS protected_until TIMESTAMP; -- NULL means no expiry
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS protection_note TEXT;
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS protected_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS protected_by_tag BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_media_items_protected ON media_items(id) WHERE is_protected OR protected_by_tag;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_media_items_genres;
+DROP INDEX IF EXISTS idx_media_items_tags;
+ALTER TABLE media_items DROP COLUMN IF EXISTS quality;
+ALTER TABLE media_items DROP COLUMN IF EXISTS quality_profile;
+ALTER TABLE media_items DROP COLUMN IF EXISTS rating;
+ALTER TABLE media_items DROP COLUMN IF EXISTS year;
+ALTER TABLE media_items DROP COLUMN IF EXISTS genres;
+ALTER TABLE media_items DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,10 @@
+-- Descriptive metadata synced from Radarr/Sonarr, for rules, filters and protection
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]'; -- tag labels
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS genres JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS year INTEGER;
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS rating DOUBLE PRECISION; -- 0-10, IMDb first for movies
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS quality_profile VARCHAR(255);
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS quality VARCHAR(100); -- quality of the file on disk (Radarr only), e.g. 'Remux-2160p'
+
+CREATE INDEX IF NOT EXISTS idx_media_items_tags ON media_items USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_media_items_genres ON media_items USING GIN (genres);
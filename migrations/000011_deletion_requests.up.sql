@@ -0,0 +1,26 @@
+-- Users flag media they are done with; admins approve or reject, and approved requests are
+-- executed once the media item is eligible for deletion
+-- The media title and type are copied so executed requests still show what was deleted
+CREATE TABLE IF NOT EXISTS deletion_requests (
+    id SERIAL PRIMARY KEY,
+    media_item_id INTEGER REFERENCES media_items(id) ON DELETE SET NULL,
+    media_title VARCHAR(500) NOT NULL,
+    media_type VARCHAR(50) NOT NULL,
+    requested_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'requested', -- 'requested', 'approved', 'rejected' or 'executed'
+    note TEXT,
+    reviewed_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    review_note TEXT,
+    executed_at TIMESTAMP,
+    last_error TEXT, -- why the last execution attempt failed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT deletion_requests_status_check CHECK (status IN ('requested', 'approved', 'rejected', 'executed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_deletion_requests_status ON deletion_requests(status);
+CREATE INDEX IF NOT EXISTS idx_deletion_requests_user ON deletion_requests(requested_by_user_id);
+-- At most one open request per media item
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deletion_requests_open ON deletion_requests(media_item_id)
+    WHERE status IN ('requested', 'approved');
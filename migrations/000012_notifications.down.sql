@@ -0,0 +1,2 @@
+ALTER TABLE media_items DROP COLUMN IF EXISTS eligible_since;
+ALTER TABLE users DROP COLUMN IF EXISTS notification_events;
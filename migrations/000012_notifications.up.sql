@@ -0,0 +1,6 @@
+-- Event types each user wants emailed, e.g. ["media_deleted", "media_eligible"]
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notification_events JSONB NOT NULL DEFAULT '[]';
+
+-- When the media item last became eligible for deletion, NULL while it isn't
+-- Used to notify once per transition rather than on every sync
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS eligible_since TIMESTAMP;
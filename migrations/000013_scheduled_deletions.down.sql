@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS scheduled_deletions;
+ALTER TABLE media_items DROP COLUMN IF EXISTS requester_email;
@@ -0,0 +1,28 @@
+-- Email of the Overseerr user who requested the media, for deletion heads-ups
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS requester_email VARCHAR(255);
+
+-- Deletions that run once their grace period is over, unless postponed or cancelled
+-- The requester is notified when the deletion is scheduled, with a link to postpone it or keep the media
+CREATE TABLE IF NOT EXISTS scheduled_deletions (
+    id SERIAL PRIMARY KEY,
+    media_item_id INTEGER REFERENCES media_items(id) ON DELETE SET NULL,
+    media_title VARCHAR(500) NOT NULL,
+    media_type VARCHAR(50) NOT NULL,
+    scheduled_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled', -- 'scheduled', 'cancelled' or 'executed'
+    execute_after TIMESTAMP NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE, -- authorizes the postpone/keep link sent to the requester
+    notified_email VARCHAR(255), -- where the heads-up was sent
+    postponed_count INTEGER NOT NULL DEFAULT 0,
+    cancel_reason TEXT,
+    last_error TEXT, -- why the last execution attempt failed
+    executed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT scheduled_deletions_status_check CHECK (status IN ('scheduled', 'cancelled', 'executed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_deletions_due ON scheduled_deletions(execute_after) WHERE status = 'scheduled';
+-- At most one pending deletion per media item
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_deletions_open ON scheduled_deletions(media_item_id)
+    WHERE status = 'scheduled';
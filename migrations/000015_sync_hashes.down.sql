@@ -0,0 +1,2 @@
+ALTER TABLE torrents DROP COLUMN IF EXISTS sync_hash;
+ALTER TABLE media_items DROP COLUMN IF EXISTS sync_hash;
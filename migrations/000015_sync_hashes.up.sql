@@ -0,0 +1,3 @@
+-- Fingerprints of the fields each sync writes, so rows that haven't changed since the last sync are skipped
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS sync_hash VARCHAR(64);
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS sync_hash VARCHAR(64);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_torrents_missing;
+DROP INDEX IF EXISTS idx_media_items_missing;
+ALTER TABLE torrents DROP COLUMN IF EXISTS missing_since;
+ALTER TABLE torrents DROP COLUMN IF EXISTS sync_generation;
+ALTER TABLE media_items DROP COLUMN IF EXISTS missing_since;
+ALTER TABLE media_items DROP COLUMN IF EXISTS sync_generation;
+DROP TABLE IF EXISTS sync_generations;
@@ -0,0 +1,15 @@
+-- Full sync generation per source (sonarr, radarr, qbittorrent)
+-- Each successful full sync bumps it and stamps the rows it saw; rows left behind are marked missing
+CREATE TABLE IF NOT EXISTS sync_generations (
+    source VARCHAR(50) PRIMARY KEY,
+    generation BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS sync_generation BIGINT;
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS missing_since TIMESTAMP;
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS sync_generation BIGINT;
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS missing_since TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_media_items_missing ON media_items(missing_since) WHERE missing_since IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_torrents_missing ON torrents(missing_since) WHERE missing_since IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sync_runs_started;
+DROP INDEX IF EXISTS idx_sync_runs_source;
+DROP TABLE IF EXISTS sync_runs;
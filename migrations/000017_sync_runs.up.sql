@@ -0,0 +1,19 @@
+-- One row per source per full sync run, for the sync status
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id BIGSERIAL PRIMARY KEY,
+    source VARCHAR(50) NOT NULL, -- 'sonarr', 'radarr', 'overseerr', 'tautulli' or 'qbittorrent'
+    trigger VARCHAR(50) NOT NULL, -- what started the run, e.g. 'periodic', 'manual', 'dashboard'
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP, -- NULL while running
+    duration_ms BIGINT,
+    error TEXT,
+    rows_seen INTEGER NOT NULL DEFAULT 0,
+    rows_added INTEGER NOT NULL DEFAULT 0,
+    rows_updated INTEGER NOT NULL DEFAULT 0,
+    rows_unchanged INTEGER NOT NULL DEFAULT 0,
+    rows_failed INTEGER NOT NULL DEFAULT 0,
+    rows_missing INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_runs_source ON sync_runs(source, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_sync_runs_started ON sync_runs(started_at);
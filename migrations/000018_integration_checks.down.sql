@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_integration_checks_checked;
+DROP INDEX IF EXISTS idx_integration_checks_integration;
+DROP TABLE IF EXISTS integration_checks;
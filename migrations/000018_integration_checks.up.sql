@@ -0,0 +1,13 @@
+-- Results of the periodic integration health checks
+CREATE TABLE IF NOT EXISTS integration_checks (
+    id BIGSERIAL PRIMARY KEY,
+    integration VARCHAR(50) NOT NULL, -- 'overseerr', 'sonarr', 'radarr', 'prowlarr', 'qbittorrent', 'tautulli' or 'plex'
+    checked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status VARCHAR(20) NOT NULL, -- 'ok', 'unauthorized', 'unreachable' or 'error'
+    version VARCHAR(100), -- reported by the integration, NULL unless the check succeeded
+    latency_ms BIGINT NOT NULL,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_integration_checks_integration ON integration_checks(integration, checked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_integration_checks_checked ON integration_checks(checked_at);
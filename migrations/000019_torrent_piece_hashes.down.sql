@@ -0,0 +1 @@
+ALTER TABLE torrents DROP COLUMN IF EXISTS piece_hash;
@@ -0,0 +1,3 @@
+-- Digest of a torrent's piece hashes, fetched once for cross-seed detection
+-- Piece hashes never change for a torrent hash, so they are not fetched again
+ALTER TABLE torrents ADD COLUMN IF NOT EXISTS piece_hash VARCHAR(64);
@@ -0,0 +1,3 @@
+UPDATE audit_logs
+SET details = details->'message'
+WHERE jsonb_typeof(details) = 'object' AND details ? 'message' AND details - 'message' = '{}'::jsonb;
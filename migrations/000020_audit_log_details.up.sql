@@ -0,0 +1,5 @@
+-- Audit log details are a JSON object; the first deletions recorded a plain message instead
+-- Keep that message under "message" so every row can be read the same way
+UPDATE audit_logs
+SET details = jsonb_build_object('message', details #>> '{}')
+WHERE details IS NOT NULL AND jsonb_typeof(details) <> 'object';
@@ -0,0 +1 @@
+ALTER TABLE media_items DROP COLUMN IF EXISTS protected_by_admin;
@@ -0,0 +1,7 @@
+-- Whether an admin set the manual protection; only admins may replace or clear it
+-- Kept as a flag so it doesn't change if the user loses admin rights or is deleted
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS protected_by_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE media_items m SET protected_by_admin = TRUE
+FROM users u
+WHERE u.id = m.protected_by_user_id AND u.is_admin AND m.is_protected;
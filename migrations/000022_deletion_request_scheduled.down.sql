@@ -0,0 +1,5 @@
+UPDATE deletion_requests SET status = 'executed', executed_at = COALESCE(executed_at, updated_at) WHERE status = 'scheduled';
+
+ALTER TABLE deletion_requests DROP CONSTRAINT IF EXISTS deletion_requests_status_check;
+ALTER TABLE deletion_requests ADD CONSTRAINT deletion_requests_status_check
+    CHECK (status IN ('requested', 'approved', 'rejected', 'executed'));
@@ -0,0 +1,11 @@
+-- An approved request whose deletion waits for a grace period is done once the deletion is scheduled;
+-- the scheduled deletion takes it from there, so the request no longer stays approved until the media is gone
+ALTER TABLE deletion_requests DROP CONSTRAINT IF EXISTS deletion_requests_status_check;
+ALTER TABLE deletion_requests ADD CONSTRAINT deletion_requests_status_check
+    CHECK (status IN ('requested', 'approved', 'rejected', 'scheduled', 'executed'));
+
+UPDATE deletion_requests d SET status = 'scheduled', last_error = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE d.status = 'approved' AND EXISTS (
+    SELECT 1 FROM scheduled_deletions sd
+    WHERE sd.media_item_id = d.media_item_id AND sd.created_at >= d.reviewed_at
+);
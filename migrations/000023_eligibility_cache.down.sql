@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_media_items_score;
+DROP INDEX IF EXISTS idx_media_items_estimated_eligible_at;
+ALTER TABLE media_items DROP COLUMN IF EXISTS score;
+ALTER TABLE media_items DROP COLUMN IF EXISTS estimated_eligible_at;
@@ -0,0 +1,7 @@
+-- Eligibility estimate and dead weight score of each media item, refreshed after every sync
+-- so the dashboard can sort and filter by them in SQL instead of checking every item's eligibility
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS estimated_eligible_at TIMESTAMP; -- NULL while eligible or if there is no estimate
+ALTER TABLE media_items ADD COLUMN IF NOT EXISTS score DOUBLE PRECISION; -- NULL if not scored, e.g. protected
+
+CREATE INDEX IF NOT EXISTS idx_media_items_estimated_eligible_at ON media_items(estimated_eligible_at);
+CREATE INDEX IF NOT EXISTS idx_media_items_score ON media_items(score);